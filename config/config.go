@@ -0,0 +1,265 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config là toàn bộ cấu hình của app, được load một lần lúc khởi động.
+type Config struct {
+	Port            int
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	HTTP            HTTPConfig
+	DB              DBConfig
+	Log             LogConfig
+	Health          HealthConfig
+
+	// MigrateOnStart chạy "migrate up" trước khi server nhận request
+	MigrateOnStart bool
+	// LowStockThreshold: sách có stock <= ngưỡng này được báo trong metric low-stock
+	LowStockThreshold int
+	// IdempotencyTTL là thời gian giữ Idempotency-Key của POST /orders; hết hạn thì key được dùng lại
+	IdempotencyTTL time.Duration
+	Reservation    ReservationConfig
+	// RedisAddr (host:port) bật kiểm tra Redis trong /readyz; để trống thì bỏ qua
+	RedisAddr string
+
+	// Args là các tham số còn lại sau flags (vd: subcommand).
+	Args []string
+}
+
+type ReservationConfig struct {
+	// TTL là thời gian giữ hàng khi client không gửi ttl_seconds, MaxTTL là giới hạn trên
+	TTL    time.Duration
+	MaxTTL time.Duration
+	// SweepInterval là chu kỳ đánh dấu expired các reservation đã quá hạn
+	SweepInterval time.Duration
+}
+
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+}
+
+type HealthConfig struct {
+	// ReadinessTimeout là thời gian tối đa cho tất cả check của một lần gọi /readyz
+	ReadinessTimeout time.Duration
+	// DrainDelay là thời gian /readyz báo "draining" trước khi server ngừng nhận kết nối,
+	// để load balancer kịp gỡ instance ra khỏi pool
+	DrainDelay time.Duration
+}
+
+type LogConfig struct {
+	Level slog.Level
+	// Format là "json" (mặc định) hoặc "text"
+	Format string
+}
+
+// Addr trả về địa chỉ listen cho http.Server.
+func (c *Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// ValidationError liệt kê tất cả key thiếu hoặc sai, không dừng lại ở lỗi đầu tiên.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// option mô tả một key cấu hình: tên env, tên flag, giá trị mặc định và cách gán vào Config.
+type option struct {
+	env      string
+	flag     string
+	def      string
+	required bool
+	usage    string
+	set      func(c *Config, value string) error
+}
+
+var options = []option{
+	{env: "PORT", flag: "port", def: "8080", usage: "HTTP listen port", set: func(c *Config, v string) error { return setPort(&c.Port, v) }},
+	{env: "REQUEST_TIMEOUT", flag: "request-timeout", def: "15s", usage: "deadline applied to every request", set: func(c *Config, v string) error { return setDuration(&c.RequestTimeout, v) }},
+	{env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", def: "30s", usage: "time to drain in-flight requests on shutdown", set: func(c *Config, v string) error { return setDuration(&c.ShutdownTimeout, v) }},
+	{env: "SHUTDOWN_DRAIN_DELAY", flag: "shutdown-drain-delay", def: "5s", usage: "time /readyz reports draining before the server stops accepting connections (0 disables)", set: func(c *Config, v string) error { return setNonNegativeDuration(&c.Health.DrainDelay, v) }},
+	{env: "READINESS_TIMEOUT", flag: "readiness-timeout", def: "2s", usage: "deadline for all /readyz dependency checks", set: func(c *Config, v string) error { return setDuration(&c.Health.ReadinessTimeout, v) }},
+	{env: "REDIS_ADDR", flag: "redis-addr", usage: "Redis host:port checked by /readyz (optional)", set: func(c *Config, v string) error { c.RedisAddr = v; return nil }},
+	{env: "MIGRATE_ON_START", flag: "migrate-on-start", def: "false", usage: "apply pending schema migrations before serving", set: func(c *Config, v string) error { return setBool(&c.MigrateOnStart, v) }},
+	{env: "IDEMPOTENCY_TTL", flag: "idempotency-ttl", def: "24h", usage: "how long an Idempotency-Key replays the original order", set: func(c *Config, v string) error { return setDuration(&c.IdempotencyTTL, v) }},
+	{env: "RESERVATION_TTL", flag: "reservation-ttl", def: "15m", usage: "default hold time of a stock reservation", set: func(c *Config, v string) error { return setDuration(&c.Reservation.TTL, v) }},
+	{env: "RESERVATION_MAX_TTL", flag: "reservation-max-ttl", def: "1h", usage: "longest hold time a client may request", set: func(c *Config, v string) error { return setDuration(&c.Reservation.MaxTTL, v) }},
+	{env: "RESERVATION_SWEEP_INTERVAL", flag: "reservation-sweep-interval", def: "30s", usage: "how often expired reservations are swept", set: func(c *Config, v string) error { return setDuration(&c.Reservation.SweepInterval, v) }},
+	{env: "LOW_STOCK_THRESHOLD", flag: "low-stock-threshold", def: "5", usage: "stock level at or below which a book is reported as low stock", set: func(c *Config, v string) error { return setNonNegativeInt(&c.LowStockThreshold, v) }},
+	{env: "LOG_LEVEL", flag: "log-level", def: "info", usage: "minimum log level: debug, info, warn or error", set: setLogLevel},
+	{env: "LOG_FORMAT", flag: "log-format", def: "json", usage: "log format: json or text", set: setLogFormat},
+	{env: "HTTP_READ_HEADER_TIMEOUT", flag: "http-read-header-timeout", def: "5s", usage: "http.Server ReadHeaderTimeout", set: func(c *Config, v string) error { return setDuration(&c.HTTP.ReadHeaderTimeout, v) }},
+	{env: "HTTP_READ_TIMEOUT", flag: "http-read-timeout", def: "10s", usage: "http.Server ReadTimeout", set: func(c *Config, v string) error { return setDuration(&c.HTTP.ReadTimeout, v) }},
+	{env: "HTTP_WRITE_TIMEOUT", flag: "http-write-timeout", def: "20s", usage: "http.Server WriteTimeout", set: func(c *Config, v string) error { return setDuration(&c.HTTP.WriteTimeout, v) }},
+	{env: "HTTP_IDLE_TIMEOUT", flag: "http-idle-timeout", def: "60s", usage: "http.Server IdleTimeout", set: func(c *Config, v string) error { return setDuration(&c.HTTP.IdleTimeout, v) }},
+
+	{env: "DB_USER", flag: "db-user", required: true, usage: "MySQL user", set: func(c *Config, v string) error { c.DB.User = v; return nil }},
+	{env: "DB_PASS", flag: "db-pass", usage: "MySQL password", set: func(c *Config, v string) error { c.DB.Password = v; return nil }},
+	{env: "DB_HOST", flag: "db-host", required: true, usage: "MySQL host (host or host:port)", set: func(c *Config, v string) error { c.DB.Host = v; return nil }},
+	{env: "DB_PORT", flag: "db-port", def: "3306", usage: "MySQL port, ignored when DB_HOST already has a port", set: func(c *Config, v string) error { return setPort(&c.DB.Port, v) }},
+	{env: "DB_NAME", flag: "db-name", required: true, usage: "MySQL database name", set: func(c *Config, v string) error { c.DB.Name = v; return nil }},
+	{env: "DB_TLS", flag: "db-tls", def: "false", usage: "TLS mode: false, true, skip-verify or preferred", set: setTLS},
+	{env: "DB_TIMEZONE", flag: "db-timezone", def: "UTC", usage: "IANA time zone used to parse DATETIME values", set: setTimezone},
+	{env: "DB_CONNECT_TIMEOUT", flag: "db-connect-timeout", def: "5s", usage: "timeout for establishing a MySQL connection", set: func(c *Config, v string) error { return setDuration(&c.DB.ConnectTimeout, v) }},
+	{env: "DB_MAX_OPEN_CONNS", flag: "db-max-open-conns", def: "25", usage: "maximum open connections in the pool", set: func(c *Config, v string) error { return setNonNegativeInt(&c.DB.MaxOpenConns, v) }},
+	{env: "DB_MAX_IDLE_CONNS", flag: "db-max-idle-conns", def: "25", usage: "maximum idle connections in the pool", set: func(c *Config, v string) error { return setNonNegativeInt(&c.DB.MaxIdleConns, v) }},
+	{env: "DB_CONN_MAX_LIFETIME", flag: "db-conn-max-lifetime", def: "5m", usage: "maximum lifetime of a pooled connection", set: func(c *Config, v string) error { return setDuration(&c.DB.ConnMaxLifetime, v) }},
+}
+
+// Load đọc cấu hình của process. Thứ tự ưu tiên: flag > biến môi trường > file .env > mặc định.
+func Load(args []string) (*Config, error) {
+	env, err := godotenv.Read(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+	if env == nil {
+		env = map[string]string{}
+	}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return Parse(args, env)
+}
+
+// Parse dựng Config từ args và map env cho sẵn, không đụng tới môi trường của process
+// nên test có thể gọi trực tiếp.
+func Parse(args []string, env map[string]string) (*Config, error) {
+	fs := flag.NewFlagSet("re-book-api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flagValues := make(map[string]*string, len(options))
+	for _, opt := range options {
+		flagValues[opt.flag] = fs.String(opt.flag, "", opt.usage+" (env "+opt.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	cfg := &Config{Args: fs.Args()}
+	var problems []string
+	for _, opt := range options {
+		value, source := opt.def, "default"
+		if v, ok := env[opt.env]; ok && v != "" {
+			value, source = v, opt.env
+		}
+		if explicit[opt.flag] {
+			value, source = *flagValues[opt.flag], "-"+opt.flag
+		}
+		if value == "" {
+			if opt.required {
+				problems = append(problems, fmt.Sprintf("%s is required (env %s or flag -%s)", opt.env, opt.env, opt.flag))
+			}
+			continue
+		}
+		if err := opt.set(cfg, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %q from %s: %v", opt.env, value, source, err))
+		}
+	}
+	if cfg.Reservation.TTL > cfg.Reservation.MaxTTL {
+		problems = append(problems, "RESERVATION_TTL must not exceed RESERVATION_MAX_TTL")
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// Usage in danh sách flag/env được hỗ trợ.
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Configuration (flag > env > .env > default):")
+	for _, opt := range options {
+		def := opt.def
+		if opt.required {
+			def = "required"
+		}
+		fmt.Fprintf(w, "  -%-26s %-26s %s [%s]\n", opt.flag, opt.env, opt.usage, def)
+	}
+}
+
+func setDuration(dst *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return errors.New("expected a duration such as 10s or 1m")
+	}
+	if d <= 0 {
+		return errors.New("must be greater than zero")
+	}
+	*dst = d
+	return nil
+}
+
+func setNonNegativeDuration(dst *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return errors.New("expected a duration such as 10s or 1m")
+	}
+	if d < 0 {
+		return errors.New("must not be negative")
+	}
+	*dst = d
+	return nil
+}
+
+func setLogLevel(c *Config, value string) error {
+	if err := c.Log.Level.UnmarshalText([]byte(value)); err != nil {
+		return errors.New("expected one of debug, info, warn, error")
+	}
+	return nil
+}
+
+func setLogFormat(c *Config, value string) error {
+	switch v := strings.ToLower(value); v {
+	case "json", "text":
+		c.Log.Format = v
+		return nil
+	}
+	return errors.New("expected json or text")
+}
+
+func setBool(dst *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("expected true or false")
+	}
+	*dst = b
+	return nil
+}
+
+func setPort(dst *int, value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return errors.New("expected a port between 1 and 65535")
+	}
+	*dst = port
+	return nil
+}
+
+func setNonNegativeInt(dst *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New("expected a non-negative integer")
+	}
+	*dst = n
+	return nil
+}
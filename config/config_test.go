@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func baseEnv() map[string]string {
+	return map[string]string{
+		"DB_USER": "root",
+		"DB_PASS": "secret",
+		"DB_HOST": "db",
+		"DB_NAME": "books",
+	}
+}
+
+func TestParse_Defaults(t *testing.T) {
+	cfg, err := config.Parse(nil, baseEnv())
+	require.NoError(t, err)
+
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, ":8080", cfg.Addr())
+	assert.Equal(t, 15*time.Second, cfg.RequestTimeout)
+	assert.Equal(t, 30*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, 5*time.Second, cfg.Health.DrainDelay)
+	assert.Equal(t, 2*time.Second, cfg.Health.ReadinessTimeout)
+	assert.Equal(t, 24*time.Hour, cfg.IdempotencyTTL)
+	assert.Equal(t, 15*time.Minute, cfg.Reservation.TTL)
+	assert.Equal(t, time.Hour, cfg.Reservation.MaxTTL)
+	assert.Equal(t, 30*time.Second, cfg.Reservation.SweepInterval)
+	assert.Empty(t, cfg.RedisAddr)
+	assert.Equal(t, 5*time.Second, cfg.HTTP.ReadHeaderTimeout)
+	assert.Equal(t, 3306, cfg.DB.Port)
+	assert.Equal(t, 25, cfg.DB.MaxOpenConns)
+	assert.Equal(t, 5*time.Minute, cfg.DB.ConnMaxLifetime)
+	assert.Equal(t, "UTC", cfg.DB.Location.String())
+}
+
+func TestParse_Precedence(t *testing.T) {
+	env := baseEnv()
+	env["PORT"] = "9000"
+	env["REQUEST_TIMEOUT"] = "3s"
+
+	cfg, err := config.Parse([]string{"-port", "9100", "migrate", "up"}, env)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9100, cfg.Port, "flag wins over env")
+	assert.Equal(t, 3*time.Second, cfg.RequestTimeout, "env wins over default")
+	assert.Equal(t, []string{"migrate", "up"}, cfg.Args)
+}
+
+func TestParse_ReportsAllProblems(t *testing.T) {
+	env := map[string]string{
+		"DB_HOST":           "db",
+		"PORT":              "http",
+		"REQUEST_TIMEOUT":   "-1s",
+		"DB_TLS":            "maybe",
+		"DB_MAX_OPEN_CONNS": "many",
+	}
+
+	_, err := config.Parse(nil, env)
+
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Problems, 6)
+	for _, key := range []string{"DB_USER", "DB_NAME", "PORT", "REQUEST_TIMEOUT", "DB_TLS", "DB_MAX_OPEN_CONNS"} {
+		assert.Contains(t, err.Error(), key)
+	}
+}
+
+func TestParse_ReservationTTLWithinMax(t *testing.T) {
+	env := baseEnv()
+	env["RESERVATION_TTL"] = "2h"
+
+	_, err := config.Parse(nil, env)
+
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Contains(t, err.Error(), "RESERVATION_MAX_TTL")
+}
+
+func TestParse_UnknownFlag(t *testing.T) {
+	_, err := config.Parse([]string{"-nope"}, baseEnv())
+
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+}
+
+func TestDBConfig_DSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{
+			name:     "Host and port joined",
+			env:      map[string]string{"DB_PORT": "3307"},
+			expected: "root:secret@tcp(db:3307)/books?parseTime=true&timeout=5s",
+		},
+		{
+			name:     "Legacy host:port kept as is",
+			env:      map[string]string{"DB_HOST": "mysql:3308", "DB_PORT": "3307"},
+			expected: "root:secret@tcp(mysql:3308)/books?parseTime=true&timeout=5s",
+		},
+		{
+			name:     "TLS and time zone",
+			env:      map[string]string{"DB_TLS": "skip-verify", "DB_TIMEZONE": "Asia/Ho_Chi_Minh"},
+			expected: "root:secret@tcp(db:3306)/books?loc=Asia%2FHo_Chi_Minh&parseTime=true&timeout=5s&tls=skip-verify",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env := baseEnv()
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			cfg, err := config.Parse(nil, env)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.DB.DSN())
+		})
+	}
+}
@@ -1,23 +1,72 @@
 package config
 
 import (
-	"fmt"
-	"os"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql" // MySQL driver
-	"github.com/joho/godotenv"
+	"github.com/go-sql-driver/mysql" // MySQL driver
 )
 
-func init() {
-	// Load biến môi trường từ .env, không lỗi nếu không tìm thấy file
-	_ = godotenv.Load(".env")
+type DBConfig struct {
+	User     string
+	Password string
+	Host     string
+	Port     int
+	Name     string
+	// TLS là giá trị tls của driver: "false", "true", "skip-verify" hoặc "preferred"
+	TLS      string
+	Location *time.Location
+
+	ConnectTimeout  time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Addr ghép host và port. DB_HOST dạng "host:port" (như trước đây) vẫn được giữ nguyên.
+func (c DBConfig) Addr() string {
+	if _, _, err := net.SplitHostPort(c.Host); err == nil {
+		return c.Host
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// DSN dựng chuỗi kết nối cho go-sql-driver/mysql.
+func (c DBConfig) DSN() string {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = c.Addr()
+	cfg.DBName = c.Name
+	cfg.ParseTime = true
+	cfg.Timeout = c.ConnectTimeout
+	if c.Location != nil {
+		cfg.Loc = c.Location
+	}
+	if c.TLS != "" && c.TLS != "false" {
+		cfg.TLSConfig = c.TLS
+	}
+	return cfg.FormatDSN()
+}
+
+func setTLS(c *Config, value string) error {
+	switch v := strings.ToLower(value); v {
+	case "false", "true", "skip-verify", "preferred":
+		c.DB.TLS = v
+		return nil
+	}
+	return errors.New("expected one of false, true, skip-verify, preferred")
 }
 
-func GetDSN() string {
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASS")
-	host := os.Getenv("DB_HOST")
-	database := os.Getenv("DB_NAME")
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", user, password, host, database)
-	return dsn
+func setTimezone(c *Config, value string) error {
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return errors.New("unknown time zone")
+	}
+	c.DB.Location = loc
+	return nil
 }
@@ -0,0 +1,146 @@
+// Package apperror chứa các lỗi domain dùng chung cho repository, service và handler.
+// Các tầng bên dưới bọc lỗi bằng các constructor ở đây, handler chỉ cần errors.Is/As
+// để quyết định HTTP status thay vì so sánh chuỗi.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrValidation        = errors.New("validation failed")
+	ErrConflict          = errors.New("conflict")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrForeignKey        = errors.New("foreign key violation")
+	ErrUnprocessable     = errors.New("unprocessable")
+)
+
+// Detailer được các lỗi mang thêm dữ liệu cho client implement (vd: field lỗi, danh sách sách thiếu hàng).
+type Detailer interface {
+	Details() map[string]any
+}
+
+// Error gắn một thông điệp cụ thể với một trong các lỗi sentinel ở trên.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error // nguyên nhân gốc (vd: *mysql.MySQLError), có thể nil
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind, cause error, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: cause}
+}
+
+func NotFound(format string, args ...any) error {
+	return newError(ErrNotFound, nil, format, args...)
+}
+
+func Conflict(format string, args ...any) error {
+	return newError(ErrConflict, nil, format, args...)
+}
+
+func InsufficientStock(format string, args ...any) error {
+	return newError(ErrInsufficientStock, nil, format, args...)
+}
+
+// Unprocessable báo request hợp lệ về cú pháp nhưng không thể xử lý
+// (vd: dùng lại Idempotency-Key với payload khác).
+func Unprocessable(format string, args ...any) error {
+	return newError(ErrUnprocessable, nil, format, args...)
+}
+
+// ForeignKey bọc lỗi vi phạm ràng buộc khoá ngoại từ database; cause có thể nil khi repository tự kiểm tra.
+func ForeignKey(cause error, format string, args ...any) error {
+	return newError(ErrForeignKey, cause, format, args...)
+}
+
+// ValidationError báo lỗi dữ liệu đầu vào của một field cụ thể.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func NewValidation(field, message string) error {
+	return &ValidationError{Field: field, Message: message}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) Details() map[string]any {
+	if e.Field == "" {
+		return nil
+	}
+	return map[string]any{"field": e.Field}
+}
+
+// TransitionError báo một lần chuyển trạng thái không hợp lệ, kèm các trạng thái được phép
+// để client biết bước tiếp theo.
+type TransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func InvalidTransition(from, to string, allowed []string) error {
+	return &TransitionError{From: from, To: to, Allowed: allowed}
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %q to %q", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *TransitionError) Details() map[string]any {
+	allowed := e.Allowed
+	if allowed == nil {
+		allowed = []string{} // trạng thái cuối: trả [] thay vì null
+	}
+	return map[string]any{"current_status": e.From, "allowed_transitions": allowed}
+}
+
+// StockShortage mô tả một dòng không đủ hàng.
+type StockShortage struct {
+	BookID    int `json:"book_id"`
+	Requested int `json:"requested"`
+	Available int `json:"available"`
+}
+
+// InsufficientStockError liệt kê tất cả các sách thiếu hàng, không chỉ sách đầu tiên.
+type InsufficientStockError struct {
+	Items []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	return "not enough stock available"
+}
+
+func (e *InsufficientStockError) Is(target error) bool {
+	return target == ErrInsufficientStock
+}
+
+func (e *InsufficientStockError) Details() map[string]any {
+	return map[string]any{"items": e.Items}
+}
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+
 	"github.com/maithuc2003/re-book-api/config"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -12,18 +14,22 @@ type MySQLConnection struct {
 	DB *sql.DB
 }
 
-// NewMySQLConnection tạo và trả về kết nối DB mới
-func NewMySQLConnection() (*MySQLConnection, error) {
-	dsn := config.GetDSN()
-
-	db, err := sql.Open("mysql", dsn)
+// NewMySQLConnection tạo và trả về kết nối DB mới theo cấu hình đã được validate
+func NewMySQLConnection(cfg config.DBConfig) (*MySQLConnection, error) {
+	db, err := sql.Open("mysql", cfg.DSN())
 	if err != nil {
-		fmt.Println("Failed connect:", err) // nếu có lỗi thì in ra
-		return nil, err
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-	if err := db.Ping(); err != nil {
-		fmt.Println("Error connect:", err) // nếu có lỗi thì in ra
-		return nil, err
+	// Cấu hình connection pool
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping %s: %w", cfg.Addr(), err)
 	}
 	return &MySQLConnection{DB: db}, nil
 }
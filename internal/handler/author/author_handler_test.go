@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 
-	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/handler/author"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	mock "github.com/maithuc2003/re-book-api/test/mockservice"
 	"github.com/stretchr/testify/assert"
 	testifymock "github.com/stretchr/testify/mock"
@@ -20,46 +22,49 @@ import (
 func TestGetAllAuthors(t *testing.T) {
 	tests := []struct {
 		name           string
-		httpMethod     string
-		mockReturn     []*models.Author
+		url            string
+		expectedFilter models.AuthorFilter
+		mockReturn     *pagination.Page[*models.Author]
 		mockError      error
 		expectedStatus int
-		expectedResult []*models.Author
+		expectedResult *pagination.Page[*models.Author]
 		expectErrorMsg string
 	}{
 		{
 			name:           "success with authors",
-			httpMethod:     http.MethodGet,
-			mockReturn:     []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}},
+			url:            "/authors",
+			mockReturn:     &pagination.Page[*models.Author]{Data: []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}}, Total: 2},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedResult: []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}},
+			expectedResult: &pagination.Page[*models.Author]{Data: []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}}, Total: 2},
 		},
 		{
-			name:           "method not allowed",
-			httpMethod:     http.MethodPost,
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
+			name:           "Query parameters are passed as filter",
+			url:            "/authors?limit=2&sort=-name&nationality=VN",
+			expectedFilter: models.AuthorFilter{Params: pagination.Params{Limit: 2, Sort: "-name"}, Nationality: "VN"},
+			mockReturn:     &pagination.Page[*models.Author]{Data: []*models.Author{{ID: 3, Name: "Nam Cao"}}, NextCursor: "next", Total: 4},
+			expectedStatus: http.StatusOK,
+			expectedResult: &pagination.Page[*models.Author]{Data: []*models.Author{{ID: 3, Name: "Nam Cao"}}, NextCursor: "next", Total: 4},
 		},
 		{
 			name:           "No found author error",
-			httpMethod:     http.MethodGet,
-			mockReturn:     []*models.Author{},
-			mockError:      errors.New("no authors found in the system"),
+			url:            "/authors",
+			mockReturn:     nil,
+			mockError:      apperror.NotFound("no authors found in the system"),
 			expectedStatus: http.StatusNotFound,
 			expectErrorMsg: "no authors found in the system",
 		},
 		{
 			name:           "Empty author list",
-			httpMethod:     http.MethodGet,
-			mockReturn:     []*models.Author{},
+			url:            "/authors",
+			mockReturn:     &pagination.Page[*models.Author]{Data: []*models.Author{}},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedResult: []*models.Author{},
+			expectedResult: &pagination.Page[*models.Author]{Data: []*models.Author{}},
 		},
 		{
 			name:           "Database error from service",
-			httpMethod:     http.MethodGet,
+			url:            "/authors",
 			mockReturn:     nil,
 			mockError:      errors.New("DB error"),
 			expectedStatus: http.StatusInternalServerError,
@@ -71,20 +76,18 @@ func TestGetAllAuthors(t *testing.T) {
 			// Tạo một mock service để thay thế service thật
 			mock_service := new(mock.MockAuthorService)
 			// Tạo một handler, truyền mock service vào
-			handler := author.NewAuthorHandler(mock_service)
+			handler := author.NewAuthorHandler(mock_service, slog.New(slog.DiscardHandler))
 			// Định nghĩa hành vi giả của mock:
-			// Khi gọi GetAllAuthor thì trả về kết quả mock và lỗi mock tương ứng
-			if tc.httpMethod == http.MethodGet {
-				mock_service.On("GetAllAuthors").Return(tc.mockReturn, tc.mockError)
-			}
+			// Khi gọi GetAllAuthor với filter đọc từ query thì trả về kết quả mock và lỗi mock tương ứng
+			mock_service.On("GetAllAuthors", testifymock.Anything, tc.expectedFilter).Return(tc.mockReturn, tc.mockError)
 			// Tạo HTTP request giả (GET /authors) và response recorder
-			req := httptest.NewRequest(tc.httpMethod, "/authors", nil)
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
 			w := httptest.NewRecorder()
 			// Gọi handler để xử lý request
 			handler.GetAllAuthors(w, req)
 			assert.Equal(t, tc.expectedStatus, w.Code)
 			if tc.expectedStatus == http.StatusOK && tc.mockError == nil {
-				var result []*models.Author
+				var result *pagination.Page[*models.Author]
 				err := json.NewDecoder(w.Body).Decode(&result)
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedResult, result)
@@ -121,19 +124,12 @@ func TestCreateAuthor(t *testing.T) {
 			expectErrorMsg: "Invalid request body",
 		},
 		{
-			name:           "MySQL foreign key error",
+			name:           "Duplicate author ID",
 			httpMethod:     http.MethodPost,
-			requestBody:    &models.Author{Name: "Jane Doe"},
-			mockError:      &mysql.MySQLError{Number: 1452, Message: "foreign key constraint fails"},
-			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "author_id does not exist",
-		},
-		{
-			name:           "Wrong HTTP method",
-			httpMethod:     http.MethodGet,
-			requestBody:    nil,
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "method not allowed",
+			requestBody:    &models.Author{ID: 7, Name: "Jane Doe"},
+			mockError:      apperror.Conflict("author with ID 7 already exists"),
+			expectedStatus: http.StatusConflict,
+			expectErrorMsg: "author with ID 7 already exists",
 		},
 		{
 			name:           "Internal server error",
@@ -147,7 +143,7 @@ func TestCreateAuthor(t *testing.T) {
 			name:           "Author is nil",
 			httpMethod:     http.MethodPost,
 			requestBody:    nil,
-			mockError:      errors.New("author is nil"),
+			mockError:      apperror.NewValidation("", "author is nil"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "author is nil",
 		},
@@ -155,7 +151,7 @@ func TestCreateAuthor(t *testing.T) {
 			name:           "Empty author name",
 			httpMethod:     http.MethodPost,
 			requestBody:    &models.Author{Name: "   "},
-			mockError:      errors.New("author name cannot be empty"),
+			mockError:      apperror.NewValidation("name", "author name cannot be empty"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "author name cannot be empty",
 		},
@@ -163,8 +159,8 @@ func TestCreateAuthor(t *testing.T) {
 			name:           "Duplicate author",
 			httpMethod:     http.MethodPost,
 			requestBody:    &models.Author{Name: "Jane Austen"},
-			mockError:      errors.New("author with the same name already exists"),
-			expectedStatus: http.StatusBadRequest,
+			mockError:      apperror.Conflict("author with the same name already exists"),
+			expectedStatus: http.StatusConflict,
 			expectErrorMsg: "already exists",
 		},
 	}
@@ -172,7 +168,7 @@ func TestCreateAuthor(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockService := new(mock.MockAuthorService)
-			handler := author.NewAuthorHandler(mockService)
+			handler := author.NewAuthorHandler(mockService, slog.New(slog.DiscardHandler))
 
 			var bodyBytes []byte
 			var err error
@@ -187,7 +183,7 @@ func TestCreateAuthor(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			if tc.httpMethod == http.MethodPost && tc.expectErrorMsg != "Invalid request body" {
-				mockService.On("CreateAuthor", testifymock.AnythingOfType("*models.Author")).Return(tc.mockError)
+				mockService.On("CreateAuthor", testifymock.Anything, testifymock.AnythingOfType("*models.Author")).Return(tc.mockError)
 			}
 
 			handler.CreateAuthor(w, req)
@@ -245,34 +241,27 @@ func TestGetByAuthorID(t *testing.T) {
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=99",
 			mockReturn:     nil,
-			mockError:      errors.New("author not found"),
+			mockError:      apperror.NotFound("author not found"),
 			expectedStatus: http.StatusNotFound,
 			expectErrorMsg: "author not found",
 		},
-		{
-			name:           "Wrong HTTP method",
-			httpMethod:     http.MethodPost,
-			queryParam:     "id=1",
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
-		},
 		{
 			name:           "Invalid author ID (service error)",
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=0",
 			mockReturn:     nil,
-			mockError:      errors.New("invalid author ID"),
+			mockError:      apperror.NewValidation("id", "invalid author ID"),
 			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "invalid author ID\n",
+			expectErrorMsg: "invalid author ID",
 		},
 		{
 			name:           "Author not found (service error)",
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=999",
 			mockReturn:     nil,
-			mockError:      errors.New("author not found"),
+			mockError:      apperror.NotFound("author not found"),
 			expectedStatus: http.StatusNotFound,
-			expectErrorMsg: "author not found\n",
+			expectErrorMsg: "author not found",
 		},
 		{
 			name:           "Failed to retrieve author (DB error)",
@@ -281,7 +270,7 @@ func TestGetByAuthorID(t *testing.T) {
 			mockReturn:     nil,
 			mockError:      errors.New("failed to retrieve author: DB error"),
 			expectedStatus: http.StatusInternalServerError,
-			expectErrorMsg: "failed to retrieve author: DB error\n",
+			expectErrorMsg: "Unexpected error",
 		},
 		{
 			name:           "Unexpected error from service",
@@ -290,13 +279,13 @@ func TestGetByAuthorID(t *testing.T) {
 			mockReturn:     nil,
 			mockError:      errors.New("some strange unexpected error"),
 			expectedStatus: http.StatusInternalServerError,
-			expectErrorMsg: "Unexpected error\n",
+			expectErrorMsg: "Unexpected error",
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockService := new(mock.MockAuthorService)
-			handler := author.NewAuthorHandler(mockService)
+			handler := author.NewAuthorHandler(mockService, slog.New(slog.DiscardHandler))
 
 			url := "/author"
 			if tc.queryParam != "" {
@@ -306,7 +295,7 @@ func TestGetByAuthorID(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			if tc.httpMethod == http.MethodGet && tc.queryParam != "" && tc.mockError != nil || tc.mockReturn != nil {
-				mockService.On("GetByAuthorID", testifymock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
+				mockService.On("GetByAuthorID", testifymock.Anything, testifymock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
 			}
 
 			handler.GetByAuthorID(w, req)
@@ -353,13 +342,13 @@ func TestDeleteById(t *testing.T) {
 			mockReturn:     nil,
 			mockError:      errors.New("failed to delete author: database unreachable"),
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "failed to delete author: database unreachable",
+			expectedBody:   "Failed to delete author",
 		},
 		{
 			name:           "Invalid author ID (negative)",
 			queryParam:     "id=-1",
 			mockReturn:     nil,
-			mockError:      errors.New("invalid author ID"),
+			mockError:      apperror.NewValidation("id", "invalid author ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "invalid author ID",
 		},
@@ -367,7 +356,7 @@ func TestDeleteById(t *testing.T) {
 			name:           "Author not found or already deleted",
 			queryParam:     "id=999",
 			mockReturn:     nil,
-			mockError:      errors.New("author not found or already deleted"),
+			mockError:      apperror.NotFound("author not found or already deleted"),
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "author not found or already deleted",
 		},
@@ -382,7 +371,7 @@ func TestDeleteById(t *testing.T) {
 			name:           "Author not found",
 			queryParam:     "id=99",
 			mockReturn:     nil,
-			mockError:      errors.New("author not found"),
+			mockError:      apperror.NotFound("author not found"),
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "author not found",
 		},
@@ -390,9 +379,9 @@ func TestDeleteById(t *testing.T) {
 			name:           "Existing author constraint error",
 			queryParam:     "id=2",
 			mockReturn:     nil,
-			mockError:      errors.New("existing author with books"),
+			mockError:      apperror.ForeignKey(nil, "cannot delete author: existing books depend on it"),
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "existing author with books",
+			expectedBody:   "existing books depend on it",
 		},
 		{
 			name:           "Unexpected error not matching any known cases",
@@ -400,14 +389,14 @@ func TestDeleteById(t *testing.T) {
 			mockReturn:     nil,
 			mockError:      errors.New("something went terribly wrong"),
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "Unexpected error",
+			expectedBody:   "Failed to delete author",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockService := new(mock.MockAuthorService)
-			handler := author.NewAuthorHandler(mockService)
+			handler := author.NewAuthorHandler(mockService, slog.New(slog.DiscardHandler))
 
 			url := "/author/delete"
 			if tc.queryParam != "" {
@@ -421,7 +410,7 @@ func TestDeleteById(t *testing.T) {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					id, err := strconv.Atoi(idStr)
 					if err == nil {
-						mockService.On("DeleteById", id).Return(tc.mockReturn, tc.mockError)
+						mockService.On("DeleteById", testifymock.Anything, id).Return(tc.mockReturn, tc.mockError)
 					}
 				}
 			}
@@ -484,7 +473,7 @@ func TestUpdateById(t *testing.T) {
 			queryParam:     "id=99",
 			requestBody:    `{"name":"Unknown"}`,
 			mockReturn:     nil,
-			mockError:      errors.New("author not found"),
+			mockError:      apperror.NotFound("author not found"),
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "author not found",
 		},
@@ -493,7 +482,7 @@ func TestUpdateById(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockService := new(mock.MockAuthorService)
-			handler := author.NewAuthorHandler(mockService)
+			handler := author.NewAuthorHandler(mockService, slog.New(slog.DiscardHandler))
 
 			url := "/author/update"
 			if tc.queryParam != "" {
@@ -508,7 +497,7 @@ func TestUpdateById(t *testing.T) {
 			if tc.mockReturn != nil || tc.mockError != nil {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					if id, err := strconv.Atoi(idStr); err == nil {
-						mockService.On("UpdateById", testifymock.MatchedBy(func(a *models.Author) bool {
+						mockService.On("UpdateById", testifymock.Anything, testifymock.MatchedBy(func(a *models.Author) bool {
 							return a.ID == id
 						})).Return(tc.mockReturn, tc.mockError)
 					}
@@ -2,39 +2,37 @@ package author
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
 	"github.com/maithuc2003/re-book-api/internal/models"
 	"github.com/maithuc2003/re-book-api/internal/service/author"
 )
 
 type AuthorHandler struct {
 	serviceAuthor author.AuthorServiceInterface
+	logger        *slog.Logger
 }
 
-func NewAuthorHandler(serviceAuthor author.AuthorServiceInterface) *AuthorHandler {
-	return &AuthorHandler{serviceAuthor: serviceAuthor}
+func NewAuthorHandler(serviceAuthor author.AuthorServiceInterface, logger *slog.Logger) *AuthorHandler {
+	return &AuthorHandler{serviceAuthor: serviceAuthor, logger: logger}
 }
 
+// GetAllAuthors hỗ trợ ?limit=&cursor=&sort= và filter nationality
 func (h *AuthorHandler) GetAllAuthors(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	page, err := params.Page(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-
-	authors, err := h.serviceAuthor.GetAllAuthors()
+	filter := models.AuthorFilter{Params: page, Nationality: r.URL.Query().Get("nationality")}
+	authors, err := h.serviceAuthor.GetAllAuthors(r.Context(), filter)
 	if err != nil {
-		log.Printf("GetAllAuthors error : %v", err)
-		if err.Error() == "no authors found in the system" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to get authors", http.StatusInternalServerError)
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list authors failed", "err", err)
+		httperror.Write(w, err, "Failed to get authors")
 		return
 	}
 
@@ -44,42 +42,21 @@ func (h *AuthorHandler) GetAllAuthors(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthorHandler) GetByAuthorID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// 1. Get the 'id' parameter from query
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
-		return
-	}
-
-	// 2. Convert 'id' to integer
-	id, err := strconv.Atoi(idStr)
+	// 1. Get the 'id' parameter from path (or query for legacy routes)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
 
-	// 3. Call service to fetch author
-	author, err := h.serviceAuthor.GetByAuthorID(id)
+	// 2. Call service to fetch author
+	author, err := h.serviceAuthor.GetByAuthorID(r.Context(), id)
 	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "invalid author ID"):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case strings.Contains(err.Error(), "author not found"):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case strings.Contains(err.Error(), "failed to retrieve author"):
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		default:
-			http.Error(w, "Unexpected error", http.StatusInternalServerError)
-		}
+		httperror.Write(w, err, "Unexpected error")
 		return
 	}
 
-	// 4. Return author in JSON format
+	// 3. Return author in JSON format
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(author)
@@ -87,10 +64,6 @@ func (h *AuthorHandler) GetByAuthorID(w http.ResponseWriter, r *http.Request) {
 
 // Thuộc tính Fontend gửi backend gửi cái gì (intetnet) tcp,http
 func (h *AuthorHandler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	// Parse the request body to get the book details
 	var author models.Author
 	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
@@ -100,26 +73,12 @@ func (h *AuthorHandler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
 
 	// Set createdAt hiện tại
 	author.CreatedAt = time.Now()
-	err := h.serviceAuthor.CreateAuthor(&author)
+	err := h.serviceAuthor.CreateAuthor(r.Context(), &author)
 
 	if err != nil {
 		// Log chi tiết lỗi ở server để biết nguyên nhân
-		log.Printf("Created author error : %v", err)
-		// error user (invalid input)
-		if strings.Contains(err.Error(), "name cannot be empty") ||
-			strings.Contains(err.Error(), "already exists") ||
-			strings.Contains(err.Error(), "author is nil") {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
-
-		//error from Mysql
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
-			http.Error(w, "Failed to create author: the author_id does not exist.", http.StatusBadRequest)
-			return
-		}
-
-		http.Error(w, "Failed to create author due to internal server error.", http.StatusInternalServerError)
-		//Server gặp lỗi khi tạo sách
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create author failed", "err", err)
+		httperror.Write(w, err, "Failed to create author due to internal server error.")
 		return
 	}
 
@@ -130,33 +89,16 @@ func (h *AuthorHandler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthorHandler) DeleteById(w http.ResponseWriter, r *http.Request) {
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
-		return
-	}
-	// 2. Chuyển id từ string sang int
-	id, err := strconv.Atoi(idStr)
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
-	// 3.Gọi service để xóa sách
-	author, err := h.serviceAuthor.DeleteById(id)
+	// 2.Gọi service để xóa tác giả
+	author, err := h.serviceAuthor.DeleteById(r.Context(), id)
 	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "invalid author ID"):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case strings.Contains(err.Error(), "author not found"):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case strings.Contains(err.Error(), "existing author with books"):
-			http.Error(w, err.Error(), http.StatusBadRequest) 
-		case strings.Contains(err.Error(), "failed to delete author"):
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		default:
-			http.Error(w, "Unexpected error", http.StatusInternalServerError)
-		}
+		httperror.Write(w, err, "Failed to delete author")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -165,16 +107,10 @@ func (h *AuthorHandler) DeleteById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthorHandler) UpdateById(w http.ResponseWriter, r *http.Request) {
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	// fmt.Println(idStr)
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
-		return
-	}
-	id, err := strconv.Atoi(idStr)
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
 
@@ -184,17 +120,40 @@ func (h *AuthorHandler) UpdateById(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	// 3. Gán lại id cho book để chắc chắn đúng
+	h.update(w, r, id, &updateAuthor)
+}
+
+// PatchById chỉ cập nhật các field có trong body, các field còn lại giữ nguyên.
+func (h *AuthorHandler) PatchById(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	existing, err := h.serviceAuthor.GetByAuthorID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Unexpected error")
+		return
+	}
+	// Decode đè lên bản ghi hiện tại: field nào không gửi lên thì giữ giá trị cũ
+	if err := json.NewDecoder(r.Body).Decode(existing); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, existing)
+}
+
+func (h *AuthorHandler) update(w http.ResponseWriter, r *http.Request, id int, updateAuthor *models.Author) {
+	// Gán lại id cho author để chắc chắn đúng
 	updateAuthor.ID = id // Gán ID từ URL vào struct
 	updateAuthor.UpdatedAt = time.Now()
-	// 3.Gọi service để cập nhất sách
-	author, err := h.serviceAuthor.UpdateById(&updateAuthor)
+	// Gọi service để cập nhất tác giả
+	author, err := h.serviceAuthor.UpdateById(r.Context(), updateAuthor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		httperror.Write(w, err, "Failed to update author")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(author)
-
 }
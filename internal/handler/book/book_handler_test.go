@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -12,38 +13,79 @@ import (
 	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/handler/book"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/maithuc2003/re-book-api/test/mockservice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func TestGetAllBooks(t *testing.T) {
+	minStock, maxStock := 1, 10
 	tests := []struct {
 		name             string
-		httpMethod       string
-		mockReturn       []*models.Book
+		url              string
+		expectedFilter   models.BookFilter
+		mockReturn       *pagination.Page[*models.Book]
 		mockError        error
+		skipService      bool
 		expectedStatus   int
-		expectedResult   []*models.Book
+		expectedResult   *pagination.Page[*models.Book]
 		expectedErrorMsg string
 	}{
 		{
-			name:       "Success - return list of books",
-			httpMethod: http.MethodGet,
-			mockReturn: []*models.Book{
-				{ID: 1, Title: "Go Programming", AuthorID: 2, CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+			name: "Success - return list of books",
+			url:  "/books",
+			mockReturn: &pagination.Page[*models.Book]{
+				Data: []*models.Book{
+					{ID: 1, Title: "Go Programming", AuthorID: 2, CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+				},
+				NextCursor: "next",
+				Total:      3,
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedResult: []*models.Book{
-				{ID: 1, Title: "Go Programming", AuthorID: 2, CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+			expectedResult: &pagination.Page[*models.Book]{
+				Data: []*models.Book{
+					{ID: 1, Title: "Go Programming", AuthorID: 2, CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+				},
+				NextCursor: "next",
+				Total:      3,
+			},
+		},
+		{
+			name: "Query parameters are passed as filter",
+			url:  "/books?limit=5&cursor=abc&sort=-title&author_id=2&min_stock=1&max_stock=10&title_prefix=Go",
+			expectedFilter: models.BookFilter{
+				Params:      pagination.Params{Limit: 5, Cursor: "abc", Sort: "-title"},
+				AuthorID:    2,
+				MinStock:    &minStock,
+				MaxStock:    &maxStock,
+				TitlePrefix: "Go",
 			},
+			mockReturn:     &pagination.Page[*models.Book]{Data: []*models.Book{}},
+			expectedStatus: http.StatusOK,
+			expectedResult: &pagination.Page[*models.Book]{Data: []*models.Book{}},
+		},
+		{
+			name:             "Invalid limit",
+			url:              "/books?limit=abc",
+			skipService:      true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedErrorMsg: "Invalid 'limit' parameter",
+		},
+		{
+			name:             "Invalid min_stock",
+			url:              "/books?min_stock=many",
+			skipService:      true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedErrorMsg: "Invalid 'min_stock' parameter",
 		},
 		{
 			name:             "Error from service - internal server error",
-			httpMethod:       http.MethodGet,
+			url:              "/books",
 			mockReturn:       nil,
 			mockError:        errors.New("database error"),
 			expectedStatus:   http.StatusInternalServerError,
@@ -51,36 +93,30 @@ func TestGetAllBooks(t *testing.T) {
 		},
 		{
 			name:             "No books found - 404 error",
-			httpMethod:       http.MethodGet,
+			url:              "/books",
 			mockReturn:       nil,
-			mockError:        errors.New("no books found"),
+			mockError:        apperror.NotFound("no books found"),
 			expectedStatus:   http.StatusNotFound,
 			expectedErrorMsg: "no books found",
 		},
-		{
-			name:             "Invalid HTTP method - Method Not Allowed",
-			httpMethod:       http.MethodPost,
-			expectedStatus:   http.StatusMethodNotAllowed,
-			expectedErrorMsg: "Method not allowed",
-		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockBookService)
-			handler := book.NewBookHandler(mock_service)
+			handler := book.NewBookHandler(mock_service, slog.New(slog.DiscardHandler))
 
-			if tc.httpMethod == http.MethodGet {
-				mock_service.On("GetAllBooks").Return(tc.mockReturn, tc.mockError)
+			if !tc.skipService {
+				mock_service.On("GetAllBooks", mock.Anything, tc.expectedFilter).Return(tc.mockReturn, tc.mockError)
 			}
-			req := httptest.NewRequest(tc.httpMethod, "/books", nil)
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
 			w := httptest.NewRecorder()
 
 			handler.GetAllBooks(w, req)
 			assert.Equal(t, tc.expectedStatus, w.Code)
 
 			if tc.expectedStatus == http.StatusOK && tc.mockError == nil {
-				var result []*models.Book
+				var result *pagination.Page[*models.Book]
 				err := json.NewDecoder(w.Body).Decode(&result)
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedResult, result)
@@ -119,12 +155,6 @@ func TestCreateBook(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "Invalid request body",
 		},
-		{
-			name:           "Invalid method - GET not allowed",
-			httpMethod:     http.MethodGet,
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
-		},
 		{
 			name:       "MySQL foreign key error",
 			httpMethod: http.MethodPost,
@@ -133,12 +163,12 @@ func TestCreateBook(t *testing.T) {
 				Stock:    50,
 				AuthorID: 999,
 			},
-			mockError: &mysql.MySQLError{
+			mockError: apperror.ForeignKey(&mysql.MySQLError{
 				Number:  1452,
 				Message: "Cannot add or update a child row: a foreign key constraint fails",
-			},
+			}, "author_id 999 does not exist"),
 			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "book_id does not exist",
+			expectErrorMsg: "author_id 999 does not exist",
 		},
 		{
 			name:       "Internal server error",
@@ -156,7 +186,7 @@ func TestCreateBook(t *testing.T) {
 			name:           "Book is nil",
 			httpMethod:     http.MethodPost,
 			requestBody:    &models.Book{}, // Gửi rỗng để mô phỏng book bị nil trong service
-			mockError:      errors.New("book is nil"),
+			mockError:      apperror.NewValidation("", "book is nil"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "book is nil",
 		},
@@ -167,7 +197,7 @@ func TestCreateBook(t *testing.T) {
 				Stock:    10,
 				AuthorID: 1,
 			},
-			mockError:      errors.New("book title is required"),
+			mockError:      apperror.NewValidation("title", "book title is required"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "book title is required",
 		},
@@ -178,7 +208,7 @@ func TestCreateBook(t *testing.T) {
 				Title: "No Author",
 				Stock: 5,
 			},
-			mockError:      errors.New("book author ID is required"),
+			mockError:      apperror.NewValidation("author_id", "book author ID is required"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "book author ID is required",
 		},
@@ -190,7 +220,7 @@ func TestCreateBook(t *testing.T) {
 				Stock:    -5,
 				AuthorID: 1,
 			},
-			mockError:      errors.New("book quantity cannot be negative"),
+			mockError:      apperror.NewValidation("stock", "book quantity cannot be negative"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "book quantity cannot be negative",
 		},
@@ -198,7 +228,7 @@ func TestCreateBook(t *testing.T) {
 	for _, tc := range Tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockBookService)
-			handler := book.NewBookHandler(mock_service)
+			handler := book.NewBookHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			var bodyBytes []byte
 			var err error
@@ -212,15 +242,8 @@ func TestCreateBook(t *testing.T) {
 			req := httptest.NewRequest(tc.httpMethod, "/book/add", bytes.NewReader(bodyBytes))
 			w := httptest.NewRecorder()
 
-			if tc.httpMethod != http.MethodPost {
-				handler.CreateBook(w, req)
-				assert.Equal(t, tc.expectedStatus, w.Code)
-				assert.Contains(t, w.Body.String(), tc.expectErrorMsg)
-				return
-			}
-
 			if tc.expectErrorMsg != "Invalid request body" {
-				mock_service.On("CreateBook", mock.AnythingOfType("*models.Book")).Return(tc.mockError)
+				mock_service.On("CreateBook", mock.Anything, mock.AnythingOfType("*models.Book")).Return(tc.mockError)
 			}
 			handler.CreateBook(w, req)
 			assert.Equal(t, tc.expectedStatus, w.Code)
@@ -263,7 +286,7 @@ func TestDeleteById(t *testing.T) {
 			httpMethod:     http.MethodDelete,
 			queryParam:     "id=0", // id <= 0 sẽ gây ra lỗi
 			mockReturn:     nil,
-			mockError:      errors.New("invalid book ID"),
+			mockError:      apperror.NewValidation("id", "invalid book ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "invalid book ID",
 		},
@@ -280,7 +303,7 @@ func TestDeleteById(t *testing.T) {
 			httpMethod:     http.MethodDelete,
 			queryParam:     "id=2",
 			mockReturn:     nil,
-			mockError:      errors.New("book not found"),
+			mockError:      apperror.NotFound("book not found"),
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "book not found",
 		},
@@ -289,7 +312,7 @@ func TestDeleteById(t *testing.T) {
 			httpMethod:     http.MethodDelete,
 			queryParam:     "id=3",
 			mockReturn:     nil,
-			mockError:      errors.New("book has existing orders"),
+			mockError:      apperror.ForeignKey(nil, "book has existing orders"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "book has existing orders",
 		},
@@ -307,19 +330,12 @@ func TestDeleteById(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   `"title":"Clean Code"`,
 		},
-		{
-			name:           "Invalid HTTP method",
-			httpMethod:     http.MethodGet, // ✅ sai method để test
-			queryParam:     "id=1",
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed",
-		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockBookService)
-			handler := book.NewBookHandler(mock_service)
+			handler := book.NewBookHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			url := "/book/delete"
 			if tc.queryParam != "" {
@@ -334,7 +350,7 @@ func TestDeleteById(t *testing.T) {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					id, err := strconv.Atoi(idStr)
 					if err == nil {
-						mock_service.On("DeleteById", id).Return(tc.mockReturn, tc.mockError)
+						mock_service.On("DeleteById", mock.Anything, id).Return(tc.mockReturn, tc.mockError)
 					}
 				}
 			}
@@ -389,7 +405,7 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:     "id=2",
 			requestBody:    `{"title":"New Title","stock":5,"author_id":1}`,
 			mockReturn:     nil,
-			mockError:      errors.New("book not found"),
+			mockError:      apperror.NotFound("book not found"),
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "book not found",
 			httpMethod:     http.MethodPut,
@@ -409,20 +425,12 @@ func TestUpdateByID(t *testing.T) {
 			expectedBody:   `"title":"Updated Book"`,
 			httpMethod:     http.MethodPut,
 		},
-		{
-			name:           "Invalid Method",
-			queryParam:     "id=1",
-			requestBody:    `{"title":"Should Fail","stock":5,"author_id":1}`,
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed",
-			httpMethod:     http.MethodGet,
-		},
 		{
 			name:           "Book is nil",
 			queryParam:     "id=1",
 			requestBody:    `{"title":"Updated Book","stock":5,"author_id":1}`, // vẫn cần requestBody để decode thành book
 			mockReturn:     nil,
-			mockError:      errors.New("book is nil"),
+			mockError:      apperror.NewValidation("", "book is nil"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "book is nil",
 			httpMethod:     http.MethodPut,
@@ -432,7 +440,7 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:     "id=-1",
 			requestBody:    `{"title":"Updated Book","stock":5,"author_id":1}`,
 			mockReturn:     nil,
-			mockError:      errors.New("invalid book ID"),
+			mockError:      apperror.NewValidation("id", "invalid book ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "invalid book ID",
 			httpMethod:     http.MethodPut,
@@ -442,7 +450,7 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:     "id=1",
 			requestBody:    `{"title":" ","stock":5,"author_id":1}`,
 			mockReturn:     nil,
-			mockError:      errors.New("book title is required"),
+			mockError:      apperror.NewValidation("title", "book title is required"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "book title is required",
 			httpMethod:     http.MethodPut,
@@ -452,7 +460,7 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:     "id=1",
 			requestBody:    `{"title":"Book","stock":5,"author_id":0}`,
 			mockReturn:     nil,
-			mockError:      errors.New("book author ID is required"),
+			mockError:      apperror.NewValidation("author_id", "book author ID is required"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "book author ID is required",
 			httpMethod:     http.MethodPut,
@@ -462,7 +470,7 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:     "id=1",
 			requestBody:    `{"title":"Book","stock":-5,"author_id":1}`,
 			mockReturn:     nil,
-			mockError:      errors.New("book quantity cannot be negative"),
+			mockError:      apperror.NewValidation("stock", "book quantity cannot be negative"),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "book quantity cannot be negative",
 			httpMethod:     http.MethodPut,
@@ -472,7 +480,7 @@ func TestUpdateByID(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockBookService)
-			handler := book.NewBookHandler(mock_service)
+			handler := book.NewBookHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			url := "/book/update"
 			if tc.queryParam != "" {
@@ -486,7 +494,7 @@ func TestUpdateByID(t *testing.T) {
 			if tc.mockReturn != nil || tc.mockError != nil {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					if id, err := strconv.Atoi(idStr); err == nil {
-						mock_service.On("UpdateById", mock.MatchedBy(func(a *models.Book) bool {
+						mock_service.On("UpdateById", mock.Anything, mock.MatchedBy(func(a *models.Book) bool {
 							return a.ID == id
 						})).Return(tc.mockReturn, tc.mockError)
 					}
@@ -531,7 +539,7 @@ func TestGetBookByID(t *testing.T) {
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=1",
 			mockReturn:     nil,
-			mockError:      errors.New("book not found"),
+			mockError:      apperror.NotFound("book not found"),
 			expectedStatus: http.StatusNotFound,
 			expectErrorMsg: "book not found",
 		},
@@ -553,18 +561,11 @@ func TestGetBookByID(t *testing.T) {
 				AuthorID: 1,
 			},
 		},
-		{
-			name:           "Wrong HTTP method",
-			httpMethod:     http.MethodPost,
-			queryParam:     "id=1",
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
-		},
 	}
 
 	for _, tc := range tests {
 		mock_service := new(mockservice.MockBookService)
-		handler := book.NewBookHandler(mock_service)
+		handler := book.NewBookHandler(mock_service, slog.New(slog.DiscardHandler))
 
 		url := "/book"
 		if tc.queryParam != "" {
@@ -574,7 +575,7 @@ func TestGetBookByID(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		if tc.httpMethod == http.MethodGet && tc.queryParam != "" && (tc.mockError != nil || tc.mockReturn != nil) {
-			mock_service.On("GetByBookID", mock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
+			mock_service.On("GetByBookID", mock.Anything, mock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
 		}
 
 		handler.GetByBookID(w, req)
@@ -2,32 +2,27 @@ package book
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
 	"github.com/maithuc2003/re-book-api/internal/models"
 	"github.com/maithuc2003/re-book-api/internal/service/book"
-
-	"github.com/go-sql-driver/mysql"
 )
 
 type BookHandler struct {
 	serviceBook book.BookServiceInterface
+	logger      *slog.Logger
 }
 
-func NewBookHandler(serviceBook book.BookServiceInterface) *BookHandler {
-	return &BookHandler{serviceBook: serviceBook}
+func NewBookHandler(serviceBook book.BookServiceInterface, logger *slog.Logger) *BookHandler {
+	return &BookHandler{serviceBook: serviceBook, logger: logger}
 }
 
 // Thuộc tính Fontend gửi backend gửi cái gì (intetnet) tcp,http
 func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	// Parse the request body to get the book details
 	var book models.Book
 	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
@@ -37,34 +32,12 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
 
 	// Set createdAt hiện tại
 	book.CreatedAt = time.Now()
-	err := h.serviceBook.CreateBook(&book)
+	err := h.serviceBook.CreateBook(r.Context(), &book)
 
 	if err != nil {
 		// Log chi tiết lỗi ở server để biết nguyên nhân
-		log.Printf("Created book error : %v", err)
-		// Nếu là lỗi business logic (validate), trả lỗi chi tiết cho client
-		switch err.Error() {
-		case "book is nil":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		case "book title is required":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		case "book author ID is required":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		case "book quantity cannot be negative":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
-			http.Error(w, "Failed to create book: the book_id does not exist.", http.StatusBadRequest)
-			return
-		}
-
-		http.Error(w, "Failed to create book due to internal server error.", http.StatusInternalServerError)
-		//Server gặp lỗi khi tạo sách
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create book failed", "err", err)
+		httperror.Write(w, err, "Failed to create book due to internal server error.")
 		return
 	}
 
@@ -74,19 +47,17 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// GetAllBooks hỗ trợ ?limit=&cursor=&sort= và filter author_id, category_id, work_id, publisher_id, min_stock, max_stock, title_prefix
 func (h *BookHandler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	filter, err := bookFilter(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	books, err := h.serviceBook.GetAllBooks()
+	books, err := h.serviceBook.GetAllBooks(r.Context(), filter)
 	if err != nil {
-		log.Printf("GetAllBooks error : %v", err)
-		if err.Error() == "no books found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to get books", http.StatusInternalServerError)
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list books failed", "err", err)
+		httperror.Write(w, err, "Failed to get books")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -94,89 +65,155 @@ func (h *BookHandler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(books)
 }
 
-func (h *BookHandler) GetByBookID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// GetBooksByAuthor trả về sách của một tác giả: GET /authors/{id}/books
+func (h *BookHandler) GetBooksByAuthor(w http.ResponseWriter, r *http.Request) {
+	authorID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+	books, err := h.serviceBook.GetBooksByAuthorID(r.Context(), authorID)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list books by author failed", "err", err)
+		httperror.Write(w, err, "Failed to get books")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(books)
+}
+
+// GetBooksByCategory trả về sách của một category và các category con cháu: GET /categories/{id}/books
+func (h *BookHandler) GetBooksByCategory(w http.ResponseWriter, r *http.Request) {
+	h.listBy(w, r, "category", func(f *models.BookFilter, id int) { f.CategoryID = id })
+}
+
+// GetEditions trả về mọi ấn bản của một work: GET /works/{id}/editions
+func (h *BookHandler) GetEditions(w http.ResponseWriter, r *http.Request) {
+	h.listBy(w, r, "work", func(f *models.BookFilter, id int) { f.WorkID = id })
+}
+
+// GetBooksByPublisher trả về danh mục sách của một nhà xuất bản: GET /publishers/{id}/books
+func (h *BookHandler) GetBooksByPublisher(w http.ResponseWriter, r *http.Request) {
+	h.listBy(w, r, "publisher", func(f *models.BookFilter, id int) { f.PublisherID = id })
+}
+
+// listBy lấy id từ path làm filter, cùng phân trang và các filter khác như GET /books
+func (h *BookHandler) listBy(w http.ResponseWriter, r *http.Request, by string, set func(f *models.BookFilter, id int)) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	// 2. Chuyển id từ string sang int
-	id, err := strconv.Atoi(idStr)
+	filter, err := bookFilter(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
-	// 3. Gọi service để lấy sách
-	book, err := h.serviceBook.GetByBookID(id)
+	set(&filter, id)
+	books, err := h.serviceBook.GetAllBooks(r.Context(), filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list books by "+by+" failed", "err", err)
+		httperror.Write(w, err, "Failed to get books")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(book)
+	json.NewEncoder(w).Encode(books)
 }
 
-func (h *BookHandler) DeleteById(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+func bookFilter(r *http.Request) (models.BookFilter, error) {
+	var filter models.BookFilter
+	var err error
+	if filter.Params, err = params.Page(r); err != nil {
+		return filter, err
+	}
+	authorID, err := params.QueryInt(r, "author_id")
+	if err != nil {
+		return filter, err
+	}
+	if authorID != nil {
+		filter.AuthorID = *authorID
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{
+		{"category_id", &filter.CategoryID},
+		{"work_id", &filter.WorkID},
+		{"publisher_id", &filter.PublisherID},
+	} {
+		id, err := params.QueryInt(r, p.name)
+		if err != nil {
+			return filter, err
+		}
+		if id != nil {
+			*p.dst = *id
+		}
+	}
+	if filter.MinStock, err = params.QueryInt(r, "min_stock"); err != nil {
+		return filter, err
+	}
+	if filter.MaxStock, err = params.QueryInt(r, "max_stock"); err != nil {
+		return filter, err
 	}
+	filter.TitlePrefix = r.URL.Query().Get("title_prefix")
+	return filter, nil
+}
 
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+func (h *BookHandler) GetByBookID(w http.ResponseWriter, r *http.Request) {
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	// 2. Chuyển id từ string sang int
-	id, err := strconv.Atoi(idStr)
+	// 2. Gọi service để lấy sách
+	book, err := h.serviceBook.GetByBookID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "Failed to get book")
 		return
 	}
-	// 3.Gọi service để xóa sách
-	book, err := h.serviceBook.DeleteById(id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(book)
+}
+
+// GetByISBN tìm sách theo mã quét từ máy đọc: GET /books/isbn/{isbn}, nhận cả ISBN-10 và ISBN-13
+func (h *BookHandler) GetByISBN(w http.ResponseWriter, r *http.Request) {
+	book, err := h.serviceBook.GetByISBN(r.Context(), r.PathValue("isbn"))
 	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "invalid book ID"):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		case strings.Contains(err.Error(), "existing orders"):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		default:
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
+		httperror.Write(w, err, "Failed to get book")
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(book)
 }
 
-func (h *BookHandler) UpdateById(w http.ResponseWriter, r *http.Request) {
-	// Bảo vệ: chỉ cho phép PUT
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *BookHandler) DeleteById(w http.ResponseWriter, r *http.Request) {
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	// fmt.Println(idStr)
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+	// 2.Gọi service để xóa sách
+	book, err := h.serviceBook.DeleteById(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to delete book")
 		return
 	}
-	id, err := strconv.Atoi(idStr)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(book)
+}
+
+func (h *BookHandler) UpdateById(w http.ResponseWriter, r *http.Request) {
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
 
@@ -186,22 +223,47 @@ func (h *BookHandler) UpdateById(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	// 3. Gán lại id cho book để chắc chắn đúng
+	h.update(w, r, id, &updateBook)
+}
+
+// PatchById chỉ cập nhật các field có trong body, các field còn lại giữ nguyên.
+func (h *BookHandler) PatchById(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	existing, err := h.serviceBook.GetByBookID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get book")
+		return
+	}
+	// author_id (payload cũ) và authors cùng mô tả tác giả nên được gỡ ra trước khi decode:
+	// body có field nào thì field đó thay toàn bộ danh sách, không có thì giữ danh sách cũ
+	authorID, authors := existing.AuthorID, existing.Authors
+	existing.AuthorID, existing.Authors = 0, nil
+	// Decode đè lên bản ghi hiện tại: field nào không gửi lên thì giữ giá trị cũ
+	if err := json.NewDecoder(r.Body).Decode(existing); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if existing.AuthorID == 0 && existing.Authors == nil {
+		existing.AuthorID, existing.Authors = authorID, authors
+	}
+	h.update(w, r, id, existing)
+}
+
+func (h *BookHandler) update(w http.ResponseWriter, r *http.Request, id int, updateBook *models.Book) {
+	// Gán lại id cho book để chắc chắn đúng
 	updateBook.ID = id // Gán ID từ URL vào struct
 	updateBook.UpdatedAt = time.Now()
-	// 3.Gọi service để cập nhất sách
-	book, err := h.serviceBook.UpdateById(&updateBook)
-	if err != nil {
-		switch err.Error() {
-		case "book is nil", "invalid book ID", "book title is required", "book author ID is required", "book quantity cannot be negative":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, err.Error(), http.StatusNotFound)
-		}
+	// Gọi service để cập nhất sách
+	book, err := h.serviceBook.UpdateById(r.Context(), updateBook)
+	if err != nil {
+		httperror.Write(w, err, "Failed to update book")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(book)
-
 }
@@ -0,0 +1,123 @@
+package category_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/category"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetByID(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		prepare        func(m *mockservice.MockCategoryService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "By id",
+			key:  "4",
+			prepare: func(m *mockservice.MockCategoryService) {
+				m.On("GetByID", mock.Anything, 4).Return(&models.Category{ID: 4, Slug: "tieu-thuyet"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"slug":"tieu-thuyet"`,
+		},
+		{
+			name: "By slug",
+			key:  "tieu-thuyet",
+			prepare: func(m *mockservice.MockCategoryService) {
+				m.On("GetBySlug", mock.Anything, "tieu-thuyet").Return(&models.Category{ID: 4, Slug: "tieu-thuyet"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":4`,
+		},
+		{
+			name: "Unknown slug",
+			key:  "khong-co",
+			prepare: func(m *mockservice.MockCategoryService) {
+				m.On("GetBySlug", mock.Anything, "khong-co").Return(nil, apperror.NotFound("category with slug khong-co not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "category with slug khong-co not found",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockCategoryService)
+			tc.prepare(service)
+			handler := category.NewCategoryHandler(service, slog.New(slog.DiscardHandler))
+			req := httptest.NewRequest(http.MethodGet, "/categories/"+tc.key, nil)
+			req.SetPathValue("id", tc.key)
+			w := httptest.NewRecorder()
+
+			handler.GetByID(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPatchByID(t *testing.T) {
+	parent := 1
+	tests := []struct {
+		name           string
+		body           string
+		match          func(c *models.Category) bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "Fields not in body are kept",
+			body: `{"position":3}`,
+			match: func(c *models.Category) bool {
+				return c.ID == 4 && c.Name == "Tiểu thuyết" && c.Position == 3 && c.ParentID != nil && *c.ParentID == 1
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Null parent moves the category to the root",
+			body:           `{"parent_id":null}`,
+			match:          func(c *models.Category) bool { return c.ParentID == nil },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Move under a subcategory",
+			body:           `{"parent_id":9}`,
+			match:          func(c *models.Category) bool { return *c.ParentID == 9 },
+			mockError:      apperror.Unprocessable("category 4 cannot be moved under itself or its subcategory 9"),
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockCategoryService)
+			service.On("GetByID", mock.Anything, 4).Return(&models.Category{ID: 4, ParentID: &parent, Name: "Tiểu thuyết", Slug: "tieu-thuyet"}, nil)
+			if tc.mockError != nil {
+				service.On("UpdateByID", mock.Anything, mock.MatchedBy(tc.match)).Return(nil, tc.mockError)
+			} else {
+				service.On("UpdateByID", mock.Anything, mock.MatchedBy(tc.match)).Return(&models.Category{ID: 4}, nil)
+			}
+			handler := category.NewCategoryHandler(service, slog.New(slog.DiscardHandler))
+			req := httptest.NewRequest(http.MethodPatch, "/categories/4", strings.NewReader(tc.body))
+			req.SetPathValue("id", "4")
+			w := httptest.NewRecorder()
+
+			handler.PatchByID(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			service.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package category
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/category"
+)
+
+type CategoryHandler struct {
+	serviceCategory category.CategoryServiceInterface
+	logger          *slog.Logger
+}
+
+func NewCategoryHandler(serviceCategory category.CategoryServiceInterface, logger *slog.Logger) *CategoryHandler {
+	return &CategoryHandler{serviceCategory: serviceCategory, logger: logger}
+}
+
+// CreateCategory: POST /categories {"name", "slug", "parent_id", "position"}
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var c models.Category
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	c.ID = 0
+	if err := h.serviceCategory.CreateCategory(r.Context(), &c); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create category failed", "slug", c.Slug, "err", err)
+		httperror.Write(w, err, "Failed to create category")
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// GetTree trả về toàn bộ cây category cho menu storefront
+func (h *CategoryHandler) GetTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.serviceCategory.GetTree(r.Context())
+	if err != nil {
+		httperror.Write(w, err, "Failed to get categories")
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// GetByID nhận id hoặc slug: GET /categories/12 hoặc GET /categories/van-hoc.
+// Slug không thể toàn chữ số nên không nhầm với id.
+func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("id")
+	var (
+		c   *models.Category
+		err error
+	)
+	if id, convErr := strconv.Atoi(key); convErr == nil {
+		c, err = h.serviceCategory.GetByID(r.Context(), id)
+	} else {
+		c, err = h.serviceCategory.GetBySlug(r.Context(), key)
+	}
+	if err != nil {
+		httperror.Write(w, err, "Failed to get category")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *CategoryHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	var c models.Category
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, &c)
+}
+
+// PatchByID chỉ cập nhật các field có trong body; gửi "parent_id": null để chuyển thành category gốc.
+func (h *CategoryHandler) PatchByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	existing, err := h.serviceCategory.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get category")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(existing); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, existing)
+}
+
+func (h *CategoryHandler) update(w http.ResponseWriter, r *http.Request, id int, c *models.Category) {
+	c.ID = id
+	updated, err := h.serviceCategory.UpdateByID(r.Context(), c)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "update category failed", "category_id", id, "err", err)
+		httperror.Write(w, err, "Failed to update category")
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteByID: category còn category con hoặc còn sách không xoá được
+func (h *CategoryHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	c, err := h.serviceCategory.DeleteByID(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "delete category failed", "category_id", id, "err", err)
+		httperror.Write(w, err, "Failed to delete category")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
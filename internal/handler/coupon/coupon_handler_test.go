@@ -0,0 +1,70 @@
+package coupon_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/coupon"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateCoupon(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		skipService    bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			body:           `{"code":"SALE","type":"percentage","value":10}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"id":3`,
+		},
+		{
+			name:           "Duplicate code",
+			body:           `{"code":"SALE","type":"percentage","value":10}`,
+			mockError:      apperror.Conflict("coupon code SALE already exists"),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Validation error",
+			body:           `{"code":"SALE","type":"bogo"}`,
+			mockError:      apperror.NewValidation("type", "type must be one of percentage, fixed"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid body",
+			body:           `{`,
+			skipService:    true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockCouponService)
+			if !tc.skipService {
+				service.On("CreateCoupon", mock.Anything, mock.AnythingOfType("*models.Coupon")).Run(func(args mock.Arguments) {
+					args.Get(1).(*models.Coupon).ID = 3
+				}).Return(tc.mockError)
+			}
+			handler := coupon.NewCouponHandler(service, slog.New(slog.DiscardHandler))
+
+			w := httptest.NewRecorder()
+			handler.CreateCoupon(w, httptest.NewRequest(http.MethodPost, "/coupons", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package coupon
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/coupon"
+)
+
+type CouponHandler struct {
+	serviceCoupon coupon.CouponServiceInterface
+	logger        *slog.Logger
+}
+
+func NewCouponHandler(serviceCoupon coupon.CouponServiceInterface, logger *slog.Logger) *CouponHandler {
+	return &CouponHandler{serviceCoupon: serviceCoupon, logger: logger}
+}
+
+// CreateCoupon: POST /coupons {"code", "type", "value", "currency", "min_order_value", "book_ids", "author_ids",
+// "starts_at", "ends_at", "max_redemptions", "max_per_user"}
+func (h *CouponHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var c models.Coupon
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.serviceCoupon.CreateCoupon(r.Context(), &c); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create coupon failed", "code", c.Code, "err", err)
+		httperror.Write(w, err, "Failed to create coupon")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *CouponHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	coupons, err := h.serviceCoupon.GetAll(r.Context())
+	if err != nil {
+		httperror.Write(w, err, "Failed to get coupons")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupons)
+}
+
+func (h *CouponHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	c, err := h.serviceCoupon.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get coupon")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
+
+// DeleteByID: coupon đã có lượt dùng không xoá được
+func (h *CouponHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	c, err := h.serviceCoupon.DeleteByID(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "delete coupon failed", "coupon_id", id, "err", err)
+		httperror.Write(w, err, "Failed to delete coupon")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
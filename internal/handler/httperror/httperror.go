@@ -0,0 +1,71 @@
+// Package httperror chuyển lỗi domain (apperror) thành HTTP response.
+package httperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+)
+
+// statusTable là bảng duy nhất ánh xạ lỗi domain sang HTTP status.
+// Thứ tự có ý nghĩa: entry đầu tiên khớp với errors.Is sẽ được dùng.
+var statusTable = []struct {
+	kind   error
+	status int
+}{
+	{apperror.ErrValidation, http.StatusBadRequest},
+	{apperror.ErrNotFound, http.StatusNotFound},
+	{apperror.ErrConflict, http.StatusConflict},
+	{apperror.ErrInsufficientStock, http.StatusBadRequest},
+	{apperror.ErrForeignKey, http.StatusBadRequest},
+	{apperror.ErrUnprocessable, http.StatusUnprocessableEntity},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	{context.Canceled, StatusClientClosedRequest},
+}
+
+// StatusClientClosedRequest (nginx 499) được dùng khi client huỷ request giữa chừng.
+const StatusClientClosedRequest = 499
+
+// StatusCode trả về HTTP status của err theo statusTable, mặc định 500.
+func StatusCode(err error) int {
+	for _, entry := range statusTable {
+		if errors.Is(err, entry.kind) {
+			return entry.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// LogLevel chọn level log cho err: lỗi phía client (4xx) chỉ là Info, lỗi server (5xx) là Error.
+func LogLevel(err error) slog.Level {
+	if StatusCode(err) >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Write trả lỗi dạng JSON. Với lỗi 5xx, chi tiết nội bộ được thay bằng fallback
+// để không lộ thông tin database ra client.
+func Write(w http.ResponseWriter, err error, fallback string) {
+	status := StatusCode(err)
+	msg := err.Error()
+	if status >= http.StatusInternalServerError {
+		msg = fallback
+	}
+
+	body := map[string]any{"error": msg}
+	var detailer apperror.Detailer
+	if errors.As(err, &detailer) {
+		for k, v := range detailer.Details() {
+			body[k] = v
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
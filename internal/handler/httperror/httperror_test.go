@@ -0,0 +1,80 @@
+package httperror_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Validation", apperror.NewValidation("title", "book title is required"), http.StatusBadRequest},
+		{"Not found", apperror.NotFound("book with ID %d not found", 1), http.StatusNotFound},
+		{"Conflict", apperror.Conflict("author with the same name already exists"), http.StatusConflict},
+		{"Insufficient stock", apperror.InsufficientStock("not enough stock available"), http.StatusBadRequest},
+		{"Foreign key", apperror.ForeignKey(nil, "cannot delete book"), http.StatusBadRequest},
+		{"Unprocessable", apperror.Unprocessable("idempotency key reused with a different payload"), http.StatusUnprocessableEntity},
+		{"Wrapped with %w", fmt.Errorf("failed to retrieve author: %w", apperror.NotFound("author not found")), http.StatusNotFound},
+		{"Deadline exceeded", fmt.Errorf("failed to query books: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"Client canceled", context.Canceled, httperror.StatusClientClosedRequest},
+		{"Unknown error", errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedStatus, httperror.StatusCode(tc.err))
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("Validation error includes field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		httperror.Write(w, apperror.NewValidation("title", "book title is required"), "fallback")
+
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "book title is required", body["error"])
+		assert.Equal(t, "title", body["field"])
+	})
+
+	t.Run("Internal error hides details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		httperror.Write(w, errors.New("dial tcp 10.0.0.1:3306: connection refused"), "Failed to get books")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "Failed to get books")
+		assert.NotContains(t, w.Body.String(), "10.0.0.1")
+	})
+
+	t.Run("Invalid transition lists allowed statuses", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		httperror.Write(w, apperror.InvalidTransition("delivered", "pending", []string{"refunded"}), "fallback")
+
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, `cannot change status from "delivered" to "pending"`, body["error"])
+		assert.Equal(t, "delivered", body["current_status"])
+		assert.Equal(t, []any{"refunded"}, body["allowed_transitions"])
+	})
+}
+
+func TestLogLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelInfo, httperror.LogLevel(apperror.NotFound("book not found")))
+	assert.Equal(t, slog.LevelError, httperror.LogLevel(errors.New("db down")))
+}
@@ -2,31 +2,33 @@ package order
 
 import (
 	"encoding/json"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
 	"github.com/maithuc2003/re-book-api/internal/models"
 	"github.com/maithuc2003/re-book-api/internal/service/order"
-
-	"github.com/go-sql-driver/mysql"
 )
 
 type OrderHandler struct {
 	serviceOrder order.OrderServiceInterface
+	logger       *slog.Logger
 }
 
-func NewOrderHandler(serviceOrder order.OrderServiceInterface) *OrderHandler {
-	return &OrderHandler{serviceOrder: serviceOrder}
+func NewOrderHandler(serviceOrder order.OrderServiceInterface, logger *slog.Logger) *OrderHandler {
+	return &OrderHandler{serviceOrder: serviceOrder, logger: logger}
 }
 
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader báo response là bản lưu của lần tạo trước
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	// Parse request body
 	var order models.Order
 	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
@@ -35,69 +37,48 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	order.OrderedAt = time.Now()
 
-	err := h.serviceOrder.CreateOrder(&order)
+	// Có Idempotency-Key thì request retry nhận lại đúng order đã tạo, không trừ stock lần nữa
+	var err error
+	if len(r.Header.Values(IdempotencyKeyHeader)) > 0 {
+		var replayed bool
+		replayed, err = h.serviceOrder.CreateOrderIdempotent(r.Context(), &order, r.Header.Get(IdempotencyKeyHeader))
+		if replayed {
+			w.Header().Set(IdempotentReplayedHeader, "true")
+		}
+	} else {
+		err = h.serviceOrder.CreateOrder(r.Context(), &order)
+	}
 	if err != nil {
 		// Log lỗi server
-		log.Printf("CreateOrder error: %v", err)
-		// Validate các lỗi đầu vào từ service
-		switch err.Error() {
-		case "order is nil", "invalid book ID", "invalid user ID", "quantity must be greater than zero", "status is required":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		// Kiểm tra lỗi MySQL foreign key
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
-			http.Error(w, "Failed to create order: foreign key constraint violation.", http.StatusBadRequest)
-			return
-		}
-
-		// Xử lý lỗi theo nội dung chuỗi error message
-		w.Header().Set("Content-Type", "application/json")
-
-		var statusCode int
-		var errorMsg string
-
-		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
-			statusCode = http.StatusNotFound
-			errorMsg = "Product not found or no stock information"
-		case strings.Contains(err.Error(), "not enough stock"):
-			statusCode = http.StatusBadRequest
-			errorMsg = "Not enough stock available"
-		default:
-			statusCode = http.StatusInternalServerError
-			errorMsg = "Internal server error"
-		}
-
-		w.WriteHeader(statusCode)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": errorMsg,
-		})
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create order failed", "err", err)
+		httperror.Write(w, err, "Internal server error")
 		return
 	}
 
 	// Nếu thành công trả về JSON
+	message := "Order created successfully"
+	if order.Status == models.OrderBackordered {
+		message = "Order backordered until stock is available"
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Order created successfully",
+		"message": message,
 		"order":   order,
 	})
 }
 
+// GetAllOrders hỗ trợ ?limit=&cursor=&sort= và filter status, user_id, book_id, from, to
 func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	filter, err := orderFilter(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	orders, err := h.serviceOrder.GetAllOrders()
+	orders, err := h.serviceOrder.GetAllOrders(r.Context(), filter)
 	if err != nil {
-		log.Printf("GetAllOrder errr: %v", err)
-		if err.Error() == "no books found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to get order", http.StatusInternalServerError)
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list orders failed", "err", err)
+		httperror.Write(w, err, "Failed to get order")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -105,33 +86,46 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(orders)
 }
 
-func (h *OrderHandler) GetByOrderID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+func orderFilter(r *http.Request) (models.OrderFilter, error) {
+	filter := models.OrderFilter{Status: r.URL.Query().Get("status")}
+	var err error
+	if filter.Params, err = params.Page(r); err != nil {
+		return filter, err
 	}
-	// 1. Lấy tham số id từ url query
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
-		return
+	userID, err := params.QueryInt(r, "user_id")
+	if err != nil {
+		return filter, err
+	}
+	if userID != nil {
+		filter.UserID = *userID
+	}
+	bookID, err := params.QueryInt(r, "book_id")
+	if err != nil {
+		return filter, err
+	}
+	if bookID != nil {
+		filter.BookID = *bookID
 	}
-	id, err := strconv.Atoi(idStr)
+	if filter.From, err = params.QueryTime(r, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = params.QueryTime(r, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func (h *OrderHandler) GetByOrderID(w http.ResponseWriter, r *http.Request) {
+	// 1. Lấy tham số id từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
-	// 3. Gọi service để lấy order
-	order, err := h.serviceOrder.GetByOrderID(id)
+	// 2. Gọi service để lấy order
+	order, err := h.serviceOrder.GetByOrderID(r.Context(), id)
 	if err != nil {
-		switch err.Error() {
-		case "invalid order ID":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case "existing orders": // nếu có xử lý cụ thể, giữ lại
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, err.Error(), http.StatusNotFound)
-		}
+		httperror.Write(w, err, "Failed to get order")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -141,28 +135,14 @@ func (h *OrderHandler) GetByOrderID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *OrderHandler) DeleteByOrderID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
-		return
-	}
-	id, err := strconv.Atoi(idStr)
+	id, err := params.ID(r)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "")
 		return
 	}
-	order, err := h.serviceOrder.DeleteByOrderID(id)
+	order, err := h.serviceOrder.DeleteByOrderID(r.Context(), id)
 	if err != nil {
-		if err.Error() == "invalid order ID" {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusNotFound)
+		httperror.Write(w, err, "Failed to delete order")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -172,54 +152,112 @@ func (h *OrderHandler) DeleteByOrderID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *OrderHandler) UpdateByOrderID(w http.ResponseWriter, r *http.Request) {
-	// Bảo vệ: chỉ cho phép PUT
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// 1. Lấy tham số `id` từ path (hoặc query với route legacy)
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	// 2. Deconde body (JSON) vào struct Order
+	var updateOrder models.Order
+	if err := json.NewDecoder(r.Body).Decode(&updateOrder); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	// 1. Lấy tham số `id` từ URL query
-	idStr := r.URL.Query().Get("id")
-	// fmt.Println(idStr)
-	if idStr == "" {
-		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+	h.update(w, r, id, &updateOrder)
+}
+
+// PatchByOrderID chỉ cập nhật các field có trong body, các field còn lại giữ nguyên.
+func (h *OrderHandler) PatchByOrderID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
 		return
 	}
-	id, err := strconv.Atoi(idStr)
+	existing, err := h.serviceOrder.GetByOrderID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
+		httperror.Write(w, err, "Failed to get order")
 		return
 	}
-	// 2. Deconde body (JSON) vào struct BOOK
-	var updateOrder models.Order
-	if err := json.NewDecoder(r.Body).Decode(&updateOrder); err != nil {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	// 3. Gán lại id cho order để đúng
+	// Client cũ chỉ gửi book_id/quantity: với order một dòng thì dựng lại items từ hai field này
+	var probe struct {
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if probe.Items == nil && len(existing.Items) == 1 {
+		existing.Items = nil
+	}
+	// Decode đè lên bản ghi hiện tại: field nào không gửi lên thì giữ giá trị cũ
+	if err := json.Unmarshal(body, existing); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, existing)
+}
+
+func (h *OrderHandler) update(w http.ResponseWriter, r *http.Request, id int, updateOrder *models.Order) {
+	// Gán lại id cho order để đúng
 	updateOrder.ID = id
 	updateOrder.UpdatedAt = time.Now()
-	// 3. Gọi service để cập nhập order
-	order, err := h.serviceOrder.UpdateByOrderID(&updateOrder)
-	if err != nil {
-		switch err.Error() {
-		case "order is nil",
-			"invalid order ID",
-			"invalid book ID",
-			"invalid user ID",
-			"quantity must be greater than zero",
-			"status is required":
-			http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
-			return
-		case "foreign key constraint fails: book_id does not exist":
-			http.Error(w, "Invalid book_id: book does not exist", http.StatusBadRequest)
+	// Gọi service để cập nhập order
+	order, err := h.serviceOrder.UpdateByOrderID(r.Context(), updateOrder)
+	if err != nil {
+		httperror.Write(w, err, "Failed to update order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// Transition trả về handler cho POST /orders/{id}/{action}: chuyển order sang status,
+// trả 409 kèm các trạng thái hợp lệ nếu vòng đời không cho phép.
+func (h *OrderHandler) Transition(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := params.ID(r)
+		if err != nil {
+			httperror.Write(w, err, "")
 			return
-		default:
-			http.Error(w, "Failed to update order: "+err.Error(), http.StatusInternalServerError)
+		}
+		order, err := h.serviceOrder.TransitionOrder(r.Context(), id, status)
+		if err != nil {
+			h.logger.Log(r.Context(), httperror.LogLevel(err), "order transition failed", "order_id", id, "status", status, "err", err)
+			httperror.Write(w, err, "Failed to update order status")
 			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
 	}
+}
 
+// ConfirmReservation xử lý POST /reservations/{id}/confirm: tạo order từ hàng đang giữ,
+// trả 409 nếu reservation đã hết hạn hoặc không còn active.
+func (h *OrderHandler) ConfirmReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	order, err := h.serviceOrder.ConfirmReservation(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "confirm reservation failed", "reservation_id", id, "err", err)
+		httperror.Write(w, err, "Failed to confirm reservation")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(order)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Order created successfully",
+		"order":   order,
+	})
 }
@@ -4,96 +4,124 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/handler/order"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/maithuc2003/re-book-api/test/mockservice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func TestGetAllOrders(t *testing.T) {
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name             string
-		httpMethod       string
-		mockReturn       []*models.Order
+		url              string
+		expectedFilter   models.OrderFilter
+		mockReturn       *pagination.Page[*models.Order]
 		mockError        error
+		skipService      bool
 		expectedStatus   int
-		expectedResult   []*models.Order
+		expectedResult   *pagination.Page[*models.Order]
 		expectedErrorMsg string
 	}{
 		{
-			name:       "Success - return orders",
-			httpMethod: http.MethodGet,
-			mockReturn: []*models.Order{
-				{
-					ID:       1,
-					BookID:   101,
-					UserID:   202,
-					Quantity: 2,
-					Status:   "Pending",
+			name: "Success - return orders",
+			url:  "/orders",
+			mockReturn: &pagination.Page[*models.Order]{
+				Data: []*models.Order{
+					{
+						ID:       1,
+						BookID:   101,
+						UserID:   202,
+						Quantity: 2,
+						Status:   "Pending",
+					},
 				},
+				Total: 1,
 			},
 
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedResult: []*models.Order{
-				{
-					ID:       1,
-					BookID:   101,
-					UserID:   202,
-					Quantity: 2,
-					Status:   "Pending",
+			expectedResult: &pagination.Page[*models.Order]{
+				Data: []*models.Order{
+					{
+						ID:       1,
+						BookID:   101,
+						UserID:   202,
+						Quantity: 2,
+						Status:   "Pending",
+					},
 				},
+				Total: 1,
+			},
+		},
+		{
+			name: "Query parameters are passed as filter",
+			url:  "/orders?limit=10&sort=id&status=pending&user_id=202&book_id=101&from=2024-03-01&to=2024-04-01T00:00:00Z",
+			expectedFilter: models.OrderFilter{
+				Params: pagination.Params{Limit: 10, Sort: "id"},
+				Status: "pending",
+				UserID: 202,
+				BookID: 101,
+				From:   &from,
+				To:     &to,
 			},
+			mockReturn:     &pagination.Page[*models.Order]{Data: []*models.Order{}},
+			expectedStatus: http.StatusOK,
+			expectedResult: &pagination.Page[*models.Order]{Data: []*models.Order{}},
+		},
+		{
+			name:             "Invalid date",
+			url:              "/orders?from=yesterday",
+			skipService:      true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedErrorMsg: "Invalid 'from' parameter",
 		},
 		{
-			name:             "No books found - 404 error",
-			httpMethod:       http.MethodGet,
+			name:             "No orders found - 404 error",
+			url:              "/orders",
 			mockReturn:       nil,
-			mockError:        errors.New("no books found"),
+			mockError:        apperror.NotFound("no orders found"),
 			expectedStatus:   http.StatusNotFound,
-			expectedErrorMsg: "no books found",
+			expectedErrorMsg: "no orders found",
 		},
 		{
 			name:             "Error from service",
-			httpMethod:       http.MethodGet,
+			url:              "/orders",
 			mockReturn:       nil,
 			mockError:        errors.New("DB error"),
 			expectedStatus:   http.StatusInternalServerError,
 			expectedErrorMsg: "Failed to get order",
 		},
-		{
-			name:             "Method Not Allowed",
-			httpMethod:       http.MethodPost, // hoặc PUT, DELETE,...
-			mockReturn:       nil,
-			mockError:        nil,
-			expectedStatus:   http.StatusMethodNotAllowed,
-			expectedErrorMsg: "Method not allowed",
-		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockOrderService)
-			handler := order.NewOrderHandler(mock_service)
+			handler := order.NewOrderHandler(mock_service, slog.New(slog.DiscardHandler))
 
-			if tc.httpMethod == http.MethodGet {
-				mock_service.On("GetAllOrders").Return(tc.mockReturn, tc.mockError)
+			if !tc.skipService {
+				mock_service.On("GetAllOrders", mock.Anything, tc.expectedFilter).Return(tc.mockReturn, tc.mockError)
 			}
-			req := httptest.NewRequest(tc.httpMethod, "/orders", nil)
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
 			w := httptest.NewRecorder()
 
 			handler.GetAllOrders(w, req)
 			assert.Equal(t, tc.expectedStatus, w.Code)
 
 			if tc.expectedStatus == http.StatusOK && tc.mockError == nil {
-				var result []*models.Order
+				var result *pagination.Page[*models.Order]
 				err := json.NewDecoder(w.Body).Decode(&result)
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedResult, result)
@@ -135,22 +163,15 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 3,
 				Status:   "Pending",
 			},
-			mockError:      &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			mockError:      apperror.ForeignKey(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, "foreign key constraint fails: book_id or user_id does not exist"),
 			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "foreign key constraint violation",
-		},
-		{
-			name:           "Invalid method",
-			httpMethod:     http.MethodGet,
-			requestBody:    nil,
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
+			expectErrorMsg: "foreign key constraint fails",
 		},
 		{
 			name:           "Order is nil",
 			httpMethod:     http.MethodPost,
 			requestBody:    &models.Order{}, // vẫn cần truyền JSON hợp lệ
-			mockError:      errors.New("order is nil"),
+			mockError:      apperror.NewValidation("", "order is nil"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "order is nil",
 		},
@@ -163,7 +184,7 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 3,
 				Status:   "Pending",
 			},
-			mockError:      errors.New("invalid book ID"),
+			mockError:      apperror.NewValidation("book_id", "invalid book ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "invalid book ID",
 		},
@@ -176,7 +197,7 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 3,
 				Status:   "Pending",
 			},
-			mockError:      errors.New("invalid user ID"),
+			mockError:      apperror.NewValidation("user_id", "invalid user ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "invalid user ID",
 		},
@@ -189,7 +210,7 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 0,
 				Status:   "Pending",
 			},
-			mockError:      errors.New("quantity must be greater than zero"),
+			mockError:      apperror.NewValidation("quantity", "quantity must be greater than zero"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "quantity must be greater than zero",
 		},
@@ -202,12 +223,12 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 1,
 				Status:   "",
 			},
-			mockError:      errors.New("status is required"),
+			mockError:      apperror.NewValidation("status", "status is required"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "status is required",
 		},
 		{
-			name:       "No rows in result set",
+			name:       "Book not found",
 			httpMethod: http.MethodPost,
 			requestBody: &models.Order{
 				BookID:   1,
@@ -215,9 +236,9 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 3,
 				Status:   "Pending",
 			},
-			mockError:      errors.New("sql: no rows in result set"),
+			mockError:      apperror.NotFound("book with ID 1 not found"),
 			expectedStatus: http.StatusNotFound,
-			expectErrorMsg: "Product not found or no stock information",
+			expectErrorMsg: "book with ID 1 not found",
 		},
 		{
 			name:       "Not enough stock available",
@@ -228,9 +249,9 @@ func TestCreateOrder(t *testing.T) {
 				Quantity: 3,
 				Status:   "Pending",
 			},
-			mockError:      errors.New("not enough stock"),
+			mockError:      apperror.InsufficientStock("not enough stock available"),
 			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "Not enough stock available",
+			expectErrorMsg: "not enough stock available",
 		},
 		{
 			name:           "Invalid JSON body",
@@ -257,7 +278,7 @@ func TestCreateOrder(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockOrderService)
-			handler := order.NewOrderHandler(mock_service)
+			handler := order.NewOrderHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			var bodyBytes []byte
 			var err error
@@ -273,15 +294,8 @@ func TestCreateOrder(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			w := httptest.NewRecorder()
 
-			if tc.httpMethod != http.MethodPost {
-				handler.CreateOrder(w, req)
-				assert.Equal(t, tc.expectedStatus, w.Code)
-				assert.Contains(t, w.Body.String(), tc.expectErrorMsg)
-				return
-			}
-
 			if tc.expectErrorMsg != "Invalid request body" {
-				mock_service.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(tc.mockError)
+				mock_service.On("CreateOrder", mock.Anything, mock.AnythingOfType("*models.Order")).Return(tc.mockError)
 			}
 
 			handler.CreateOrder(w, req)
@@ -297,6 +311,74 @@ func TestCreateOrder(t *testing.T) {
 	}
 }
 
+func TestCreateOrder_Backordered(t *testing.T) {
+	mockService := new(mockservice.MockOrderService)
+	mockService.On("CreateOrder", mock.Anything, mock.AnythingOfType("*models.Order")).Run(func(args mock.Arguments) {
+		o := args.Get(1).(*models.Order)
+		o.ID, o.Status = 4, models.OrderBackordered
+	}).Return(nil)
+	handler := order.NewOrderHandler(mockService, slog.New(slog.DiscardHandler))
+
+	w := httptest.NewRecorder()
+	handler.CreateOrder(w, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"book_id":1,"user_id":2,"quantity":3}`)))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"backordered"`)
+	assert.Contains(t, w.Body.String(), "Order backordered until stock is available")
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateOrder_IdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name             string
+		key              string
+		replayed         bool
+		mockError        error
+		expectedStatus   int
+		expectedReplayed string
+		expectedBody     string
+	}{
+		{name: "First request", key: "retry-1", expectedStatus: http.StatusCreated},
+		{
+			name: "Replay returns the same order and status", key: "retry-1", replayed: true,
+			expectedStatus: http.StatusCreated, expectedReplayed: "true", expectedBody: `"id":5`,
+		},
+		{
+			name: "Key reused with another payload", key: "retry-1",
+			mockError:      apperror.Unprocessable("Idempotency-Key \"retry-1\" was already used with a different request"),
+			expectedStatus: http.StatusUnprocessableEntity, expectedBody: "already used",
+		},
+		{
+			name: "Empty key is still validated", key: "",
+			mockError:      apperror.NewValidation("Idempotency-Key", "Idempotency-Key must be 1 to 255 characters"),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockservice.MockOrderService)
+			handler := order.NewOrderHandler(mockService, slog.New(slog.DiscardHandler))
+			mockService.On("CreateOrderIdempotent", mock.Anything, mock.AnythingOfType("*models.Order"), tc.key).
+				Run(func(args mock.Arguments) {
+					if tc.replayed {
+						args.Get(1).(*models.Order).ID = 5
+					}
+				}).
+				Return(tc.replayed, tc.mockError)
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"book_id":1,"user_id":2,"quantity":1}`))
+			req.Header.Set(order.IdempotencyKeyHeader, tc.key)
+			w := httptest.NewRecorder()
+			handler.CreateOrder(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedReplayed, w.Header().Get(order.IdempotentReplayedHeader))
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDeleteById(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -316,13 +398,6 @@ func TestDeleteById(t *testing.T) {
 			expectedStatus:   http.StatusOK,
 			expectedErrorMsg: `"id":1`,
 		},
-		{
-			name:             "Method not allowed",
-			httpMethod:       http.MethodGet,
-			queryParam:       "id=1",
-			expectedStatus:   http.StatusMethodNotAllowed,
-			expectedErrorMsg: "Method not allowed",
-		},
 		{
 			name:             "Missing ID parameter",
 			httpMethod:       http.MethodDelete,
@@ -342,7 +417,7 @@ func TestDeleteById(t *testing.T) {
 			httpMethod:       http.MethodDelete,
 			queryParam:       "id=999",
 			mockReturn:       nil,
-			mockError:        errors.New("Order not found"),
+			mockError:        apperror.NotFound("Order not found"),
 			expectedStatus:   http.StatusNotFound,
 			expectedErrorMsg: "Order not found",
 		},
@@ -351,7 +426,7 @@ func TestDeleteById(t *testing.T) {
 			httpMethod:       http.MethodDelete,
 			queryParam:       "id=-1",
 			mockReturn:       nil,
-			mockError:        errors.New("invalid order ID"),
+			mockError:        apperror.NewValidation("id", "invalid order ID"),
 			expectedStatus:   http.StatusBadRequest,
 			expectedErrorMsg: "invalid order ID",
 		},
@@ -360,7 +435,7 @@ func TestDeleteById(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockOrderService)
-			handler := order.NewOrderHandler(mock_service)
+			handler := order.NewOrderHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			url := "/order/delete"
 			if tc.queryParam != "" {
@@ -375,7 +450,7 @@ func TestDeleteById(t *testing.T) {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					id, err := strconv.Atoi(idStr)
 					if err == nil {
-						mock_service.On("DeleteByOrderID", id).Return(tc.mockReturn, tc.mockError)
+						mock_service.On("DeleteByOrderID", mock.Anything, id).Return(tc.mockReturn, tc.mockError)
 					}
 				}
 			}
@@ -410,14 +485,6 @@ func TestUpdateByID(t *testing.T) {
 			expectedErrorMsg: `"id":1`,
 			httpMethod:       http.MethodPut,
 		},
-		{
-			name:             "Invalid method",
-			queryParam:       "id=1",
-			requestBody:      `{"book_id":101}`,
-			expectedStatus:   http.StatusMethodNotAllowed,
-			expectedErrorMsg: "Method not allowed",
-			httpMethod:       http.MethodGet,
-		},
 		{
 			name:             "Missing ID param",
 			queryParam:       "",
@@ -447,9 +514,9 @@ func TestUpdateByID(t *testing.T) {
 			queryParam:       "id=1",
 			requestBody:      `{"book_id":999,"user_id":1,"quantity":1,"status":"Pending"}`,
 			mockReturn:       nil,
-			mockError:        errors.New("foreign key constraint fails: book_id does not exist"),
+			mockError:        apperror.ForeignKey(nil, "foreign key constraint fails: book_id does not exist"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Invalid book_id: book does not exist",
+			expectedErrorMsg: "book_id does not exist",
 			httpMethod:       http.MethodPut,
 		},
 		{
@@ -466,45 +533,45 @@ func TestUpdateByID(t *testing.T) {
 			name:             "Invalid order ID (<= 0)",
 			queryParam:       "id=0",
 			requestBody:      `{"book_id":101,"user_id":201,"quantity":2,"status":"Confirmed"}`,
-			mockError:        errors.New("invalid order ID"),
+			mockError:        apperror.NewValidation("id", "invalid order ID"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Validation error: invalid order ID",
+			expectedErrorMsg: "invalid order ID",
 			httpMethod:       http.MethodPut,
 		},
 		{
 			name:             "Invalid book ID (<= 0)",
 			queryParam:       "id=1",
 			requestBody:      `{"book_id":0,"user_id":201,"quantity":2,"status":"Confirmed"}`,
-			mockError:        errors.New("invalid book ID"),
+			mockError:        apperror.NewValidation("book_id", "invalid book ID"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Validation error: invalid book ID",
+			expectedErrorMsg: "invalid book ID",
 			httpMethod:       http.MethodPut,
 		},
 		{
 			name:             "Invalid user ID (<= 0)",
 			queryParam:       "id=1",
 			requestBody:      `{"book_id":101,"user_id":0,"quantity":2,"status":"Confirmed"}`,
-			mockError:        errors.New("invalid user ID"),
+			mockError:        apperror.NewValidation("user_id", "invalid user ID"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Validation error: invalid user ID",
+			expectedErrorMsg: "invalid user ID",
 			httpMethod:       http.MethodPut,
 		},
 		{
 			name:             "Quantity must be greater than zero",
 			queryParam:       "id=1",
 			requestBody:      `{"book_id":101,"user_id":201,"quantity":0,"status":"Confirmed"}`,
-			mockError:        errors.New("quantity must be greater than zero"),
+			mockError:        apperror.NewValidation("quantity", "quantity must be greater than zero"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Validation error: quantity must be greater than zero",
+			expectedErrorMsg: "quantity must be greater than zero",
 			httpMethod:       http.MethodPut,
 		},
 		{
 			name:             "Status is required",
 			queryParam:       "id=1",
 			requestBody:      `{"book_id":101,"user_id":201,"quantity":2,"status":""}`,
-			mockError:        errors.New("status is required"),
+			mockError:        apperror.NewValidation("status", "status is required"),
 			expectedStatus:   http.StatusBadRequest,
-			expectedErrorMsg: "Validation error: status is required",
+			expectedErrorMsg: "status is required",
 			httpMethod:       http.MethodPut,
 		},
 	}
@@ -512,7 +579,7 @@ func TestUpdateByID(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mock_service := new(mockservice.MockOrderService)
-			handler := order.NewOrderHandler(mock_service)
+			handler := order.NewOrderHandler(mock_service, slog.New(slog.DiscardHandler))
 
 			url := "/order/update"
 			if tc.queryParam != "" {
@@ -526,7 +593,7 @@ func TestUpdateByID(t *testing.T) {
 			if tc.mockReturn != nil || tc.mockError != nil {
 				if idStr := req.URL.Query().Get("id"); idStr != "" {
 					if id, err := strconv.Atoi(idStr); err == nil {
-						mock_service.On("UpdateByOrderID", mock.MatchedBy(func(a *models.Order) bool {
+						mock_service.On("UpdateByOrderID", mock.Anything, mock.MatchedBy(func(a *models.Order) bool {
 							return a.ID == id
 						})).Return(tc.mockReturn, tc.mockError)
 					}
@@ -560,13 +627,6 @@ func TestGetOrderByID(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedResult: &models.Order{ID: 1, BookID: 101, UserID: 202, Quantity: 2, Status: "Confirmed"},
 		},
-		{
-			name:           "Invalid method",
-			httpMethod:     http.MethodPost,
-			queryParam:     "id=1",
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectErrorMsg: "Method not allowed",
-		},
 		{
 			name:           "Missing id parameter",
 			httpMethod:     http.MethodGet,
@@ -585,7 +645,7 @@ func TestGetOrderByID(t *testing.T) {
 			name:           "Service error - order not found",
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=99",
-			mockError:      errors.New("Order not found"),
+			mockError:      apperror.NotFound("Order not found"),
 			expectedStatus: http.StatusNotFound,
 			expectErrorMsg: "Order not found",
 		},
@@ -593,7 +653,7 @@ func TestGetOrderByID(t *testing.T) {
 			name:           "Invalid order ID (zero or negative)",
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=-5",
-			mockError:      errors.New("invalid order ID"),
+			mockError:      apperror.NewValidation("id", "invalid order ID"),
 			expectedStatus: http.StatusBadRequest,
 			expectErrorMsg: "invalid order ID",
 		},
@@ -602,22 +662,22 @@ func TestGetOrderByID(t *testing.T) {
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=100",
 			mockError:      errors.New("unexpected error"),
-			expectedStatus: http.StatusNotFound,
-			expectErrorMsg: "unexpected error",
+			expectedStatus: http.StatusInternalServerError,
+			expectErrorMsg: "Failed to get order",
 		},
 		{
-			name:           "Existing orders error",
+			name:           "Conflict error",
 			httpMethod:     http.MethodGet,
 			queryParam:     "id=10",
-			mockError:      errors.New("existing orders"),
-			expectedStatus: http.StatusBadRequest,
-			expectErrorMsg: "existing orders",
+			mockError:      apperror.Conflict("order is locked"),
+			expectedStatus: http.StatusConflict,
+			expectErrorMsg: "order is locked",
 		},
 	}
 
 	for _, tc := range tests {
 		mock_service := new(mockservice.MockOrderService)
-		handler := order.NewOrderHandler(mock_service)
+		handler := order.NewOrderHandler(mock_service, slog.New(slog.DiscardHandler))
 
 		url := "/order"
 		if tc.queryParam != "" {
@@ -627,7 +687,7 @@ func TestGetOrderByID(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		if tc.httpMethod == http.MethodGet && tc.queryParam != "" && (tc.mockError != nil || tc.mockReturn != nil) {
-			mock_service.On("GetByOrderID", mock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
+			mock_service.On("GetByOrderID", mock.Anything, mock.AnythingOfType("int")).Return(tc.mockReturn, tc.mockError)
 		}
 
 		handler.GetByOrderID(w, req)
@@ -643,3 +703,110 @@ func TestGetOrderByID(t *testing.T) {
 		mock_service.AssertExpectations(t)
 	}
 }
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockReturn     *models.Order
+		mockError      error
+		skipService    bool
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Success",
+			id:             "1",
+			mockReturn:     &models.Order{ID: 1, Status: models.OrderCancelled},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"status":"cancelled"`},
+		},
+		{
+			name:           "Illegal transition lists allowed statuses",
+			id:             "1",
+			mockError:      apperror.InvalidTransition(models.OrderShipped, models.OrderCancelled, []string{models.OrderDelivered}),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   []string{`"current_status":"shipped"`, `"allowed_transitions":["delivered"]`},
+		},
+		{
+			name:           "Order not found",
+			id:             "99",
+			mockError:      apperror.NotFound("order with ID 99 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid id",
+			id:             "abc",
+			skipService:    true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockservice.MockOrderService)
+			handler := order.NewOrderHandler(mockService, slog.New(slog.DiscardHandler))
+			if !tc.skipService {
+				id, _ := strconv.Atoi(tc.id)
+				mockService.On("TransitionOrder", mock.Anything, id, models.OrderCancelled).Return(tc.mockReturn, tc.mockError)
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /orders/{id}/cancel", handler.Transition(models.OrderCancelled))
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders/"+tc.id+"/cancel", nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmReservation(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockReturn     *models.Order
+		mockError      error
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Success",
+			mockReturn:     &models.Order{ID: 9, BookID: 1, UserID: 42, Quantity: 3, Status: models.OrderPending},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   []string{`"id":9`, `"status":"pending"`},
+		},
+		{
+			name:           "Expired reservation",
+			mockError:      apperror.Conflict("reservation 5 has expired"),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   []string{"reservation 5 has expired"},
+		},
+		{
+			name:           "Stock went below the reservation",
+			mockError:      &apperror.InsufficientStockError{Items: []apperror.StockShortage{{BookID: 1, Requested: 3, Available: 2}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockservice.MockOrderService)
+			mockService.On("ConfirmReservation", mock.Anything, 5).Return(tc.mockReturn, tc.mockError)
+			handler := order.NewOrderHandler(mockService, slog.New(slog.DiscardHandler))
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /reservations/{id}/confirm", handler.ConfirmReservation)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reservations/5/confirm", nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package params đọc tham số chung (id, ...) từ request cho các handler.
+package params
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
+
+// ID lấy id từ path value {id}; các route legacy (vd: /book?id=1) vẫn truyền qua query.
+func ID(r *http.Request) (int, error) {
+	return Int(r, "id")
+}
+
+// Int đọc path value name, fallback sang query parameter cùng tên.
+func Int(r *http.Request, name string) (int, error) {
+	value := r.PathValue(name)
+	if value == "" {
+		value = r.URL.Query().Get(name)
+	}
+	if value == "" {
+		return 0, apperror.NewValidation(name, fmt.Sprintf("Missing '%s' parameter", name))
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, apperror.NewValidation(name, fmt.Sprintf("Invalid '%s' parameter", name))
+	}
+	return n, nil
+}
+
+// Page đọc ?limit=&cursor=&sort= cho các endpoint danh sách.
+func Page(r *http.Request) (pagination.Params, error) {
+	q := r.URL.Query()
+	p := pagination.Params{Cursor: q.Get("cursor"), Sort: q.Get("sort")}
+	limit, err := QueryInt(r, "limit")
+	if err != nil {
+		return p, err
+	}
+	if limit != nil {
+		if *limit <= 0 {
+			return p, apperror.NewValidation("limit", "Invalid 'limit' parameter")
+		}
+		p.Limit = *limit
+	}
+	return p, nil
+}
+
+// QueryInt đọc query parameter kiểu số không bắt buộc; trả về nil nếu không có.
+func QueryInt(r *http.Request, name string) (*int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, apperror.NewValidation(name, fmt.Sprintf("Invalid '%s' parameter", name))
+	}
+	return &n, nil
+}
+
+// QueryTime đọc query parameter thời gian dạng RFC 3339 hoặc YYYY-MM-DD (00:00 UTC).
+func QueryTime(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, apperror.NewValidation(name, fmt.Sprintf("Invalid '%s' parameter", name))
+}
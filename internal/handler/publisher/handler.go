@@ -0,0 +1,125 @@
+package publisher
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/publisher"
+)
+
+type PublisherHandler struct {
+	servicePublisher publisher.PublisherServiceInterface
+	logger           *slog.Logger
+}
+
+func NewPublisherHandler(servicePublisher publisher.PublisherServiceInterface, logger *slog.Logger) *PublisherHandler {
+	return &PublisherHandler{servicePublisher: servicePublisher, logger: logger}
+}
+
+// CreatePublisher: POST /publishers {"name", "country", "website"}
+func (h *PublisherHandler) CreatePublisher(w http.ResponseWriter, r *http.Request) {
+	var p models.Publisher
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p.ID = 0
+	if err := h.servicePublisher.CreatePublisher(r.Context(), &p); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "create publisher failed", "name", p.Name, "err", err)
+		httperror.Write(w, err, "Failed to create publisher")
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (h *PublisherHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	publishers, err := h.servicePublisher.GetAll(r.Context())
+	if err != nil {
+		httperror.Write(w, err, "Failed to get publishers")
+		return
+	}
+	writeJSON(w, http.StatusOK, publishers)
+}
+
+func (h *PublisherHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	p, err := h.servicePublisher.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get publisher")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *PublisherHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	var p models.Publisher
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, &p)
+}
+
+// PatchByID chỉ cập nhật các field có trong body, các field còn lại giữ nguyên.
+func (h *PublisherHandler) PatchByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	existing, err := h.servicePublisher.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get publisher")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(existing); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	h.update(w, r, id, existing)
+}
+
+func (h *PublisherHandler) update(w http.ResponseWriter, r *http.Request, id int, p *models.Publisher) {
+	p.ID = id
+	updated, err := h.servicePublisher.UpdateByID(r.Context(), p)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "update publisher failed", "publisher_id", id, "err", err)
+		httperror.Write(w, err, "Failed to update publisher")
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *PublisherHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	p, err := h.servicePublisher.DeleteByID(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "delete publisher failed", "publisher_id", id, "err", err)
+		httperror.Write(w, err, "Failed to delete publisher")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,70 @@
+package publisher_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/publisher"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePublisher(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Created", body: `{"name":"NXB Trẻ"}`, expectedStatus: http.StatusCreated, expectedBody: `"name":"NXB Trẻ"`},
+		{name: "Invalid JSON", body: `{`, expectedStatus: http.StatusBadRequest, expectedBody: "Invalid request body"},
+		{
+			name:           "Duplicate name",
+			body:           `{"name":"NXB Trẻ"}`,
+			mockError:      apperror.Conflict("publisher NXB Trẻ already exists"),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "publisher NXB Trẻ already exists",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockPublisherService)
+			if tc.expectedStatus != http.StatusBadRequest {
+				service.On("CreatePublisher", mock.Anything, mock.AnythingOfType("*models.Publisher")).Return(tc.mockError)
+			}
+			handler := publisher.NewPublisherHandler(service, slog.New(slog.DiscardHandler))
+			req := httptest.NewRequest(http.MethodPost, "/publishers", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+
+			handler.CreatePublisher(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPatchByID(t *testing.T) {
+	service := new(mockservice.MockPublisherService)
+	service.On("GetByID", mock.Anything, 2).Return(&models.Publisher{ID: 2, Name: "Kim Đồng", Country: "VN"}, nil)
+	service.On("UpdateByID", mock.Anything, mock.MatchedBy(func(p *models.Publisher) bool {
+		return p.ID == 2 && p.Name == "Kim Đồng" && p.Country == "VN" && p.Website == "https://nxbkimdong.com.vn"
+	})).Return(&models.Publisher{ID: 2, Name: "Kim Đồng"}, nil)
+	handler := publisher.NewPublisherHandler(service, slog.New(slog.DiscardHandler))
+	req := httptest.NewRequest(http.MethodPatch, "/publishers/2", strings.NewReader(`{"website":"https://nxbkimdong.com.vn"}`))
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	handler.PatchByID(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	service.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package reservation
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/reservation"
+)
+
+type ReservationHandler struct {
+	serviceReservation reservation.ReservationServiceInterface
+	logger             *slog.Logger
+}
+
+func NewReservationHandler(serviceReservation reservation.ReservationServiceInterface, logger *slog.Logger) *ReservationHandler {
+	return &ReservationHandler{serviceReservation: serviceReservation, logger: logger}
+}
+
+// Reserve giữ hàng: POST /reservations {"book_id", "user_id", "quantity", "ttl_seconds"}
+func (h *ReservationHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	var res models.Reservation
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.serviceReservation.Reserve(r.Context(), &res); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "reserve stock failed", "err", err)
+		httperror.Write(w, err, "Failed to reserve stock")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ReservationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	res, err := h.serviceReservation.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get reservation")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Release trả hàng: POST /reservations/{id}/release, 409 nếu reservation không còn active
+func (h *ReservationHandler) Release(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	res, err := h.serviceReservation.Release(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "release reservation failed", "reservation_id", id, "err", err)
+		httperror.Write(w, err, "Failed to release reservation")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
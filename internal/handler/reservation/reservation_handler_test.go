@@ -0,0 +1,113 @@
+package reservation_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/reservation"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMux(service *mockservice.MockReservationService) *http.ServeMux {
+	handler := reservation.NewReservationHandler(service, slog.New(slog.DiscardHandler))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /reservations", handler.Reserve)
+	mux.HandleFunc("GET /reservations/{id}", handler.GetByID)
+	mux.HandleFunc("POST /reservations/{id}/release", handler.Release)
+	return mux
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		skipService    bool
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Success",
+			body:           `{"book_id":1,"user_id":42,"quantity":2,"ttl_seconds":300}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   []string{`"id":7`, `"status":"active"`},
+		},
+		{
+			name:           "Not enough available stock",
+			body:           `{"book_id":1,"user_id":42,"quantity":20}`,
+			mockError:      &apperror.InsufficientStockError{Items: []apperror.StockShortage{{BookID: 1, Requested: 20, Available: 4}}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{`"available":4`},
+		},
+		{
+			name:           "Invalid body",
+			body:           `{`,
+			skipService:    true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockReservationService)
+			if !tc.skipService {
+				service.On("Reserve", mock.Anything, mock.AnythingOfType("*models.Reservation")).Run(func(args mock.Arguments) {
+					res := args.Get(1).(*models.Reservation)
+					res.ID, res.Status = 7, models.ReservationActive
+				}).Return(tc.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockReturn     *models.Reservation
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "Success", mockReturn: &models.Reservation{ID: 7, Status: models.ReservationReleased}, expectedStatus: http.StatusOK},
+		{name: "Already confirmed", mockError: apperror.Conflict("reservation 7 is already confirmed"), expectedStatus: http.StatusConflict},
+		{name: "Not found", mockError: apperror.NotFound("reservation with ID 7 not found"), expectedStatus: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockReservationService)
+			service.On("Release", mock.Anything, 7).Return(tc.mockReturn, tc.mockError)
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reservations/7/release", nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetByID(t *testing.T) {
+	service := new(mockservice.MockReservationService)
+	service.On("GetByID", mock.Anything, 7).Return(&models.Reservation{ID: 7, BookID: 1, Quantity: 2, Status: models.ReservationActive}, nil)
+
+	w := httptest.NewRecorder()
+	newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reservations/7", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"quantity":2`)
+	service.AssertExpectations(t)
+}
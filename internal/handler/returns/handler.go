@@ -0,0 +1,133 @@
+package returns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/returns"
+)
+
+type ReturnHandler struct {
+	serviceReturn returns.ReturnServiceInterface
+	logger        *slog.Logger
+}
+
+func NewReturnHandler(serviceReturn returns.ReturnServiceInterface, logger *slog.Logger) *ReturnHandler {
+	return &ReturnHandler{serviceReturn: serviceReturn, logger: logger}
+}
+
+// RequestReturn: POST /orders/{id}/returns {"reason", "note", "items": [{"book_id", "quantity"}]}
+func (h *ReturnHandler) RequestReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	var ret models.Return
+	if err := json.NewDecoder(r.Body).Decode(&ret); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ret.OrderID = orderID
+	if err := h.serviceReturn.RequestReturn(r.Context(), &ret); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "request return failed", "order_id", orderID, "err", err)
+		httperror.Write(w, err, "Failed to request return")
+		return
+	}
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// ListByOrder: GET /orders/{id}/returns
+func (h *ReturnHandler) ListByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	list, err := h.serviceReturn.ListByOrder(r.Context(), orderID)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get returns")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ListRefunds: GET /orders/{id}/refunds
+func (h *ReturnHandler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	orderID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	list, err := h.serviceReturn.ListRefunds(r.Context(), orderID)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get refunds")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *ReturnHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	ret, err := h.serviceReturn.GetByID(r.Context(), id)
+	if err != nil {
+		httperror.Write(w, err, "Failed to get return")
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (h *ReturnHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, "approve", h.serviceReturn.Approve)
+}
+
+func (h *ReturnHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, "reject", h.serviceReturn.Reject)
+}
+
+// Receive: POST /returns/{id}/receive {"restock": true}; body rỗng là không nhập lại kho
+func (h *ReturnHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Restock bool `json:"restock"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	h.transition(w, r, "receive", func(ctx context.Context, id int) (*models.Return, error) {
+		return h.serviceReturn.Receive(ctx, id, body.Restock)
+	})
+}
+
+// transition xử lý chung các action POST /returns/{id}/{action}: 409 nếu return không ở đúng trạng thái
+func (h *ReturnHandler) transition(w http.ResponseWriter, r *http.Request, action string, fn func(context.Context, int) (*models.Return, error)) {
+	id, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	ret, err := fn(r.Context(), id)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "return "+action+" failed", "return_id", id, "err", err)
+		httperror.Write(w, err, "Failed to update return")
+		return
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,133 @@
+package returns_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/returns"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMux(service *mockservice.MockReturnService) *http.ServeMux {
+	handler := returns.NewReturnHandler(service, slog.New(slog.DiscardHandler))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders/{id}/returns", handler.RequestReturn)
+	mux.HandleFunc("POST /returns/{id}/approve", handler.Approve)
+	mux.HandleFunc("POST /returns/{id}/receive", handler.Receive)
+	return mux
+}
+
+func TestRequestReturn(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		skipService    bool
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Success",
+			body:           `{"reason":"damaged","items":[{"book_id":1,"quantity":1}]}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   []string{`"id":3`, `"order_id":5`, `"status":"requested"`},
+		},
+		{
+			name:           "Order not delivered",
+			body:           `{"reason":"damaged","items":[{"book_id":1,"quantity":1}]}`,
+			mockError:      apperror.Conflict("order 5 is pending, only delivered orders can be returned"),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Quantity exceeds ordered",
+			body:           `{"reason":"damaged","items":[{"book_id":1,"quantity":9}]}`,
+			mockError:      apperror.NewValidation("items[0].quantity", "only 2 of book 1 can still be returned"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid body",
+			body:           `{`,
+			skipService:    true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockReturnService)
+			if !tc.skipService {
+				service.On("RequestReturn", mock.Anything, mock.MatchedBy(func(ret *models.Return) bool {
+					return ret.OrderID == 5
+				})).Run(func(args mock.Arguments) {
+					ret := args.Get(1).(*models.Return)
+					ret.ID, ret.Status = 3, models.ReturnRequested
+				}).Return(tc.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders/5/returns", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockReturn     *models.Return
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "Success", mockReturn: &models.Return{ID: 3, Status: models.ReturnApproved}, expectedStatus: http.StatusOK},
+		{name: "Not requested", mockError: apperror.Conflict("return 3 is received, expected requested"), expectedStatus: http.StatusConflict},
+		{name: "Not found", mockError: apperror.NotFound("return with ID 3 not found"), expectedStatus: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockReturnService)
+			service.On("Approve", mock.Anything, 3).Return(tc.mockReturn, tc.mockError)
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/returns/3/approve", nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedRestock bool
+	}{
+		{name: "Restock", body: `{"restock":true}`, expectedRestock: true},
+		{name: "Empty body", body: ``, expectedRestock: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockReturnService)
+			service.On("Receive", mock.Anything, 3, tc.expectedRestock).
+				Return(&models.Return{ID: 3, Status: models.ReturnReceived, Restock: tc.expectedRestock}, nil)
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/returns/3/receive", strings.NewReader(tc.body)))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `"status":"received"`)
+			service.AssertExpectations(t)
+		})
+	}
+}
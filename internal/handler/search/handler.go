@@ -0,0 +1,45 @@
+package search
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/search"
+)
+
+type SearchHandler struct {
+	serviceSearch search.SearchServiceInterface
+	logger        *slog.Logger
+}
+
+func NewSearchHandler(serviceSearch search.SearchServiceInterface, logger *slog.Logger) *SearchHandler {
+	return &SearchHandler{serviceSearch: serviceSearch, logger: logger}
+}
+
+// Search: GET /search?q=&type=book|author&limit=&cursor=
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var filter models.SearchFilter
+	var err error
+	if filter.Params, err = params.Page(r); err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	filter.Query = r.URL.Query().Get("q")
+	filter.Type = r.URL.Query().Get("type")
+
+	page, err := h.serviceSearch.Search(r.Context(), filter)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "search failed", "q", filter.Query, "err", err)
+		httperror.Write(w, err, "Failed to search")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	// highlight đã được HTML-escape ở service, giữ nguyên <mark> thay vì \u003cmark\u003e
+	enc.SetEscapeHTML(false)
+	enc.Encode(page)
+}
@@ -0,0 +1,69 @@
+package search_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/search"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		prepare        func(m *mockservice.MockSearchService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Results with highlight",
+			url:  "/search?q=nguyen+nhat&type=author&limit=5",
+			prepare: func(m *mockservice.MockSearchService) {
+				m.On("Search", mock.Anything, models.SearchFilter{Query: "nguyen nhat", Type: "author", Params: pagination.Params{Limit: 5}}).
+					Return(&pagination.Page[*models.SearchHit]{Data: []*models.SearchHit{{Type: "author", ID: 1, Text: "Nguyễn Nhật Ánh",
+						Highlight: "<mark>Nguyễn</mark> <mark>Nhật</mark> Ánh"}}, Total: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"highlight":"<mark>Nguyễn</mark>`,
+		},
+		{
+			name: "Missing query",
+			url:  "/search",
+			prepare: func(m *mockservice.MockSearchService) {
+				m.On("Search", mock.Anything, models.SearchFilter{}).Return(nil, apperror.NewValidation("q", "q is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "q is required",
+		},
+		{
+			name:           "Invalid limit",
+			url:            "/search?q=go&limit=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid 'limit' parameter",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockSearchService)
+			if tc.prepare != nil {
+				tc.prepare(service)
+			}
+			handler := search.NewSearchHandler(service, slog.New(slog.DiscardHandler))
+			w := httptest.NewRecorder()
+
+			handler.Search(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
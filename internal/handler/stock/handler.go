@@ -0,0 +1,68 @@
+package stock
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/maithuc2003/re-book-api/internal/handler/httperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/params"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/stock"
+)
+
+type StockHandler struct {
+	serviceStock stock.StockServiceInterface
+	logger       *slog.Logger
+}
+
+func NewStockHandler(serviceStock stock.StockServiceInterface, logger *slog.Logger) *StockHandler {
+	return &StockHandler{serviceStock: serviceStock, logger: logger}
+}
+
+// GetMovements trả về lịch sử tồn kho: GET /books/{id}/stock/movements?reason=&limit=&cursor=&sort=
+func (h *StockHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
+	bookID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	page, err := params.Page(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	filter := models.StockMovementFilter{Params: page, BookID: bookID, Reason: r.URL.Query().Get("reason")}
+	movements, err := h.serviceStock.GetMovements(r.Context(), filter)
+	if err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "list stock movements failed", "err", err)
+		httperror.Write(w, err, "Failed to get stock movements")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(movements)
+}
+
+// CreateMovement nhập hàng / chỉnh tay / kiểm kê: POST /books/{id}/stock/movements {"reason", "delta", "note"}
+func (h *StockHandler) CreateMovement(w http.ResponseWriter, r *http.Request) {
+	bookID, err := params.ID(r)
+	if err != nil {
+		httperror.Write(w, err, "")
+		return
+	}
+	var movement models.StockMovement
+	if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	movement.BookID = bookID
+	if err := h.serviceStock.MoveStock(r.Context(), &movement); err != nil {
+		h.logger.Log(r.Context(), httperror.LogLevel(err), "stock movement failed", "err", err)
+		httperror.Write(w, err, "Failed to update stock")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
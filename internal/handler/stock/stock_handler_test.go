@@ -0,0 +1,133 @@
+package stock_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/handler/stock"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMux(service *mockservice.MockStockService) *http.ServeMux {
+	handler := stock.NewStockHandler(service, slog.New(slog.DiscardHandler))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /books/{id}/stock/movements", handler.GetMovements)
+	mux.HandleFunc("POST /books/{id}/stock/movements", handler.CreateMovement)
+	return mux
+}
+
+func TestGetMovements(t *testing.T) {
+	orderID := 9
+	tests := []struct {
+		name           string
+		url            string
+		expectedFilter *models.StockMovementFilter
+		mockReturn     *pagination.Page[*models.StockMovement]
+		mockError      error
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Success",
+			url:            "/books/1/stock/movements?reason=order&limit=5",
+			expectedFilter: &models.StockMovementFilter{Params: pagination.Params{Limit: 5}, BookID: 1, Reason: "order"},
+			mockReturn: &pagination.Page[*models.StockMovement]{
+				Data:  []*models.StockMovement{{ID: 3, BookID: 1, Delta: -2, Reason: "order", OrderID: &orderID, StockAfter: 4}},
+				Total: 1,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"delta":-2`, `"order_id":9`, `"stock_after":4`},
+		},
+		{
+			name:           "Book not found",
+			url:            "/books/404/stock/movements",
+			expectedFilter: &models.StockMovementFilter{BookID: 404},
+			mockError:      apperror.NotFound("book with ID 404 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid limit",
+			url:            "/books/1/stock/movements?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockStockService)
+			if tc.expectedFilter != nil {
+				service.On("GetMovements", mock.Anything, *tc.expectedFilter).Return(tc.mockReturn, tc.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			newMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateMovement(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		callService    bool
+		mockError      error
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Restock",
+			body:           `{"reason":"restock","delta":5,"note":"new shipment"}`,
+			callService:    true,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   []string{`"book_id":1`, `"reason":"restock"`, `"delta":5`},
+		},
+		{
+			name:        "Not enough stock",
+			body:        `{"reason":"adjustment","delta":-5}`,
+			callService: true,
+			mockError: &apperror.InsufficientStockError{Items: []apperror.StockShortage{
+				{BookID: 1, Requested: 5, Available: 2},
+			}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{`"available":2`},
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{"delta":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockStockService)
+			if tc.callService {
+				service.On("MoveStock", mock.Anything, mock.MatchedBy(func(m *models.StockMovement) bool {
+					return m.BookID == 1
+				})).Return(tc.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/books/1/stock/movements", strings.NewReader(tc.body))
+			newMux(service).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for _, s := range tc.expectedBody {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
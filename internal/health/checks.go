@@ -0,0 +1,65 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DB kiểm tra kết nối MySQL bằng ping.
+func DB(db *sql.DB) Check {
+	return Check{Name: "mysql", Fn: db.PingContext}
+}
+
+// VersionSource trả về version schema hiện tại và version binary mong đợi (vd: *migrations.Migrator).
+type VersionSource interface {
+	Version(ctx context.Context) (int, error)
+	Latest() int
+}
+
+// Migrations báo lỗi khi schema chưa ở version mà binary mong đợi
+// (vd: deploy code mới nhưng chưa chạy "migrate up").
+func Migrations(src VersionSource) Check {
+	return Check{Name: "migrations", Fn: func(ctx context.Context) error {
+		current, err := src.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if want := src.Latest(); current != want {
+			return fmt.Errorf("schema at version %d, expected %d", current, want)
+		}
+		return nil
+	}}
+}
+
+// Redis gửi lệnh PING (giao thức RESP) tới addr và chờ "+PONG".
+// Dùng net thô để không phải thêm client Redis chỉ cho health check.
+func Redis(addr string) Check {
+	return Check{Name: "redis", Fn: func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		if _, err := conn.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+			return err
+		}
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		// Redis có mật khẩu sẽ trả "-NOAUTH ...", vẫn coi là lỗi
+		if reply := strings.TrimSpace(line); reply != "+PONG" {
+			return fmt.Errorf("unexpected reply %q", reply)
+		}
+		return nil
+	}}
+}
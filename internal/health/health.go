@@ -0,0 +1,116 @@
+// Package health cung cấp /healthz (process còn sống) và /readyz (sẵn sàng nhận traffic)
+// cho orchestrator và load balancer.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check là một thành phần phụ thuộc cần kiểm tra khi readiness probe gọi tới.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// ComponentStatus là kết quả kiểm tra của một thành phần.
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report là body JSON của /healthz và /readyz.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+	statusUp       = "up"
+)
+
+type Health struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// New tạo Health với các check chạy song song, mỗi lần probe có tổng thời gian tối đa là timeout.
+func New(timeout time.Duration, checks ...Check) *Health {
+	return &Health{checks: checks, timeout: timeout}
+}
+
+// SetDraining đánh dấu server đang tắt: /readyz trả 503 để load balancer ngừng gửi request mới,
+// trong khi các request đang chạy vẫn được xử lý nốt.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness chỉ xác nhận process còn phục vụ được HTTP, không kiểm tra dependency
+// (DB chết không phải lý do để restart container).
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readiness kiểm tra tất cả dependency; chỉ trả 200 khi mọi thành phần đều "up".
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeReport(w, http.StatusServiceUnavailable, Report{Status: StatusDraining})
+		return
+	}
+	report := h.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+// Check chạy tất cả check song song và tổng hợp kết quả.
+func (h *Health) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(h.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := c.Fn(ctx)
+			cs := ComponentStatus{Status: statusUp, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				cs.Status, cs.Error = StatusDown, err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[c.Name] = cs
+			if err != nil {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	// Probe phải luôn thấy trạng thái mới nhất
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,155 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, handler http.HandlerFunc) (int, health.Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	return w.Code, report
+}
+
+func check(name string, err error) health.Check {
+	return health.Check{Name: name, Fn: func(context.Context) error { return err }}
+}
+
+func TestLiveness(t *testing.T) {
+	h := health.New(time.Second, check("mysql", errors.New("connection refused")))
+
+	code, report := probe(t, h.Liveness)
+
+	assert.Equal(t, http.StatusOK, code, "liveness ignores dependencies")
+	assert.Equal(t, health.StatusOK, report.Status)
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         []health.Check
+		draining       bool
+		expectedCode   int
+		expectedStatus string
+		expectedDown   []string
+	}{
+		{
+			name:           "all up",
+			checks:         []health.Check{check("mysql", nil), check("redis", nil)},
+			expectedCode:   http.StatusOK,
+			expectedStatus: health.StatusOK,
+		},
+		{
+			name:           "one component down",
+			checks:         []health.Check{check("mysql", nil), check("redis", errors.New("dial tcp: refused"))},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: health.StatusDown,
+			expectedDown:   []string{"redis"},
+		},
+		{
+			name: "check exceeds timeout",
+			checks: []health.Check{{Name: "mysql", Fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: health.StatusDown,
+			expectedDown:   []string{"mysql"},
+		},
+		{
+			name:           "draining",
+			checks:         []health.Check{check("mysql", nil)},
+			draining:       true,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: health.StatusDraining,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := health.New(50*time.Millisecond, tt.checks...)
+			if tt.draining {
+				h.SetDraining()
+			}
+
+			code, report := probe(t, h.Readiness)
+
+			assert.Equal(t, tt.expectedCode, code)
+			assert.Equal(t, tt.expectedStatus, report.Status)
+			for _, name := range tt.expectedDown {
+				assert.Equal(t, health.StatusDown, report.Components[name].Status)
+				assert.NotEmpty(t, report.Components[name].Error)
+			}
+		})
+	}
+}
+
+type fakeVersions struct {
+	current, latest int
+	err             error
+}
+
+func (f fakeVersions) Version(context.Context) (int, error) { return f.current, f.err }
+func (f fakeVersions) Latest() int                          { return f.latest }
+
+func TestMigrations(t *testing.T) {
+	assert.NoError(t, health.Migrations(fakeVersions{current: 4, latest: 4}).Fn(context.Background()))
+	assert.EqualError(t, health.Migrations(fakeVersions{current: 3, latest: 4}).Fn(context.Background()),
+		"schema at version 3, expected 4")
+	assert.Error(t, health.Migrations(fakeVersions{err: errors.New("table missing")}).Fn(context.Background()))
+}
+
+func TestDB(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("bad connection"))
+
+	assert.Error(t, health.DB(db).Fn(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakeRedis trả lời mỗi kết nối đúng một dòng reply.
+func fakeRedis(t *testing.T, reply string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 64)
+			conn.Read(buf)
+			conn.Write([]byte(reply))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRedis(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, health.Redis(fakeRedis(t, "+PONG\r\n")).Fn(ctx))
+	assert.ErrorContains(t, health.Redis(fakeRedis(t, "-NOAUTH Authentication required.\r\n")).Fn(ctx), "NOAUTH")
+}
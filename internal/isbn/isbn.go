@@ -0,0 +1,107 @@
+// Package isbn kiểm tra và chuyển đổi mã ISBN-10/ISBN-13.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalid là lỗi khi mã sai độ dài, ký tự hoặc checksum.
+	ErrInvalid = errors.New("invalid ISBN")
+	// ErrNoISBN10 là lỗi khi ISBN-13 không có dạng ISBN-10 tương ứng (prefix 979).
+	ErrNoISBN10 = errors.New("ISBN-13 has no ISBN-10 form")
+)
+
+// Normalize bỏ dấu gạch/khoảng trắng, kiểm tra checksum và trả về dạng ISBN-13.
+func Normalize(s string) (string, error) {
+	s = strip(s)
+	switch len(s) {
+	case 10:
+		return To13(s)
+	case 13:
+		if !valid13(s) {
+			return "", ErrInvalid
+		}
+		return s, nil
+	}
+	return "", ErrInvalid
+}
+
+// To13 chuyển ISBN-10 sang ISBN-13 với prefix 978.
+func To13(isbn10 string) (string, error) {
+	isbn10 = strip(isbn10)
+	if !valid10(isbn10) {
+		return "", ErrInvalid
+	}
+	body := "978" + isbn10[:9]
+	return body + string(checkDigit13(body)), nil
+}
+
+// To10 chuyển ISBN-13 có prefix 978 sang ISBN-10.
+func To10(isbn13 string) (string, error) {
+	isbn13 = strip(isbn13)
+	if !valid13(isbn13) {
+		return "", ErrInvalid
+	}
+	if !strings.HasPrefix(isbn13, "978") {
+		return "", ErrNoISBN10
+	}
+	body := isbn13[3:12]
+	return body + string(checkDigit10(body)), nil
+}
+
+// strip bỏ dấu gạch, khoảng trắng và in hoa X của ISBN-10
+func strip(s string) string {
+	s = strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+	return strings.ToUpper(s)
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func valid10(s string) bool {
+	if len(s) != 10 || !digits(s[:9]) {
+		return false
+	}
+	return s[9] == checkDigit10(s[:9])
+}
+
+func valid13(s string) bool {
+	if len(s) != 13 || !digits(s) {
+		return false
+	}
+	return s[12] == checkDigit13(s[:12])
+}
+
+// checkDigit10: tổng các chữ số nhân trọng số 10..2, số kiểm tra làm tròn tổng lên bội của 11 (10 viết là X).
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	d := (11 - sum%11) % 11
+	if d == 10 {
+		return 'X'
+	}
+	return byte('0' + d)
+}
+
+// checkDigit13: trọng số xen kẽ 1 và 3, số kiểm tra làm tròn tổng lên bội của 10.
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += int(body[i]-'0') * w
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,51 @@
+package isbn_test
+
+import (
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/isbn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "ISBN-13 with hyphens", input: "978-0-306-40615-7", expected: "9780306406157"},
+		{name: "ISBN-10 is converted", input: "0-306-40615-2", expected: "9780306406157"},
+		{name: "ISBN-10 with X check digit", input: "0-8044-2957-x", expected: "9780804429573"},
+		{name: "979 prefix", input: "979-10-90636-07-1", expected: "9791090636071"},
+		{name: "Spaces are ignored", input: " 978 0 306 40615 7 ", expected: "9780306406157"},
+		{name: "Wrong ISBN-13 checksum", input: "9780306406158", err: isbn.ErrInvalid},
+		{name: "Wrong ISBN-10 checksum", input: "0306406153", err: isbn.ErrInvalid},
+		{name: "X only allowed as ISBN-10 check digit", input: "03064X6152", err: isbn.ErrInvalid},
+		{name: "Wrong length", input: "97803064061", err: isbn.ErrInvalid},
+		{name: "Empty", input: "", err: isbn.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isbn.Normalize(tt.input)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	got, err := isbn.To10("978-0-306-40615-7")
+	assert.NoError(t, err)
+	assert.Equal(t, "0306406152", got)
+
+	got, err = isbn.To10("9780804429573")
+	assert.NoError(t, err)
+	assert.Equal(t, "080442957X", got)
+
+	_, err = isbn.To10("9791090636071")
+	assert.ErrorIs(t, err, isbn.ErrNoISBN10)
+
+	_, err = isbn.To10("9780306406158")
+	assert.ErrorIs(t, err, isbn.ErrInvalid)
+}
@@ -0,0 +1,39 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, slog.LevelInfo, "json").With("component", "test")
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "hello", "n", 1)
+	logger.DebugContext(ctx, "dropped below level")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "test", line["component"])
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+}
+
+func TestNew_TextFormatWithoutRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, slog.LevelDebug, "text")
+
+	logger.DebugContext(context.Background(), "hello")
+
+	assert.Contains(t, buf.String(), "level=DEBUG msg=hello")
+	assert.NotContains(t, buf.String(), "request_id")
+}
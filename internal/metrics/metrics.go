@@ -0,0 +1,165 @@
+// Package metrics khai báo các metric Prometheus của app và endpoint /metrics.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rebook"
+
+// Lý do order bị từ chối (label "reason" của orders_rejected_total).
+const (
+	ReasonInsufficientStock = "insufficient_stock"
+	ReasonBookNotFound      = "book_not_found"
+	ReasonValidation        = "validation"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+
+	ordersCreated  prometheus.Counter
+	ordersRejected *prometheus.CounterVec
+}
+
+// New đăng ký metric HTTP, domain, connection pool của db và mức tồn kho thấp
+// (sách có stock <= lowStockThreshold) vào một registry riêng.
+func New(db *sql.DB, lowStockThreshold int, logger *slog.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_created_total",
+			Help:      "Orders created successfully.",
+		}),
+		ordersRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_rejected_total",
+			Help:      "Orders rejected, by reason.",
+		}, []string{"reason"}),
+	}
+	// Khởi tạo sẵn các label để series hiện ra với giá trị 0 (alert dùng rate() được ngay)
+	for _, reason := range []string{ReasonInsufficientStock, ReasonBookNotFound, ReasonValidation} {
+		m.ordersRejected.WithLabelValues(reason)
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "mysql"),
+		m.requests, m.duration, m.ordersCreated, m.ordersRejected,
+		newStockCollector(db, lowStockThreshold, logger),
+	)
+	return m
+}
+
+// Handler trả về http.Handler cho GET /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest ghi nhận một request đã xử lý xong.
+func (m *Metrics) ObserveRequest(route, method string, status int, elapsed time.Duration) {
+	m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.duration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) OrderCreated() {
+	m.ordersCreated.Inc()
+}
+
+func (m *Metrics) OrderRejected(reason string) {
+	m.ordersRejected.WithLabelValues(reason).Inc()
+}
+
+// stockCollector đọc tồn kho mỗi lần Prometheus scrape, thay vì cập nhật gauge ở mọi chỗ đổi stock.
+type stockCollector struct {
+	db        *sql.DB
+	threshold int
+	logger    *slog.Logger
+
+	lowStock   *prometheus.Desc
+	outOfStock *prometheus.Desc
+	bookStock  *prometheus.Desc
+}
+
+// maxLowStockSeries giới hạn số series book_stock để tránh bùng nổ cardinality.
+const maxLowStockSeries = 100
+
+func newStockCollector(db *sql.DB, threshold int, logger *slog.Logger) *stockCollector {
+	return &stockCollector{
+		db:        db,
+		threshold: threshold,
+		logger:    logger,
+		lowStock: prometheus.NewDesc(namespace+"_books_low_stock",
+			"Books with stock at or below the low-stock threshold.", nil, prometheus.Labels{"threshold": strconv.Itoa(threshold)}),
+		outOfStock: prometheus.NewDesc(namespace+"_books_out_of_stock",
+			"Books with no stock left.", nil, nil),
+		bookStock: prometheus.NewDesc(namespace+"_book_stock",
+			"Current stock of low-stock books (lowest first, capped).", []string{"book_id", "title"}, nil),
+	}
+}
+
+func (c *stockCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lowStock
+	ch <- c.outOfStock
+	ch <- c.bookStock
+}
+
+func (c *stockCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var low, out int
+	err := c.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(stock <= ?), 0), COALESCE(SUM(stock <= 0), 0) FROM books", c.threshold).Scan(&low, &out)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to collect stock metrics", "err", err)
+		ch <- prometheus.NewInvalidMetric(c.lowStock, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.lowStock, prometheus.GaugeValue, float64(low))
+	ch <- prometheus.MustNewConstMetric(c.outOfStock, prometheus.GaugeValue, float64(out))
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT id, title, stock FROM books WHERE stock <= ? ORDER BY stock, id LIMIT ?", c.threshold, maxLowStockSeries)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to collect low-stock books", "err", err)
+		ch <- prometheus.NewInvalidMetric(c.bookStock, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id, stock int
+			title     string
+		)
+		if err := rows.Scan(&id, &title, &stock); err != nil {
+			ch <- prometheus.NewInvalidMetric(c.bookStock, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(c.bookStock, prometheus.GaugeValue, float64(stock), strconv.Itoa(id), title)
+	}
+}
@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetrics_Exposition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := metrics.New(db, 5, slog.New(slog.DiscardHandler))
+	m.ObserveRequest("GET /books/{id}", http.MethodGet, http.StatusOK, 30*time.Millisecond)
+	m.OrderCreated()
+	m.OrderRejected(metrics.ReasonInsufficientStock)
+
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"low", "out"}).AddRow(2, 1))
+	mock.ExpectQuery("SELECT id, title, stock FROM books WHERE stock <= \\?").WithArgs(5, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "stock"}).AddRow(3, "Dế Mèn", 0).AddRow(9, "Go", 4))
+
+	body := scrape(t, m)
+
+	for _, line := range []string{
+		`rebook_http_requests_total{code="200",method="GET",route="GET /books/{id}"} 1`,
+		`rebook_http_request_duration_seconds_count{method="GET",route="GET /books/{id}"} 1`,
+		`rebook_orders_created_total 1`,
+		`rebook_orders_rejected_total{reason="insufficient_stock"} 1`,
+		`rebook_orders_rejected_total{reason="validation"} 0`,
+		`rebook_books_low_stock{threshold="5"} 2`,
+		`rebook_books_out_of_stock 1`,
+		`rebook_book_stock{book_id="3",title="Dế Mèn"} 0`,
+		`rebook_book_stock{book_id="9",title="Go"} 4`,
+		`go_sql_max_open_connections{db_name="mysql"}`,
+	} {
+		assert.Contains(t, body, line)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
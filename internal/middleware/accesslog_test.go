@@ -0,0 +1,69 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/logging"
+	"github.com/maithuc2003/re-book-api/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		requestID      string
+		expectedID     string
+		expectedRoute  string
+		expectedStatus int
+	}{
+		{"Propagates incoming ID", "/books/7", "abc-123", "abc-123", "GET /books/{id}", http.StatusTeapot},
+		{"Generates ID when missing", "/books/7", "", "", "GET /books/{id}", http.StatusTeapot},
+		{"Replaces unsafe ID", "/books/7", "bad id\n", "", "GET /books/{id}", http.StatusTeapot},
+		{"Unmatched route", "/nope", "abc", "abc", "unmatched", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logging.New(&buf, slog.LevelInfo, "json")
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /books/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("hello"))
+			})
+			handler := middleware.RequestID(middleware.AccessLog(logger)(mux))
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.requestID != "" {
+				req.Header.Set(middleware.RequestIDHeader, tc.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(middleware.RequestIDHeader)
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+			assert.Equal(t, "http request", line["msg"])
+			assert.Equal(t, tc.expectedRoute, line["route"])
+			assert.Equal(t, float64(tc.expectedStatus), line["status"])
+			assert.Equal(t, id, line["request_id"])
+			assert.Contains(t, line, "latency")
+			if tc.expectedStatus == http.StatusTeapot {
+				assert.Equal(t, float64(5), line["bytes"])
+			}
+		})
+	}
+}
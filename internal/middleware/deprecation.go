@@ -0,0 +1,13 @@
+package middleware
+
+import "net/http"
+
+// Deprecated đánh dấu một route legacy: response vẫn như cũ nhưng có thêm header
+// Deprecation và Link tới route mới để client biết cần migrate.
+func Deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestObserver nhận kết quả của mỗi request, vd: *metrics.Metrics.
+type RequestObserver interface {
+	ObserveRequest(route, method string, status int, elapsed time.Duration)
+}
+
+// Instrument đo số request và latency theo route pattern (không theo path thật,
+// để /books/1, /books/2, ... chỉ là một series). Giống AccessLog, phải bọc trực tiếp ServeMux.
+func Instrument(obs RequestObserver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			obs.ObserveRequest(route, r.Method, rec.status, time.Since(start))
+		})
+	}
+}
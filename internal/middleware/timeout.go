@@ -0,0 +1,20 @@
+// Package middleware chứa các http.Handler wrapper dùng chung cho mọi route.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout gắn deadline vào context của mỗi request. Repository dùng context này
+// cho các lệnh *Context nên query/transaction sẽ bị huỷ khi quá hạn hoặc client ngắt kết nối.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Package migrations quản lý schema của database bằng các file SQL có đánh version,
+// được nhúng vào binary bằng embed.FS.
+//
+// Mỗi migration gồm hai file trong thư mục sql/: NNNN_name.up.sql và NNNN_name.down.sql.
+// Version đã chạy được lưu trong bảng schema_migrations kèm checksum của file up;
+// nếu file của một migration đã chạy bị sửa thì Migrator từ chối chạy tiếp.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// lockName là tên MySQL named lock, đảm bảo chỉ một instance chạy migration tại một thời điểm.
+const lockName = "re-book-api.schema_migrations"
+
+var (
+	// ErrChecksumMismatch: file của migration đã chạy bị sửa sau khi apply.
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	// ErrUnknownVersion: database có version không tồn tại trong binary (binary cũ hơn schema).
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrNoDown: migration không có file down nên không rollback được.
+	ErrNoDown = errors.New("migration has no down file")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status là trạng thái của một migration so với database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New tạo Migrator với các migration được nhúng trong binary.
+func New(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return NewFromFS(db, sub)
+}
+
+// NewFromFS đọc migration từ thư mục gốc của fsys (dùng cho test).
+func NewFromFS(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load đọc và kiểm tra các file migration, sắp xếp theo version tăng dần.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q (expected NNNN_name.up.sql or NNNN_name.down.sql)", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest trả về version mới nhất có trong binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up chạy tất cả migration chưa apply.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rollback migration mới nhất đã apply.
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		current := currentVersion(applied)
+		if current == 0 {
+			return nil
+		}
+		target := 0
+		for _, mig := range m.migrations {
+			if mig.Version < current && applied[mig.Version] != nil {
+				target = mig.Version
+			}
+		}
+		done, err = m.migrate(ctx, conn, applied, target)
+		return err
+	})
+	return done, err
+}
+
+// To đưa schema về đúng version: chạy up nếu version lớn hơn hiện tại, down nếu nhỏ hơn.
+// version 0 nghĩa là rollback toàn bộ.
+func (m *Migrator) To(ctx context.Context, version int) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		done, err = m.migrate(ctx, conn, applied, version)
+		return err
+	})
+	return done, err
+}
+
+// Status liệt kê tất cả migration và cho biết migration nào đã apply.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			st := Status{Migration: mig}
+			if row := applied[mig.Version]; row != nil {
+				st.Applied = true
+				st.AppliedAt = row.appliedAt
+			}
+			statuses = append(statuses, st)
+		}
+		_, err = m.verifyApplied(applied)
+		return err
+	})
+	return statuses, err
+}
+
+// Version trả về version cao nhất đã apply trong database.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	err := m.db.QueryRowContext(ctx, "SELECT MAX(`version`) FROM `schema_migrations`").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+type appliedRow struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// migrate chạy các bước up/down cần thiết để đi từ trạng thái hiện tại tới target.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, applied map[int]*appliedRow, target int) ([]Migration, error) {
+	var done []Migration
+	// Up: các migration <= target chưa apply, theo thứ tự tăng dần
+	for _, mig := range m.migrations {
+		if mig.Version > target || applied[mig.Version] != nil {
+			continue
+		}
+		if err := exec(ctx, conn, mig.Up); err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+		_, err := conn.ExecContext(ctx,
+			"INSERT INTO `schema_migrations` (`version`, `name`, `checksum`, `applied_at`) VALUES (?, ?, ?, ?)",
+			mig.Version, mig.Name, mig.Checksum, time.Now().UTC())
+		if err != nil {
+			return done, fmt.Errorf("failed to record migration %d: %w", mig.Version, err)
+		}
+		done = append(done, mig)
+	}
+	// Down: các migration > target đã apply, theo thứ tự giảm dần
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= target || applied[mig.Version] == nil {
+			continue
+		}
+		if strings.TrimSpace(mig.Down) == "" {
+			return done, fmt.Errorf("%w: %d_%s", ErrNoDown, mig.Version, mig.Name)
+		}
+		if err := exec(ctx, conn, mig.Down); err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := conn.ExecContext(ctx, "DELETE FROM `schema_migrations` WHERE `version` = ?", mig.Version); err != nil {
+			return done, fmt.Errorf("failed to unrecord migration %d: %w", mig.Version, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// withLock giữ một connection riêng và MySQL named lock trong suốt quá trình migrate,
+// để nhiều instance cùng khởi động với auto-migrate không chạy trùng.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, 30).Scan(&got); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if got.Int64 != 1 {
+		return errors.New("timed out waiting for migration lock")
+	}
+	defer func() {
+		// Dùng context riêng: ctx có thể đã bị huỷ nhưng lock vẫn phải được nhả
+		if _, releaseErr := conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName); releaseErr != nil && err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", releaseErr)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS `schema_migrations` ("+
+		"`version` BIGINT NOT NULL PRIMARY KEY, "+
+		"`name` VARCHAR(255) NOT NULL, "+
+		"`checksum` CHAR(64) NOT NULL, "+
+		"`applied_at` DATETIME NOT NULL"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]*appliedRow, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT `version`, `checksum`, `applied_at` FROM `schema_migrations`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]*appliedRow{}
+	for rows.Next() {
+		var version int
+		row := &appliedRow{}
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
+
+// verify đọc các migration đã apply và đảm bảo chúng khớp với file trong binary.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int]*appliedRow, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return m.verifyApplied(applied)
+}
+
+func (m *Migrator) verifyApplied(applied map[int]*appliedRow) (map[int]*appliedRow, error) {
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		mig := m.find(v)
+		if mig == nil {
+			return nil, fmt.Errorf("%w: %d is applied but not in this binary", ErrUnknownVersion, v)
+		}
+		if applied[v].checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s (checksum %s, file %s)", ErrChecksumMismatch, mig.Version, mig.Name, applied[v].checksum, mig.Checksum)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func currentVersion(applied map[int]*appliedRow) int {
+	current := 0
+	for v := range applied {
+		current = max(current, v)
+	}
+	return current
+}
+
+// exec chạy từng câu lệnh trong file. Driver mặc định không bật multiStatements
+// nên file được tách theo dấu ';' ở cuối dòng.
+func exec(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range Split(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Split tách script thành các câu lệnh, bỏ qua dòng comment "--" và dòng trống.
+func Split(script string) []string {
+	var (
+		stmts []string
+		buf   strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(buf.String()), ";"))
+			buf.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
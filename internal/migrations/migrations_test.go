@@ -0,0 +1,184 @@
+package migrations_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFS = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("-- bảng a\nCREATE TABLE a (id INT);\n")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;\n")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT);\nCREATE INDEX idx_b ON b (id);\n")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;\n")},
+}
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// expectLock mô phỏng phần mở đầu chung của mọi lệnh: lấy lock và tạo bảng schema_migrations.
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT GET_LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations`").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, rows ...[]any) {
+	result := sqlmock.NewRows([]string{"version", "checksum", "applied_at"})
+	for _, r := range rows {
+		values := make([]driver.Value, len(r))
+		for i, v := range r {
+			values[i] = v
+		}
+		result.AddRow(values...)
+	}
+	mock.ExpectQuery("SELECT `version`, `checksum`, `applied_at` FROM `schema_migrations`").WillReturnRows(result)
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	assert.Positive(t, m.Latest())
+}
+
+func TestLoad_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fs   fstest.MapFS
+	}{
+		{"Bad file name", fstest.MapFS{"create_a.up.sql": {Data: []byte("x")}}},
+		{"Missing up file", fstest.MapFS{"0001_a.down.sql": {Data: []byte("x")}}},
+		{"Same version, different names", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("x")},
+			"0001_b.up.sql": {Data: []byte("y")},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := migrations.Load(tc.fs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	stmts := migrations.Split("-- comment\nCREATE TABLE a (\n  id INT\n);\n\nDROP TABLE b;\nSELECT 1")
+	assert.Equal(t, []string{"CREATE TABLE a (\n  id INT\n)", "DROP TABLE b", "SELECT 1"}, stmts)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS)
+	require.NoError(t, err)
+
+	expectLock(mock)
+	expectApplied(mock, []any{1, checksum("-- bảng a\nCREATE TABLE a (id INT);\n"), time.Now()})
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX idx_b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `schema_migrations`").
+		WithArgs(2, "create_b", checksum("CREATE TABLE b (id INT);\nCREATE INDEX idx_b ON b (id);\n"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DO RELEASE_LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, 2, done[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS)
+	require.NoError(t, err)
+
+	expectLock(mock)
+	expectApplied(mock,
+		[]any{1, checksum("-- bảng a\nCREATE TABLE a (id INT);\n"), time.Now()},
+		[]any{2, checksum("CREATE TABLE b (id INT);\nCREATE INDEX idx_b ON b (id);\n"), time.Now()},
+	)
+	mock.ExpectExec("DROP TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `schema_migrations`").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DO RELEASE_LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Down(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, 2, done[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_RefusesToRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied [][]any
+		errIs   error
+	}{
+		{
+			name:    "Applied file was edited",
+			applied: [][]any{{1, checksum("CREATE TABLE a (id BIGINT);\n"), time.Now()}},
+			errIs:   migrations.ErrChecksumMismatch,
+		},
+		{
+			name:    "Database is ahead of binary",
+			applied: [][]any{{1, checksum("-- bảng a\nCREATE TABLE a (id INT);\n"), time.Now()}, {9, "abc", time.Now()}},
+			errIs:   migrations.ErrUnknownVersion,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			m, err := migrations.NewFromFS(db, testFS)
+			require.NoError(t, err)
+
+			expectLock(mock)
+			expectApplied(mock, tc.applied...)
+			// Không có Exec nào cho migration: chỉ nhả lock rồi dừng
+			mock.ExpectExec("DO RELEASE_LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			done, err := m.Up(context.Background())
+
+			assert.True(t, errors.Is(err, tc.errIs), "got %v", err)
+			assert.Empty(t, done)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_ToUnknownVersion(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS)
+	require.NoError(t, err)
+
+	_, err = m.To(context.Background(), 7)
+	assert.ErrorIs(t, err, migrations.ErrUnknownVersion)
+}
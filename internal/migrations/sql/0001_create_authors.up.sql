@@ -0,0 +1,10 @@
+-- IF NOT EXISTS để database đã có sẵn bảng (tạo tay trước đây) vẫn chạy được migration đầu tiên
+CREATE TABLE IF NOT EXISTS `authors` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(255) NOT NULL,
+  `nationality` VARCHAR(100) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_authors_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
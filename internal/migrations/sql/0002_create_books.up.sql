@@ -0,0 +1,12 @@
+-- fk_books_author (RESTRICT) là nguồn của lỗi 1451 khi xoá author còn sách, 1452 khi author_id không tồn tại
+CREATE TABLE IF NOT EXISTS `books` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) NOT NULL,
+  `author_id` INT NOT NULL,
+  `stock` INT NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_books_author_id` (`author_id`),
+  CONSTRAINT `fk_books_author` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,14 @@
+-- fk_orders_book (RESTRICT) chặn xoá sách đã có order (lỗi 1451 trong bookRepo.DeleteById)
+CREATE TABLE IF NOT EXISTS `orders` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `book_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `status` VARCHAR(32) NOT NULL,
+  `ordered_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_orders_book_id` (`book_id`),
+  KEY `idx_orders_user_id` (`user_id`),
+  CONSTRAINT `fk_orders_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
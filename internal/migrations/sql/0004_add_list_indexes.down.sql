@@ -0,0 +1,7 @@
+DROP INDEX `idx_orders_status_ordered_at` ON `orders`;
+DROP INDEX `idx_orders_ordered_at` ON `orders`;
+DROP INDEX `idx_authors_created_at` ON `authors`;
+DROP INDEX `idx_authors_nationality` ON `authors`;
+DROP INDEX `idx_books_created_at` ON `books`;
+DROP INDEX `idx_books_stock` ON `books`;
+DROP INDEX `idx_books_title` ON `books`;
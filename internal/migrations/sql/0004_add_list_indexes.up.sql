@@ -0,0 +1,8 @@
+-- Index cho filter/sort của các endpoint danh sách (keyset pagination dùng (cột sort, id))
+CREATE INDEX `idx_books_title` ON `books` (`title`);
+CREATE INDEX `idx_books_stock` ON `books` (`stock`);
+CREATE INDEX `idx_books_created_at` ON `books` (`created_at`);
+CREATE INDEX `idx_authors_nationality` ON `authors` (`nationality`);
+CREATE INDEX `idx_authors_created_at` ON `authors` (`created_at`);
+CREATE INDEX `idx_orders_ordered_at` ON `orders` (`ordered_at`);
+CREATE INDEX `idx_orders_status_ordered_at` ON `orders` (`status`, `ordered_at`);
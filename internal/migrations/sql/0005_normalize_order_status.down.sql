@@ -0,0 +1,3 @@
+ALTER TABLE `orders` ALTER COLUMN `status` DROP DEFAULT;
+UPDATE `orders` o JOIN `legacy_order_statuses` l ON l.`order_id` = o.`id` SET o.`status` = l.`status`;
+DROP TABLE IF EXISTS `legacy_order_statuses`;
@@ -0,0 +1,16 @@
+-- Trước đây status là chuỗi tự do; đưa dữ liệu cũ về vòng đời mới (xem models.OrderActions)
+UPDATE `orders` SET `status` = LOWER(TRIM(`status`));
+-- Status không nhận ra được lưu lại nguyên văn trước khi chuẩn hoá, để vận hành xem xét từng order;
+-- order nào không còn hiệu lực thì huỷ qua POST /orders/{id}/cancel
+CREATE TABLE IF NOT EXISTS `legacy_order_statuses` (
+  `order_id` INT NOT NULL,
+  `status` VARCHAR(255) NOT NULL,
+  PRIMARY KEY (`order_id`),
+  CONSTRAINT `fk_legacy_order_statuses_order` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO `legacy_order_statuses` (`order_id`, `status`)
+  SELECT `id`, `status` FROM `orders`
+  WHERE `status` NOT IN ('pending', 'confirmed', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded');
+UPDATE `orders` SET `status` = 'pending'
+  WHERE `id` IN (SELECT `order_id` FROM `legacy_order_statuses`);
+ALTER TABLE `orders` ALTER COLUMN `status` SET DEFAULT 'pending';
@@ -0,0 +1,12 @@
+-- Order nhiều dòng chỉ giữ lại sách có id nhỏ nhất; order không có dòng nào bị xoá
+ALTER TABLE `orders` ADD COLUMN `book_id` INT NULL AFTER `id`;
+UPDATE `orders` o
+  JOIN (SELECT `order_id`, MIN(`book_id`) AS `book_id` FROM `order_items` GROUP BY `order_id`) i ON i.`order_id` = o.`id`
+  SET o.`book_id` = i.`book_id`;
+UPDATE `orders` o
+  JOIN `order_items` oi ON oi.`order_id` = o.`id` AND oi.`book_id` = o.`book_id`
+  SET o.`quantity` = oi.`quantity`;
+DELETE FROM `orders` WHERE `book_id` IS NULL;
+ALTER TABLE `orders` MODIFY `book_id` INT NOT NULL, ADD KEY `idx_orders_book_id` (`book_id`);
+ALTER TABLE `orders` ADD CONSTRAINT `fk_orders_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE RESTRICT;
+DROP TABLE IF EXISTS `order_items`;
@@ -0,0 +1,17 @@
+-- Một order gồm nhiều dòng sách; orders.quantity giữ tổng số lượng để sort/filter
+CREATE TABLE IF NOT EXISTS `order_items` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `order_id` INT NOT NULL,
+  `book_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_order_items_order_book` (`order_id`, `book_id`),
+  KEY `idx_order_items_book_id` (`book_id`),
+  CONSTRAINT `fk_order_items_order` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`) ON DELETE CASCADE,
+  -- fk_order_items_book (RESTRICT) thay cho fk_orders_book: vẫn chặn xoá sách đã có order
+  CONSTRAINT `fk_order_items_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO `order_items` (`order_id`, `book_id`, `quantity`)
+  SELECT `id`, `book_id`, `quantity` FROM `orders`;
+ALTER TABLE `orders` DROP FOREIGN KEY `fk_orders_book`;
+ALTER TABLE `orders` DROP INDEX `idx_orders_book_id`, DROP COLUMN `book_id`;
@@ -0,0 +1,20 @@
+-- Sổ cái tồn kho: mọi thay đổi books.stock đều ghi một dòng trong cùng transaction,
+-- nên SUM(delta) của một sách luôn bằng stock hiện tại (xem lệnh reconcile-stock)
+CREATE TABLE IF NOT EXISTS `stock_movements` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `book_id` INT NOT NULL,
+  `delta` INT NOT NULL,
+  `reason` VARCHAR(32) NOT NULL,
+  -- Không có FK tới orders: order bị xoá thì lịch sử tồn kho vẫn phải còn
+  `order_id` INT NULL,
+  `note` VARCHAR(255) NOT NULL DEFAULT '',
+  `stock_after` INT NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_stock_movements_book_id` (`book_id`, `id`),
+  KEY `idx_stock_movements_order_id` (`order_id`),
+  CONSTRAINT `fk_stock_movements_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Số dư đầu kỳ: stock hiện có được ghi như một lần kiểm kê
+INSERT INTO `stock_movements` (`book_id`, `delta`, `reason`, `note`, `stock_after`)
+  SELECT `id`, `stock`, 'stocktake', 'opening balance', `stock` FROM `books` WHERE `stock` <> 0;
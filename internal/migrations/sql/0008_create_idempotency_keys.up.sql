@@ -0,0 +1,13 @@
+-- Idempotency-Key của POST /orders. Row được insert đầu transaction tạo order và điền
+-- order_id/response trước khi commit: request trùng key chạy song song sẽ chờ row lock
+-- thay vì tạo thêm order.
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `idempotency_key` VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+  `fingerprint` CHAR(64) NOT NULL,
+  `order_id` INT NULL,
+  -- Order lúc tạo (JSON), replay trả lại đúng bản này dù order đã đổi sau đó
+  `response` TEXT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`idempotency_key`),
+  KEY `idx_idempotency_keys_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
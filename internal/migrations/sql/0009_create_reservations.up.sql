@@ -0,0 +1,18 @@
+-- Giữ hàng trong lúc khách thanh toán: reservation không trừ books.stock mà giảm
+-- available-to-sell = stock - SUM(quantity) của các reservation active chưa hết hạn
+CREATE TABLE IF NOT EXISTS `reservations` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `book_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `status` VARCHAR(32) NOT NULL DEFAULT 'active',
+  `expires_at` DATETIME NOT NULL,
+  -- Order được tạo khi confirm
+  `order_id` INT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_reservations_book_status` (`book_id`, `status`, `expires_at`),
+  KEY `idx_reservations_status_expires_at` (`status`, `expires_at`),
+  CONSTRAINT `fk_reservations_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- Order đang chờ hàng không còn ý nghĩa khi bỏ backorder
+UPDATE `orders` SET `status` = 'cancelled' WHERE `status` = 'backordered';
+ALTER TABLE `books` DROP COLUMN `expected_at`, DROP COLUMN `backorder_policy`;
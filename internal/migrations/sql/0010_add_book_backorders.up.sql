@@ -0,0 +1,5 @@
+-- Sách hết hàng vẫn nhận order (backordered) nếu cho phép đặt trước:
+-- 'none' từ chối như cũ, 'backorder' chờ nhập thêm, 'preorder' chưa phát hành
+ALTER TABLE `books`
+  ADD COLUMN `backorder_policy` VARCHAR(16) NOT NULL DEFAULT 'none',
+  ADD COLUMN `expected_at` DATE NULL;
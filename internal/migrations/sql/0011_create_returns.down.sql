@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `refunds`;
+DROP TABLE IF EXISTS `return_items`;
+DROP TABLE IF EXISTS `returns`;
+UPDATE `orders` SET `status` = 'delivered' WHERE `status` IN ('partially_returned', 'returned');
@@ -0,0 +1,2 @@
+ALTER TABLE `order_items` DROP COLUMN `currency`, DROP COLUMN `unit_price`;
+ALTER TABLE `books` DROP COLUMN `currency`, DROP COLUMN `price`;
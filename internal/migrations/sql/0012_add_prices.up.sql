@@ -0,0 +1,8 @@
+-- Giá lưu theo đơn vị nhỏ nhất của currency (đồng, cent) để không lệch số khi cộng dồn
+ALTER TABLE `books`
+  ADD COLUMN `price` BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'VND';
+-- order_items giữ giá tại thời điểm đặt hàng; order cũ không có giá nên để 0
+ALTER TABLE `order_items`
+  ADD COLUMN `unit_price` BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'VND';
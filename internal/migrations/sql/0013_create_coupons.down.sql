@@ -0,0 +1,5 @@
+ALTER TABLE `orders` DROP COLUMN `discount`, DROP COLUMN `coupon_code`;
+DROP TABLE IF EXISTS `coupon_redemptions`;
+DROP TABLE IF EXISTS `coupon_authors`;
+DROP TABLE IF EXISTS `coupon_books`;
+DROP TABLE IF EXISTS `coupons`;
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS `coupons` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `code` VARCHAR(32) NOT NULL,
+  `type` VARCHAR(16) NOT NULL,
+  `value` BIGINT NOT NULL,
+  `currency` CHAR(3) NOT NULL DEFAULT 'VND',
+  `min_order_value` BIGINT NOT NULL DEFAULT 0,
+  `starts_at` DATETIME NULL,
+  `ends_at` DATETIME NULL,
+  `max_redemptions` INT NOT NULL DEFAULT 0,
+  `max_per_user` INT NOT NULL DEFAULT 0,
+  -- Đếm trong transaction tạo order, dưới row lock của coupon
+  `redemptions` INT NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_coupons_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS `coupon_books` (
+  `coupon_id` INT NOT NULL,
+  `book_id` INT NOT NULL,
+  PRIMARY KEY (`coupon_id`, `book_id`),
+  CONSTRAINT `fk_coupon_books_coupon` FOREIGN KEY (`coupon_id`) REFERENCES `coupons` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_coupon_books_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS `coupon_authors` (
+  `coupon_id` INT NOT NULL,
+  `author_id` INT NOT NULL,
+  PRIMARY KEY (`coupon_id`, `author_id`),
+  CONSTRAINT `fk_coupon_authors_coupon` FOREIGN KEY (`coupon_id`) REFERENCES `coupons` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_coupon_authors_author` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Mỗi order dùng tối đa một coupon; user_id để đếm giới hạn theo user
+CREATE TABLE IF NOT EXISTS `coupon_redemptions` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `coupon_id` INT NOT NULL,
+  `order_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `discount` BIGINT NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_coupon_redemptions_order` (`order_id`),
+  KEY `idx_coupon_redemptions_coupon_user` (`coupon_id`, `user_id`),
+  CONSTRAINT `fk_coupon_redemptions_coupon` FOREIGN KEY (`coupon_id`) REFERENCES `coupons` (`id`) ON DELETE RESTRICT,
+  CONSTRAINT `fk_coupon_redemptions_order` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Order giữ lại mã và số tiền đã giảm để total tính lại được
+ALTER TABLE `orders`
+  ADD COLUMN `coupon_code` VARCHAR(32) NULL,
+  ADD COLUMN `discount` BIGINT NOT NULL DEFAULT 0;
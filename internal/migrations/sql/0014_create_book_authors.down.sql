@@ -0,0 +1,11 @@
+-- Chỉ giữ lại người đầu tiên của mỗi sách (ưu tiên role author)
+ALTER TABLE `books` ADD COLUMN `author_id` INT NULL AFTER `title`;
+UPDATE `books` b
+  SET b.`author_id` = (
+    SELECT ba.`author_id` FROM `book_authors` ba
+    WHERE ba.`book_id` = b.`id`
+    ORDER BY ba.`role` <> 'author', ba.`position`
+    LIMIT 1);
+ALTER TABLE `books` MODIFY `author_id` INT NOT NULL, ADD KEY `idx_books_author_id` (`author_id`);
+ALTER TABLE `books` ADD CONSTRAINT `fk_books_author` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`) ON DELETE RESTRICT;
+DROP TABLE IF EXISTS `book_authors`;
@@ -0,0 +1,17 @@
+-- Một sách có nhiều người tham gia (đồng tác giả, biên tập, dịch giả, minh hoạ);
+-- position giữ thứ tự hiển thị. fk_book_authors_author (RESTRICT) thay cho fk_books_author:
+-- vẫn chặn xoá author còn sách.
+CREATE TABLE IF NOT EXISTS `book_authors` (
+  `book_id` INT NOT NULL,
+  `author_id` INT NOT NULL,
+  `role` VARCHAR(16) NOT NULL DEFAULT 'author',
+  `position` INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`book_id`, `author_id`, `role`),
+  KEY `idx_book_authors_author_id` (`author_id`, `book_id`),
+  CONSTRAINT `fk_book_authors_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_book_authors_author` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO `book_authors` (`book_id`, `author_id`, `role`, `position`)
+  SELECT `id`, `author_id`, 'author', 0 FROM `books`;
+ALTER TABLE `books` DROP FOREIGN KEY `fk_books_author`;
+ALTER TABLE `books` DROP INDEX `idx_books_author_id`, DROP COLUMN `author_id`;
@@ -0,0 +1 @@
+ALTER TABLE `books` DROP INDEX `uq_books_isbn`, DROP COLUMN `isbn`;
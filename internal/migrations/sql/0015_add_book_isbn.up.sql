@@ -0,0 +1,4 @@
+-- isbn lưu dạng ISBN-13 không dấu gạch; NULL với sách cũ chưa có mã (unique index cho phép nhiều NULL)
+ALTER TABLE `books`
+  ADD COLUMN `isbn` CHAR(13) NULL AFTER `title`,
+  ADD UNIQUE KEY `uq_books_isbn` (`isbn`);
@@ -0,0 +1,25 @@
+-- MySQL 5.7 không có recursive CTE nên cây được lưu thêm materialized path:
+-- path = "/<id tổ tiên>/.../<id>/", cây con của X là các row có path LIKE 'path của X%'.
+CREATE TABLE IF NOT EXISTS `categories` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `parent_id` INT NULL,
+  `name` VARCHAR(100) NOT NULL,
+  `slug` VARCHAR(120) NOT NULL,
+  `position` INT NOT NULL DEFAULT 0,
+  `path` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_categories_slug` (`slug`),
+  KEY `idx_categories_parent` (`parent_id`, `position`),
+  KEY `idx_categories_path` (`path`),
+  CONSTRAINT `fk_categories_parent` FOREIGN KEY (`parent_id`) REFERENCES `categories` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS `book_categories` (
+  `book_id` INT NOT NULL,
+  `category_id` INT NOT NULL,
+  PRIMARY KEY (`book_id`, `category_id`),
+  KEY `idx_book_categories_category_id` (`category_id`, `book_id`),
+  CONSTRAINT `fk_book_categories_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_book_categories_category` FOREIGN KEY (`category_id`) REFERENCES `categories` (`id`) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+ALTER TABLE `books` DROP FOREIGN KEY `fk_books_work`, DROP FOREIGN KEY `fk_books_publisher`;
+ALTER TABLE `books`
+  DROP INDEX `idx_books_work_id`,
+  DROP INDEX `idx_books_publisher_id`,
+  DROP COLUMN `work_id`,
+  DROP COLUMN `format`,
+  DROP COLUMN `language`,
+  DROP COLUMN `published_at`,
+  DROP COLUMN `publisher_id`;
+DROP TABLE IF EXISTS `works`;
+DROP TABLE IF EXISTS `publishers`;
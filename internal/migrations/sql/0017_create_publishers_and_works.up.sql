@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS `publishers` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(255) NOT NULL,
+  `country` VARCHAR(100) NOT NULL DEFAULT '',
+  `website` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_publishers_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Work là tác phẩm; mỗi row books là một ấn bản (bìa cứng, bìa mềm, bản dịch...) của một work
+CREATE TABLE IF NOT EXISTS `works` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Sách hiện có: mỗi sách là ấn bản duy nhất của một work cùng id
+INSERT INTO `works` (`id`, `title`, `created_at`) SELECT `id`, `title`, `created_at` FROM `books`;
+ALTER TABLE `books`
+  ADD COLUMN `work_id` INT NULL AFTER `id`,
+  ADD COLUMN `format` VARCHAR(16) NOT NULL DEFAULT '',
+  ADD COLUMN `language` VARCHAR(3) NOT NULL DEFAULT '',
+  ADD COLUMN `published_at` DATE NULL,
+  ADD COLUMN `publisher_id` INT NULL;
+UPDATE `books` SET `work_id` = `id`;
+ALTER TABLE `books`
+  MODIFY `work_id` INT NOT NULL,
+  ADD KEY `idx_books_work_id` (`work_id`),
+  ADD KEY `idx_books_publisher_id` (`publisher_id`),
+  ADD CONSTRAINT `fk_books_work` FOREIGN KEY (`work_id`) REFERENCES `works` (`id`) ON DELETE RESTRICT,
+  ADD CONSTRAINT `fk_books_publisher` FOREIGN KEY (`publisher_id`) REFERENCES `publishers` (`id`) ON DELETE RESTRICT;
@@ -0,0 +1,2 @@
+ALTER TABLE `authors` DROP INDEX `ft_authors_name_folded`, DROP COLUMN `name_folded`;
+ALTER TABLE `books` DROP INDEX `ft_books_title_folded`, DROP COLUMN `title_folded`;
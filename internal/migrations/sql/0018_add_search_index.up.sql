@@ -0,0 +1,10 @@
+-- Cột *_folded là title/tên đã viết thường và bỏ dấu tiếng Việt (textnorm.Fold), do app ghi khi tạo/sửa,
+-- để FULLTEXT so khớp "nguyen nhat anh" với "Nguyễn Nhật Ánh".
+-- SQL không bỏ dấu được nên backfill ở đây chỉ viết thường; chạy "re-book-api reindex-search" sau khi migrate.
+ALTER TABLE `books` ADD COLUMN `title_folded` VARCHAR(255) NOT NULL DEFAULT '' AFTER `title`;
+ALTER TABLE `authors` ADD COLUMN `name_folded` VARCHAR(255) NOT NULL DEFAULT '' AFTER `name`;
+UPDATE `books` SET `title_folded` = LOWER(`title`);
+UPDATE `authors` SET `name_folded` = LOWER(`name`);
+-- InnoDB chỉ tạo được một FULLTEXT index mỗi câu ALTER
+ALTER TABLE `books` ADD FULLTEXT KEY `ft_books_title_folded` (`title_folded`);
+ALTER TABLE `authors` ADD FULLTEXT KEY `ft_authors_name_folded` (`name_folded`);
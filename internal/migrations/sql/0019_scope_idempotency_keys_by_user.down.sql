@@ -0,0 +1,6 @@
+-- Key trùng giữa các user không giữ được dưới khoá toàn cục: chỉ giữ key tạo sớm nhất
+DELETE k FROM `idempotency_keys` k JOIN `idempotency_keys` o
+  ON o.`idempotency_key` = k.`idempotency_key`
+  AND (o.`created_at` < k.`created_at` OR (o.`created_at` = k.`created_at` AND o.`user_id` < k.`user_id`));
+ALTER TABLE `idempotency_keys` DROP PRIMARY KEY, ADD PRIMARY KEY (`idempotency_key`);
+ALTER TABLE `idempotency_keys` DROP COLUMN `user_id`;
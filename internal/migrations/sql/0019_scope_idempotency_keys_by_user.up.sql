@@ -0,0 +1,7 @@
+-- Idempotency-Key chỉ duy nhất trong phạm vi một user (trước đây là toàn cục).
+-- Key cũ lấy user_id từ order đã tạo; key không còn order thì không replay được nữa nên xoá đi.
+ALTER TABLE `idempotency_keys` ADD COLUMN `user_id` INT NOT NULL DEFAULT 0 FIRST;
+UPDATE `idempotency_keys` k JOIN `orders` o ON o.`id` = k.`order_id` SET k.`user_id` = o.`user_id`;
+DELETE FROM `idempotency_keys` WHERE `user_id` = 0;
+ALTER TABLE `idempotency_keys` DROP PRIMARY KEY, ADD PRIMARY KEY (`user_id`, `idempotency_key`);
+ALTER TABLE `idempotency_keys` ALTER COLUMN `user_id` DROP DEFAULT;
@@ -0,0 +1,2 @@
+UPDATE `books` SET `price` = 0 WHERE `price` IS NULL;
+ALTER TABLE `books` MODIFY COLUMN `price` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Sửa cho 0012: sách có từ trước nhận price = 0, trùng với giá của sách miễn phí nên sẽ bị bán không lấy tiền.
+-- NULL nghĩa là chưa định giá; order tới sách đó bị từ chối (422) cho tới khi được đặt giá qua
+-- PATCH /books/{id}. Xem mục "Giá sách" trong README.
+ALTER TABLE `books` MODIFY COLUMN `price` BIGINT NULL DEFAULT NULL;
+UPDATE `books` SET `price` = NULL WHERE `price` <= 0;
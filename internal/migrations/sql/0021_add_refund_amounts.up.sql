@@ -0,0 +1,19 @@
+-- Số tiền hoàn theo đơn giá đã chốt trên order_items, trừ phần coupon chia theo tỉ lệ giá trị.
+ALTER TABLE `refunds`
+  ADD COLUMN `amount` BIGINT NOT NULL DEFAULT 0 AFTER `quantity`,
+  ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'VND' AFTER `amount`;
+-- Refund cũ: tính lại từng refund riêng lẻ (app tính luỹ kế nên có thể lệch 1 đơn vị do làm tròn)
+UPDATE `refunds` f
+  JOIN (
+    SELECT ri.`return_id`, SUM(ri.`quantity` * oi.`unit_price`) AS `gross`, MAX(oi.`currency`) AS `currency`
+    FROM `return_items` ri
+    JOIN `returns` r ON r.`id` = ri.`return_id`
+    JOIN `order_items` oi ON oi.`order_id` = r.`order_id` AND oi.`book_id` = ri.`book_id`
+    GROUP BY ri.`return_id`
+  ) g ON g.`return_id` = f.`return_id`
+  JOIN (
+    SELECT `order_id`, SUM(`quantity` * `unit_price`) AS `subtotal` FROM `order_items` GROUP BY `order_id`
+  ) s ON s.`order_id` = f.`order_id`
+  JOIN `orders` o ON o.`id` = f.`order_id`
+  SET f.`amount` = g.`gross` - IF(s.`subtotal` > 0, FLOOR(LEAST(o.`discount`, s.`subtotal`) * g.`gross` / s.`subtotal`), 0),
+      f.`currency` = g.`currency`;
@@ -2,13 +2,140 @@ package models
 
 import (
 	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/isbn"
 )
 
 type Book struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Stock     int       `json:"stock"`
-	AuthorID  int       `json:"author_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// ISBN luôn lưu dạng ISBN-13 không dấu gạch; ISBN10 do server điền, chỉ có với mã prefix 978
+	ISBN   string `json:"isbn,omitempty"`
+	ISBN10 string `json:"isbn_10,omitempty"`
+	Stock  int    `json:"stock"`
+	// AvailableStock là stock trừ hàng đang được reservation giữ, chỉ có khi đọc
+	AvailableStock int `json:"available_stock"`
+	// AuthorID là tác giả chính, giữ cho client cũ gửi/đọc {author_id}; Authors là danh sách đầy đủ
+	AuthorID int          `json:"author_id"`
+	Authors  []BookAuthor `json:"authors"`
+	// CategoryIDs là các category được gán trực tiếp (không gồm category cha)
+	CategoryIDs []int `json:"category_ids"`
+	// Price tính theo đơn vị nhỏ nhất của Currency (vd: đồng với VND, cent với USD).
+	// nil là sách chưa được định giá (có từ trước migration 0012), chưa đặt hàng được.
+	Price    *int64 `json:"price"`
+	Currency string `json:"currency"`
+	// BackorderPolicy cho phép nhận order khi thiếu hàng (xem Backorder*)
+	BackorderPolicy string `json:"backorder_policy"`
+	// ExpectedAt là ngày dự kiến có hàng, bắt buộc với preorder
+	ExpectedAt *time.Time `json:"expected_at,omitempty"`
+	// Mỗi Book là một ấn bản của một Work; các ấn bản cùng work có stock riêng.
+	// WorkID = 0 khi tạo nghĩa là ấn bản đầu tiên của một work mới.
+	WorkID      int        `json:"work_id"`
+	Format      string     `json:"format,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PublisherID *int       `json:"publisher_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Định dạng ấn bản.
+const (
+	FormatHardcover = "hardcover"
+	FormatPaperback = "paperback"
+	FormatEbook     = "ebook"
+	FormatAudiobook = "audiobook"
+)
+
+// BookFormats là các định dạng hợp lệ.
+var BookFormats = []string{FormatHardcover, FormatPaperback, FormatEbook, FormatAudiobook}
+
+// IsLanguageCode kiểm tra mã ngôn ngữ ISO 639-1/639-2 viết thường, vd: vi, en, fra.
+func IsLanguageCode(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// SetISBN10 điền ISBN10 từ ISBN; mã prefix 979 không có dạng ISBN-10.
+func (b *Book) SetISBN10() {
+	b.ISBN10 = ""
+	if b.ISBN != "" {
+		b.ISBN10, _ = isbn.To10(b.ISBN)
+	}
+}
+
+// Vai trò của một người trong sách.
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+// AuthorRoles là các vai trò hợp lệ.
+var AuthorRoles = []string{RoleAuthor, RoleEditor, RoleTranslator, RoleIllustrator}
+
+// BookAuthor là một người tham gia sách; thứ tự trong Book.Authors là thứ tự hiển thị.
+type BookAuthor struct {
+	AuthorID int    `json:"author_id"`
+	Role     string `json:"role"`
+	// Name chỉ có khi đọc
+	Name string `json:"name,omitempty"`
+}
+
+// NormalizeAuthors đổi payload cũ {author_id} thành một tác giả rồi đặt lại AuthorID là
+// tác giả chính: người đầu tiên có role author, không có thì người đầu tiên trong danh sách.
+func (b *Book) NormalizeAuthors() {
+	if len(b.Authors) == 0 && b.AuthorID != 0 {
+		b.Authors = []BookAuthor{{AuthorID: b.AuthorID, Role: RoleAuthor}}
+	}
+	b.AuthorID = 0
+	for _, a := range b.Authors {
+		if a.Role == RoleAuthor {
+			b.AuthorID = a.AuthorID
+			return
+		}
+	}
+	if len(b.Authors) > 0 {
+		b.AuthorID = b.Authors[0].AuthorID
+	}
+}
+
+// DefaultCurrency dùng khi sách được tạo không kèm currency.
+const DefaultCurrency = "VND"
+
+// IsCurrencyCode kiểm tra mã tiền tệ 3 chữ cái in hoa theo ISO 4217.
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Chính sách khi sách không đủ hàng cho order.
+const (
+	BackorderNone     = "none"      // từ chối order như cũ
+	BackorderAllowed  = "backorder" // nhận order, chờ nhập thêm hàng
+	BackorderPreorder = "preorder"  // sách chưa phát hành, có ngày dự kiến
+)
+
+// IsBackorderPolicy kiểm tra policy có hợp lệ không.
+func IsBackorderPolicy(policy string) bool {
+	switch policy {
+	case BackorderNone, BackorderAllowed, BackorderPreorder:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+type Category struct {
+	ID       int    `json:"id"`
+	ParentID *int   `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	// Position là thứ tự giữa các category cùng cha, nhỏ đứng trước
+	Position int `json:"position"`
+	// Path là id của các tổ tiên và chính nó, vd: "/1/4/9/", dùng để lấy cả cây con bằng LIKE
+	Path      string      `json:"-"`
+	Children  []*Category `json:"children,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// BuildCategoryTree gắn mỗi category vào Children của cha và trả về các gốc;
+// các category cùng cha được sắp theo position rồi name.
+// Category có cha không nằm trong danh sách được coi là gốc.
+func BuildCategoryTree(categories []*Category) []*Category {
+	byID := make(map[int]*Category, len(categories))
+	for _, c := range categories {
+		c.Children = nil
+		byID[c.ID] = c
+	}
+	roots := []*Category{}
+	for _, c := range categories {
+		if c.ParentID != nil {
+			if parent := byID[*c.ParentID]; parent != nil {
+				parent.Children = append(parent.Children, c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+	sortCategories(roots)
+	return roots
+}
+
+func sortCategories(categories []*Category) {
+	slices.SortFunc(categories, func(a, b *Category) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	for _, c := range categories {
+		sortCategories(c.Children)
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Loại giảm giá của coupon.
+const (
+	CouponPercentage = "percentage" // Value là phần trăm (1-100) của các dòng được áp dụng
+	CouponFixed      = "fixed"      // Value là số tiền giảm, theo đơn vị nhỏ nhất của Currency
+)
+
+// Coupon là mã giảm giá áp dụng khi tạo order. BookIDs/AuthorIDs rỗng nghĩa là
+// áp dụng cho mọi sách; có giá trị thì chỉ các dòng khớp sách hoặc tác giả được giảm.
+type Coupon struct {
+	ID       int    `json:"id"`
+	Code     string `json:"code"`
+	Type     string `json:"type"`
+	Value    int64  `json:"value"`
+	Currency string `json:"currency"`
+	// MinOrderValue so với subtotal của cả order, 0 là không giới hạn
+	MinOrderValue int64      `json:"min_order_value"`
+	BookIDs       []int      `json:"book_ids"`
+	AuthorIDs     []int      `json:"author_ids"`
+	StartsAt      *time.Time `json:"starts_at,omitempty"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+	// MaxRedemptions/MaxPerUser bằng 0 là không giới hạn
+	MaxRedemptions int       `json:"max_redemptions"`
+	MaxPerUser     int       `json:"max_per_user"`
+	Redemptions    int       `json:"redemptions"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ActiveAt kiểm tra now nằm trong khoảng [StartsAt, EndsAt).
+func (c *Coupon) ActiveAt(now time.Time) bool {
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	return c.EndsAt == nil || now.Before(*c.EndsAt)
+}
+
+// Restricted cho biết coupon chỉ áp dụng cho một số sách/tác giả.
+func (c *Coupon) Restricted() bool {
+	return len(c.BookIDs) > 0 || len(c.AuthorIDs) > 0
+}
+
+// Discount tính số tiền giảm trên eligible (tổng tiền các dòng được áp dụng);
+// phần trăm làm tròn xuống, không bao giờ giảm quá eligible.
+func (c *Coupon) Discount(eligible int64) int64 {
+	discount := c.Value
+	if c.Type == CouponPercentage {
+		discount = eligible * c.Value / 100
+	}
+	return min(discount, eligible)
+}
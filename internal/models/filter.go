@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
+
+// BookFilter là điều kiện lọc cho GET /books. Field có giá trị zero (hoặc nil) nghĩa là không lọc.
+type BookFilter struct {
+	pagination.Params
+	AuthorID int
+	// CategoryID lọc sách thuộc category này hoặc bất kỳ category con cháu nào
+	CategoryID  int
+	WorkID      int
+	PublisherID int
+	MinStock    *int
+	MaxStock    *int
+	TitlePrefix string
+}
+
+// AuthorFilter là điều kiện lọc cho GET /authors.
+type AuthorFilter struct {
+	pagination.Params
+	Nationality string
+	// Name lọc đúng tên (không phân biệt hoa thường), dùng khi kiểm tra trùng tên
+	Name string
+}
+
+// OrderFilter là điều kiện lọc cho GET /orders. Khoảng thời gian là [From, To).
+type OrderFilter struct {
+	pagination.Params
+	Status string
+	UserID int
+	BookID int
+	From   *time.Time
+	To     *time.Time
+}
+
+// StockMovementFilter là điều kiện lọc cho GET /books/{id}/stock/movements.
+type StockMovementFilter struct {
+	pagination.Params
+	BookID int
+	Reason string
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyKey gắn một header Idempotency-Key với request tạo order:
+// cùng UserID, cùng Key và cùng Fingerprint thì trả lại order đã tạo thay vì tạo order mới.
+type IdempotencyKey struct {
+	// Key chỉ duy nhất trong phạm vi một user: hai client chọn trùng key không ảnh hưởng nhau
+	UserID int
+	Key    string
+	// Fingerprint là hash của payload đã chuẩn hoá
+	Fingerprint string
+	// ExpiredBefore: key tạo trước thời điểm này đã hết hạn và được dùng lại
+	ExpiredBefore time.Time
+}
@@ -2,12 +2,82 @@ package models
 
 import "time"
 
+// OrderItem là một dòng sách trong order.
+// UnitPrice/Currency là giá sách tại thời điểm đặt hàng, do repository ghi lại;
+// giá client gửi lên bị bỏ qua.
+type OrderItem struct {
+	BookID    int    `json:"book_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"`
+	Currency  string `json:"currency,omitempty"`
+	LineTotal int64  `json:"line_total"`
+}
+
 type Order struct {
-	ID        int       `json:"id"`
-	BookID    int       `json:"book_id"`
-	UserID    int       `json:"user_id"`
-	Quantity  int       `json:"quantity"`
-	Status    string    `json:"status"`
-	OrderedAt time.Time `json:"ordered_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID int `json:"id"`
+	// BookID chỉ có giá trị với order một dòng, giữ cho client cũ gửi/đọc {book_id, quantity}
+	BookID int `json:"book_id,omitempty"`
+	UserID int `json:"user_id"`
+	// Quantity là tổng số lượng của tất cả các dòng
+	Quantity int         `json:"quantity"`
+	Items    []OrderItem `json:"items"`
+	// Subtotal/Total được tính từ items, theo đơn vị nhỏ nhất của Currency
+	Currency string `json:"currency,omitempty"`
+	Subtotal int64  `json:"subtotal"`
+	// CouponCode do client gửi khi tạo order; Discount là số tiền coupon đã giảm, chốt lúc tạo
+	CouponCode string    `json:"coupon_code,omitempty"`
+	Discount   int64     `json:"discount"`
+	Total      int64     `json:"total"`
+	Status     string    `json:"status"`
+	OrderedAt  time.Time `json:"ordered_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NormalizeItems đồng bộ Items với các field cũ: payload chỉ có book_id/quantity được
+// đổi thành một dòng; Quantity luôn là tổng, BookID chỉ giữ khi order có đúng một dòng.
+// Subtotal được tính lại từ giá của từng dòng, Total là Subtotal trừ Discount.
+func (o *Order) NormalizeItems() {
+	if len(o.Items) == 0 && o.BookID != 0 {
+		o.Items = []OrderItem{{BookID: o.BookID, Quantity: o.Quantity}}
+	}
+	o.BookID, o.Quantity, o.Subtotal = 0, 0, 0
+	for i := range o.Items {
+		item := &o.Items[i]
+		item.LineTotal = item.UnitPrice * int64(item.Quantity)
+		o.Quantity += item.Quantity
+		o.Subtotal += item.LineTotal
+		if item.Currency != "" {
+			o.Currency = item.Currency
+		}
+	}
+	o.Total = max(o.Subtotal-o.Discount, 0)
+	if len(o.Items) == 1 {
+		o.BookID = o.Items[0].BookID
+	}
+}
+
+// RefundAmount là số tiền hoàn khi nhận thêm các sách returning (book_id → số lượng), biết các
+// sách received đã được hoàn trước đó. Giá lấy từ đơn giá đã chốt trên items, còn coupon được
+// chia theo tỉ lệ giá trị. Tính luỹ kế nên khi trả hết, tổng các lần hoàn đúng bằng Total.
+func (o *Order) RefundAmount(received, returning map[int]int) int64 {
+	before := o.itemsValue(received)
+	after := before + o.itemsValue(returning)
+	return o.netValue(after) - o.netValue(before)
+}
+
+// itemsValue là giá trị theo giá lúc đặt của các sách quantities (book_id → số lượng).
+func (o *Order) itemsValue(quantities map[int]int) int64 {
+	var value int64
+	for _, item := range o.Items {
+		value += item.UnitPrice * int64(min(quantities[item.BookID], item.Quantity))
+	}
+	return value
+}
+
+// netValue trừ khỏi value phần coupon tương ứng với tỉ lệ value/Subtotal.
+func (o *Order) netValue(value int64) int64 {
+	if o.Subtotal <= 0 {
+		return value
+	}
+	return value - min(o.Discount, o.Subtotal)*value/o.Subtotal
 }
@@ -0,0 +1,93 @@
+package models
+
+import "slices"
+
+// Vòng đời của order:
+//
+//	pending → confirmed → paid → shipped → delivered
+//	pending/confirmed → cancelled (chưa thanh toán)
+//	paid/delivered → refunded (đã thanh toán)
+//	backordered → pending (khi nhập hàng, không qua API) hoặc cancelled
+//	delivered → partially_returned → returned (khi nhận hàng trả, không qua API)
+const (
+	OrderPending   = "pending"
+	OrderConfirmed = "confirmed"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+	// OrderBackordered là order chưa trừ stock, chờ được phân bổ hàng theo thứ tự đặt
+	OrderBackordered = "backordered"
+	// Đã nhận lại một phần / toàn bộ sách khách trả (xem models.Return)
+	OrderPartiallyReturned = "partially_returned"
+	OrderReturned          = "returned"
+)
+
+// orderTransitions liệt kê các trạng thái kế tiếp hợp lệ; cancelled và refunded là trạng thái cuối.
+var orderTransitions = map[string][]string{
+	OrderPending:   {OrderConfirmed, OrderCancelled},
+	OrderConfirmed: {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered},
+	OrderDelivered: {OrderRefunded},
+	OrderCancelled: {},
+	OrderRefunded:  {},
+	// Chuyển sang pending chỉ do phân bổ hàng (AllocateBackorders), vì lúc đó mới trừ stock
+	OrderBackordered: {OrderCancelled},
+	// Trạng thái trả hàng chỉ đổi theo các return đã nhận
+	OrderPartiallyReturned: {},
+	OrderReturned:          {},
+}
+
+// OrderAcceptsReturns báo order đã giao và còn có thể yêu cầu trả hàng.
+func OrderAcceptsReturns(status string) bool {
+	return status == OrderDelivered || status == OrderPartiallyReturned
+}
+
+// OrderActions ánh xạ endpoint POST /orders/{id}/{action} sang trạng thái đích.
+var OrderActions = map[string]string{
+	"confirm": OrderConfirmed,
+	"pay":     OrderPaid,
+	"ship":    OrderShipped,
+	"deliver": OrderDelivered,
+	"cancel":  OrderCancelled,
+	"refund":  OrderRefunded,
+}
+
+// IsOrderStatus kiểm tra status có thuộc vòng đời của order không.
+func IsOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// NextOrderStatuses trả về các trạng thái có thể chuyển tới từ status (nil nếu status không hợp lệ).
+func NextOrderStatuses(status string) []string {
+	return slices.Clone(orderTransitions[status])
+}
+
+// CanTransitionOrder báo order có được chuyển từ from sang to không.
+func CanTransitionOrder(from, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+// OrderHoldsStock báo stock đã trừ cho order nhưng sách chưa rời kho: huỷ, hoàn tiền
+// hoặc xoá order ở các trạng thái này phải trả lại stock.
+func OrderHoldsStock(status string) bool {
+	switch status {
+	case OrderPending, OrderConfirmed, OrderPaid:
+		return true
+	}
+	return false
+}
+
+// OrderReleasesCoupon báo order chuyển sang to có trả lại lượt dùng coupon không.
+func OrderReleasesCoupon(to string) bool {
+	return to == OrderCancelled || to == OrderRefunded
+}
+
+// OrderReleasesStock báo chuyển from → to có trả stock về kho không
+// (hoàn tiền sau khi đã giao thì sách trả về đi theo luồng return riêng).
+func OrderReleasesStock(from, to string) bool {
+	return OrderHoldsStock(from) && (to == OrderCancelled || to == OrderRefunded)
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Publisher struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Country   string    `json:"country"`
+	Website   string    `json:"website"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Trạng thái của reservation: chỉ active mới giữ hàng.
+const (
+	ReservationActive    = "active"
+	ReservationConfirmed = "confirmed" // đã thành order
+	ReservationReleased  = "released"  // khách huỷ
+	ReservationExpired   = "expired"   // sweeper đánh dấu khi quá expires_at
+)
+
+// Reservation giữ Quantity cuốn của một sách cho tới ExpiresAt mà không trừ stock.
+type Reservation struct {
+	ID        int       `json:"id"`
+	BookID    int       `json:"book_id"`
+	UserID    int       `json:"user_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	OrderID   *int      `json:"order_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// TTLSeconds là thời gian giữ hàng client yêu cầu khi tạo; 0 dùng mặc định
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Vòng đời của yêu cầu trả hàng:
+//
+//	requested → approved → received (nhận hàng trả, tạo refund)
+//	requested → rejected
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+)
+
+// ReturnReasons là các mã lý do khách được chọn khi trả hàng.
+var ReturnReasons = []string{"damaged", "defective", "wrong_item", "not_as_described", "no_longer_needed", "other"}
+
+// ReturnItem là số lượng trả của một sách trong order.
+type ReturnItem struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
+
+// Return là một yêu cầu trả hàng cho order đã giao, có thể chỉ trả một phần.
+type Return struct {
+	ID      int          `json:"id"`
+	OrderID int          `json:"order_id"`
+	Status  string       `json:"status"`
+	Reason  string       `json:"reason"`
+	Note    string       `json:"note,omitempty"`
+	Items   []ReturnItem `json:"items"`
+	// Restock báo sách trả được nhập lại kho khi nhận (sách hỏng thì không)
+	Restock   bool      `json:"restock"`
+	Refund    *Refund   `json:"refund,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Refund ghi nhận phần hoàn tiền cho order, tạo khi nhận hàng trả.
+type Refund struct {
+	ID       int  `json:"id"`
+	OrderID  int  `json:"order_id"`
+	ReturnID *int `json:"return_id,omitempty"`
+	// Quantity là tổng số sách được hoàn tiền
+	Quantity int `json:"quantity"`
+	// Amount là số tiền hoàn theo đơn vị nhỏ nhất của Currency (xem Order.RefundAmount)
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "github.com/maithuc2003/re-book-api/internal/pagination"
+
+const (
+	SearchTypeBook   = "book"
+	SearchTypeAuthor = "author"
+)
+
+// SearchFilter là tham số của GET /search. Kết quả luôn xếp theo độ liên quan nên Sort không được dùng.
+type SearchFilter struct {
+	pagination.Params
+	Query string
+	// Type giới hạn loại kết quả: "book", "author" hoặc rỗng (cả hai)
+	Type string
+}
+
+// SearchQuery là truy vấn đã chuẩn hoá gửi xuống search backend.
+type SearchQuery struct {
+	// Terms là các từ khoá đã viết thường và bỏ dấu, chỉ gồm chữ và số
+	Terms  []string
+	Type   string
+	Limit  int
+	Offset int
+}
+
+// SearchHit là một kết quả tìm kiếm: một cuốn sách (theo title) hoặc một tác giả (theo tên).
+type SearchHit struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	// Highlight là Text đã HTML-escape, các từ khớp được bọc trong <mark></mark>
+	Highlight string  `json:"highlight"`
+	Score     float64 `json:"score"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Lý do của một dòng trong sổ cái tồn kho.
+const (
+	StockOrder        = "order"        // trừ khi tạo/sửa order
+	StockCancellation = "cancellation" // trả lại khi huỷ, hoàn tiền hoặc xoá order
+	StockRestock      = "restock"      // nhập thêm hàng
+	StockAdjustment   = "adjustment"   // chỉnh tay (vd: sửa stock qua PUT /books/{id})
+	StockStocktake    = "stocktake"    // điều chỉnh theo kết quả kiểm kê
+	StockReturn       = "return"       // khách trả sách, nhập lại kho khi nhận hàng trả
+)
+
+// StockMovement là một dòng trong sổ cái tồn kho: stock của sách đổi delta đơn vị vì reason.
+type StockMovement struct {
+	ID         int       `json:"id"`
+	BookID     int       `json:"book_id"`
+	Delta      int       `json:"delta"`
+	Reason     string    `json:"reason"`
+	OrderID    *int      `json:"order_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	StockAfter int       `json:"stock_after"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ManualStockReasons là các lý do client được phép ghi qua API; order và cancellation
+// chỉ do order tự ghi.
+var ManualStockReasons = []string{StockRestock, StockAdjustment, StockStocktake}
+
+// StockDrift là một sách có stock lệch với tổng delta trong sổ cái.
+type StockDrift struct {
+	BookID      int `json:"book_id"`
+	Stock       int `json:"stock"`
+	LedgerStock int `json:"ledger_stock"`
+	Drift       int `json:"drift"`
+}
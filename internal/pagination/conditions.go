@@ -0,0 +1,32 @@
+package pagination
+
+import "strings"
+
+// Conditions gom các điều kiện WHERE (nối bằng AND) cùng tham số của chúng.
+type Conditions struct {
+	clauses []string
+	args    []any
+}
+
+func (c *Conditions) Add(clause string, args ...any) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+// SQL trả về " WHERE ..." hoặc chuỗi rỗng nếu không có điều kiện nào.
+func (c *Conditions) SQL() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+func (c *Conditions) Args() []any {
+	return c.args
+}
+
+// LikePrefix escape ký tự đặc biệt của LIKE để prefix do client gửi lên được so khớp nguyên văn.
+func LikePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
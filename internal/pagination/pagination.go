@@ -0,0 +1,212 @@
+// Package pagination cung cấp keyset (cursor) pagination dùng chung cho các endpoint danh sách.
+//
+// Cursor là vị trí của bản ghi cuối cùng trong trang trước (giá trị cột sort + id),
+// nên trang sau được lấy bằng WHERE thay vì OFFSET và không bị lệch khi có bản ghi mới chen vào.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Params là tham số phân trang client gửi lên (?limit=&cursor=&sort=).
+// Sort là tên field, thêm tiền tố "-" để sắp xếp giảm dần, vd: "-created_at".
+type Params struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Page là envelope trả về cho client.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+type Kind int
+
+const (
+	Int Kind = iota
+	String
+	Time
+)
+
+// Field là một field được phép sort, ánh xạ sang cột trong SQL.
+type Field struct {
+	Column string
+	Kind   Kind
+}
+
+// Fields là whitelist các field được phép sort của một resource.
+type Fields map[string]Field
+
+type cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    int             `json:"id"`
+}
+
+// Keyset là kết quả đã validate của Params, dùng để dựng câu SQL.
+type Keyset struct {
+	Limit    int
+	sort     string
+	field    Field
+	desc     bool
+	after    any
+	afterID  int
+	hasAfter bool
+}
+
+// Resolve kiểm tra limit, sort (theo whitelist) và cursor. Lỗi trả về là lỗi validation.
+func Resolve(p Params, fields Fields, defaultSort string) (Keyset, error) {
+	k := Keyset{Limit: p.Limit}
+	switch {
+	case k.Limit == 0:
+		k.Limit = DefaultLimit
+	case k.Limit < 0 || k.Limit > MaxLimit:
+		return Keyset{}, apperror.NewValidation("limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+	}
+
+	k.sort = p.Sort
+	if k.sort == "" {
+		k.sort = defaultSort
+	}
+	name := strings.TrimPrefix(k.sort, "-")
+	field, ok := fields[name]
+	if !ok {
+		return Keyset{}, apperror.NewValidation("sort", fmt.Sprintf("sort must be one of: %s", fields.names()))
+	}
+	k.field, k.desc = field, strings.HasPrefix(k.sort, "-")
+
+	if p.Cursor != "" {
+		if err := k.decode(p.Cursor); err != nil {
+			return Keyset{}, apperror.NewValidation("cursor", "invalid cursor")
+		}
+	}
+	return k, nil
+}
+
+// Where trả về điều kiện keyset "(col, id) sau cursor" và tham số tương ứng.
+// Trả về chuỗi rỗng nếu là trang đầu.
+func (k Keyset) Where() (string, []any) {
+	if !k.hasAfter {
+		return "", nil
+	}
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	col := k.field.Column
+	// Không dùng row constructor (col, id) > (?, ?) vì MySQL 5.7 không dùng được index cho nó
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", col, op, col, op), []any{k.after, k.after, k.afterID}
+}
+
+// OrderBy trả về mệnh đề ORDER BY, luôn kèm id để thứ tự ổn định khi giá trị trùng nhau.
+func (k Keyset) OrderBy() string {
+	dir := "ASC"
+	if k.desc {
+		dir = "DESC"
+	}
+	if k.field.Column == "id" {
+		return "id " + dir
+	}
+	return fmt.Sprintf("%s %s, id %s", k.field.Column, dir, dir)
+}
+
+// Fetch là số dòng cần SELECT: thêm 1 dòng để biết còn trang sau hay không.
+func (k Keyset) Fetch() int {
+	return k.Limit + 1
+}
+
+// Paginate cắt dòng thừa và dựng next_cursor từ bản ghi cuối của trang.
+// key trả về giá trị của cột sort (theo Kind) và id của bản ghi.
+func Paginate[T any](k Keyset, items []T, total int, key func(T, string) (any, int)) (*Page[T], error) {
+	page := &Page[T]{Data: items, Total: total}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(items) <= k.Limit {
+		return page, nil
+	}
+	page.Data = items[:k.Limit]
+	value, id := key(page.Data[k.Limit-1], strings.TrimPrefix(k.sort, "-"))
+	next, err := k.encode(value, id)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next
+	return page, nil
+}
+
+func (k Keyset) encode(value any, id int) (string, error) {
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Format(time.RFC3339Nano)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	b, err := json.Marshal(cursor{Sort: k.sort, Value: raw, ID: id})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (k *Keyset) decode(s string) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return err
+	}
+	// Cursor của sort khác không dùng được: vị trí không còn ý nghĩa
+	if c.Sort != k.sort {
+		return fmt.Errorf("cursor was issued for sort %q", c.Sort)
+	}
+	switch k.field.Kind {
+	case Int:
+		var v int
+		err = json.Unmarshal(c.Value, &v)
+		k.after = v
+	case String:
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		k.after = v
+	case Time:
+		var v string
+		if err = json.Unmarshal(c.Value, &v); err == nil {
+			var t time.Time
+			t, err = time.Parse(time.RFC3339Nano, v)
+			k.after = t
+		}
+	}
+	if err != nil {
+		return err
+	}
+	k.afterID, k.hasAfter = c.ID, true
+	return nil
+}
+
+func (f Fields) names() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
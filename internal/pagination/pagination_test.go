@@ -0,0 +1,120 @@
+package pagination_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID        int
+	Title     string
+	CreatedAt time.Time
+}
+
+var fields = pagination.Fields{
+	"id":         {Column: "id", Kind: pagination.Int},
+	"title":      {Column: "title", Kind: pagination.String},
+	"created_at": {Column: "created_at", Kind: pagination.Time},
+}
+
+func key(it item, field string) (any, int) {
+	switch field {
+	case "title":
+		return it.Title, it.ID
+	case "created_at":
+		return it.CreatedAt, it.ID
+	}
+	return it.ID, it.ID
+}
+
+func TestResolve_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		params pagination.Params
+		field  string
+	}{
+		{"Limit too large", pagination.Params{Limit: pagination.MaxLimit + 1}, "limit"},
+		{"Negative limit", pagination.Params{Limit: -1}, "limit"},
+		{"Sort not in whitelist", pagination.Params{Sort: "password"}, "sort"},
+		{"Garbage cursor", pagination.Params{Cursor: "%%%"}, "cursor"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := pagination.Resolve(tc.params, fields, "id")
+			require.ErrorIs(t, err, apperror.ErrValidation)
+			assert.Equal(t, map[string]any{"field": tc.field}, err.(apperror.Detailer).Details())
+		})
+	}
+}
+
+func TestKeyset_FirstPage(t *testing.T) {
+	k, err := pagination.Resolve(pagination.Params{Sort: "-title"}, fields, "id")
+	require.NoError(t, err)
+
+	cond, args := k.Where()
+	assert.Empty(t, cond)
+	assert.Nil(t, args)
+	assert.Equal(t, "title DESC, id DESC", k.OrderBy())
+	assert.Equal(t, pagination.DefaultLimit+1, k.Fetch())
+}
+
+func TestPaginate_CursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, time.May, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		sort         string
+		expectedCond string
+		expectedArgs []any
+	}{
+		{"Int ascending", "id", "(id > ? OR (id = ? AND id > ?))", []any{2, 2, 2}},
+		{"String descending", "-title", "(title < ? OR (title = ? AND id < ?))", []any{"B", "B", 2}},
+		{"Time ascending", "created_at", "(created_at > ? OR (created_at = ? AND id > ?))", []any{created, created, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			k, err := pagination.Resolve(pagination.Params{Limit: 2, Sort: tc.sort}, fields, "id")
+			require.NoError(t, err)
+
+			items := []item{{1, "A", created}, {2, "B", created}, {3, "C", created}}
+			page, err := pagination.Paginate(k, items, 10, key)
+			require.NoError(t, err)
+			assert.Len(t, page.Data, 2)
+			assert.Equal(t, 10, page.Total)
+			require.NotEmpty(t, page.NextCursor)
+
+			next, err := pagination.Resolve(pagination.Params{Limit: 2, Sort: tc.sort, Cursor: page.NextCursor}, fields, "id")
+			require.NoError(t, err)
+			cond, args := next.Where()
+			assert.Equal(t, tc.expectedCond, cond)
+			assert.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestPaginate_LastPage(t *testing.T) {
+	k, err := pagination.Resolve(pagination.Params{Limit: 5}, fields, "id")
+	require.NoError(t, err)
+
+	page, err := pagination.Paginate[item](k, nil, 0, key)
+	require.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+	assert.NotNil(t, page.Data, "data is [] instead of null in JSON")
+}
+
+func TestResolve_CursorFromOtherSort(t *testing.T) {
+	k, _ := pagination.Resolve(pagination.Params{Limit: 1, Sort: "title"}, fields, "id")
+	page, err := pagination.Paginate(k, []item{{1, "A", time.Time{}}, {2, "B", time.Time{}}}, 2, key)
+	require.NoError(t, err)
+
+	_, err = pagination.Resolve(pagination.Params{Sort: "-title", Cursor: page.NextCursor}, fields, "id")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+}
+
+func TestLikePrefix(t *testing.T) {
+	assert.Equal(t, `50\%\_off%`, pagination.LikePrefix("50%_off"))
+}
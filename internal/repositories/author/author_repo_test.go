@@ -0,0 +1,71 @@
+package author_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/author"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) (author.AuthorRepositoriesInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return author.NewAuthorRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+func TestAuthorRepo_CreateAuthor_Duplicate(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		message string
+		errMsg  string
+	}{
+		{
+			name:    "Duplicate name",
+			message: "Duplicate entry 'Tô Hoài' for key 'uq_authors_name'",
+			errMsg:  `author with name "Tô Hoài" already exists`,
+		},
+		{
+			name:    "Duplicate id",
+			id:      3,
+			message: "Duplicate entry '3' for key 'PRIMARY'",
+			errMsg:  "author with ID 3 already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			m.ExpectExec("INSERT INTO `authors`").WithArgs(tt.id, "Tô Hoài", "to hoai", "VN", now).
+				WillReturnError(&mysql.MySQLError{Number: 1062, Message: tt.message})
+
+			err := repo.CreateAuthor(context.Background(), &models.Author{ID: tt.id, Name: "Tô Hoài", Nationality: "VN", CreatedAt: now})
+
+			assert.ErrorIs(t, err, apperror.ErrConflict)
+			assert.EqualError(t, err, tt.errMsg)
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthorRepo_UpdateById_DuplicateName(t *testing.T) {
+	repo, m := newRepo(t)
+	m.ExpectQuery("SELECT EXISTS").WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	m.ExpectExec("UPDATE authors").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Tô Hoài' for key 'uq_authors_name'"})
+
+	_, err := repo.UpdateById(context.Background(), &models.Author{ID: 4, Name: "Tô Hoài"})
+
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	assert.EqualError(t, err, `author with name "Tô Hoài" already exists`)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
@@ -1,23 +1,60 @@
 package author
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
 
 	"github.com/go-sql-driver/mysql"
 )
 
 type authorRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAuthorRepo(db *sql.DB, logger *slog.Logger) AuthorRepositoriesInterface {
+	return &authorRepo{db: db, logger: logger}
 }
 
-func NewAuthorRepo(db *sql.DB) AuthorRepositoriesInterface {
-	return &authorRepo{db: db}
+// authorSortFields là whitelist field được phép dùng trong ?sort=
+var authorSortFields = pagination.Fields{
+	"id":         {Column: "id", Kind: pagination.Int},
+	"name":       {Column: "name", Kind: pagination.String},
+	"created_at": {Column: "created_at", Kind: pagination.Time},
 }
 
-func (r *authorRepo) GetAllAuthors() ([]*models.Author, error) {
-	rows, err := r.db.Query("SELECT `id`, `name`, `nationality`, `created_at`, `updated_at` FROM `authors`")
+func (r *authorRepo) GetAllAuthors(ctx context.Context, filter models.AuthorFilter) (*pagination.Page[*models.Author], error) {
+	keyset, err := pagination.Resolve(filter.Params, authorSortFields, "id")
+	if err != nil {
+		return nil, err
+	}
+	var where pagination.Conditions
+	if filter.Nationality != "" {
+		where.Add("`nationality` = ?", filter.Nationality)
+	}
+	if filter.Name != "" {
+		where.Add("`name` = ?", filter.Name)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM `authors`"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count authors: %w", err)
+	}
+	if cond, args := keyset.Where(); cond != "" {
+		where.Add(cond, args...)
+	}
+	query := "SELECT `id`, `name`, `nationality`, `created_at`, `updated_at` FROM `authors`" + where.SQL() +
+		" ORDER BY " + keyset.OrderBy() + " LIMIT ?"
+	rows, err := r.db.QueryContext(ctx, query, append(where.Args(), keyset.Fetch())...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query author: %w", err)
 	}
@@ -32,17 +69,31 @@ func (r *authorRepo) GetAllAuthors() ([]*models.Author, error) {
 		}
 		authors = append(authors, author)
 	}
-	return authors, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pagination.Paginate(keyset, authors, total, authorSortKey)
+}
+
+// authorSortKey trả về giá trị của field sort để dựng next_cursor
+func authorSortKey(a *models.Author, field string) (any, int) {
+	switch field {
+	case "name":
+		return a.Name, a.ID
+	case "created_at":
+		return a.CreatedAt, a.ID
+	}
+	return a.ID, a.ID
 }
 
-func (r *authorRepo) GetByAuthorID(id int) (*models.Author, error) {
-	row := r.db.QueryRow("SELECT `id`, `name`, `nationality`, `created_at`, `updated_at` FROM `authors` WHERE id = ?", id)
+func (r *authorRepo) GetByAuthorID(ctx context.Context, id int) (*models.Author, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT `id`, `name`, `nationality`, `created_at`, `updated_at` FROM `authors` WHERE id = ?", id)
 	author := &models.Author{}
 	err := row.Scan(&author.ID, &author.Name, &author.Nationality, &author.CreatedAt, &author.UpdatedAt)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("author with ID %d not found", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("author with ID %d not found", id)
 		}
 		return nil, fmt.Errorf("failed to fetch author: %w", err)
 	}
@@ -50,10 +101,14 @@ func (r *authorRepo) GetByAuthorID(id int) (*models.Author, error) {
 }
 
 // Implement the BookReader interface
-func (r *authorRepo) CreateAuthor(author *models.Author) error {
-	query := "INSERT INTO `authors`(`id`, `name`, `nationality`, `created_at`) VALUES (?,?,?,?)"
-	result, err := r.db.Exec(query, author.ID, author.Name, author.Nationality, author.CreatedAt)
+func (r *authorRepo) CreateAuthor(ctx context.Context, author *models.Author) error {
+	// name_folded là tên không dấu, dùng cho FULLTEXT search
+	query := "INSERT INTO `authors`(`id`, `name`, `name_folded`, `nationality`, `created_at`) VALUES (?,?,?,?,?)"
+	result, err := r.db.ExecContext(ctx, query, author.ID, author.Name, textnorm.Fold(author.Name), author.Nationality, author.CreatedAt)
 	if err != nil {
+		if conflict := r.duplicateAuthor(ctx, err, author); conflict != nil {
+			return conflict
+		}
 		return err
 	}
 	id, err := result.LastInsertId()
@@ -64,16 +119,17 @@ func (r *authorRepo) CreateAuthor(author *models.Author) error {
 	return nil
 }
 
-func (r *authorRepo) DeleteById(id int) (*models.Author, error) {
-	author, err := r.GetByAuthorID(id)
+func (r *authorRepo) DeleteById(ctx context.Context, id int) (*models.Author, error) {
+	author, err := r.GetByAuthorID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	result, err := r.db.Exec("DELETE FROM `authors` WHERE id = ?", id)
+	result, err := r.db.ExecContext(ctx, "DELETE FROM `authors` WHERE id = ?", id)
 	if err != nil {
 		// Kiểm tra nếu lỗi là lỗi khóa ngoại (foreign key)
 		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
-			return nil, fmt.Errorf("cannot delete author: existing orders depend on it")
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return nil, apperror.ForeignKey(err, "cannot delete author: existing books depend on it")
 		}
 		return nil, err
 	}
@@ -82,27 +138,30 @@ func (r *authorRepo) DeleteById(id int) (*models.Author, error) {
 		return nil, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no author found with id %d", id)
+		return nil, apperror.NotFound("no author found with id %d", id)
 	}
 	return author, nil
 }
 
-func (r *authorRepo) UpdateById(author *models.Author) (*models.Author, error) {
+func (r *authorRepo) UpdateById(ctx context.Context, author *models.Author) (*models.Author, error) {
 	// Kiểm tra author_id có tồn tại không
 	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM authors WHERE id = ?)", author.ID).Scan(&exists)
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM authors WHERE id = ?)", author.ID).Scan(&exists)
 	if err != nil {
 		return nil, err
 	}
 	if !exists {
-		return nil, fmt.Errorf("author_id %d does not exist", author.ID)
+		return nil, apperror.NotFound("author_id %d does not exist", author.ID)
 	}
-	result, err := r.db.Exec(`
+	result, err := r.db.ExecContext(ctx, `
 			UPDATE authors
-			SET name = ?, nationality = ? , updated_at = ?
+			SET name = ?, name_folded = ?, nationality = ? , updated_at = ?
 			WHERE id = ?`,
-		author.Name, author.Nationality, author.UpdatedAt, author.ID)
+		author.Name, textnorm.Fold(author.Name), author.Nationality, author.UpdatedAt, author.ID)
 	if err != nil {
+		if conflict := r.duplicateAuthor(ctx, err, author); conflict != nil {
+			return nil, conflict
+		}
 		return nil, fmt.Errorf("failed to update author: %w", err)
 	}
 	// Kiểm tra có hàng nào bị ảnh hưởng không
@@ -111,7 +170,21 @@ func (r *authorRepo) UpdateById(author *models.Author) (*models.Author, error) {
 		return nil, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no book updated with id %d", author.ID)
+		return nil, apperror.NotFound("no author updated with id %d", author.ID)
 	}
 	return author, nil
 }
+
+// duplicateAuthor đổi lỗi trùng unique key thành Conflict theo key bị vi phạm
+// (uq_authors_name hoặc primary key), lỗi khác trả về nil.
+func (r *authorRepo) duplicateAuthor(ctx context.Context, err error, author *models.Author) error {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok || mysqlErr.Number != 1062 {
+		return nil
+	}
+	r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+	if strings.Contains(mysqlErr.Message, "uq_authors_name") {
+		return apperror.Conflict("author with name %q already exists", author.Name)
+	}
+	return apperror.Conflict("author with ID %d already exists", author.ID)
+}
@@ -0,0 +1,124 @@
+package book
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// querier là phần chung của *sql.DB và *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// checkAuthors kiểm tra mọi author tồn tại và điền Name để trả về cùng sách vừa ghi.
+func checkAuthors(ctx context.Context, q querier, authors []models.BookAuthor) error {
+	if len(authors) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(authors))
+	for _, a := range authors {
+		args = append(args, a.AuthorID)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT `id`, `name` FROM `authors` WHERE `id` IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return fmt.Errorf("failed to check authors: %w", err)
+	}
+	defer rows.Close()
+	names := make(map[int]string, len(authors))
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i, a := range authors {
+		name, ok := names[a.AuthorID]
+		if !ok {
+			return apperror.ForeignKey(nil, "author_id %d does not exist", a.AuthorID)
+		}
+		authors[i].Name = name
+	}
+	return nil
+}
+
+// insertAuthors ghi danh sách tác giả của sách, position theo thứ tự trong book.Authors.
+func (r *bookRepo) insertAuthors(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if len(book.Authors) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(book.Authors)*4)
+	for i, a := range book.Authors {
+		args = append(args, book.ID, a.AuthorID, a.Role, i)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?), ", len(book.Authors)), ", ")
+	_, err := tx.ExecContext(ctx, "INSERT INTO `book_authors` (`book_id`, `author_id`, `role`, `position`) VALUES "+values, args...)
+	if err != nil {
+		// Author bị xoá giữa lúc kiểm tra và lúc ghi
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return apperror.ForeignKey(err, "author does not exist")
+		}
+		return fmt.Errorf("failed to insert book authors: %w", err)
+	}
+	return nil
+}
+
+// loadAuthors đọc danh sách tác giả (kèm tên) của nhiều sách và đặt lại AuthorID là tác giả chính.
+func loadAuthors(ctx context.Context, q querier, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Book, len(books))
+	args := make([]any, 0, len(books))
+	for _, b := range books {
+		b.Authors = []models.BookAuthor{}
+		byID[b.ID] = b
+		args = append(args, b.ID)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT ba.`book_id`, ba.`author_id`, ba.`role`, a.`name` FROM `book_authors` ba "+
+			"JOIN `authors` a ON a.`id` = ba.`author_id` "+
+			"WHERE ba.`book_id` IN ("+placeholders(len(args))+") ORDER BY ba.`book_id`, ba.`position`", args...)
+	if err != nil {
+		return fmt.Errorf("failed to load book authors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			bookID int
+			a      models.BookAuthor
+		)
+		if err := rows.Scan(&bookID, &a.AuthorID, &a.Role, &a.Name); err != nil {
+			return err
+		}
+		if b := byID[bookID]; b != nil {
+			b.Authors = append(b.Authors, a)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, b := range books {
+		b.NormalizeAuthors()
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,429 @@
+package book_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/book"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bookColumns = []string{"id", "work_id", "title", "isbn", "stock", "price", "currency", "backorder_policy", "expected_at",
+	"format", "language", "published_at", "publisher_id", "created_at", "updated_at", "reserved"}
+
+func priceOf(v int64) *int64 { return &v }
+
+func newRepo(t *testing.T) (book.BookRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return book.NewBookRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+// expectAuthorNames giả lập bảng authors chỉ có các id trong names
+func expectAuthorNames(m sqlmock.Sqlmock, names map[int]string, ids ...driver.Value) {
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	for id, name := range names {
+		rows.AddRow(id, name)
+	}
+	m.ExpectQuery("SELECT `id`, `name` FROM `authors` WHERE `id` IN").WithArgs(ids...).WillReturnRows(rows)
+}
+
+// expectCategories giả lập book_categories, mỗi cặp là (book_id, category_id)
+func expectCategories(m sqlmock.Sqlmock, pairs [][2]int, bookIDs ...driver.Value) {
+	rows := sqlmock.NewRows([]string{"book_id", "category_id"})
+	for _, p := range pairs {
+		rows.AddRow(p[0], p[1])
+	}
+	m.ExpectQuery("SELECT `book_id`, `category_id` FROM `book_categories`").WithArgs(bookIDs...).WillReturnRows(rows)
+}
+
+func TestBookRepo_Create(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		authors    []models.BookAuthor
+		categories []int
+		// names là các author tồn tại, categoriesFound là các category tồn tại
+		names           map[int]string
+		categoriesFound []int
+		inserted        bool
+		expected        []models.BookAuthor
+		errMsg          string
+	}{
+		{
+			name:            "Authors are stored in order with their roles, categories are assigned",
+			authors:         []models.BookAuthor{{AuthorID: 2, Role: "translator"}, {AuthorID: 1, Role: "author"}},
+			names:           map[int]string{1: "Nguyễn Nhật Ánh", 2: "Trần A"},
+			categories:      []int{3, 8},
+			categoriesFound: []int{3, 8},
+			inserted:        true,
+			expected:        []models.BookAuthor{{AuthorID: 2, Role: "translator", Name: "Trần A"}, {AuthorID: 1, Role: "author", Name: "Nguyễn Nhật Ánh"}},
+		},
+		{
+			name:    "Missing author",
+			authors: []models.BookAuthor{{AuthorID: 1, Role: "author"}, {AuthorID: 9, Role: "editor"}},
+			names:   map[int]string{1: "Nguyễn Nhật Ánh"},
+			errMsg:  "author_id 9 does not exist",
+		},
+		{
+			name:            "Missing category",
+			authors:         []models.BookAuthor{{AuthorID: 1, Role: "author"}},
+			names:           map[int]string{1: "Nguyễn Nhật Ánh"},
+			categories:      []int{3, 4},
+			categoriesFound: []int{3},
+			errMsg:          "category_id 4 does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectBegin()
+			ids := make([]driver.Value, 0, len(tt.authors))
+			for _, a := range tt.authors {
+				ids = append(ids, a.AuthorID)
+			}
+			expectAuthorNames(m, tt.names, ids...)
+			if tt.categories != nil {
+				rows := sqlmock.NewRows([]string{"id"})
+				for _, id := range tt.categoriesFound {
+					rows.AddRow(id)
+				}
+				args := make([]driver.Value, 0, len(tt.categories))
+				for _, id := range tt.categories {
+					args = append(args, id)
+				}
+				m.ExpectQuery("SELECT `id` FROM `categories` WHERE `id` IN").WithArgs(args...).WillReturnRows(rows)
+			}
+			if tt.inserted {
+				m.ExpectExec("INSERT INTO `works`").WithArgs("Mắt biếc", now).WillReturnResult(sqlmock.NewResult(5, 1))
+				m.ExpectExec("INSERT INTO `books`").WillReturnResult(sqlmock.NewResult(5, 1))
+				m.ExpectExec("INSERT INTO `book_authors` \\(`book_id`, `author_id`, `role`, `position`\\) VALUES \\(\\?, \\?, \\?, \\?\\), \\(\\?, \\?, \\?, \\?\\)").
+					WithArgs(5, 2, "translator", 0, 5, 1, "author", 1).WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectExec("INSERT INTO `book_categories` \\(`book_id`, `category_id`\\) VALUES \\(\\?, \\?\\), \\(\\?, \\?\\)").
+					WithArgs(5, 3, 5, 8).WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit()
+			} else {
+				m.ExpectRollback()
+			}
+
+			b := &models.Book{Title: "Mắt biếc", Authors: tt.authors, CategoryIDs: tt.categories, Price: priceOf(1000), Currency: "VND", BackorderPolicy: "none", CreatedAt: now}
+			err := repo.Create(context.Background(), b)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrForeignKey)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 5, b.ID)
+				assert.Equal(t, 5, b.WorkID)
+				assert.Equal(t, tt.expected, b.Authors)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookRepo_GetByAuthorID(t *testing.T) {
+	repo, m := newRepo(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM authors WHERE id = \\?\\)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	m.ExpectQuery("FROM books WHERE id IN \\(SELECT book_id FROM book_authors WHERE author_id = \\?\\)").
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows(bookColumns).
+			AddRow(1, 1, "Mắt biếc", nil, 3, 1000, "VND", "none", nil, "", "", nil, nil, now, now, 0).
+			AddRow(4, 4, "Tuyển tập", nil, 2, 2000, "VND", "none", nil, "", "", nil, nil, now, now, 0))
+	// Tác giả 2 là dịch giả của sách 1 và đồng tác giả thứ hai của sách 4
+	m.ExpectQuery("FROM `book_authors` ba JOIN `authors` a").WithArgs(1, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "author_id", "role", "name"}).
+			AddRow(1, 1, "author", "A").
+			AddRow(1, 2, "translator", "B").
+			AddRow(4, 3, "author", "C").
+			AddRow(4, 2, "author", "B"))
+	expectCategories(m, [][2]int{{4, 7}}, 1, 4)
+
+	books, err := repo.GetByAuthorID(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	assert.Equal(t, 1, books[0].AuthorID)
+	assert.Equal(t, []models.BookAuthor{{AuthorID: 1, Role: "author", Name: "A"}, {AuthorID: 2, Role: "translator", Name: "B"}}, books[0].Authors)
+	assert.Equal(t, 3, books[1].AuthorID)
+	assert.Len(t, books[1].Authors, 2)
+	assert.Equal(t, []int{}, books[0].CategoryIDs)
+	assert.Equal(t, []int{7}, books[1].CategoryIDs)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestBookRepo_GetByISBN(t *testing.T) {
+	repo, m := newRepo(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExpectQuery("FROM books WHERE isbn = \\?").WithArgs(sqlmock.AnyArg(), "9780306406157").
+		WillReturnRows(sqlmock.NewRows(bookColumns).AddRow(1, 1, "Mắt biếc", "9780306406157", 3, 1000, "VND", "none", nil, "", "", nil, nil, now, now, 0))
+	m.ExpectQuery("FROM `book_authors` ba JOIN `authors` a").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "author_id", "role", "name"}).AddRow(1, 1, "author", "A"))
+	expectCategories(m, [][2]int{{1, 3}, {1, 8}}, 1)
+
+	b, err := repo.GetByISBN(context.Background(), "9780306406157")
+	require.NoError(t, err)
+	assert.Equal(t, "9780306406157", b.ISBN)
+	assert.Equal(t, "0306406152", b.ISBN10)
+	assert.Equal(t, []int{3, 8}, b.CategoryIDs)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestBookRepo_Create_DuplicateISBN(t *testing.T) {
+	repo, m := newRepo(t)
+	m.ExpectBegin()
+	expectAuthorNames(m, map[int]string{1: "A"}, 1)
+	m.ExpectExec("INSERT INTO `works`").WillReturnResult(sqlmock.NewResult(2, 1))
+	m.ExpectExec("INSERT INTO `books`").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '9780306406157' for key 'uq_books_isbn'"})
+	m.ExpectRollback()
+
+	b := &models.Book{Title: "Mắt biếc", ISBN: "9780306406157", Authors: []models.BookAuthor{{AuthorID: 1, Role: "author"}}, Price: priceOf(1000)}
+	err := repo.Create(context.Background(), b)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	assert.EqualError(t, err, "book with ISBN 9780306406157 already exists")
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestBookRepo_GetAllBooks_CategoryIncludesDescendants(t *testing.T) {
+	repo, m := newRepo(t)
+	// Sách của category 3 và mọi category có path bắt đầu bằng path của 3
+	filterSQL := "EXISTS \\(SELECT 1 FROM book_categories bc JOIN categories c ON c.id = bc.category_id " +
+		"JOIN categories root ON root.id = \\? WHERE bc.book_id = books.id AND c.path LIKE CONCAT\\(root.path, '%'\\)\\)"
+	m.ExpectQuery("SELECT COUNT\\(\\*\\) FROM books WHERE " + filterSQL).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	m.ExpectQuery("FROM books WHERE "+filterSQL).WithArgs(sqlmock.AnyArg(), 3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(bookColumns))
+
+	page, err := repo.GetAllBooks(context.Background(), models.BookFilter{CategoryID: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestBookRepo_Create_Edition(t *testing.T) {
+	publisher := 4
+	tests := []struct {
+		name string
+		// publisherFound, workFound là publisher 4 / work 2 có tồn tại không
+		publisherFound bool
+		workFound      bool
+		errMsg         string
+	}{
+		{name: "New edition of an existing work", publisherFound: true, workFound: true},
+		{name: "Missing publisher", errMsg: "publisher_id 4 does not exist"},
+		{name: "Missing work", publisherFound: true, errMsg: "work_id 2 does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectBegin()
+			expectAuthorNames(m, map[int]string{1: "A"}, 1)
+			m.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM `publishers` WHERE `id` = \\?\\)").WithArgs(4).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.publisherFound))
+			if tt.publisherFound {
+				rows := sqlmock.NewRows([]string{"id"})
+				if tt.workFound {
+					rows.AddRow(2)
+				}
+				m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? LOCK IN SHARE MODE").WithArgs(2).WillReturnRows(rows)
+			}
+			if tt.errMsg == "" {
+				m.ExpectExec("INSERT INTO `books`").
+					WithArgs(0, 2, "Mắt biếc", "mat biec", nil, 0, 1000, "VND", "", nil, "hardcover", "vi", nil, &publisher, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(6, 1))
+				m.ExpectExec("INSERT INTO `book_authors`").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			} else {
+				m.ExpectRollback()
+			}
+
+			b := &models.Book{Title: "Mắt biếc", WorkID: 2, Format: "hardcover", Language: "vi", PublisherID: &publisher,
+				Authors: []models.BookAuthor{{AuthorID: 1, Role: "author"}}, Price: priceOf(1000), Currency: "VND"}
+			err := repo.Create(context.Background(), b)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrForeignKey)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 6, b.ID)
+				assert.Equal(t, 2, b.WorkID)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookRepo_DeleteById(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deleteWork := "DELETE FROM `works` WHERE `id` = \\? AND NOT EXISTS \\(SELECT 1 FROM `books` WHERE `work_id` = \\?\\)"
+	tests := []struct {
+		name        string
+		prepareMock func(m sqlmock.Sqlmock)
+		errIs       error
+		errMsg      string
+	}{
+		{
+			name: "Last edition removes its work",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `books` WHERE id = \\?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(deleteWork).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "Work with other editions is kept",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `books` WHERE id = \\?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(deleteWork).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "Orders depend on the book",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `books` WHERE id = \\?").WithArgs(7).
+					WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+				m.ExpectRollback()
+			},
+			errIs:  apperror.ErrForeignKey,
+			errMsg: "cannot delete book: existing orders depend on it",
+		},
+		{
+			name: "Book deleted concurrently",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `books` WHERE id = \\?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectRollback()
+			},
+			errIs:  apperror.ErrNotFound,
+			errMsg: "no book found with id 7",
+		},
+		{
+			name: "Work cleanup fails",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `books` WHERE id = \\?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(deleteWork).WithArgs(2, 2).WillReturnError(errors.New("lock wait timeout"))
+				m.ExpectRollback()
+			},
+			errMsg: "failed to delete work: lock wait timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectQuery("FROM books WHERE id = \\?").WithArgs(sqlmock.AnyArg(), 7).
+				WillReturnRows(sqlmock.NewRows(bookColumns).AddRow(7, 2, "Mắt biếc", nil, 3, 1000, "VND", "none", nil, "", "", nil, nil, now, now, 0))
+			m.ExpectQuery("FROM `book_authors` ba JOIN `authors` a").WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"book_id", "author_id", "role", "name"}).AddRow(7, 1, "author", "A"))
+			expectCategories(m, nil, 7)
+			m.ExpectBegin()
+			m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? FOR UPDATE").WithArgs(2).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			tt.prepareMock(m)
+
+			b, err := repo.DeleteById(context.Background(), 7)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 2, b.WorkID)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookRepo_UpdateById_Work(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deleteWork := "DELETE FROM `works` WHERE `id` = \\? AND NOT EXISTS \\(SELECT 1 FROM `books` WHERE `work_id` = \\?\\)"
+	tests := []struct {
+		name        string
+		workID      int
+		prepareMock func(m sqlmock.Sqlmock)
+		wantWork    int
+		errMsg      string
+	}{
+		{
+			name:   "Moving the last edition removes the old work",
+			workID: 3,
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? LOCK IN SHARE MODE").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				m.ExpectExec("UPDATE books").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_authors").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `book_authors`").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_categories").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? FOR UPDATE").WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				m.ExpectExec(deleteWork).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			wantWork: 3,
+		},
+		{
+			name:   "Same work is kept",
+			workID: 0,
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("UPDATE books").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_authors").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `book_authors`").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_categories").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectCommit()
+			},
+			wantWork: 2,
+		},
+		{
+			name:   "Old work cleanup fails",
+			workID: 3,
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? LOCK IN SHARE MODE").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				m.ExpectExec("UPDATE books").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_authors").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `book_authors`").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM book_categories").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery("SELECT `id` FROM `works` WHERE `id` = \\? FOR UPDATE").WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				m.ExpectExec(deleteWork).WithArgs(2, 2).WillReturnError(errors.New("lock wait timeout"))
+				m.ExpectRollback()
+			},
+			errMsg: "failed to delete work: lock wait timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectBegin()
+			expectAuthorNames(m, map[int]string{1: "A"}, 1)
+			m.ExpectQuery("SELECT stock, work_id FROM books WHERE id = \\? FOR UPDATE").WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"stock", "work_id"}).AddRow(3, 2))
+			tt.prepareMock(m)
+
+			b, _, err := repo.UpdateById(context.Background(), &models.Book{
+				ID: 7, WorkID: tt.workID, Title: "Mắt biếc", Stock: 3, Price: priceOf(1000), Currency: "VND",
+				BackorderPolicy: models.BackorderNone, Authors: []models.BookAuthor{{AuthorID: 1, Role: models.RoleAuthor}},
+				UpdatedAt: now,
+			})
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantWork, b.WorkID)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
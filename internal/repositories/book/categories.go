@@ -0,0 +1,98 @@
+package book
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// checkCategories kiểm tra mọi category được gán đều tồn tại.
+func checkCategories(ctx context.Context, q querier, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := q.QueryContext(ctx, "SELECT `id` FROM `categories` WHERE `id` IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return fmt.Errorf("failed to check categories: %w", err)
+	}
+	defer rows.Close()
+	found := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return apperror.ForeignKey(nil, "category_id %d does not exist", id)
+		}
+	}
+	return nil
+}
+
+// insertCategories gán sách vào các category trong book.CategoryIDs.
+func insertCategories(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if len(book.CategoryIDs) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(book.CategoryIDs)*2)
+	for _, id := range book.CategoryIDs {
+		args = append(args, book.ID, id)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(book.CategoryIDs)), ", ")
+	if _, err := tx.ExecContext(ctx, "INSERT INTO `book_categories` (`book_id`, `category_id`) VALUES "+values, args...); err != nil {
+		return fmt.Errorf("failed to insert book categories: %w", err)
+	}
+	return nil
+}
+
+// loadCategories đọc category_ids của nhiều sách.
+func loadCategories(ctx context.Context, q querier, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Book, len(books))
+	args := make([]any, 0, len(books))
+	for _, b := range books {
+		b.CategoryIDs = []int{}
+		byID[b.ID] = b
+		args = append(args, b.ID)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT `book_id`, `category_id` FROM `book_categories` WHERE `book_id` IN ("+placeholders(len(args))+") ORDER BY `book_id`, `category_id`", args...)
+	if err != nil {
+		return fmt.Errorf("failed to load book categories: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID, categoryID int
+		if err := rows.Scan(&bookID, &categoryID); err != nil {
+			return err
+		}
+		if b := byID[bookID]; b != nil {
+			b.CategoryIDs = append(b.CategoryIDs, categoryID)
+		}
+	}
+	return rows.Err()
+}
+
+// loadDetails đọc tác giả và category của các sách vừa scan.
+func loadDetails(ctx context.Context, q querier, books []*models.Book) error {
+	if err := loadAuthors(ctx, q, books); err != nil {
+		return err
+	}
+	return loadCategories(ctx, q, books)
+}
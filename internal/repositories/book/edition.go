@@ -0,0 +1,74 @@
+package book
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// resolveWork tạo work mới lấy title của sách khi WorkID = 0, ngược lại kiểm tra work tồn tại.
+func resolveWork(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if book.WorkID == 0 {
+		result, err := tx.ExecContext(ctx, "INSERT INTO `works` (`title`, `created_at`) VALUES (?, ?)", book.Title, book.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create work: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		book.WorkID = int(id)
+		return nil
+	}
+	// Lock share để request đang xoá work (lockWork) chạy xong trước; work bị xoá thì trả lỗi
+	// khoá ngoại ở đây thay vì để INSERT/UPDATE books lỗi 1452
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT `id` FROM `works` WHERE `id` = ? LOCK IN SHARE MODE", book.WorkID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.ForeignKey(nil, "work_id %d does not exist", book.WorkID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check work: %w", err)
+	}
+	return nil
+}
+
+// lockWork lock row works trước khi xoá ấn bản. Hai request xoá hai ấn bản cuối của cùng work
+// chạy tuần tự, nên request sau thấy work đã trống và xoá được work.
+func lockWork(ctx context.Context, tx *sql.Tx, workID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT `id` FROM `works` WHERE `id` = ? FOR UPDATE", workID).Scan(&id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to lock work: %w", err)
+	}
+	return nil
+}
+
+// deleteOrphanWork xoá work không còn ấn bản nào; work còn ấn bản khác thì giữ nguyên.
+func deleteOrphanWork(ctx context.Context, tx *sql.Tx, workID int) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM `works` WHERE `id` = ? AND NOT EXISTS (SELECT 1 FROM `books` WHERE `work_id` = ?)", workID, workID)
+	if err != nil {
+		return fmt.Errorf("failed to delete work: %w", err)
+	}
+	return nil
+}
+
+// checkPublisher kiểm tra publisher (nếu có) tồn tại.
+func checkPublisher(ctx context.Context, tx *sql.Tx, publisherID *int) error {
+	if publisherID == nil {
+		return nil
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM `publishers` WHERE `id` = ?)", *publisherID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check publisher: %w", err)
+	}
+	if !exists {
+		return apperror.ForeignKey(nil, "publisher_id %d does not exist", *publisherID)
+	}
+	return nil
+}
@@ -1,12 +1,20 @@
 package book
 
-import "github.com/maithuc2003/re-book-api/internal/models"
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
 
 // internal/repositories/book/interface.go
 type BookRepoInterface interface {
-	Create(book *models.Book) error
-	GetAllBooks() ([]*models.Book, error)
-	GetByBookID(id int) (*models.Book, error)
-	DeleteById(id int) (*models.Book, error)
-	UpdateById(book *models.Book) (*models.Book, error)
+	Create(ctx context.Context, book *models.Book) error
+	GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error)
+	GetByBookID(ctx context.Context, id int) (*models.Book, error)
+	GetByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	GetByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error)
+	DeleteById(ctx context.Context, id int) (*models.Book, error)
+	// UpdateById trả thêm chênh lệch stock so với trước khi sửa, để service biết có nhập thêm hàng không
+	UpdateById(ctx context.Context, book *models.Book) (*models.Book, int, error)
 }
@@ -1,40 +1,148 @@
 package book
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
 
 	"github.com/go-sql-driver/mysql"
 )
 
 type bookRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func NewBookRepo(db *sql.DB) BookRepoInterface {
-	return &bookRepo{db: db}
+func NewBookRepo(db *sql.DB, logger *slog.Logger) BookRepoInterface {
+	return &bookRepo{db: db, logger: logger}
 }
 
 // Implement the BookReader interface
-func (r *bookRepo) Create(book *models.Book) error {
-	query := "INSERT INTO `books`(`id`, `title`, `author_id`, `stock`, `created_at`) VALUES (?,?,?,?,?)"
-	result, err := r.db.Exec(query, book.ID, book.Title, book.AuthorID, book.Stock, book.CreatedAt)
+// Create ghi stock ban đầu vào sổ cái như một lần nhập hàng, trong cùng transaction.
+func (r *bookRepo) Create(ctx context.Context, book *models.Book) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	if err := checkAuthors(ctx, tx, book.Authors); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := checkCategories(ctx, tx, book.CategoryIDs); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := checkPublisher(ctx, tx, book.PublisherID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := resolveWork(ctx, tx, book); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	// title_folded là title không dấu, dùng cho FULLTEXT search (xem internal/repositories/search)
+	query := "INSERT INTO `books`(`id`, `work_id`, `title`, `title_folded`, `isbn`, `stock`, `price`, `currency`, `backorder_policy`, `expected_at`, " +
+		"`format`, `language`, `published_at`, `publisher_id`, `created_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+	result, err := tx.ExecContext(ctx, query, book.ID, book.WorkID, book.Title, textnorm.Fold(book.Title), nullString(book.ISBN), book.Stock, book.Price, book.Currency, book.BackorderPolicy, book.ExpectedAt,
+		book.Format, book.Language, book.PublishedAt, book.PublisherID, book.CreatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		if conflict := r.isbnConflict(ctx, err, book.ISBN); conflict != nil {
+			return conflict
+		}
+		return err
+	}
 	id, err := result.LastInsertId()
 	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		return err
 	}
 	book.ID = int(id)
+	if err := r.insertAuthors(ctx, tx, book); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := insertCategories(ctx, tx, book); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if book.Stock != 0 {
+		err := stock.Record(ctx, tx, &models.StockMovement{
+			BookID: book.ID, Delta: book.Stock, Reason: models.StockRestock, StockAfter: book.Stock, CreatedAt: book.CreatedAt,
+		})
+		if err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+// bookSortFields là whitelist field được phép dùng trong ?sort=
+var bookSortFields = pagination.Fields{
+	"id":         {Column: "id", Kind: pagination.Int},
+	"title":      {Column: "title", Kind: pagination.String},
+	"stock":      {Column: "stock", Kind: pagination.Int},
+	"created_at": {Column: "created_at", Kind: pagination.Time},
+}
+
 // Implement interface method
-func (r *bookRepo) GetAllBooks() ([]*models.Book, error) {
-	rows, err := r.db.Query("SELECT id, title, author_id, stock, created_at, updated_at FROM books")
+func (r *bookRepo) GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error) {
+	keyset, err := pagination.Resolve(filter.Params, bookSortFields, "id")
+	if err != nil {
+		return nil, err
+	}
+	var where pagination.Conditions
+	if filter.AuthorID > 0 {
+		// Sách có author này ở bất kỳ vai trò nào
+		where.Add("EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = books.id AND ba.author_id = ?)", filter.AuthorID)
+	}
+	if filter.WorkID > 0 {
+		where.Add("work_id = ?", filter.WorkID)
+	}
+	if filter.PublisherID > 0 {
+		where.Add("publisher_id = ?", filter.PublisherID)
+	}
+	if filter.CategoryID > 0 {
+		// Gồm cả sách thuộc category con cháu: path của chúng bắt đầu bằng path của category được lọc
+		where.Add("EXISTS (SELECT 1 FROM book_categories bc JOIN categories c ON c.id = bc.category_id "+
+			"JOIN categories root ON root.id = ? WHERE bc.book_id = books.id AND c.path LIKE CONCAT(root.path, '%'))", filter.CategoryID)
+	}
+	if filter.MinStock != nil {
+		where.Add("stock >= ?", *filter.MinStock)
+	}
+	if filter.MaxStock != nil {
+		where.Add("stock <= ?", *filter.MaxStock)
+	}
+	if filter.TitlePrefix != "" {
+		where.Add("title LIKE ?", pagination.LikePrefix(filter.TitlePrefix))
+	}
+
+	// total đếm theo filter, không tính cursor
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count books: %w", err)
+	}
+	if cond, args := keyset.Where(); cond != "" {
+		where.Add(cond, args...)
+	}
+	query := "SELECT " + bookColumns + " FROM books" + where.SQL() +
+		" ORDER BY " + keyset.OrderBy() + " LIMIT ?"
+	args := append([]any{time.Now()}, where.Args()...)
+	rows, err := r.db.QueryContext(ctx, query, append(args, keyset.Fetch())...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query books: %w", err)
 	}
@@ -42,77 +150,295 @@ func (r *bookRepo) GetAllBooks() ([]*models.Book, error) {
 
 	var books []*models.Book
 	for rows.Next() {
-		book := &models.Book{}
-		err := rows.Scan(&book.ID, &book.Title, &book.AuthorID, &book.Stock, &book.CreatedAt, &book.UpdatedAt)
+		book, err := scanBook(rows)
 		if err != nil {
 			return nil, err
 		}
 		books = append(books, book)
 	}
-	return books, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadDetails(ctx, r.db, books); err != nil {
+		return nil, err
+	}
+	return pagination.Paginate(keyset, books, total, bookSortKey)
 }
 
-func (r *bookRepo) GetByBookID(id int) (*models.Book, error) {
-	row := r.db.QueryRow("SELECT id, title, author_id, stock, created_at, updated_at FROM books WHERE id = ?", id)
+// bookColumns kèm số lượng đang bị reservation active giữ (tham số đầu là thời điểm hiện tại)
+// để tính available_stock.
+const bookColumns = "id, work_id, title, isbn, stock, price, currency, backorder_policy, expected_at, " +
+	"format, language, published_at, publisher_id, created_at, updated_at, " +
+	"(SELECT COALESCE(SUM(quantity), 0) FROM reservations WHERE book_id = books.id AND status = 'active' AND expires_at > ?)"
+
+// scanBook đọc một row theo thứ tự bookColumns
+func scanBook(row interface{ Scan(dest ...any) error }) (*models.Book, error) {
 	book := &models.Book{}
-	err := row.Scan(&book.ID, &book.Title, &book.AuthorID, &book.Stock, &book.CreatedAt, &book.UpdatedAt)
+	var (
+		reserved    int
+		isbn        sql.NullString
+		expectedAt  sql.NullTime
+		publishedAt sql.NullTime
+		publisherID sql.NullInt64
+		price       sql.NullInt64
+	)
+	if err := row.Scan(&book.ID, &book.WorkID, &book.Title, &isbn, &book.Stock, &price, &book.Currency, &book.BackorderPolicy, &expectedAt,
+		&book.Format, &book.Language, &publishedAt, &publisherID, &book.CreatedAt, &book.UpdatedAt, &reserved); err != nil {
+		return nil, err
+	}
+	if publishedAt.Valid {
+		book.PublishedAt = &publishedAt.Time
+	}
+	if price.Valid {
+		book.Price = &price.Int64
+	}
+	if publisherID.Valid {
+		id := int(publisherID.Int64)
+		book.PublisherID = &id
+	}
+	book.ISBN = isbn.String
+	book.SetISBN10()
+	if expectedAt.Valid {
+		book.ExpectedAt = &expectedAt.Time
+	}
+	book.AvailableStock = stock.Available(book.Stock, reserved)
+	return book, nil
+}
+
+// bookSortKey trả về giá trị của field sort để dựng next_cursor
+func bookSortKey(b *models.Book, field string) (any, int) {
+	switch field {
+	case "title":
+		return b.Title, b.ID
+	case "stock":
+		return b.Stock, b.ID
+	case "created_at":
+		return b.CreatedAt, b.ID
+	}
+	return b.ID, b.ID
+}
+
+func (r *bookRepo) GetByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM authors WHERE id = ?)", authorID).Scan(&exists)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("book with ID %d not found", id)
+		return nil, err
+	}
+	if !exists {
+		return nil, apperror.NotFound("author with ID %d not found", authorID)
+	}
+	// Gồm cả sách mà author là biên tập, dịch giả, minh hoạ
+	rows, err := r.db.QueryContext(ctx, "SELECT "+bookColumns+" FROM books WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = ?) ORDER BY id",
+		time.Now(), authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query books: %w", err)
+	}
+	defer rows.Close()
+
+	books := []*models.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadDetails(ctx, r.db, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *bookRepo) GetByBookID(ctx context.Context, id int) (*models.Book, error) {
+	book, err := scanBook(r.db.QueryRowContext(ctx, "SELECT "+bookColumns+" FROM books WHERE id = ?", time.Now(), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("book with ID %d not found", id)
 		}
 		return nil, fmt.Errorf("failed to fetch book: %w", err)
 	}
+	if err := loadDetails(ctx, r.db, []*models.Book{book}); err != nil {
+		return nil, err
+	}
 	return book, nil
 }
 
-func (r *bookRepo) DeleteById(id int) (*models.Book, error) {
-	book, err := r.GetByBookID(id)
+// GetByISBN tìm sách theo ISBN-13 đã chuẩn hoá
+func (r *bookRepo) GetByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	book, err := scanBook(r.db.QueryRowContext(ctx, "SELECT "+bookColumns+" FROM books WHERE isbn = ?", time.Now(), isbn))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("book with ISBN %s not found", isbn)
+		}
+		return nil, fmt.Errorf("failed to fetch book: %w", err)
+	}
+	if err := loadDetails(ctx, r.db, []*models.Book{book}); err != nil {
 		return nil, err
 	}
-	result, err := r.db.Exec("DELETE FROM `books` WHERE id = ?", id)
+	return book, nil
+}
+
+func (r *bookRepo) DeleteById(ctx context.Context, id int) (*models.Book, error) {
+	book, err := r.GetByBookID(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+	// Xoá ấn bản cuối cùng thì xoá luôn work trong cùng transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockWork(ctx, tx, book.WorkID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM `books` WHERE id = ?", id)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		// Kiểm tra nếu lỗi là lỗi khóa ngoại (foreign key)
 		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
-			return nil, fmt.Errorf("cannot delete book: existing orders depend on it")
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return nil, apperror.ForeignKey(err, "cannot delete book: existing orders depend on it")
 		}
 		return nil, err
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		return nil, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no book found with id %d", id)
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.NotFound("no book found with id %d", id)
+	}
+	if err := deleteOrphanWork(ctx, tx, book.WorkID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return book, nil
 }
 
-func (r *bookRepo) UpdateById(book *models.Book) (*models.Book, error) {
-	// Kiểm tra author_id có tồn tại không
-	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM authors WHERE id = ?)", book.AuthorID).Scan(&exists)
+func (r *bookRepo) UpdateById(ctx context.Context, book *models.Book) (*models.Book, int, error) {
+	// Lock row books để tính đúng chênh lệch stock cần ghi vào sổ cái
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	if !exists {
-		return nil, fmt.Errorf("author_id %d does not exist", book.AuthorID)
+	if err := checkAuthors(ctx, tx, book.Authors); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
+	}
+	if err := checkCategories(ctx, tx, book.CategoryIDs); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
+	}
+	var current, currentWork int
+	if err := tx.QueryRowContext(ctx, "SELECT stock, work_id FROM books WHERE id = ? FOR UPDATE", book.ID).Scan(&current, &currentWork); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, apperror.NotFound("no book updated with id %d", book.ID)
+		}
+		return nil, 0, fmt.Errorf("failed to fetch current stock: %w", err)
 	}
-	result, err := r.db.Exec(`
+	// Không gửi work_id thì ấn bản vẫn thuộc work hiện tại
+	if book.WorkID == 0 {
+		book.WorkID = currentWork
+	} else if book.WorkID != currentWork {
+		if err := resolveWork(ctx, tx, book); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, 0, err
+		}
+	}
+	if err := checkPublisher(ctx, tx, book.PublisherID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
+	}
+	result, err := tx.ExecContext(ctx, `
 			UPDATE books
-			SET title = ?, author_id = ?, stock = ? , updated_at = ?
+			SET work_id = ?, title = ?, title_folded = ?, isbn = ?, stock = ? , price = ?, currency = ?, backorder_policy = ?, expected_at = ?,
+				format = ?, language = ?, published_at = ?, publisher_id = ?, updated_at = ?
 			WHERE id = ?`,
-		book.Title, book.AuthorID, book.Stock, book.UpdatedAt, book.ID)
+		book.WorkID, book.Title, textnorm.Fold(book.Title), nullString(book.ISBN), book.Stock, book.Price, book.Currency, book.BackorderPolicy, book.ExpectedAt,
+		book.Format, book.Language, book.PublishedAt, book.PublisherID, book.UpdatedAt, book.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update book: %w", err)
+		txutil.Rollback(ctx, tx, r.logger)
+		if conflict := r.isbnConflict(ctx, err, book.ISBN); conflict != nil {
+			return nil, 0, conflict
+		}
+		return nil, 0, fmt.Errorf("failed to update book: %w", err)
 	}
 	// Kiểm tra có hàng nào bị ảnh hưởng không
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return nil, err
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no book updated with id %d", book.ID)
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, apperror.NotFound("no book updated with id %d", book.ID)
 	}
-	return book, nil
+	// Danh sách tác giả và category được thay toàn bộ
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book_authors WHERE book_id = ?", book.ID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, fmt.Errorf("failed to clear book authors: %w", err)
+	}
+	if err := r.insertAuthors(ctx, tx, book); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book_categories WHERE book_id = ?", book.ID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, fmt.Errorf("failed to clear book categories: %w", err)
+	}
+	if err := insertCategories(ctx, tx, book); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, 0, err
+	}
+	// Ấn bản chuyển sang work khác: work cũ không còn ấn bản nào thì xoá như DeleteById,
+	// lock work cũ trước để không chạy xen với request khác đang xoá hay thêm ấn bản vào work đó
+	if book.WorkID != currentWork {
+		if err := lockWork(ctx, tx, currentWork); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, 0, err
+		}
+		if err := deleteOrphanWork(ctx, tx, currentWork); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, 0, err
+		}
+	}
+	// Ghi đè stock qua PUT/PATCH được ghi vào sổ cái như một lần chỉnh tay
+	delta := book.Stock - current
+	if delta != 0 {
+		err := stock.Record(ctx, tx, &models.StockMovement{
+			BookID: book.ID, Delta: delta, Reason: models.StockAdjustment, StockAfter: book.Stock, CreatedAt: book.UpdatedAt,
+		})
+		if err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return book, delta, nil
+}
+
+// isbnConflict đổi lỗi trùng unique index isbn thành Conflict, lỗi khác trả về nil.
+func (r *bookRepo) isbnConflict(ctx context.Context, err error, isbn string) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+		r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+		return apperror.Conflict("book with ISBN %s already exists", isbn)
+	}
+	return nil
+}
+
+// nullString ghi chuỗi rỗng thành NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
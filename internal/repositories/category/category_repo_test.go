@@ -0,0 +1,196 @@
+package category_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/category"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) (category.CategoryRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return category.NewCategoryRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+func expectLockPath(m sqlmock.Sqlmock, id int, path string) {
+	rows := sqlmock.NewRows([]string{"path"})
+	if path != "" {
+		rows.AddRow(path)
+	}
+	m.ExpectQuery("SELECT `path` FROM `categories` WHERE `id` = \\? FOR UPDATE").WithArgs(id).WillReturnRows(rows)
+}
+
+// expectLockMove giả lập lock category id cùng parent mới; row trả về theo id tăng dần,
+// parentPath rỗng nghĩa là parent không tồn tại
+func expectLockMove(m sqlmock.Sqlmock, id int, path string, parentID *int, parentPath string) {
+	args := []driver.Value{id}
+	paths := map[int]string{id: path}
+	if parentID != nil && *parentID != id {
+		args = append(args, *parentID)
+		if parentPath != "" {
+			paths[*parentID] = parentPath
+		}
+	}
+	rows := sqlmock.NewRows([]string{"id", "path"})
+	for _, id := range slices.Sorted(maps.Keys(paths)) {
+		rows.AddRow(id, paths[id])
+	}
+	m.ExpectQuery("SELECT `id`, `path` FROM `categories` WHERE `id` IN \\(.+\\) ORDER BY `id` FOR UPDATE").
+		WithArgs(args...).WillReturnRows(rows)
+}
+
+func TestCategoryRepo_Create(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	parent := 4
+	tests := []struct {
+		name     string
+		parentID *int
+		// parentPath rỗng nghĩa là parent không tồn tại
+		parentPath string
+		insertErr  error
+		path       string
+		err        error
+		errMsg     string
+	}{
+		{name: "Root category", path: "/9/"},
+		{name: "Child category", parentID: &parent, parentPath: "/1/4/", path: "/1/4/9/"},
+		{name: "Missing parent", parentID: &parent, err: apperror.ErrForeignKey, errMsg: "parent_id 4 does not exist"},
+		{
+			name:      "Duplicate slug",
+			insertErr: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'van-hoc' for key 'uq_categories_slug'"},
+			err:       apperror.ErrConflict,
+			errMsg:    "category with slug van-hoc already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectBegin()
+			if tt.parentID != nil {
+				expectLockPath(m, *tt.parentID, tt.parentPath)
+			}
+			if tt.parentID == nil || tt.parentPath != "" {
+				insert := m.ExpectExec("INSERT INTO `categories`").WithArgs(tt.parentID, "Văn học", "van-hoc", 0, now, now)
+				if tt.insertErr != nil {
+					insert.WillReturnError(tt.insertErr)
+				} else {
+					insert.WillReturnResult(sqlmock.NewResult(9, 1))
+					m.ExpectExec("UPDATE `categories` SET `path` = \\? WHERE `id` = \\?").WithArgs(tt.path, 9).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+			if tt.err != nil {
+				m.ExpectRollback()
+			} else {
+				m.ExpectCommit()
+			}
+
+			c := &models.Category{ParentID: tt.parentID, Name: "Văn học", Slug: "van-hoc", CreatedAt: now}
+			err := repo.Create(context.Background(), c)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 9, c.ID)
+				assert.Equal(t, tt.path, c.Path)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCategoryRepo_Update(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		name       string
+		parentID   *int
+		parentPath string
+		// rewrite là có viết lại path của cả cây con
+		rewrite bool
+		path    string
+		errIs   error
+		errMsg  string
+	}{
+		{name: "Rename in place", parentID: intPtr(1), parentPath: "/1/", path: "/1/4/"},
+		{name: "Move subtree to another parent", parentID: intPtr(2), parentPath: "/2/", rewrite: true, path: "/2/4/"},
+		{name: "Move to root", rewrite: true, path: "/4/"},
+		{
+			name:       "Move under its own subcategory",
+			parentID:   intPtr(9),
+			parentPath: "/1/4/9/",
+			errIs:      apperror.ErrUnprocessable,
+			errMsg:     "category 4 cannot be moved under itself or its subcategory 9",
+		},
+		{
+			name:     "Move under itself",
+			parentID: intPtr(4),
+			errIs:    apperror.ErrUnprocessable,
+			errMsg:   "category 4 cannot be moved under itself or its subcategory 4",
+		},
+		{
+			name:     "Parent does not exist",
+			parentID: intPtr(7),
+			errIs:    apperror.ErrForeignKey,
+			errMsg:   "parent_id 7 does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectBegin()
+			expectLockMove(m, 4, "/1/4/", tt.parentID, tt.parentPath)
+			if tt.errMsg == "" {
+				m.ExpectExec("UPDATE `categories` SET `parent_id` = \\?, `name` = \\?, `slug` = \\?, `position` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+					WithArgs(tt.parentID, "Tiểu thuyết", "tieu-thuyet", 2, now, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.rewrite {
+					m.ExpectExec("UPDATE `categories` SET `path` = CONCAT\\(\\?, SUBSTRING\\(`path`, \\?\\)\\) WHERE `path` LIKE \\?").
+						WithArgs(tt.path, 6, "/1/4/%").WillReturnResult(sqlmock.NewResult(0, 3))
+				}
+				m.ExpectCommit()
+			} else {
+				m.ExpectRollback()
+			}
+
+			c := &models.Category{ID: 4, ParentID: tt.parentID, Name: "Tiểu thuyết", Slug: "tieu-thuyet", Position: 2, UpdatedAt: now}
+			updated, err := repo.Update(context.Background(), c)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.path, updated.Path)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCategoryRepo_DeleteByID_InUse(t *testing.T) {
+	repo, m := newRepo(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExpectQuery("FROM `categories` WHERE `id` = \\?").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "name", "slug", "position", "path", "created_at", "updated_at"}).
+			AddRow(4, 1, "Tiểu thuyết", "tieu-thuyet", 0, "/1/4/", now, now))
+	m.ExpectExec("DELETE FROM `categories` WHERE `id` = \\?").WithArgs(4).
+		WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+
+	_, err := repo.DeleteByID(context.Background(), 4)
+	assert.ErrorIs(t, err, apperror.ErrForeignKey)
+	assert.EqualError(t, err, "cannot delete category: it still has subcategories or books")
+	assert.NoError(t, m.ExpectationsWereMet())
+}
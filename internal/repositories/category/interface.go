@@ -0,0 +1,17 @@
+package category
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+type CategoryRepoInterface interface {
+	Create(ctx context.Context, c *models.Category) error
+	GetAll(ctx context.Context) ([]*models.Category, error)
+	GetByID(ctx context.Context, id int) (*models.Category, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Category, error)
+	// Update ghi đè name, slug, position và chuyển category (kèm cây con) sang parent mới
+	Update(ctx context.Context, c *models.Category) (*models.Category, error)
+	DeleteByID(ctx context.Context, id int) (*models.Category, error)
+}
@@ -0,0 +1,263 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// maxPathLength là độ dài cột path, giới hạn độ sâu của cây
+const maxPathLength = 255
+
+type categoryRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewCategoryRepo(db *sql.DB, logger *slog.Logger) CategoryRepoInterface {
+	return &categoryRepo{db: db, logger: logger}
+}
+
+const categoryColumns = "`id`, `parent_id`, `name`, `slug`, `position`, `path`, `created_at`, `updated_at`"
+
+// Create ghi category rồi điền path khi đã có id. Row cha bị lock để không bị chuyển chỗ giữa chừng.
+func (r *categoryRepo) Create(ctx context.Context, c *models.Category) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	parentPath, err := lockParent(ctx, tx, c.ParentID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO `categories` (`parent_id`, `name`, `slug`, `position`, `path`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, '', ?, ?)",
+		c.ParentID, c.Name, c.Slug, c.Position, c.CreatedAt, c.CreatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return r.writeError(ctx, err, c)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	c.ID = int(id)
+	c.Path = parentPath + strconv.Itoa(c.ID) + "/"
+	if len(c.Path) > maxPathLength {
+		txutil.Rollback(ctx, tx, r.logger)
+		return apperror.Unprocessable("category tree is too deep")
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE `categories` SET `path` = ? WHERE `id` = ?", c.Path, c.ID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to set category path: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	c.UpdatedAt = c.CreatedAt
+	return nil
+}
+
+func (r *categoryRepo) GetAll(ctx context.Context) ([]*models.Category, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM `categories` ORDER BY `id`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+	categories := []*models.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *categoryRepo) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	c, err := scanCategory(r.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM `categories` WHERE `id` = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("category with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	return c, nil
+}
+
+func (r *categoryRepo) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	c, err := scanCategory(r.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM `categories` WHERE `slug` = ?", slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("category with slug %s not found", slug)
+		}
+		return nil, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	return c, nil
+}
+
+// Update lock category và parent mới, chặn chuyển category vào chính cây con của nó
+// rồi viết lại path của cả cây con.
+func (r *categoryRepo) Update(ctx context.Context, c *models.Category) (*models.Category, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	oldPath, parentPath, err := lockMove(ctx, tx, c)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if strings.HasPrefix(parentPath, oldPath) {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.Unprocessable("category %d cannot be moved under itself or its subcategory %d", c.ID, *c.ParentID)
+	}
+	c.Path = parentPath + strconv.Itoa(c.ID) + "/"
+	if len(c.Path) > maxPathLength {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.Unprocessable("category tree is too deep")
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE `categories` SET `parent_id` = ?, `name` = ?, `slug` = ?, `position` = ?, `updated_at` = ? WHERE `id` = ?",
+		c.ParentID, c.Name, c.Slug, c.Position, c.UpdatedAt, c.ID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, r.writeError(ctx, err, c)
+	}
+	if c.Path != oldPath {
+		// Path của category và mọi hậu duệ đều bắt đầu bằng oldPath
+		_, err := tx.ExecContext(ctx, "UPDATE `categories` SET `path` = CONCAT(?, SUBSTRING(`path`, ?)) WHERE `path` LIKE ?",
+			c.Path, len(oldPath)+1, oldPath+"%")
+		if err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, r.writeError(ctx, err, c)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return c, nil
+}
+
+func (r *categoryRepo) DeleteByID(ctx context.Context, id int) (*models.Category, error) {
+	c, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, "DELETE FROM `categories` WHERE `id` = ?", id)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return nil, apperror.ForeignKey(err, "cannot delete category: it still has subcategories or books")
+		}
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, apperror.NotFound("no category found with id %d", id)
+	}
+	return c, nil
+}
+
+// lockMove lock category và parent mới bằng một câu query theo id tăng dần, để hai request
+// chuyển hai category vào nhau (A vào B, B vào A) không lock ngược thứ tự rồi deadlock.
+// Trả về path hiện tại của category và path của parent ("/" với category gốc).
+func lockMove(ctx context.Context, tx *sql.Tx, c *models.Category) (string, string, error) {
+	args := []any{c.ID}
+	if c.ParentID != nil && *c.ParentID != c.ID {
+		args = append(args, *c.ParentID)
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT `id`, `path` FROM `categories` WHERE `id` IN ("+txutil.Placeholders(len(args))+") ORDER BY `id` FOR UPDATE", args...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to lock category: %w", err)
+	}
+	defer rows.Close()
+	paths := make(map[int]string, len(args))
+	for rows.Next() {
+		var (
+			id   int
+			path string
+		)
+		if err := rows.Scan(&id, &path); err != nil {
+			return "", "", err
+		}
+		paths[id] = path
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+	oldPath, ok := paths[c.ID]
+	if !ok {
+		return "", "", apperror.NotFound("no category updated with id %d", c.ID)
+	}
+	if c.ParentID == nil {
+		return oldPath, "/", nil
+	}
+	parentPath, ok := paths[*c.ParentID]
+	if !ok {
+		return "", "", apperror.ForeignKey(nil, "parent_id %d does not exist", *c.ParentID)
+	}
+	return oldPath, parentPath, nil
+}
+
+// lockParent trả về path của parent ("/" với category gốc) và giữ row lock tới hết transaction.
+func lockParent(ctx context.Context, tx *sql.Tx, parentID *int) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+	var path string
+	err := tx.QueryRowContext(ctx, "SELECT `path` FROM `categories` WHERE `id` = ? FOR UPDATE", *parentID).Scan(&path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", apperror.ForeignKey(nil, "parent_id %d does not exist", *parentID)
+		}
+		return "", fmt.Errorf("failed to lock parent category: %w", err)
+	}
+	return path, nil
+}
+
+// writeError đổi lỗi MySQL khi ghi category: trùng slug, path vượt độ dài cột.
+func (r *categoryRepo) writeError(ctx context.Context, err error, c *models.Category) error {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok {
+		return err
+	}
+	switch mysqlErr.Number {
+	case 1062:
+		r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+		return apperror.Conflict("category with slug %s already exists", c.Slug)
+	case 1406:
+		return apperror.Unprocessable("category tree is too deep")
+	}
+	return err
+}
+
+func scanCategory(row interface{ Scan(dest ...any) error }) (*models.Category, error) {
+	c := &models.Category{}
+	var parentID sql.NullInt64
+	if err := row.Scan(&c.ID, &parentID, &c.Name, &c.Slug, &c.Position, &c.Path, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	return c, nil
+}
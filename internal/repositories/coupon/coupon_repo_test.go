@@ -0,0 +1,232 @@
+package coupon_test
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/coupon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var couponColumns = []string{"id", "code", "type", "value", "currency", "min_order_value", "starts_at", "ends_at",
+	"max_redemptions", "max_per_user", "redemptions", "created_at", "updated_at"}
+
+var targetColumns = []string{"coupon_id", "kind", "id"}
+
+// newOrder dựng order đã chốt giá: hai dòng sách 1 (2 x 10000) và sách 2 (1 x 30000)
+func newOrder(code string) *models.Order {
+	o := &models.Order{UserID: 42, CouponCode: code, Items: []models.OrderItem{
+		{BookID: 1, Quantity: 2, UnitPrice: 10000, Currency: "VND"},
+		{BookID: 2, Quantity: 1, UnitPrice: 30000, Currency: "VND"},
+	}}
+	o.NormalizeItems()
+	return o
+}
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	type row struct {
+		typ                     string
+		value, minOrder         int64
+		currency                string
+		startsAt, endsAt        *time.Time
+		maxTotal, maxUser, used int
+	}
+	tests := []struct {
+		name    string
+		coupon  *row
+		targets [][3]any
+		// userRedemptions là số lượt user đã dùng, chỉ được đọc khi coupon có max_per_user
+		userRedemptions int
+		// authorBooks là các sách trong order có tác giả thuộc coupon, chỉ được đọc khi coupon giới hạn theo tác giả
+		authorBooks []int
+		discount    int64
+		errMsg      string
+	}{
+		{
+			name:     "Percentage on the whole order",
+			coupon:   &row{typ: "percentage", value: 10, currency: "VND"},
+			discount: 5000,
+		},
+		{
+			name:     "Fixed amount never exceeds eligible lines",
+			coupon:   &row{typ: "fixed", value: 50000, currency: "VND"},
+			targets:  [][3]any{{3, "book", 2}},
+			discount: 30000,
+		},
+		{
+			name:        "Restricted to an author",
+			coupon:      &row{typ: "percentage", value: 50, currency: "VND"},
+			targets:     [][3]any{{3, "author", 7}},
+			authorBooks: []int{1},
+			discount:    10000,
+		},
+		{
+			name:    "No line matches",
+			coupon:  &row{typ: "percentage", value: 10, currency: "VND"},
+			targets: [][3]any{{3, "book", 9}},
+			errMsg:  "coupon SALE does not apply to any item in the order",
+		},
+		{
+			name:   "Expired",
+			coupon: &row{typ: "percentage", value: 10, currency: "VND", endsAt: &past},
+			errMsg: "coupon SALE is not active",
+		},
+		{
+			name:   "Not started",
+			coupon: &row{typ: "percentage", value: 10, currency: "VND", startsAt: &future},
+			errMsg: "coupon SALE is not active",
+		},
+		{
+			name:   "Other currency",
+			coupon: &row{typ: "fixed", value: 5, currency: "USD"},
+			errMsg: "coupon SALE only applies to USD orders",
+		},
+		{
+			name:   "Below minimum order value",
+			coupon: &row{typ: "fixed", value: 5000, currency: "VND", minOrder: 100000},
+			errMsg: "coupon SALE requires a minimum order value of 100000",
+		},
+		{
+			name:   "Global limit reached",
+			coupon: &row{typ: "fixed", value: 5000, currency: "VND", maxTotal: 3, used: 3},
+			errMsg: "coupon SALE has reached its usage limit",
+		},
+		{
+			name:            "Per-user limit reached",
+			coupon:          &row{typ: "fixed", value: 5000, currency: "VND", maxUser: 1},
+			userRedemptions: 1,
+			errMsg:          "coupon SALE has reached its usage limit for this user",
+		},
+		{
+			name:   "Unknown code",
+			errMsg: "coupon SALE does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, m, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			m.ExpectBegin()
+			lock := m.ExpectQuery("FROM `coupons` WHERE `code` = \\? FOR UPDATE").WithArgs("SALE")
+			if c := tt.coupon; c != nil {
+				lock.WillReturnRows(sqlmock.NewRows(couponColumns).AddRow(3, "SALE", c.typ, c.value, c.currency, c.minOrder,
+					c.startsAt, c.endsAt, c.maxTotal, c.maxUser, c.used, now, now))
+			} else {
+				lock.WillReturnError(sql.ErrNoRows)
+			}
+			if tt.coupon != nil && tt.coupon.maxUser > 0 {
+				m.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `coupon_redemptions` WHERE `coupon_id` = \\? AND `user_id` = \\?").
+					WithArgs(3, 42).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.userRedemptions))
+			}
+			if tt.discount != 0 || tt.targets != nil {
+				rows := sqlmock.NewRows(targetColumns)
+				for _, r := range tt.targets {
+					rows.AddRow(r[0], r[1], r[2])
+				}
+				m.ExpectQuery("FROM `coupon_books`").WithArgs(3, 3).WillReturnRows(rows)
+			}
+			if tt.authorBooks != nil {
+				rows := sqlmock.NewRows([]string{"book_id"})
+				for _, id := range tt.authorBooks {
+					rows.AddRow(id)
+				}
+				m.ExpectQuery("SELECT DISTINCT `book_id` FROM `book_authors` WHERE `book_id` IN \\(\\?, \\?\\) AND `author_id` IN \\(\\?\\)").
+					WithArgs(1, 2, 7).WillReturnRows(rows)
+			}
+			if tt.discount != 0 {
+				m.ExpectExec("UPDATE `coupons` SET `redemptions` = `redemptions` \\+ 1 WHERE `id` = \\?").WithArgs(3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			tx, err := db.Begin()
+			require.NoError(t, err)
+			order := newOrder("SALE")
+
+			applied, err := coupon.Apply(context.Background(), tx, order, now)
+
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrUnprocessable)
+				assert.EqualError(t, err, tt.errMsg)
+				assert.Zero(t, order.Discount)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 3, applied.ID)
+				assert.Equal(t, tt.discount, order.Discount)
+				assert.Equal(t, order.Subtotal-tt.discount, order.Total)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCouponRepo_Create(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		prepare func(sqlmock.Sqlmock)
+		errIs   error
+	}{
+		{
+			name: "Success",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec("INSERT INTO `coupons`").
+					WithArgs("SALE", "percentage", 10, "VND", 0, nil, nil, 0, 1, now, now).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				m.ExpectExec("INSERT INTO `coupon_books` \\(`coupon_id`, `book_id`\\) VALUES \\(\\?, \\?\\), \\(\\?, \\?\\)").
+					WithArgs(3, 1, 3, 2).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "Duplicate code",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec("INSERT INTO `coupons`").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrConflict,
+		},
+		{
+			name: "Unknown book",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec("INSERT INTO `coupons`").WillReturnResult(sqlmock.NewResult(3, 1))
+				m.ExpectExec("INSERT INTO `coupon_books`").WillReturnError(&mysql.MySQLError{Number: 1452, Message: "a foreign key constraint fails"})
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrForeignKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, m, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			tt.prepare(m)
+			c := &models.Coupon{Code: "SALE", Type: "percentage", Value: 10, Currency: "VND", MaxPerUser: 1, BookIDs: []int{1, 2}, CreatedAt: now}
+
+			err = coupon.NewCouponRepo(db, slog.New(slog.DiscardHandler)).Create(context.Background(), c)
+
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 3, c.ID)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package coupon
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// internal/repositories/coupon/interface.go
+type CouponRepoInterface interface {
+	Create(ctx context.Context, c *models.Coupon) error
+	GetByID(ctx context.Context, id int) (*models.Coupon, error)
+	GetAll(ctx context.Context) ([]*models.Coupon, error)
+	// DeleteByID chỉ xoá được coupon chưa có lượt dùng nào
+	DeleteByID(ctx context.Context, id int) (*models.Coupon, error)
+}
@@ -0,0 +1,210 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const couponColumns = "`id`, `code`, `type`, `value`, `currency`, `min_order_value`, `starts_at`, `ends_at`, " +
+	"`max_redemptions`, `max_per_user`, `redemptions`, `created_at`, `updated_at`"
+
+type couponRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewCouponRepo(db *sql.DB, logger *slog.Logger) CouponRepoInterface {
+	return &couponRepo{db: db, logger: logger}
+}
+
+// Create ghi coupon cùng danh sách sách/tác giả được áp dụng trong một transaction.
+func (r *couponRepo) Create(ctx context.Context, c *models.Coupon) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	c.UpdatedAt = c.CreatedAt
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO `coupons` (`code`, `type`, `value`, `currency`, `min_order_value`, `starts_at`, `ends_at`, `max_redemptions`, `max_per_user`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		c.Code, c.Type, c.Value, c.Currency, c.MinOrderValue, c.StartsAt, c.EndsAt, c.MaxRedemptions, c.MaxPerUser, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return apperror.Conflict("coupon code %s already exists", c.Code)
+		}
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+	c.ID = int(id)
+	if err := insertTargets(ctx, tx, "coupon_books", "book_id", c.ID, c.BookIDs); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := insertTargets(ctx, tx, "coupon_authors", "author_id", c.ID, c.AuthorIDs); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *couponRepo) GetByID(ctx context.Context, id int) (*models.Coupon, error) {
+	c, err := scanCoupon(r.db.QueryRowContext(ctx, "SELECT "+couponColumns+" FROM `coupons` WHERE `id` = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("coupon with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to fetch coupon: %w", err)
+	}
+	if err := loadTargets(ctx, r.db, []*models.Coupon{c}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *couponRepo) GetAll(ctx context.Context) ([]*models.Coupon, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+couponColumns+" FROM `coupons` ORDER BY `id`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coupons: %w", err)
+	}
+	defer rows.Close()
+	coupons := []*models.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close() // trả connection về pool trước khi query targets
+	if err := loadTargets(ctx, r.db, coupons); err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (r *couponRepo) DeleteByID(ctx context.Context, id int) (*models.Coupon, error) {
+	c, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, "DELETE FROM `coupons` WHERE `id` = ?", id)
+	if err != nil {
+		// Lượt dùng giữ lại để total của order tính lại được
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return nil, apperror.ForeignKey(err, "cannot delete coupon: it has already been redeemed")
+		}
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, apperror.NotFound("no coupon found with id %d", id)
+	}
+	return c, nil
+}
+
+// insertTargets ghi danh sách book_id/author_id của coupon bằng một câu INSERT.
+func insertTargets(ctx context.Context, tx *sql.Tx, table, column string, couponID int, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, 0, 2*len(ids))
+	for _, id := range ids {
+		args = append(args, couponID, id)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(ids)), ", ")
+	_, err := tx.ExecContext(ctx, "INSERT INTO `"+table+"` (`coupon_id`, `"+column+"`) VALUES "+values, args...)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return apperror.ForeignKey(err, "%s does not exist", column)
+		}
+		return fmt.Errorf("failed to insert %s: %w", table, err)
+	}
+	return nil
+}
+
+// querier là phần chung của *sql.DB và *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// loadTargets đọc sách và tác giả được áp dụng của nhiều coupon.
+func loadTargets(ctx context.Context, q querier, coupons []*models.Coupon) error {
+	if len(coupons) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Coupon, len(coupons))
+	args := make([]any, 0, len(coupons))
+	for _, c := range coupons {
+		c.BookIDs, c.AuthorIDs = []int{}, []int{}
+		byID[c.ID] = c
+		args = append(args, c.ID)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	rows, err := q.QueryContext(ctx,
+		"SELECT `coupon_id`, 'book', `book_id` FROM `coupon_books` WHERE `coupon_id` IN ("+in+") "+
+			"UNION ALL SELECT `coupon_id`, 'author', `author_id` FROM `coupon_authors` WHERE `coupon_id` IN ("+in+") "+
+			"ORDER BY 1, 2, 3", append(args, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to load coupon targets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			couponID, id int
+			kind         string
+		)
+		if err := rows.Scan(&couponID, &kind, &id); err != nil {
+			return err
+		}
+		c := byID[couponID]
+		if c == nil {
+			continue
+		}
+		if kind == "book" {
+			c.BookIDs = append(c.BookIDs, id)
+		} else {
+			c.AuthorIDs = append(c.AuthorIDs, id)
+		}
+	}
+	return rows.Err()
+}
+
+func scanCoupon(row interface{ Scan(dest ...any) error }) (*models.Coupon, error) {
+	c := &models.Coupon{}
+	var startsAt, endsAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.Code, &c.Type, &c.Value, &c.Currency, &c.MinOrderValue, &startsAt, &endsAt,
+		&c.MaxRedemptions, &c.MaxPerUser, &c.Redemptions, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if startsAt.Valid {
+		c.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		c.EndsAt = &endsAt.Time
+	}
+	return c, nil
+}
@@ -0,0 +1,188 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// Apply lock coupon theo order.CouponCode, kiểm tra điều kiện với order đã có giá rồi ghi
+// Discount vào order. Lượt dùng được cộng ngay dưới row lock nên hai order cùng lúc không
+// vượt giới hạn; caller gọi LinkOrder sau khi insert order, trong cùng transaction.
+func Apply(ctx context.Context, tx *sql.Tx, order *models.Order, now time.Time) (*models.Coupon, error) {
+	c, err := lockCoupon(ctx, tx, order.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+	if !c.ActiveAt(now) {
+		return nil, apperror.Unprocessable("coupon %s is not active", c.Code)
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return nil, apperror.Unprocessable("coupon %s has reached its usage limit", c.Code)
+	}
+	if c.MaxPerUser > 0 {
+		var used int
+		err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM `coupon_redemptions` WHERE `coupon_id` = ? AND `user_id` = ?", c.ID, order.UserID).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count coupon redemptions: %w", err)
+		}
+		if used >= c.MaxPerUser {
+			return nil, apperror.Unprocessable("coupon %s has reached its usage limit for this user", c.Code)
+		}
+	}
+	eligible, err := checkOrder(ctx, tx, c, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `coupons` SET `redemptions` = `redemptions` + 1 WHERE `id` = ?", c.ID); err != nil {
+		return nil, fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+	c.Redemptions++
+	order.CouponCode = c.Code
+	order.Discount = c.Discount(eligible)
+	order.NormalizeItems()
+	return c, nil
+}
+
+// Reapply kiểm tra lại coupon của order đã tạo sau khi đổi items, dưới row lock của coupon, rồi
+// tính lại Discount theo items mới và ghi vào coupon_redemptions. Lượt dùng đã được đếm lúc tạo
+// order nên không kiểm tra lại thời hạn và giới hạn lượt dùng.
+func Reapply(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	c, err := lockCoupon(ctx, tx, order.CouponCode)
+	if err != nil {
+		return err
+	}
+	eligible, err := checkOrder(ctx, tx, c, order)
+	if err != nil {
+		return err
+	}
+	order.Discount = c.Discount(eligible)
+	order.NormalizeItems()
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `coupon_redemptions` SET `discount` = ? WHERE `order_id` = ?", order.Discount, order.ID); err != nil {
+		return fmt.Errorf("failed to update coupon redemption: %w", err)
+	}
+	return nil
+}
+
+// lockCoupon đọc coupon theo code và giữ row lock tới hết transaction.
+func lockCoupon(ctx context.Context, tx *sql.Tx, code string) (*models.Coupon, error) {
+	c, err := scanCoupon(tx.QueryRowContext(ctx,
+		"SELECT "+couponColumns+" FROM `coupons` WHERE `code` = ? FOR UPDATE", code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.Unprocessable("coupon %s does not exist", code)
+		}
+		return nil, fmt.Errorf("failed to lock coupon: %w", err)
+	}
+	return c, nil
+}
+
+// checkOrder kiểm tra currency, giá trị tối thiểu và các dòng được giảm của order; trả về
+// số tiền được áp dụng coupon.
+func checkOrder(ctx context.Context, tx *sql.Tx, c *models.Coupon, order *models.Order) (int64, error) {
+	if c.Currency != order.Currency {
+		return 0, apperror.Unprocessable("coupon %s only applies to %s orders", c.Code, c.Currency)
+	}
+	if order.Subtotal < c.MinOrderValue {
+		return 0, apperror.Unprocessable("coupon %s requires a minimum order value of %d", c.Code, c.MinOrderValue)
+	}
+	eligible, err := eligibleAmount(ctx, tx, c, order)
+	if err != nil {
+		return 0, err
+	}
+	if eligible == 0 {
+		return 0, apperror.Unprocessable("coupon %s does not apply to any item in the order", c.Code)
+	}
+	return eligible, nil
+}
+
+// LinkOrder ghi lượt dùng coupon của order vừa tạo.
+func LinkOrder(ctx context.Context, tx *sql.Tx, c *models.Coupon, order *models.Order) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO `coupon_redemptions` (`coupon_id`, `order_id`, `user_id`, `discount`, `created_at`) VALUES (?, ?, ?, ?, ?)",
+		c.ID, order.ID, order.UserID, order.Discount, order.OrderedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record coupon redemption: %w", err)
+	}
+	return nil
+}
+
+// ReleaseOrder trả lại lượt dùng coupon của order bị huỷ, hoàn tiền hoặc xoá: xoá
+// coupon_redemptions của order và giảm redemptions, trong transaction đang trả stock của order.
+// Order không dùng coupon hoặc đã được trả lượt trước đó thì không làm gì.
+func ReleaseOrder(ctx context.Context, tx *sql.Tx, orderID int) error {
+	var couponID int
+	err := tx.QueryRowContext(ctx,
+		"SELECT `coupon_id` FROM `coupon_redemptions` WHERE `order_id` = ? FOR UPDATE", orderID).Scan(&couponID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock coupon redemption: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `coupon_redemptions` WHERE `order_id` = ?", orderID); err != nil {
+		return fmt.Errorf("failed to delete coupon redemption: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `coupons` SET `redemptions` = `redemptions` - 1 WHERE `id` = ? AND `redemptions` > 0", couponID); err != nil {
+		return fmt.Errorf("failed to release coupon: %w", err)
+	}
+	return nil
+}
+
+// eligibleAmount là tổng tiền các dòng coupon được áp dụng: mọi dòng nếu coupon không
+// giới hạn, ngược lại các dòng có sách hoặc tác giả nằm trong danh sách của coupon.
+func eligibleAmount(ctx context.Context, tx *sql.Tx, c *models.Coupon, order *models.Order) (int64, error) {
+	if err := loadTargets(ctx, tx, []*models.Coupon{c}); err != nil {
+		return 0, err
+	}
+	if !c.Restricted() {
+		return order.Subtotal, nil
+	}
+	// byAuthor là các sách trong order có ít nhất một tác giả thuộc coupon (ở bất kỳ vai trò nào)
+	byAuthor := map[int]bool{}
+	if len(c.AuthorIDs) > 0 {
+		args := make([]any, 0, len(order.Items)+len(c.AuthorIDs))
+		for _, item := range order.Items {
+			args = append(args, item.BookID)
+		}
+		for _, id := range c.AuthorIDs {
+			args = append(args, id)
+		}
+		rows, err := tx.QueryContext(ctx,
+			"SELECT DISTINCT `book_id` FROM `book_authors` WHERE `book_id` IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(order.Items)), ", ")+
+				") AND `author_id` IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(c.AuthorIDs)), ", ")+")", args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query book authors: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var bookID int
+			if err := rows.Scan(&bookID); err != nil {
+				return 0, err
+			}
+			byAuthor[bookID] = true
+		}
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+	}
+	var eligible int64
+	for _, item := range order.Items {
+		if slices.Contains(c.BookIDs, item.BookID) || byAuthor[item.BookID] {
+			eligible += item.LineTotal
+		}
+	}
+	return eligible, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+// backorderable báo mọi sách bị thiếu hàng đều cho phép backorder/preorder.
+// Các row books đã được adjustStock lock nên policy không đổi tới hết transaction.
+func backorderable(ctx context.Context, tx *sql.Tx, shortages []apperror.StockShortage) (bool, error) {
+	args := make([]any, 0, len(shortages)+1)
+	args = append(args, models.BackorderNone)
+	for _, s := range shortages {
+		args = append(args, s.BookID)
+	}
+	var n int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM `books` WHERE `backorder_policy` <> ? AND `id` IN ("+txutil.Placeholders(len(shortages))+")", args...).
+		Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to check backorder policy: %w", err)
+	}
+	return n == len(shortages), nil
+}
+
+// AllocateBackorders phân bổ stock của bookID cho các order backordered theo thứ tự đặt
+// (ordered_at, id). Mỗi order được phân bổ trong transaction riêng với cùng thứ tự lock
+// như các luồng khác (order rồi tới sách). Thứ tự ưu tiên tính theo từng sách: dừng khi
+// chính bookID hết hàng để order đặt sau không vượt lên trước, còn order chỉ thiếu sách
+// khác thì bỏ qua để không chặn các order sau.
+func (r *orderRepo) AllocateBackorders(ctx context.Context, bookID int, at time.Time) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.id FROM orders o
+		JOIN order_items i ON i.order_id = o.id
+		WHERE i.book_id = ? AND o.status = ?
+		ORDER BY o.ordered_at, o.id`, bookID, models.OrderBackordered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backorders: %w", err)
+	}
+	var waiting []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		waiting = append(waiting, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	allocated := []int{}
+	for _, id := range waiting {
+		ok, err := r.allocate(ctx, id, at)
+		if errors.Is(err, apperror.ErrInsufficientStock) {
+			if short(err, bookID) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return allocated, err
+		}
+		if ok {
+			allocated = append(allocated, id)
+		}
+	}
+	return allocated, nil
+}
+
+// short báo lỗi thiếu hàng có bao gồm bookID không. Lỗi không kèm danh sách sách
+// thiếu được coi như thiếu bookID.
+func short(err error, bookID int) bool {
+	var stockErr *apperror.InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		return true
+	}
+	for _, item := range stockErr.Items {
+		if item.BookID == bookID {
+			return true
+		}
+	}
+	return false
+}
+
+// allocate trừ stock cho cả order backordered và chuyển sang pending.
+// Trả về false nếu order đã đổi trạng thái (vd: vừa bị huỷ) trước khi lock được.
+func (r *orderRepo) allocate(ctx context.Context, id int, at time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return false, err
+	}
+	order, err := LockOrder(ctx, tx, id)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, err
+	}
+	if order.Status != models.OrderBackordered {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, nil
+	}
+	deltas := map[int]int{}
+	for _, item := range order.Items {
+		deltas[item.BookID] -= item.Quantity
+	}
+	movements, err := adjustStock(ctx, tx, deltas)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, err
+	}
+	if err := recordStock(ctx, tx, movements, models.StockOrder, id, at); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `orders` SET `status` = ?, `updated_at` = ? WHERE `id` = ?", models.OrderPending, at, id); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+// CreateIdempotent insert key trước khi đụng tới stock: request trùng key chạy song song
+// bị chặn ở row lock của key cho tới khi transaction đầu commit (→ replay) hoặc
+// rollback (→ tự tạo order). Order tạo lỗi thì key cũng bị rollback, client retry được.
+func (r *orderRepo) CreateIdempotent(ctx context.Context, order *models.Order, key models.IdempotencyKey) (bool, error) {
+	replayed, retry, err := r.createIdempotent(ctx, order, key)
+	if retry {
+		// Key cũ đã hết hạn hoặc vừa bị xoá: thử lại đúng một lần
+		replayed, retry, err = r.createIdempotent(ctx, order, key)
+		if retry {
+			return false, apperror.Conflict("Idempotency-Key %q is being reused concurrently, retry later", key.Key)
+		}
+	}
+	return replayed, err
+}
+
+func (r *orderRepo) createIdempotent(ctx context.Context, order *models.Order, key models.IdempotencyKey) (replayed, retry bool, err error) {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return false, false, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO `idempotency_keys` (`user_id`, `idempotency_key`, `fingerprint`, `created_at`) VALUES (?, ?, ?, ?)",
+		key.UserID, key.Key, key.Fingerprint, order.OrderedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return r.replay(ctx, order, key)
+		}
+		return false, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	if err := r.create(ctx, tx, order); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, false, err
+	}
+	response, err := json.Marshal(order)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, false, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `idempotency_keys` SET `order_id` = ?, `response` = ? WHERE `user_id` = ? AND `idempotency_key` = ?",
+		order.ID, response, key.UserID, key.Key); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return false, false, fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return false, false, nil
+}
+
+// replay đọc order đã lưu cho key của user. Key đã hết hạn được xoá để lần thử sau tạo order mới.
+func (r *orderRepo) replay(ctx context.Context, order *models.Order, key models.IdempotencyKey) (replayed, retry bool, err error) {
+	var (
+		fingerprint string
+		response    sql.NullString
+		createdAt   time.Time
+	)
+	err = r.db.QueryRowContext(ctx,
+		"SELECT `fingerprint`, `response`, `created_at` FROM `idempotency_keys` WHERE `user_id` = ? AND `idempotency_key` = ?",
+		key.UserID, key.Key).
+		Scan(&fingerprint, &response, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, true, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to fetch idempotency key: %w", err)
+	}
+	if createdAt.Before(key.ExpiredBefore) {
+		if _, err := r.db.ExecContext(ctx,
+			"DELETE FROM `idempotency_keys` WHERE `user_id` = ? AND `idempotency_key` = ? AND `created_at` < ?",
+			key.UserID, key.Key, key.ExpiredBefore); err != nil {
+			return false, false, fmt.Errorf("failed to release expired idempotency key: %w", err)
+		}
+		return false, true, nil
+	}
+	if fingerprint != key.Fingerprint {
+		return false, false, apperror.Unprocessable("Idempotency-Key %q was already used with a different request", key.Key)
+	}
+	// Row chỉ được commit cùng response nên response luôn có giá trị
+	if !response.Valid {
+		return false, false, fmt.Errorf("idempotency key %q has no stored response", key.Key)
+	}
+	if err := json.Unmarshal([]byte(response.String), order); err != nil {
+		return false, false, fmt.Errorf("failed to decode stored order: %w", err)
+	}
+	return true, false, nil
+}
+
+func (r *orderRepo) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM `idempotency_keys` WHERE `created_at` < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
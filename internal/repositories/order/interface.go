@@ -1,16 +1,30 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 )
 
-
-
 type OrderReposiotoryInterface interface {
-	GetByOrderID(id int) (*models.Order, error)
-	GetAllOrders() ([]*models.Order, error)
-	UpdateByOrderID(order *models.Order) (*models.Order, error)
-	DeleteByOrderID(id int) (*models.Order, error)
-	Create(order *models.Order) error
-
+	GetByOrderID(ctx context.Context, id int) (*models.Order, error)
+	GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error)
+	UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error)
+	DeleteByOrderID(ctx context.Context, id int) (*models.Order, error)
+	Create(ctx context.Context, order *models.Order) error
+	// CreateIdempotent tạo order một lần cho mỗi key; request lặp lại được điền order đã tạo
+	// vào order và trả về replayed = true
+	CreateIdempotent(ctx context.Context, order *models.Order, key models.IdempotencyKey) (replayed bool, err error)
+	// PurgeIdempotencyKeys xoá các key tạo trước before, trả về số key đã xoá
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+	// ConfirmReservation chuyển reservation đang active thành order (điền vào order)
+	ConfirmReservation(ctx context.Context, reservationID int, order *models.Order) (*models.Reservation, error)
+	// AllocateBackorders chuyển các order backordered có bookID sang pending theo thứ tự đặt
+	// khi đã đủ hàng, trả về ID các order được phân bổ
+	AllocateBackorders(ctx context.Context, bookID int, at time.Time) ([]int, error)
+	// UpdateStatus chỉ đổi status khi order vẫn đang ở trạng thái from (compare-and-set),
+	// và trả lại stock khi order bị huỷ/hoàn tiền trước khi giao
+	UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error
 }
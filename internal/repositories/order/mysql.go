@@ -1,78 +1,143 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/models"
-
-	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/repositories/coupon"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
 )
 
+const orderColumns = "`id`, `user_id`, `quantity`, `status`, `coupon_code`, `discount`, `ordered_at`, `updated_at`"
+
 type orderRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func NewOrderRepo(db *sql.DB) OrderReposiotoryInterface {
-	return &orderRepo{db: db}
+func NewOrderRepo(db *sql.DB, logger *slog.Logger) OrderReposiotoryInterface {
+	return &orderRepo{db: db, logger: logger}
 }
 
-// Implement the OrderReader interface
-func (r *orderRepo) Create(order *models.Order) error {
-	tx, err := r.db.Begin()
+// Create tạo order cùng tất cả items trong một transaction: thiếu hàng ở bất kỳ dòng nào
+// thì cả order bị huỷ, không có order nào được tạo một phần.
+func (r *orderRepo) Create(ctx context.Context, order *models.Order) error {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
 	if err != nil {
 		return err
 	}
-	// Step 1: Check current stock
-	var currentStock int
-	err = tx.QueryRow("SELECT stock FROM books WHERE id = ? FOR UPDATE;", order.BookID).Scan(&currentStock)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("rollback failed: %v, original error: %w", rbErr, err)
-		}
-		return fmt.Errorf("failed to fetch current stock: %w", err)
+	if err := r.create(ctx, tx, order); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
 	}
-	if currentStock < order.Quantity {
-		tx.Rollback()
-		return fmt.Errorf("not enough stock available")
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return nil
+}
 
-	// step 2: Insert order
-	query := "INSERT INTO orders (book_id, user_id, quantity, status ,ordered_at) VALUES (?, ?, ?, ?,?)"
-	result, err := tx.Exec(query, order.BookID, order.UserID, order.Quantity, order.Status, order.OrderedAt)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create order: %w", err)
+func (r *orderRepo) create(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	// Step 1: lock sách và trừ stock cho tất cả các dòng
+	deltas := map[int]int{}
+	for _, item := range order.Items {
+		deltas[item.BookID] -= item.Quantity
 	}
-	// time.Sleep(10 * time.Second)
-	//step 3 : update book stock
-	_, err = tx.Exec(
-		"UPDATE books SET stock = stock - ? WHERE id = ?",
-		order.Quantity, order.BookID,
-	)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update book stock: %w", err)
+	movements, err := adjustStock(ctx, tx, deltas)
+	var shortage *apperror.InsufficientStockError
+	if errors.As(err, &shortage) {
+		// Thiếu hàng nhưng mọi sách thiếu đều cho đặt trước: nhận order, chưa trừ stock
+		ok, berr := backorderable(ctx, tx, shortage.Items)
+		if berr != nil {
+			return berr
+		}
+		if !ok {
+			return err
+		}
+		order.Status = models.OrderBackordered
+		movements = nil
+	} else if err != nil {
+		return err
+	}
+	if err := priceItems(ctx, tx, order, nil); err != nil {
+		return err
+	}
+	// Coupon được kiểm tra trên giá đã chốt; lượt dùng được đếm trong cùng transaction
+	var applied *models.Coupon
+	if order.CouponCode != "" {
+		if applied, err = coupon.Apply(ctx, tx, order, order.OrderedAt); err != nil {
+			return err
+		}
 	}
-	// Step 4: Commit transaction
-	err = tx.Commit()
 
+	// Step 2: insert order và items
+	query := "INSERT INTO orders (user_id, quantity, status, coupon_code, discount, ordered_at) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, order.UserID, order.Quantity, order.Status, nullString(order.CouponCode), order.Discount, order.OrderedAt)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to create order: %w", err)
 	}
-	// Get the inserted ID
 	id, err := result.LastInsertId()
 	if err != nil {
 		return fmt.Errorf("failed to retrieve inserted order ID: %w", err)
 	}
-	order.ID = int(id) // Set the ID of the order after insertion
-	return nil
+	order.ID = int(id)
+	if err := r.insertItems(ctx, tx, order); err != nil {
+		return err
+	}
+	if applied != nil {
+		if err := coupon.LinkOrder(ctx, tx, applied, order); err != nil {
+			return err
+		}
+	}
+	return recordStock(ctx, tx, movements, models.StockOrder, order.ID, order.OrderedAt)
+}
+
+// orderSortFields là whitelist field được phép dùng trong ?sort=, mặc định mới nhất trước
+var orderSortFields = pagination.Fields{
+	"id":         {Column: "id", Kind: pagination.Int},
+	"ordered_at": {Column: "ordered_at", Kind: pagination.Time},
+	"quantity":   {Column: "quantity", Kind: pagination.Int},
 }
 
 // Implement interface method
-func (r *orderRepo) GetAllOrders() ([]*models.Order, error) {
-	rows, err := r.db.Query("SELECT * FROM `orders`")
+func (r *orderRepo) GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error) {
+	keyset, err := pagination.Resolve(filter.Params, orderSortFields, "-ordered_at")
+	if err != nil {
+		return nil, err
+	}
+	var where pagination.Conditions
+	if filter.Status != "" {
+		where.Add("`status` = ?", filter.Status)
+	}
+	if filter.UserID > 0 {
+		where.Add("`user_id` = ?", filter.UserID)
+	}
+	if filter.BookID > 0 {
+		where.Add("EXISTS (SELECT 1 FROM `order_items` WHERE `order_items`.`order_id` = `orders`.`id` AND `order_items`.`book_id` = ?)", filter.BookID)
+	}
+	if filter.From != nil {
+		where.Add("`ordered_at` >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		where.Add("`ordered_at` < ?", *filter.To)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM `orders`"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+	if cond, args := keyset.Where(); cond != "" {
+		where.Add(cond, args...)
+	}
+	query := "SELECT " + orderColumns + " FROM `orders`" + where.SQL() +
+		" ORDER BY " + keyset.OrderBy() + " LIMIT ?"
+	rows, err := r.db.QueryContext(ctx, query, append(where.Args(), keyset.Fetch())...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query orders: %w", err)
 	}
@@ -80,71 +145,231 @@ func (r *orderRepo) GetAllOrders() ([]*models.Order, error) {
 
 	var orders []*models.Order
 	for rows.Next() {
-		order := &models.Order{}
-		err := rows.Scan(&order.ID, &order.BookID, &order.UserID, &order.Quantity, &order.Status, &order.OrderedAt, &order.UpdatedAt)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
 	}
-	return orders, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close() // trả connection về pool trước khi query items
+	if err := loadItems(ctx, r.db, orders); err != nil {
+		return nil, err
+	}
+	return pagination.Paginate(keyset, orders, total, orderSortKey)
 }
 
-func (r *orderRepo) GetByOrderID(id int) (*models.Order, error) {
-	row := r.db.QueryRow("SELECT `id`, `book_id`, `user_id`, `quantity`, `status`,`ordered_at`, `updated_at` FROM `orders` WHERE id = ?", id)
-	order := &models.Order{}
-	err := row.Scan(&order.ID, &order.BookID, &order.UserID, &order.Quantity, &order.Status, &order.OrderedAt, &order.UpdatedAt)
+// orderSortKey trả về giá trị của field sort để dựng next_cursor
+func orderSortKey(o *models.Order, field string) (any, int) {
+	switch field {
+	case "ordered_at":
+		return o.OrderedAt, o.ID
+	case "quantity":
+		return o.Quantity, o.ID
+	}
+	return o.ID, o.ID
+}
+
+func (r *orderRepo) GetByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	order, err := scanOrder(r.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM `orders` WHERE id = ?", id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("order with ID %d not found", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("order with ID %d not found", id)
 		}
-		return nil, fmt.Errorf("failed to fetch book: %w", err)
+		return nil, fmt.Errorf("failed to fetch order: %w", err)
+	}
+	if err := loadItems(ctx, r.db, []*models.Order{order}); err != nil {
+		return nil, err
 	}
 	return order, nil
 }
 
-func (r *orderRepo) DeleteByOrderID(id int) (*models.Order, error) {
-	order, err := r.GetByOrderID(id)
+// DeleteByOrderID xoá order (items bị xoá theo ON DELETE CASCADE) và trả lại stock nếu sách chưa rời kho.
+func (r *orderRepo) DeleteByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
 	if err != nil {
 		return nil, err
 	}
-	result, err := r.db.Exec("DELETE FROM `orders` WHERE id = ?", id)
+	order, err := LockOrder(ctx, tx, id)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if models.OrderHoldsStock(order.Status) {
+		movements, err := adjustStock(ctx, tx, releaseDeltas(order))
+		if err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+		if err := recordStock(ctx, tx, movements, models.StockCancellation, id, time.Now()); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+	}
+	if err := releaseCoupon(ctx, tx, order); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM `orders` WHERE id = ?", id)
 	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		return nil, fmt.Errorf("failed to delete order: %w", err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		return nil, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no book found with id %d", id)
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.NotFound("no order found with id %d", id)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return order, nil
 }
 
-func (r *orderRepo) UpdateByOrderID(order *models.Order) (*models.Order, error) {
-	result, err := r.db.Exec(`
+// UpdateByOrderID cập nhật order và áp dụng chênh lệch stock (đổi items, huỷ)
+// trong cùng transaction.
+func (r *orderRepo) UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error) {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return nil, err
+	}
+	current, err := LockOrder(ctx, tx, order.ID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	// Kiểm tra lại dưới lock: service đọc status trước đó nên có thể đã cũ
+	if current.Status != order.Status && !models.CanTransitionOrder(current.Status, order.Status) {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.InvalidTransition(current.Status, order.Status, models.NextOrderStatuses(current.Status))
+	}
+	itemsChanged := !sameItems(current.Items, order.Items)
+	if itemsChanged && !models.OrderHoldsStock(current.Status) {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.Conflict("cannot change items of a %s order", current.Status)
+	}
+	movements, err := adjustStock(ctx, tx, stockDeltas(current, order))
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := recordStock(ctx, tx, movements, stockReason(current, order), order.ID, order.UpdatedAt); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if current.Status != order.Status && models.OrderReleasesCoupon(order.Status) {
+		if err := releaseCoupon(ctx, tx, current); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
 		UPDATE orders 
-		SET book_id = ?, user_id = ?, quantity = ?, status = ?, updated_at = ?
+		SET user_id = ?, quantity = ?, status = ?, updated_at = ?
 		WHERE id = ?`,
-		order.BookID, order.UserID, order.Quantity, order.Status, order.UpdatedAt, order.ID)
+		order.UserID, order.Quantity, order.Status, order.UpdatedAt, order.ID)
 	if err != nil {
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
-			if mysqlErr.Number == 1452 {
-				// foreign key violation
-				return nil, errors.New("foreign key constraint fails: book_id does not exist")
-			}
-		}
-		return nil, err
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to update order: %w", err)
 	}
 	// Kiểm tra có hàng nào bị ảnh hưởng không
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
 		return nil, err
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no order upadted with id %d", order.ID)
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, apperror.NotFound("no order updated with id %d", order.ID)
+	}
+	// Dòng giữ nguyên thì giữ giá cũ, chỉ dòng mới lấy giá hiện tại; coupon đã chốt lúc tạo order
+	order.CouponCode, order.Discount = current.CouponCode, current.Discount
+	if err := priceItems(ctx, tx, order, current.Items); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	// Đổi items thì coupon được kiểm tra lại (vd: giá trị tối thiểu) và giảm giá tính theo items mới
+	if itemsChanged && order.CouponCode != "" && !models.OrderReleasesCoupon(order.Status) {
+		if err := coupon.Reapply(ctx, tx, order); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE `orders` SET `discount` = ? WHERE `id` = ?", order.Discount, order.ID); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, fmt.Errorf("failed to update order discount: %w", err)
+		}
+	}
+	if itemsChanged {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM `order_items` WHERE `order_id` = ?", order.ID); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, fmt.Errorf("failed to replace order items: %w", err)
+		}
+		if err := r.insertItems(ctx, tx, order); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return order, nil
 }
 
+func (r *orderRepo) UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return err
+	}
+	current, err := LockOrder(ctx, tx, id)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	// Chặn hai request đổi trạng thái cùng lúc (vd: ship và cancel)
+	if current.Status != from {
+		txutil.Rollback(ctx, tx, r.logger)
+		return apperror.Conflict("order %d is no longer %s", id, from)
+	}
+	if models.OrderReleasesStock(from, to) {
+		movements, err := adjustStock(ctx, tx, releaseDeltas(current))
+		if err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return err
+		}
+		if err := recordStock(ctx, tx, movements, models.StockCancellation, id, updatedAt); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return err
+		}
+	}
+	if models.OrderReleasesCoupon(to) {
+		if err := releaseCoupon(ctx, tx, current); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `orders` SET `status` = ?, `updated_at` = ? WHERE `id` = ?", to, updatedAt, id); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// releaseCoupon trả lại lượt dùng coupon của order khi huỷ, hoàn tiền hoặc xoá;
+// order không có coupon_code thì bỏ qua, không cần query.
+func releaseCoupon(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	if order.CouponCode == "" {
+		return nil
+	}
+	return coupon.ReleaseOrder(ctx, tx, order.ID)
+}
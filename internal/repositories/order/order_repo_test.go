@@ -1,16 +1,22 @@
 package repositories_test
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	repositories "github.com/maithuc2003/re-book-api/internal/repositories/order"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // fakeBadResult dùng để mô phỏng lỗi khi gọi LastInsertId
@@ -23,15 +29,144 @@ func (f *fakeBadResult) RowsAffected() (int64, error) {
 	return 1, nil
 }
 
-func TestOrderRepo_Create(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("failed to open sqlmock database: %s", err)
+var orderColumns = []string{"id", "user_id", "quantity", "status", "coupon_code", "discount", "ordered_at", "updated_at"}
+
+// items dựng các dòng order từ cặp (book_id, quantity), giá lấy theo unitPrice
+func items(pairs ...int) []models.OrderItem {
+	var out []models.OrderItem
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out = append(out, models.OrderItem{BookID: pairs[i], Quantity: pairs[i+1],
+			UnitPrice: unitPrice, Currency: "VND", LineTotal: unitPrice * int64(pairs[i+1])})
 	}
-	defer db.Close()
+	return out
+}
+
+// newOrder dựng order đã chuẩn hoá như service truyền xuống repository
+func newOrder(id int, status string, at time.Time, lines []models.OrderItem) *models.Order {
+	o := &models.Order{ID: id, UserID: 200 + id, Status: status, Items: lines, OrderedAt: at, UpdatedAt: at}
+	o.NormalizeItems()
+	return o
+}
+
+// withCoupon gắn coupon_code cho order đã tạo bằng newOrder
+func withCoupon(o *models.Order, code string) *models.Order {
+	o.CouponCode = code
+	return o
+}
+
+// unitPrice là giá của mọi sách trong các test order
+const unitPrice int64 = 15000
+
+// expectPrices giả lập đọc giá hiện tại của các sách theo thứ tự trong order
+func expectPrices(m sqlmock.Sqlmock, bookIDs ...driver.Value) {
+	rows := sqlmock.NewRows([]string{"id", "price", "currency"})
+	for _, id := range bookIDs {
+		rows.AddRow(id, unitPrice, "VND")
+	}
+	m.ExpectQuery("SELECT `id`, `price`, `currency` FROM `books` WHERE `id` IN").
+		WithArgs(bookIDs...).
+		WillReturnRows(rows)
+}
+
+// expectItems giả lập loadItems cho các order cho trước
+func expectItems(m sqlmock.Sqlmock, rows [][3]int, orderIDs ...driver.Value) {
+	r := sqlmock.NewRows([]string{"order_id", "book_id", "quantity", "unit_price", "currency"})
+	for _, row := range rows {
+		r.AddRow(row[0], row[1], row[2], unitPrice, "VND")
+	}
+	m.ExpectQuery("SELECT `order_id`, `book_id`, `quantity`, `unit_price`, `currency` FROM `order_items` WHERE `order_id` IN").
+		WithArgs(orderIDs...).
+		WillReturnRows(r)
+}
+
+// expectLockOrder giả lập lockOrder trả về order với status và items cho trước
+func expectLockOrder(m sqlmock.Sqlmock, o *models.Order) {
+	var couponCode driver.Value
+	if o.CouponCode != "" {
+		couponCode = o.CouponCode
+	}
+	m.ExpectQuery("SELECT .* FROM `orders` WHERE id = \\? FOR UPDATE").
+		WithArgs(o.ID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(o.ID, o.UserID, o.Quantity, o.Status, couponCode, o.Discount, o.OrderedAt, o.UpdatedAt))
+	var rows [][3]int
+	for _, item := range o.Items {
+		rows = append(rows, [3]int{o.ID, item.BookID, item.Quantity})
+	}
+	expectItems(m, rows, o.ID)
+}
+
+// expectLockCoupon giả lập coupon 5 giảm 10%, không giới hạn sách, với giá trị order tối thiểu minOrderValue
+func expectLockCoupon(m sqlmock.Sqlmock, code string, minOrderValue int64) {
+	m.ExpectQuery("FROM `coupons` WHERE `code` = \\? FOR UPDATE").WithArgs(code).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "type", "value", "currency", "min_order_value", "starts_at", "ends_at",
+			"max_redemptions", "max_per_user", "redemptions", "created_at", "updated_at"}).
+			AddRow(5, code, "percentage", 10, "VND", minOrderValue, nil, nil, 100, 0, 1, time.Time{}, time.Time{}))
+}
+
+// expectReleaseCoupon giả lập trả lại lượt dùng coupon couponID đã ghi cho order
+func expectReleaseCoupon(m sqlmock.Sqlmock, orderID, couponID int) {
+	m.ExpectQuery("SELECT `coupon_id` FROM `coupon_redemptions` WHERE `order_id` = \\? FOR UPDATE").
+		WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"coupon_id"}).AddRow(couponID))
+	m.ExpectExec("DELETE FROM `coupon_redemptions` WHERE `order_id` = \\?").
+		WithArgs(orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectExec("UPDATE `coupons` SET `redemptions` = `redemptions` - 1 WHERE `id` = \\? AND `redemptions` > 0").
+		WithArgs(couponID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+const lockBookQuery = "SELECT stock FROM books WHERE id = \\? FOR UPDATE"
+
+// expectLockBook giả lập lock một row books không có reservation nào
+func expectLockBook(m sqlmock.Sqlmock, bookID, stock int) {
+	expectLockBookReserved(m, bookID, stock, 0)
+}
+
+// expectLockBookReserved giả lập stock.LockAvailable: lock sách rồi đọc số lượng reservation đang giữ
+func expectLockBookReserved(m sqlmock.Sqlmock, bookID, stock, reserved int) {
+	m.ExpectQuery(lockBookQuery).
+		WithArgs(bookID).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(stock))
+	m.ExpectQuery("SELECT COALESCE\\(SUM\\(`quantity`\\), 0\\) FROM `reservations` WHERE `book_id` = \\? AND `status` = 'active' AND `expires_at` > \\?").
+		WithArgs(bookID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(reserved))
+}
 
-	repo := repositories.NewOrderRepo(db)
+// expectBackorderable giả lập kiểm tra backorder_policy của các sách thiếu hàng;
+// allowed là số sách trong bookIDs cho phép đặt trước
+func expectBackorderable(m sqlmock.Sqlmock, allowed int, bookIDs ...driver.Value) {
+	m.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `books` WHERE `backorder_policy` <> \\? AND `id` IN").
+		WithArgs(append([]driver.Value{"none"}, bookIDs...)...).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(allowed))
+}
+
+// expectUpdateStock giả lập cộng delta vào stock của một sách
+func expectUpdateStock(m sqlmock.Sqlmock, bookID, delta int) {
+	m.ExpectExec("UPDATE books SET stock = stock \\+ \\? WHERE id = \\?").
+		WithArgs(delta, bookID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectMovement giả lập ghi một dòng sổ cái tồn kho cho order
+func expectMovement(m sqlmock.Sqlmock, bookID, delta int, reason string, orderID, stockAfter int) {
+	m.ExpectExec("INSERT INTO `stock_movements`").
+		WithArgs(bookID, delta, reason, orderID, "", stockAfter, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectAdjustStock(m sqlmock.Sqlmock, bookID, stock, delta int) {
+	expectLockBook(m, bookID, stock)
+	expectUpdateStock(m, bookID, delta)
+}
+
+func TestOrderRepo_Create(t *testing.T) {
 	fakeTime := time.Now()
+	single := func(bookID, quantity int) *models.Order {
+		o := &models.Order{UserID: 2, Status: "pending", Items: items(bookID, quantity), OrderedAt: fakeTime}
+		o.NormalizeItems()
+		return o
+	}
 
 	tests := []struct {
 		name       string
@@ -39,35 +174,183 @@ func TestOrderRepo_Create(t *testing.T) {
 		prepare    func(sqlmock.Sqlmock)
 		expectErr  bool
 		errMessage string
+		errIs      error
+		shortages  []apperror.StockShortage
 		checkID    int
+		// checkStatus là status order sau khi tạo, để trống là pending
+		checkStatus string
+		// checkTotal là tổng tiền theo giá đã chốt
+		checkTotal int64
 	}{
 		{
 			name:  "Success",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 3, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 3),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+				expectAdjustStock(mock, 1, 10, -3)
+				expectPrices(mock, 1)
 				mock.ExpectExec("INSERT INTO orders").
-					WithArgs(1, 2, 3, "pending", fakeTime).
+					WithArgs(2, 3, "pending", nil, 0, fakeTime).
 					WillReturnResult(sqlmock.NewResult(1, 1))
-				mock.ExpectExec("UPDATE books SET stock").
-					WithArgs(3, 1).
+				mock.ExpectExec("INSERT INTO `order_items` \\(`order_id`, `book_id`, `quantity`, `unit_price`, `currency`\\) VALUES \\(\\?, \\?, \\?, \\?, \\?\\)$").
+					WithArgs(1, 1, 3, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectMovement(mock, 1, -3, "order", 1, 7)
+				mock.ExpectCommit()
+			},
+			expectErr:  false,
+			checkID:    1,
+			checkTotal: 3 * unitPrice,
+		},
+		{
+			name: "Multiple items lock books in ascending order",
+			order: func() *models.Order {
+				o := &models.Order{UserID: 2, Status: "pending", Items: items(9, 1, 4, 2), OrderedAt: fakeTime}
+				o.NormalizeItems()
+				return o
+			}(),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockBook(mock, 4, 5)
+				expectLockBook(mock, 9, 5)
+				expectUpdateStock(mock, 4, -2)
+				expectUpdateStock(mock, 9, -1)
+				expectPrices(mock, 9, 4)
+				mock.ExpectExec("INSERT INTO orders").
+					WithArgs(2, 3, "pending", nil, 0, fakeTime).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+				mock.ExpectExec("INSERT INTO `order_items` .* VALUES \\(\\?, \\?, \\?, \\?, \\?\\), \\(\\?, \\?, \\?, \\?, \\?\\)").
+					WithArgs(7, 9, 1, unitPrice, "VND", 7, 4, 2, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 2))
+				expectMovement(mock, 4, -2, "order", 7, 3)
+				expectMovement(mock, 9, -1, "order", 7, 4)
+				mock.ExpectCommit()
+			},
+			checkID:    7,
+			checkTotal: 3 * unitPrice,
+		},
+		{
+			name: "Coupon discount is recorded with the order",
+			order: func() *models.Order {
+				o := single(1, 3)
+				o.CouponCode = "SALE"
+				return o
+			}(),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectAdjustStock(mock, 1, 10, -3)
+				expectPrices(mock, 1)
+				mock.ExpectQuery("FROM `coupons` WHERE `code` = \\? FOR UPDATE").WithArgs("SALE").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "code", "type", "value", "currency", "min_order_value", "starts_at", "ends_at",
+						"max_redemptions", "max_per_user", "redemptions", "created_at", "updated_at"}).
+						AddRow(5, "SALE", "percentage", 10, "VND", 0, nil, nil, 100, 0, 99, fakeTime, fakeTime))
+				mock.ExpectQuery("FROM `coupon_books`").WithArgs(5, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"coupon_id", "kind", "id"}))
+				mock.ExpectExec("UPDATE `coupons` SET `redemptions` = `redemptions` \\+ 1").WithArgs(5).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO orders").
+					WithArgs(2, 3, "pending", "SALE", 4500, fakeTime).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectExec("INSERT INTO `order_items`").
+					WithArgs(3, 1, 3, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `coupon_redemptions`").
+					WithArgs(5, 3, 2, 4500, fakeTime).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectMovement(mock, 1, -3, "order", 3, 7)
 				mock.ExpectCommit()
 			},
-			expectErr: false,
-			checkID:   1,
+			checkID:    3,
+			checkTotal: 3*unitPrice - 4500,
+		},
+		{
+			name: "Items priced in different currencies",
+			order: func() *models.Order {
+				o := &models.Order{UserID: 2, Status: "pending", Items: items(1, 1, 2, 1), OrderedAt: fakeTime}
+				o.NormalizeItems()
+				return o
+			}(),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockBook(mock, 1, 10)
+				expectLockBook(mock, 2, 10)
+				expectUpdateStock(mock, 1, -1)
+				expectUpdateStock(mock, 2, -1)
+				mock.ExpectQuery("SELECT `id`, `price`, `currency` FROM `books` WHERE `id` IN").
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "price", "currency"}).AddRow(1, 15000, "VND").AddRow(2, 1299, "USD"))
+				mock.ExpectRollback()
+			},
+			expectErr:  true,
+			errMessage: "order items must share one currency, got VND and USD",
+			errIs:      apperror.ErrUnprocessable,
+		},
+		{
+			name:  "Book without a price",
+			order: single(1, 1),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectAdjustStock(mock, 1, 10, -1)
+				mock.ExpectQuery("SELECT `id`, `price`, `currency` FROM `books` WHERE `id` IN").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "price", "currency"}).AddRow(1, nil, "VND"))
+				mock.ExpectRollback()
+			},
+			expectErr:  true,
+			errMessage: "book 1 has no price yet; set one with PATCH /books/1 before ordering it",
+			errIs:      apperror.ErrUnprocessable,
+		},
+		{
+			name: "Every short item is reported",
+			order: func() *models.Order {
+				o := &models.Order{UserID: 2, Status: "pending", Items: items(1, 5, 2, 1, 3, 4), OrderedAt: fakeTime}
+				o.NormalizeItems()
+				return o
+			}(),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockBook(mock, 1, 2)
+				expectLockBook(mock, 2, 10)
+				expectLockBook(mock, 3, 0)
+				expectBackorderable(mock, 1, 1, 3)
+				mock.ExpectRollback()
+			},
+			expectErr:  true,
+			errMessage: "not enough stock available",
+			errIs:      apperror.ErrInsufficientStock,
+			shortages: []apperror.StockShortage{
+				{BookID: 1, Requested: 5, Available: 2},
+				{BookID: 3, Requested: 4, Available: 0},
+			},
+		},
+		{
+			name:  "Short books that allow backorder are accepted without stock",
+			order: single(1, 3),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockBook(mock, 1, 1)
+				expectBackorderable(mock, 1, 1)
+				expectPrices(mock, 1)
+				mock.ExpectExec("INSERT INTO orders").
+					WithArgs(2, 3, "backordered", nil, 0, fakeTime).
+					WillReturnResult(sqlmock.NewResult(4, 1))
+				mock.ExpectExec("INSERT INTO `order_items`").
+					WithArgs(4, 1, 3, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			checkID:     4,
+			checkStatus: "backordered",
 		},
 		{
 			name:  "Insert order error",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 1, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 1),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+				expectAdjustStock(mock, 1, 10, -1)
+				expectPrices(mock, 1)
 				mock.ExpectExec("INSERT INTO orders").
-					WithArgs(1, 2, 1, "pending", fakeTime).
+					WithArgs(2, 1, "pending", nil, 0, fakeTime).
 					WillReturnError(errors.New("insert error")) // 👈 Lỗi tại đây
 				mock.ExpectRollback() // 👈 rollback khi lỗi
 			},
@@ -75,54 +358,104 @@ func TestOrderRepo_Create(t *testing.T) {
 			errMessage: "failed to create order",
 		},
 		{
-			name:  "Rollback error",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 1, Status: "pending", OrderedAt: fakeTime},
+			name:  "Insert items foreign key violation",
+			order: single(1, 1),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectAdjustStock(mock, 1, 10, -1)
+				expectPrices(mock, 1)
+				mock.ExpectExec("INSERT INTO orders").
+					WithArgs(2, 1, "pending", nil, 0, fakeTime).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `order_items`").
+					WillReturnError(&mysql.MySQLError{Number: 1452, Message: "a foreign key constraint fails"})
+				mock.ExpectRollback()
+			},
+			expectErr:  true,
+			errMessage: "foreign key constraint fails",
+			errIs:      apperror.ErrForeignKey,
+		},
+		{
+			name:  "Stock ledger error rolls back the order",
+			order: single(1, 1),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
+				expectAdjustStock(mock, 1, 10, -1)
+				expectPrices(mock, 1)
+				mock.ExpectExec("INSERT INTO orders").
+					WithArgs(2, 1, "pending", nil, 0, fakeTime).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `order_items`").
+					WithArgs(1, 1, 1, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `stock_movements`").
+					WillReturnError(errors.New("ledger error"))
+				mock.ExpectRollback()
+			},
+			expectErr:  true,
+			errMessage: "failed to record stock movement",
+		},
+		{
+			name:  "Rollback error is only logged",
+			order: single(1, 1),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockBookQuery).WithArgs(1).
 					WillReturnError(errors.New("query error"))
 
 				mock.ExpectRollback().WillReturnError(errors.New("rollback failed")) // 👈 lỗi rollback
 			},
 			expectErr:  true,
-			errMessage: "rollback failed", // vì err trả về thẳng luôn
+			errMessage: "failed to fetch current stock",
 		},
 		{
 			name:  "Not enough stock",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 5, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 5),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(2))
+				expectLockBook(mock, 1, 2)
+				expectBackorderable(mock, 0, 1)
 				mock.ExpectRollback()
 			},
 			expectErr:  true,
 			errMessage: "not enough stock available",
+			errIs:      apperror.ErrInsufficientStock,
+			shortages:  []apperror.StockShortage{{BookID: 1, Requested: 5, Available: 2}},
 		},
 		{
-			name:  "QueryRow error",
-			order: &models.Order{BookID: 999, UserID: 2, Quantity: 1, Status: "pending", OrderedAt: fakeTime},
+			name:  "Stock held by reservations is not sold",
+			order: single(1, 2),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(999).
-					WillReturnError(errors.New("query error"))
+				expectLockBookReserved(mock, 1, 5, 4)
+				expectBackorderable(mock, 0, 1)
+				mock.ExpectRollback()
+			},
+			expectErr: true,
+			errIs:     apperror.ErrInsufficientStock,
+			shortages: []apperror.StockShortage{{BookID: 1, Requested: 2, Available: 1}},
+		},
+		{
+			name:  "Book not found",
+			order: single(404, 1),
+			prepare: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockBookQuery).WithArgs(404).
+					WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
 			},
 			expectErr:  true,
-			errMessage: "failed to fetch current stock",
+			errMessage: "book with ID 404 not found",
+			errIs:      apperror.ErrNotFound,
 		},
 		{
 			name:  "Update stock error",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 2, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 2),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
-				mock.ExpectExec("INSERT INTO orders").
-					WithArgs(1, 2, 2, "pending", fakeTime).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectLockBook(mock, 1, 10)
 				mock.ExpectExec("UPDATE books SET stock").
-					WithArgs(2, 1).
+					WithArgs(-2, 1).
 					WillReturnError(errors.New("update error"))
 				mock.ExpectRollback()
 			},
@@ -131,17 +464,18 @@ func TestOrderRepo_Create(t *testing.T) {
 		},
 		{
 			name:  "Commit error",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 1, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 1),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+				expectAdjustStock(mock, 1, 10, -1)
+				expectPrices(mock, 1)
 				mock.ExpectExec("INSERT INTO orders").
-					WithArgs(1, 2, 1, "pending", fakeTime).
+					WithArgs(2, 1, "pending", nil, 0, fakeTime).
 					WillReturnResult(sqlmock.NewResult(1, 1))
-				mock.ExpectExec("UPDATE books SET stock").
-					WithArgs(1, 1).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `order_items`").
+					WithArgs(1, 1, 1, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectMovement(mock, 1, -1, "order", 1, 9)
 				mock.ExpectCommit().WillReturnError(errors.New("commit error"))
 			},
 			expectErr:  true,
@@ -149,28 +483,22 @@ func TestOrderRepo_Create(t *testing.T) {
 		},
 		{
 			name:  "LastInsertId error",
-			order: &models.Order{BookID: 1, UserID: 2, Quantity: 1, Status: "pending", OrderedAt: fakeTime},
+			order: single(1, 1),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT stock FROM books").WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
-
+				expectAdjustStock(mock, 1, 10, -1)
+				expectPrices(mock, 1)
 				mock.ExpectExec("INSERT INTO orders").
-					WithArgs(1, 2, 1, "pending", fakeTime).
+					WithArgs(2, 1, "pending", nil, 0, fakeTime).
 					WillReturnResult(&fakeBadResult{}) // 👈 dùng struct giả ở đây
-
-				mock.ExpectExec("UPDATE books SET stock").
-					WithArgs(1, 1).
-					WillReturnResult(sqlmock.NewResult(1, 1))
-				mock.ExpectCommit()
-
+				mock.ExpectRollback()
 			},
 			expectErr:  true,
 			errMessage: "failed to retrieve inserted order ID",
 		},
 		{
 			name:  "Begin transaction error",
-			order: &models.Order{BookID: 1, UserID: 1, Quantity: 1, Status: "pending", OrderedAt: time.Now()},
+			order: single(1, 1),
 			prepare: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
 			},
@@ -181,77 +509,138 @@ func TestOrderRepo_Create(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
 			tc.prepare(mock)
-			err := repo.Create(tc.order)
+			err = repo.Create(context.Background(), tc.order)
 
 			if tc.expectErr {
 				assert.Error(t, err)
 				if tc.errMessage != "" {
 					assert.Contains(t, err.Error(), tc.errMessage)
 				}
+				if tc.errIs != nil {
+					assert.ErrorIs(t, err, tc.errIs)
+				}
+				if tc.shortages != nil {
+					var stockErr *apperror.InsufficientStockError
+					require.True(t, errors.As(err, &stockErr))
+					assert.Equal(t, tc.shortages, stockErr.Items)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tc.checkID, tc.order.ID)
+				if tc.checkStatus == "" {
+					tc.checkStatus = "pending"
+				}
+				assert.Equal(t, tc.checkStatus, tc.order.Status)
+				if tc.checkTotal != 0 {
+					assert.Equal(t, tc.checkTotal, tc.order.Total)
+					assert.Equal(t, "VND", tc.order.Currency)
+				}
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-
 }
 
 func TestOrderRepo_GetAllOrders(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	repo := repositories.NewOrderRepo(db)
-	fakeTime := time.Now()
+	fakeTime := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
 
 	tests := []struct {
-		name         string
-		prepareMock  func(sqlmock.Sqlmock)
-		expectedLen  int
-		expectedErr  error
-		assertErrMsg string
+		name          string
+		filter        models.OrderFilter
+		prepareMock   func(sqlmock.Sqlmock)
+		expected      []*models.Order
+		expectedTotal int
+		expectNext    bool
+		expectedErr   error
+		errIs         error
+		assertErrMsg  string
 	}{
 		{
-			name: "Success",
+			name: "Success - default sort newest first, items attached",
 			prepareMock: func(m sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{
-					"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-				}).AddRow(1, 101, 201, 2, "pending", fakeTime, fakeTime).
-					AddRow(2, 102, 202, 1, "completed", fakeTime, fakeTime)
-
-				m.ExpectQuery("SELECT \\* FROM `orders`").
+				m.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `orders`$").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				rows := sqlmock.NewRows(orderColumns).
+					AddRow(2, 202, 3, "shipped", nil, 0, fakeTime, fakeTime).
+					AddRow(1, 201, 2, "pending", nil, 0, fakeTime, fakeTime)
+				m.ExpectQuery("FROM `orders` ORDER BY ordered_at DESC, id DESC LIMIT \\?").
+					WithArgs(21).
+					WillReturnRows(rows)
+				expectItems(m, [][3]int{{1, 101, 2}, {2, 102, 1}, {2, 103, 2}}, 2, 1)
+			},
+			expected: []*models.Order{
+				newOrder(2, "shipped", fakeTime, items(102, 1, 103, 2)),
+				newOrder(1, "pending", fakeTime, items(101, 2)),
+			},
+			expectedTotal: 2,
+		},
+		{
+			name:   "Filters and limit are pushed down, next cursor returned",
+			filter: models.OrderFilter{Params: pagination.Params{Limit: 1, Sort: "id"}, Status: "pending", UserID: 201, BookID: 101, From: &fakeTime},
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `orders` WHERE `status` = \\? AND `user_id` = \\? AND EXISTS \\(SELECT 1 FROM `order_items` WHERE .*`book_id` = \\?\\) AND `ordered_at` >= \\?").
+					WithArgs("pending", 201, 101, fakeTime).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+				rows := sqlmock.NewRows(orderColumns).
+					AddRow(1, 201, 2, "pending", nil, 0, fakeTime, fakeTime).
+					AddRow(3, 201, 1, "pending", nil, 0, fakeTime, fakeTime)
+				m.ExpectQuery("WHERE `status` = \\? AND `user_id` = \\? AND EXISTS .* AND `ordered_at` >= \\? ORDER BY id ASC LIMIT \\?").
+					WithArgs("pending", 201, 101, fakeTime, 2).
 					WillReturnRows(rows)
+				expectItems(m, [][3]int{{1, 101, 2}, {3, 101, 1}}, 1, 3)
 			},
-			expectedLen: 2,
-			expectedErr: nil,
+			expected:      []*models.Order{newOrder(1, "pending", fakeTime, items(101, 2))},
+			expectedTotal: 5,
+			expectNext:    true,
+		},
+		{
+			name:         "Sort field not in whitelist",
+			filter:       models.OrderFilter{Params: pagination.Params{Sort: "status; DROP TABLE orders"}},
+			prepareMock:  func(m sqlmock.Sqlmock) {},
+			expectedErr:  errors.New("validation"),
+			errIs:        apperror.ErrValidation,
+			assertErrMsg: "sort must be one of",
 		},
 		{
 			name: "Query error",
 			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery("SELECT \\* FROM `orders`").
+				m.ExpectQuery("SELECT COUNT").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				m.ExpectQuery("FROM `orders`").
 					WillReturnError(errors.New("query error"))
 			},
-			expectedLen:  0,
 			expectedErr:  errors.New("query error"),
 			assertErrMsg: "failed to query orders",
 		},
+		{
+			name: "Items query error",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT COUNT").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				m.ExpectQuery("FROM `orders`").
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(1, 201, 2, "pending", nil, 0, fakeTime, fakeTime))
+				m.ExpectQuery("FROM `order_items`").
+					WillReturnError(errors.New("items error"))
+			},
+			expectedErr:  errors.New("items error"),
+			assertErrMsg: "failed to query order items",
+		},
 		{
 			name: "Scan error",
 			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT COUNT").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 				// thiếu 1 cột intentionally → lỗi scan
-				rows := sqlmock.NewRows([]string{
-					"id", "book_id", "user_id", "quantity", "status", "ordered_at",
-				}).AddRow(1, 101, 201, 2, "pending", fakeTime)
-
-				m.ExpectQuery("SELECT \\* FROM `orders`").
+				rows := sqlmock.NewRows(orderColumns[:5]).AddRow(1, 201, 2, "pending", fakeTime)
+				m.ExpectQuery("FROM `orders`").
 					WillReturnRows(rows)
 			},
-			expectedLen:  0,
 			expectedErr:  errors.New("scan error"),
 			assertErrMsg: "", // error không custom, chỉ assert.Error
 		},
@@ -259,33 +648,38 @@ func TestOrderRepo_GetAllOrders(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
 			tc.prepareMock(mock)
 
-			orders, err := repo.GetAllOrders()
+			page, err := repo.GetAllOrders(context.Background(), tc.filter)
 
 			if tc.expectedErr != nil {
 				assert.Error(t, err)
 				if tc.assertErrMsg != "" {
 					assert.Contains(t, err.Error(), tc.assertErrMsg)
 				}
-				assert.Nil(t, orders)
+				if tc.errIs != nil {
+					assert.ErrorIs(t, err, tc.errIs)
+				}
+				assert.Nil(t, page)
 			} else {
 				assert.NoError(t, err)
-				assert.Len(t, orders, tc.expectedLen)
+				assert.Equal(t, tc.expected, page.Data)
+				assert.Equal(t, tc.expectedTotal, page.Total)
+				assert.Equal(t, tc.expectNext, page.NextCursor != "")
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
-
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestOrderRepo_GetByOrderIDƠ(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	repo := repositories.NewOrderRepo(db)
+func TestOrderRepo_GetByOrderID(t *testing.T) {
 	fakeTime := time.Now()
+	selectOrder := "SELECT `id`, `user_id`, `quantity`, `status`, `coupon_code`, `discount`, `ordered_at`, `updated_at` FROM `orders` WHERE id = ?"
 
 	tests := []struct {
 		name        string
@@ -296,31 +690,33 @@ func TestOrderRepo_GetByOrderIDƠ(t *testing.T) {
 		expected    *models.Order
 	}{
 		{
-			name:    "Success",
+			name:    "Success - single item keeps legacy fields",
 			orderID: 1,
 			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery("SELECT `id`, `book_id`, `user_id`, `quantity`, `status`,`ordered_at`, `updated_at` FROM `orders` WHERE id = ?").
+				m.ExpectQuery(selectOrder).
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-					}).AddRow(1, 101, 201, 3, "pending", fakeTime, fakeTime))
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(1, 201, 3, "pending", nil, 0, fakeTime, fakeTime))
+				expectItems(m, [][3]int{{1, 101, 3}}, 1)
 			},
 			expectErr: false,
-			expected: &models.Order{
-				ID:        1,
-				BookID:    101,
-				UserID:    201,
-				Quantity:  3,
-				Status:    "pending",
-				OrderedAt: fakeTime,
-				UpdatedAt: fakeTime,
+			expected:  newOrder(1, "pending", fakeTime, items(101, 3)),
+		},
+		{
+			name:    "Success - multiple items",
+			orderID: 2,
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(selectOrder).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(2, 202, 3, "paid", nil, 0, fakeTime, fakeTime))
+				expectItems(m, [][3]int{{2, 101, 1}, {2, 102, 2}}, 2)
 			},
+			expected: newOrder(2, "paid", fakeTime, items(101, 1, 102, 2)),
 		},
 		{
 			name:    "Not found",
 			orderID: 2,
 			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery("SELECT `id`, `book_id`, `user_id`, `quantity`, `status`,`ordered_at`, `updated_at` FROM `orders` WHERE id = ?").
+				m.ExpectQuery(selectOrder).
 					WithArgs(2).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -332,23 +728,23 @@ func TestOrderRepo_GetByOrderIDƠ(t *testing.T) {
 			name:    "DB error",
 			orderID: 3,
 			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery("SELECT `id`, `book_id`, `user_id`, `quantity`, `status`,`ordered_at`, `updated_at` FROM `orders` WHERE id = ?").
+				m.ExpectQuery(selectOrder).
 					WithArgs(3).
 					WillReturnError(errors.New("some db error"))
 			},
 			expectErr:  true,
-			errMessage: "failed to fetch book",
+			errMessage: "failed to fetch order",
 			expected:   nil,
 		},
 		{
 			name:    "Scan error",
 			orderID: 4,
 			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery("SELECT `id`, `book_id`, `user_id`, `quantity`, `status`,`ordered_at`, `updated_at` FROM `orders` WHERE id = ?").
+				m.ExpectQuery(selectOrder).
 					WithArgs(4).
 					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", // thiếu cột intentionally
-					}).AddRow(4, 104, 204))
+						"id", "user_id", // thiếu cột intentionally
+					}).AddRow(4, 204))
 			},
 			expectErr:  true,
 			errMessage: "", // raw error nên không cần contains
@@ -357,8 +753,13 @@ func TestOrderRepo_GetByOrderIDƠ(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
 			tc.prepareMock(mock)
-			result, err := repo.GetByOrderID(tc.orderID)
+			result, err := repo.GetByOrderID(context.Background(), tc.orderID)
 
 			if tc.expectErr {
 				assert.Error(t, err)
@@ -370,131 +771,149 @@ func TestOrderRepo_GetByOrderIDƠ(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expected, result)
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestOrderRepo_DeleteByOrderID(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	repo := repositories.NewOrderRepo(db)
 	fakeTime := time.Now()
 	tests := []struct {
 		name        string
-		orderID     int
-		prepareMock func(sqlmock.Sqlmock)
+		current     *models.Order
+		prepareMock func(sqlmock.Sqlmock, *models.Order)
 		expectErr   bool
 		errContains string
-		expected    *models.Order
 	}{
 		{
-			name:    "Success",
-			orderID: 1,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				// Mock GetByOrderID
-				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").
-					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-					}).AddRow(1, 101, 201, 3, "pending", fakeTime, fakeTime))
-
-				// Mock DELETE
+			name:    "Pending order returns stock of every item",
+			current: newOrder(1, "pending", fakeTime, items(101, 3, 102, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				expectLockBook(m, 101, 7)
+				expectLockBook(m, 102, 0)
+				expectUpdateStock(m, 101, 3)
+				expectUpdateStock(m, 102, 1)
+				expectMovement(m, 101, 3, "cancellation", 1, 10)
+				expectMovement(m, 102, 1, "cancellation", 1, 1)
 				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
 					WithArgs(1).
 					WillReturnResult(sqlmock.NewResult(0, 1)) // 1 row affected
+				m.ExpectCommit()
 			},
-			expectErr: false,
-			expected: &models.Order{
-				ID:        1,
-				BookID:    101,
-				UserID:    201,
-				Quantity:  3,
-				Status:    "pending",
-				OrderedAt: fakeTime,
-				UpdatedAt: fakeTime,
+		},
+		{
+			name:    "Pending order releases coupon redemption",
+			current: withCoupon(newOrder(1, "pending", fakeTime, items(101, 3)), "SALE"),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				expectLockBook(m, 101, 7)
+				expectUpdateStock(m, 101, 3)
+				expectMovement(m, 101, 3, "cancellation", 1, 10)
+				expectReleaseCoupon(m, 1, 5)
+				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
 			},
 		},
 		{
-			name:    "GetByOrderID returns error",
-			orderID: 2,
-			prepareMock: func(m sqlmock.Sqlmock) {
+			name:    "Cancelled order whose coupon was already released",
+			current: withCoupon(newOrder(4, "cancelled", fakeTime, items(104, 1)), "SALE"),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectQuery("SELECT `coupon_id` FROM `coupon_redemptions`").
+					WithArgs(4).
+					WillReturnError(sql.ErrNoRows)
+				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
+					WithArgs(4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Delivered order keeps stock",
+			current: newOrder(5, "delivered", fakeTime, items(105, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
+					WithArgs(5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Order not found",
+			current: newOrder(2, "pending", fakeTime, nil),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
 				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").
 					WithArgs(2).
 					WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
 			},
 			expectErr:   true,
 			errContains: "not found",
-			expected:    nil,
 		},
 		{
 			name:    "Delete query fails",
-			orderID: 3,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				// Mock GetByOrderID success
-				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").
-					WithArgs(3).
-					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-					}).AddRow(3, 103, 203, 2, "pending", fakeTime, fakeTime))
-
-				// Mock DELETE fail
+			current: newOrder(3, "cancelled", fakeTime, items(103, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
 				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
 					WithArgs(3).
 					WillReturnError(errors.New("delete failed"))
+				m.ExpectRollback()
 			},
 			expectErr:   true,
 			errContains: "failed to delete order",
-			expected:    nil,
 		},
 		{
 			name:    "RowsAffected returns error",
-			orderID: 6,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				// Giả lập GetByOrderID thành công
-				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").
-					WithArgs(6).
-					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-					}).AddRow(6, 106, 206, 1, "pending", fakeTime, fakeTime))
-
+			current: newOrder(6, "shipped", fakeTime, items(106, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
 				// Giả lập DELETE trả về đối tượng .RowsAffected() lỗi
 				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
 					WithArgs(6).
 					WillReturnResult(sqlmock.NewErrorResult(errors.New("rows affected error")))
+				m.ExpectRollback()
 			},
 			expectErr:   true,
 			errContains: "rows affected error",
-			expected:    nil,
 		},
 
 		{
 			name:    "No rows affected",
-			orderID: 4,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				// Mock GetByOrderID success
-				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").
-					WithArgs(4).
-					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "book_id", "user_id", "quantity", "status", "ordered_at", "updated_at",
-					}).AddRow(4, 104, 204, 1, "completed", fakeTime, fakeTime))
-
+			current: newOrder(4, "refunded", fakeTime, items(104, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
 				// Mock DELETE returns 0 rows affected
 				m.ExpectExec("DELETE FROM `orders` WHERE id = ?").
 					WithArgs(4).
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectRollback()
 			},
 			expectErr:   true,
-			errContains: "no book found with id",
-			expected:    nil,
+			errContains: "no order found with id",
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			tc.prepareMock(mock)
-			result, err := repo.DeleteByOrderID(tc.orderID)
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
+			tc.prepareMock(mock, tc.current)
+			result, err := repo.DeleteByOrderID(context.Background(), tc.current.ID)
 
 			if tc.expectErr {
 				assert.Error(t, err)
@@ -504,152 +923,791 @@ func TestOrderRepo_DeleteByOrderID(t *testing.T) {
 				assert.Nil(t, result)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tc.expected, result)
+				assert.Equal(t, tc.current, result)
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestOrderRepo_UpdateByOrderID(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	repo := repositories.NewOrderRepo(db)
 	fakeTime := time.Now()
-	sampleOrder := &models.Order{
-		ID:        1,
-		BookID:    101,
-		UserID:    201,
-		Quantity:  2,
-		Status:    "completed",
-		UpdatedAt: fakeTime,
+	current := newOrder(1, "paid", fakeTime, items(101, 2))
+	// updated trả về bản sao của current với các thay đổi
+	updated := func(status string, lines []models.OrderItem) *models.Order {
+		return newOrder(1, status, fakeTime, lines)
+	}
+	expectUpdate := func(m sqlmock.Sqlmock, o *models.Order) *sqlmock.ExpectedExec {
+		return m.ExpectExec("UPDATE orders").
+			WithArgs(o.UserID, o.Quantity, o.Status, o.UpdatedAt, o.ID)
+	}
+	expectReplaceItems := func(m sqlmock.Sqlmock) {
+		m.ExpectExec("DELETE FROM `order_items` WHERE `order_id` = \\?").WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectExec("INSERT INTO `order_items`").
+			WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 	tests := []struct {
 		name        string
+		current     *models.Order
 		order       *models.Order
-		prepareMock func(sqlmock.Sqlmock)
-		expected    *models.Order
+		prepareMock func(sqlmock.Sqlmock, *models.Order)
 		expectErr   bool
 		errContains string
+		errIs       error
+		discount    int64
 	}{
 		{
-			name:  "Success",
-			order: sampleOrder,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectExec("UPDATE orders").
-					WithArgs(
-						sampleOrder.BookID,
-						sampleOrder.UserID,
-						sampleOrder.Quantity,
-						sampleOrder.Status,
-						sampleOrder.UpdatedAt,
-						sampleOrder.ID,
-					).
-					WillReturnResult(sqlmock.NewResult(0, 1)) // 1 row affected
+			name:    "Success - no stock change",
+			current: current,
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1)) // 1 row affected
+				m.ExpectCommit()
 			},
-			expected:    sampleOrder,
-			expectErr:   false,
-			errContains: "",
 		},
 		{
-			name:  "Foreign key violation",
-			order: sampleOrder,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				mysqlErr := &mysql.MySQLError{
-					Number:  1452,
-					Message: "Cannot add or update a child row: a foreign key constraint fails",
-				}
-				m.ExpectExec("UPDATE orders").
-					WithArgs(
-						sampleOrder.BookID,
-						sampleOrder.UserID,
-						sampleOrder.Quantity,
-						sampleOrder.Status,
-						sampleOrder.UpdatedAt,
-						sampleOrder.ID,
-					).
-					WillReturnError(mysqlErr)
-			},
-			expected:    nil,
+			name:    "Quantity increase takes the delta",
+			current: current,
+			order:   updated("paid", items(101, 5)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectAdjustStock(m, 101, 10, -3)
+				expectMovement(m, 101, -3, "order", 1, 7)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReplaceItems(m)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Quantity increase beyond stock",
+			current: current,
+			order:   updated("paid", items(101, 5)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectLockBook(m, 101, 2)
+				m.ExpectRollback()
+			},
+			expectErr:   true,
+			errContains: "not enough stock available",
+			errIs:       apperror.ErrInsufficientStock,
+		},
+		{
+			name:    "Book change locks both books in ascending order",
+			current: current,
+			order:   updated("paid", items(50, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectLockBook(m, 50, 4)
+				expectLockBook(m, 101, 0)
+				expectUpdateStock(m, 50, -2)
+				expectUpdateStock(m, 101, 2)
+				expectMovement(m, 50, -2, "order", 1, 2)
+				expectMovement(m, 101, 2, "order", 1, 2)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectPrices(m, 50)
+				expectReplaceItems(m)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "New book does not exist",
+			current: current,
+			order:   updated("paid", items(999, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectLockBook(m, 101, 0)
+				m.ExpectQuery(lockBookQuery).
+					WithArgs(999).
+					WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			expectErr: true,
+			errIs:     apperror.ErrNotFound,
+		},
+		{
+			name:    "Changing items recomputes the coupon discount",
+			current: withCoupon(updated("paid", items(101, 2)), "SALE"),
+			order:   updated("paid", items(101, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				lock := withCoupon(updated("paid", items(101, 2)), "SALE")
+				lock.Discount = 3000
+				expectLockOrder(m, lock)
+				expectAdjustStock(m, 101, 0, 1)
+				expectMovement(m, 101, 1, "order", 1, 1)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectLockCoupon(m, "SALE", 0)
+				m.ExpectQuery("FROM `coupon_books`").WithArgs(5, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"coupon_id", "kind", "id"}))
+				m.ExpectExec("UPDATE `coupon_redemptions` SET `discount` = \\? WHERE `order_id` = \\?").WithArgs(1500, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("UPDATE `orders` SET `discount` = \\? WHERE `id` = \\?").WithArgs(1500, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReplaceItems(m)
+				m.ExpectCommit()
+			},
+			discount: 1500,
+		},
+		{
+			name:    "Coupon minimum is checked again when items change",
+			current: withCoupon(updated("paid", items(101, 2)), "SALE"),
+			order:   updated("paid", items(101, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, withCoupon(updated("paid", items(101, 2)), "SALE"))
+				expectAdjustStock(m, 101, 0, 1)
+				expectMovement(m, 101, 1, "order", 1, 1)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectLockCoupon(m, "SALE", 20000)
+				m.ExpectRollback()
+			},
+			expectErr:   true,
+			errContains: "coupon SALE requires a minimum order value of 20000",
+			errIs:       apperror.ErrUnprocessable,
+		},
+		{
+			name:    "Cancelling returns stock",
+			current: updated("confirmed", items(101, 2)),
+			order:   updated("cancelled", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, updated("confirmed", items(101, 2)))
+				expectAdjustStock(m, 101, 0, 2)
+				expectMovement(m, 101, 2, "cancellation", 1, 2)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Items of a shipped order cannot change",
+			current: updated("shipped", items(101, 2)),
+			order:   updated("shipped", items(101, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, updated("shipped", items(101, 2)))
+				m.ExpectRollback()
+			},
+			expectErr:   true,
+			errContains: "cannot change items of a shipped order",
+			errIs:       apperror.ErrConflict,
+		},
+		{
+			name:    "Status changed concurrently to an incompatible one",
+			current: updated("delivered", items(101, 2)),
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, updated("delivered", items(101, 2)))
+				m.ExpectRollback()
+			},
+			expectErr: true,
+			errIs:     apperror.ErrConflict,
+		},
+		{
+			name:    "Insert items foreign key violation",
+			current: current,
+			order:   updated("paid", items(101, 1)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectAdjustStock(m, 101, 0, 1)
+				expectMovement(m, 101, 1, "order", 1, 1)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("DELETE FROM `order_items`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `order_items`").
+					WillReturnError(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"})
+				m.ExpectRollback()
+			},
 			expectErr:   true,
 			errContains: "foreign key constraint fails",
+			errIs:       apperror.ErrForeignKey,
 		},
 		{
-			name:  "Generic DB error",
-			order: sampleOrder,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectExec("UPDATE orders").
-					WithArgs(
-						sampleOrder.BookID,
-						sampleOrder.UserID,
-						sampleOrder.Quantity,
-						sampleOrder.Status,
-						sampleOrder.UpdatedAt,
-						sampleOrder.ID,
-					).
-					WillReturnError(errors.New("db error"))
+			name:    "Generic DB error",
+			current: current,
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectUpdate(m, o).WillReturnError(errors.New("db error"))
+				m.ExpectRollback()
 			},
-			expected:    nil,
 			expectErr:   true,
 			errContains: "db error",
 		},
 		{
-			name:  "RowsAffected error",
-			order: sampleOrder,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectExec("UPDATE orders").
-					WithArgs(
-						sampleOrder.BookID,
-						sampleOrder.UserID,
-						sampleOrder.Quantity,
-						sampleOrder.Status,
-						sampleOrder.UpdatedAt,
-						sampleOrder.ID,
-					).
-					WillReturnResult(sqlmock.NewErrorResult(errors.New("rows affected error")))
+			name:    "RowsAffected error",
+			current: current,
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewErrorResult(errors.New("rows affected error")))
+				m.ExpectRollback()
 			},
-			expected:    nil,
 			expectErr:   true,
 			errContains: "rows affected error",
 		},
 		{
-			name:  "No row updated",
-			order: sampleOrder,
-			prepareMock: func(m sqlmock.Sqlmock) {
-				m.ExpectExec("UPDATE orders").
-					WithArgs(
-						sampleOrder.BookID,
-						sampleOrder.UserID,
-						sampleOrder.Quantity,
-						sampleOrder.Status,
-						sampleOrder.UpdatedAt,
-						sampleOrder.ID,
-					).
-					WillReturnResult(sqlmock.NewResult(0, 0)) // no rows affected
-			},
-			expected:    nil,
+			name:    "No row updated",
+			current: current,
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, current)
+				expectUpdate(m, o).WillReturnResult(sqlmock.NewResult(0, 0)) // no rows affected
+				m.ExpectRollback()
+			},
 			expectErr:   true,
-			errContains: "no order upadted", // typo giữ nguyên nếu code bạn dùng từ này
+			errContains: "no order updated",
+		},
+		{
+			name:    "Order not found",
+			current: current,
+			order:   updated("paid", items(101, 2)),
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT .* FROM `orders` WHERE id = ?").WithArgs(1).WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			expectErr: true,
+			errIs:     apperror.ErrNotFound,
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			tc.prepareMock(mock)
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
+			tc.prepareMock(mock, tc.order)
 
-			result, err := repo.UpdateByOrderID(tc.order)
+			result, err := repo.UpdateByOrderID(context.Background(), tc.order)
 			if tc.expectErr {
 				assert.Error(t, err)
 				if tc.errContains != "" {
 					assert.Contains(t, err.Error(), tc.errContains)
 				}
+				if tc.errIs != nil {
+					assert.ErrorIs(t, err, tc.errIs)
+				}
 				assert.Nil(t, result)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tc.expected, result)
+				assert.Equal(t, tc.order, result)
+				assert.Equal(t, tc.discount, result.Discount)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepo_Create_ContextCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockBookQuery).WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = repo.Create(ctx, &models.Order{UserID: 2, Quantity: 1, Items: items(1, 1), Status: "pending"})
+
+	// Query bị huỷ ngay khi caller huỷ, database/sql tự rollback để nhả lock FOR UPDATE
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "rollback failed")
+	assert.Error(t, ctx.Err())
+}
+
+func TestOrderRepo_UpdateStatus(t *testing.T) {
+	fakeTime := time.Now()
+	tests := []struct {
+		name        string
+		current     *models.Order
+		from, to    string
+		prepareMock func(sqlmock.Sqlmock, *models.Order)
+		errIs       error
+		errContains string
+	}{
+		{
+			name:    "Ship keeps stock",
+			current: newOrder(1, "paid", fakeTime, items(101, 2)),
+			from:    "paid", to: "shipped",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectExec("UPDATE `orders` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+					WithArgs("shipped", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Cancel returns stock of every item",
+			current: newOrder(1, "pending", fakeTime, items(102, 1, 101, 2)),
+			from:    "pending", to: "cancelled",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				expectLockBook(m, 101, 0)
+				expectLockBook(m, 102, 0)
+				expectUpdateStock(m, 101, 2)
+				expectUpdateStock(m, 102, 1)
+				expectMovement(m, 101, 2, "cancellation", 1, 2)
+				expectMovement(m, 102, 1, "cancellation", 1, 1)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("cancelled", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Cancel releases coupon redemption",
+			current: withCoupon(newOrder(1, "pending", fakeTime, items(101, 2)), "SALE"),
+			from:    "pending", to: "cancelled",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				expectLockBook(m, 101, 0)
+				expectUpdateStock(m, 101, 2)
+				expectMovement(m, 101, 2, "cancellation", 1, 2)
+				expectReleaseCoupon(m, 1, 5)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("cancelled", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Refund releases coupon redemption",
+			current: withCoupon(newOrder(1, "delivered", fakeTime, items(101, 2)), "SALE"),
+			from:    "delivered", to: "refunded",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				expectReleaseCoupon(m, 1, 5)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("refunded", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Ship keeps coupon redemption",
+			current: withCoupon(newOrder(1, "paid", fakeTime, items(101, 2)), "SALE"),
+			from:    "paid", to: "shipped",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("shipped", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Coupon release fails",
+			current: withCoupon(newOrder(1, "delivered", fakeTime, items(101, 2)), "SALE"),
+			from:    "delivered", to: "refunded",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectQuery("SELECT `coupon_id` FROM `coupon_redemptions`").
+					WithArgs(1).
+					WillReturnError(errors.New("lock wait timeout"))
+				m.ExpectRollback()
+			},
+			errContains: "lock wait timeout",
+		},
+		{
+			name:    "Refund after delivery keeps stock",
+			current: newOrder(1, "delivered", fakeTime, items(101, 2)),
+			from:    "delivered", to: "refunded",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("refunded", fakeTime, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:    "Status changed concurrently",
+			current: newOrder(1, "cancelled", fakeTime, items(101, 2)),
+			from:    "paid", to: "shipped",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectRollback()
+			},
+			errIs:       apperror.ErrConflict,
+			errContains: "no longer paid",
+		},
+		{
+			name:    "DB error",
+			current: newOrder(1, "paid", fakeTime, items(101, 2)),
+			from:    "paid", to: "shipped",
+			prepareMock: func(m sqlmock.Sqlmock, o *models.Order) {
+				m.ExpectBegin()
+				expectLockOrder(m, o)
+				m.ExpectExec("UPDATE `orders` SET `status`").
+					WithArgs("shipped", fakeTime, 1).
+					WillReturnError(errors.New("db error"))
+				m.ExpectRollback()
+			},
+			errContains: "failed to update order status",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
+			tc.prepareMock(mock, tc.current)
+
+			err = repo.UpdateStatus(context.Background(), 1, tc.from, tc.to, fakeTime)
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
 			}
+			if tc.errIs != nil {
+				assert.ErrorIs(t, err, tc.errIs)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
+}
+
+func TestOrderRepo_CreateIdempotent(t *testing.T) {
+	now := time.Now()
+	key := models.IdempotencyKey{UserID: 2, Key: "retry-1", Fingerprint: "abc", ExpiredBefore: now.Add(-24 * time.Hour)}
+	keyColumns := []string{"fingerprint", "response", "created_at"}
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'retry-1' for key 'PRIMARY'"}
+	expectInsertKey := func(m sqlmock.Sqlmock) *sqlmock.ExpectedExec {
+		return m.ExpectExec("INSERT INTO `idempotency_keys` \\(`user_id`, `idempotency_key`, `fingerprint`, `created_at`\\)").
+			WithArgs(2, "retry-1", "abc", now)
+	}
+	expectSelectKey := func(m sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+		return m.ExpectQuery("SELECT `fingerprint`, `response`, `created_at` FROM `idempotency_keys` WHERE `user_id` = \\? AND `idempotency_key` = \\?").
+			WithArgs(2, "retry-1")
+	}
+
+	tests := []struct {
+		name             string
+		prepare          func(sqlmock.Sqlmock)
+		expectedReplayed bool
+		expectedID       int
+		errIs            error
+	}{
+		{
+			name: "New key creates the order and stores it",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAdjustStock(m, 1, 10, -2)
+				expectPrices(m, 1)
+				m.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(7, 1))
+				m.ExpectExec("INSERT INTO `order_items`").WillReturnResult(sqlmock.NewResult(1, 1))
+				expectMovement(m, 1, -2, "order", 7, 8)
+				m.ExpectExec("UPDATE `idempotency_keys` SET `order_id` = \\?, `response` = \\? WHERE `user_id` = \\? AND `idempotency_key` = \\?").
+					WithArgs(7, sqlmock.AnyArg(), 2, "retry-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectedID: 7,
+		},
+		{
+			name: "Same key and payload replays the stored order",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnError(duplicate)
+				m.ExpectRollback()
+				expectSelectKey(m).WillReturnRows(sqlmock.NewRows(keyColumns).
+					AddRow("abc", `{"id":5,"user_id":2,"quantity":2,"items":[{"book_id":1,"quantity":2}],"status":"pending"}`, now))
+			},
+			expectedReplayed: true,
+			expectedID:       5,
+		},
+		{
+			name: "Same key with another payload",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnError(duplicate)
+				m.ExpectRollback()
+				expectSelectKey(m).WillReturnRows(sqlmock.NewRows(keyColumns).AddRow("other", `{"id":5}`, now))
+			},
+			errIs: apperror.ErrUnprocessable,
+		},
+		{
+			name: "Expired key is released and the order is created again",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnError(duplicate)
+				m.ExpectRollback()
+				expectSelectKey(m).WillReturnRows(sqlmock.NewRows(keyColumns).AddRow("other", `{"id":5}`, now.Add(-48*time.Hour)))
+				m.ExpectExec("DELETE FROM `idempotency_keys` WHERE `user_id` = \\? AND `idempotency_key` = \\? AND `created_at` < \\?").
+					WithArgs(2, "retry-1", key.ExpiredBefore).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectLockBook(m, 1, 0)
+				expectBackorderable(m, 0, 1)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrInsufficientStock,
+		},
+		{
+			name: "Failed order releases the key",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectInsertKey(m).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(lockBookQuery).WithArgs(1).WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+			tc.prepare(mock)
+			o := &models.Order{UserID: 2, Items: items(1, 2), Status: "pending", OrderedAt: now}
+			o.NormalizeItems()
+
+			replayed, err := repo.CreateIdempotent(context.Background(), o, key)
+
+			if tc.errIs != nil {
+				assert.ErrorIs(t, err, tc.errIs)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedID, o.ID)
+			}
+			assert.Equal(t, tc.expectedReplayed, replayed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepo_PurgeIdempotencyKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+	before := time.Now()
+	mock.ExpectExec("DELETE FROM `idempotency_keys` WHERE `created_at` < \\?").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := repo.PurgeIdempotencyKeys(context.Background(), before)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var reservationColumns = []string{"id", "book_id", "user_id", "quantity", "status", "expires_at", "order_id", "created_at", "updated_at"}
+
+func TestOrderRepo_ConfirmReservation(t *testing.T) {
+	now := time.Now()
+	lockQuery := "SELECT .* FROM `reservations` WHERE `id` = \\? FOR UPDATE"
+	reservation := func(status string, expiresAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows(reservationColumns).AddRow(5, 1, 42, 3, status, expiresAt, nil, now.Add(-time.Minute), now.Add(-time.Minute))
+	}
+
+	tests := []struct {
+		name       string
+		prepare    func(sqlmock.Sqlmock)
+		errIs      error
+		errMessage string
+	}{
+		{
+			name: "Success",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(lockQuery).WithArgs(5).WillReturnRows(reservation("active", now.Add(time.Minute)))
+				m.ExpectExec("UPDATE `reservations` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+					WithArgs("confirmed", now, 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				// Reservation đã confirmed nên không còn tính vào phần đang giữ
+				expectAdjustStock(m, 1, 10, -3)
+				expectPrices(m, 1)
+				m.ExpectExec("INSERT INTO orders").
+					WithArgs(42, 3, "pending", nil, 0, now).
+					WillReturnResult(sqlmock.NewResult(9, 1))
+				m.ExpectExec("INSERT INTO `order_items`").
+					WithArgs(9, 1, 3, unitPrice, "VND").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectMovement(m, 1, -3, "order", 9, 7)
+				m.ExpectExec("UPDATE `reservations` SET `order_id` = \\? WHERE `id` = \\?").
+					WithArgs(9, 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "Expired reservation",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(lockQuery).WithArgs(5).WillReturnRows(reservation("active", now.Add(-time.Second)))
+				m.ExpectRollback()
+			},
+			errIs:      apperror.ErrConflict,
+			errMessage: "reservation 5 has expired",
+		},
+		{
+			name: "Already released",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(lockQuery).WithArgs(5).WillReturnRows(reservation("released", now.Add(time.Minute)))
+				m.ExpectRollback()
+			},
+			errIs:      apperror.ErrConflict,
+			errMessage: "reservation 5 is already released",
+		},
+		{
+			name: "Reservation not found",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(lockQuery).WithArgs(5).WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrNotFound,
+		},
+		{
+			name: "Stock went below the reservation",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(lockQuery).WithArgs(5).WillReturnRows(reservation("active", now.Add(time.Minute)))
+				m.ExpectExec("UPDATE `reservations` SET `status`").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectLockBook(m, 1, 2)
+				expectBackorderable(m, 0, 1)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrInsufficientStock,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+			tc.prepare(mock)
+
+			order := &models.Order{Status: "pending", OrderedAt: now, UpdatedAt: now}
+			res, err := repo.ConfirmReservation(context.Background(), 5, order)
+
+			if tc.errIs != nil {
+				assert.ErrorIs(t, err, tc.errIs)
+				if tc.errMessage != "" {
+					assert.Contains(t, err.Error(), tc.errMessage)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 9, order.ID)
+				assert.Equal(t, 42, order.UserID)
+				assert.Equal(t, items(1, 3), order.Items)
+				assert.Equal(t, "confirmed", res.Status)
+				require.NotNil(t, res.OrderID)
+				assert.Equal(t, 9, *res.OrderID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepo_AllocateBackorders(t *testing.T) {
+	now := time.Now()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
+	mock.ExpectQuery("SELECT o.id FROM orders o\\s+JOIN order_items i ON i.order_id = o.id\\s+WHERE i.book_id = \\? AND o.status = \\?\\s+ORDER BY o.ordered_at, o.id").
+		WithArgs(1, "backordered").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4).AddRow(5).AddRow(6))
+	// Order 3 đặt trước nhất được phân bổ
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(3, "backordered", now, items(1, 2)))
+	expectAdjustStock(mock, 1, 5, -2)
+	expectMovement(mock, 1, -2, "order", 3, 3)
+	mock.ExpectExec("UPDATE `orders` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+		WithArgs("pending", now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// Order 4 vừa bị huỷ thì bỏ qua
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(4, "cancelled", now, items(1, 1)))
+	mock.ExpectRollback()
+	// Order 5 chưa đủ hàng: dừng lại, order 6 dù nhỏ hơn cũng không được vượt lên
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(5, "backordered", now, items(1, 4)))
+	expectLockBook(mock, 1, 3)
+	mock.ExpectRollback()
+
+	allocated, err := repo.AllocateBackorders(context.Background(), 1, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, allocated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepo_AllocateBackorders_SkipsOrderShortOnOtherBook(t *testing.T) {
+	now := time.Now()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := repositories.NewOrderRepo(db, slog.New(slog.DiscardHandler))
+
+	mock.ExpectQuery("SELECT o.id FROM orders o").
+		WithArgs(1, "backordered").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4).AddRow(5))
+	// Order 3 đủ sách 1 nhưng còn thiếu sách 2: bỏ qua, không chặn các order sau
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(3, "backordered", now, items(1, 1, 2, 5)))
+	expectLockBook(mock, 1, 5)
+	expectLockBook(mock, 2, 0)
+	mock.ExpectRollback()
+	// Order 4 chỉ cần sách 1 nên được phân bổ
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(4, "backordered", now, items(1, 2)))
+	expectAdjustStock(mock, 1, 5, -2)
+	expectMovement(mock, 1, -2, "order", 4, 3)
+	mock.ExpectExec("UPDATE `orders` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+		WithArgs("pending", now, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// Order 5 thiếu chính sách 1: dừng lại
+	mock.ExpectBegin()
+	expectLockOrder(mock, newOrder(5, "backordered", now, items(1, 4)))
+	expectLockBook(mock, 1, 3)
+	mock.ExpectRollback()
+
+	allocated, err := repo.AllocateBackorders(context.Background(), 1, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{4}, allocated)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
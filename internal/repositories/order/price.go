@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+// bookPrice là giá hiện tại của một sách; price NULL là sách chưa được định giá.
+type bookPrice struct {
+	price    sql.NullInt64
+	currency string
+}
+
+// priceItems chốt giá cho từng dòng của order: dòng đã có trong current (order đang sửa)
+// giữ giá lúc đặt hàng, dòng mới lấy giá hiện tại của sách. Gọi sau adjustStock để các
+// row books đã bị lock, giá đọc được không đổi tới khi commit.
+// Một order chỉ có một currency.
+func priceItems(ctx context.Context, tx *sql.Tx, order *models.Order, current []models.OrderItem) error {
+	kept := make(map[int]models.OrderItem, len(current))
+	for _, item := range current {
+		kept[item.BookID] = item
+	}
+	var args []any
+	for _, item := range order.Items {
+		if _, ok := kept[item.BookID]; !ok {
+			args = append(args, item.BookID)
+		}
+	}
+	prices, err := loadPrices(ctx, tx, args)
+	if err != nil {
+		return err
+	}
+	for i := range order.Items {
+		item := &order.Items[i]
+		if old, ok := kept[item.BookID]; ok {
+			item.UnitPrice, item.Currency = old.UnitPrice, old.Currency
+			continue
+		}
+		p, ok := prices[item.BookID]
+		if !ok {
+			return apperror.ForeignKey(nil, "foreign key constraint fails: book_id does not exist")
+		}
+		// Sách có từ trước khi có giá (migration 0012) chưa được định giá; giá 0 là sách miễn phí
+		if !p.price.Valid {
+			return apperror.Unprocessable("book %d has no price yet; set one with PATCH /books/%d before ordering it", item.BookID, item.BookID)
+		}
+		item.UnitPrice, item.Currency = p.price.Int64, p.currency
+	}
+	for _, item := range order.Items[1:] {
+		if item.Currency != order.Items[0].Currency {
+			return apperror.Unprocessable("order items must share one currency, got %s and %s", order.Items[0].Currency, item.Currency)
+		}
+	}
+	order.NormalizeItems()
+	return nil
+}
+
+func loadPrices(ctx context.Context, tx *sql.Tx, ids []any) (map[int]bookPrice, error) {
+	prices := make(map[int]bookPrice, len(ids))
+	if len(ids) == 0 {
+		return prices, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT `id`, `price`, `currency` FROM `books` WHERE `id` IN ("+txutil.Placeholders(len(ids))+")", ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query book prices: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id int
+			p  bookPrice
+		)
+		if err := rows.Scan(&id, &p.price, &p.currency); err != nil {
+			return nil, err
+		}
+		prices[id] = p
+	}
+	return prices, rows.Err()
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/reservation"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+// ConfirmReservation tạo order một dòng từ reservation đang active trong cùng transaction:
+// lock reservation trước rồi mới tới sách (cùng thứ tự với mọi nơi khác), đánh dấu confirmed
+// để hàng đang giữ được trả lại vào available rồi trừ stock như order bình thường.
+func (r *orderRepo) ConfirmReservation(ctx context.Context, reservationID int, order *models.Order) (*models.Reservation, error) {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return nil, err
+	}
+	res, err := reservation.Lock(ctx, tx, reservationID, order.OrderedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := reservation.Confirm(ctx, tx, res, order.OrderedAt); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	order.UserID = res.UserID
+	order.BookID = 0
+	order.Items = []models.OrderItem{{BookID: res.BookID, Quantity: res.Quantity}}
+	order.NormalizeItems()
+	if err := r.create(ctx, tx, order); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := reservation.LinkOrder(ctx, tx, res, order.ID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return res, nil
+}
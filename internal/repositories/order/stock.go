@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// LockOrder đọc order (kèm items) và giữ row lock tới hết transaction.
+// Mọi thay đổi items đều đi qua row orders đã lock nên không cần lock thêm order_items;
+// repository khác đụng tới order (vd: returns) cũng lock qua đây để thứ tự lock thống nhất.
+func LockOrder(ctx context.Context, tx *sql.Tx, id int) (*models.Order, error) {
+	order, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM `orders` WHERE id = ? FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("order with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+	if err := loadItems(ctx, tx, []*models.Order{order}); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// scanOrder đọc một row theo thứ tự orderColumns
+func scanOrder(row interface{ Scan(dest ...any) error }) (*models.Order, error) {
+	order := &models.Order{}
+	var couponCode sql.NullString
+	if err := row.Scan(&order.ID, &order.UserID, &order.Quantity, &order.Status, &couponCode, &order.Discount, &order.OrderedAt, &order.UpdatedAt); err != nil {
+		return nil, err
+	}
+	order.CouponCode = couponCode.String
+	return order, nil
+}
+
+// nullString lưu chuỗi rỗng thành NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// loadItems đọc items của nhiều order bằng một câu query rồi gắn vào từng order.
+func loadItems(ctx context.Context, q txutil.Querier, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Order, len(orders))
+	args := make([]any, 0, len(orders))
+	for _, o := range orders {
+		byID[o.ID] = o
+		o.Items = nil
+		args = append(args, o.ID)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT `order_id`, `book_id`, `quantity`, `unit_price`, `currency` FROM `order_items` WHERE `order_id` IN ("+txutil.Placeholders(len(args))+") ORDER BY `order_id`, `id`",
+		args...)
+	if err != nil {
+		return fmt.Errorf("failed to query order items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			orderID int
+			item    models.OrderItem
+		)
+		if err := rows.Scan(&orderID, &item.BookID, &item.Quantity, &item.UnitPrice, &item.Currency); err != nil {
+			return err
+		}
+		if o := byID[orderID]; o != nil {
+			o.Items = append(o.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, o := range orders {
+		o.NormalizeItems()
+	}
+	return nil
+}
+
+// insertItems ghi tất cả items của order bằng một câu INSERT.
+func (r *orderRepo) insertItems(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	args := make([]any, 0, 5*len(order.Items))
+	values := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, order.ID, item.BookID, item.Quantity, item.UnitPrice, item.Currency)
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO `order_items` (`order_id`, `book_id`, `quantity`, `unit_price`, `currency`) VALUES "+strings.Join(values, ", "), args...)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return apperror.ForeignKey(err, "foreign key constraint fails: book_id does not exist")
+		}
+		return fmt.Errorf("failed to insert order items: %w", err)
+	}
+	return nil
+}
+
+// adjustStock cộng deltas[bookID] vào stock của từng sách (âm là trừ) và trả về
+// các dòng sổ cái tương ứng để caller ghi lại bằng recordStock. Phần trừ chỉ được
+// lấy từ available-to-sell (stock trừ hàng reservation đang giữ).
+// Các row books được lock theo thứ tự id tăng dần để hai transaction cùng đụng
+// nhiều sách không deadlock lẫn nhau. Tất cả sách được kiểm tra trước khi ghi,
+// để lỗi thiếu hàng liệt kê đủ mọi dòng.
+func adjustStock(ctx context.Context, tx *sql.Tx, deltas map[int]int) ([]*models.StockMovement, error) {
+	var (
+		movements []*models.StockMovement
+		shortages []apperror.StockShortage
+		now       = time.Now()
+	)
+	for _, bookID := range slices.Sorted(maps.Keys(deltas)) {
+		delta := deltas[bookID]
+		if delta == 0 {
+			continue
+		}
+		current, reserved, err := stock.LockAvailable(ctx, tx, bookID, now)
+		if err != nil {
+			return nil, err
+		}
+		// Hàng đang được reservation khác giữ không bán được
+		if delta < 0 && current-reserved+delta < 0 {
+			shortages = append(shortages, apperror.StockShortage{BookID: bookID, Requested: -delta, Available: stock.Available(current, reserved)})
+		}
+		movements = append(movements, &models.StockMovement{BookID: bookID, Delta: delta, StockAfter: current + delta})
+	}
+	if len(shortages) > 0 {
+		return nil, &apperror.InsufficientStockError{Items: shortages}
+	}
+	for _, m := range movements {
+		if _, err := tx.ExecContext(ctx, "UPDATE books SET stock = stock + ? WHERE id = ?", m.Delta, m.BookID); err != nil {
+			return nil, fmt.Errorf("failed to update book stock: %w", err)
+		}
+	}
+	return movements, nil
+}
+
+// recordStock ghi các thay đổi stock của order vào sổ cái trong cùng transaction.
+func recordStock(ctx context.Context, tx *sql.Tx, movements []*models.StockMovement, reason string, orderID int, at time.Time) error {
+	for _, m := range movements {
+		m.Reason = reason
+		m.OrderID = &orderID
+		m.CreatedAt = at
+	}
+	return stock.Record(ctx, tx, movements...)
+}
+
+// stockReason chọn lý do ghi sổ cái khi order chuyển từ current sang updated.
+func stockReason(current, updated *models.Order) string {
+	if models.OrderReleasesStock(current.Status, updated.Status) {
+		return models.StockCancellation
+	}
+	return models.StockOrder
+}
+
+// stockDeltas tính thay đổi stock khi order chuyển từ current sang updated:
+// trả lại các dòng cũ rồi trừ các dòng mới, trừ khi order được huỷ/hoàn tiền.
+func stockDeltas(current, updated *models.Order) map[int]int {
+	deltas := map[int]int{}
+	if !models.OrderHoldsStock(current.Status) {
+		return deltas
+	}
+	for _, item := range current.Items {
+		deltas[item.BookID] += item.Quantity
+	}
+	if !models.OrderReleasesStock(current.Status, updated.Status) {
+		for _, item := range updated.Items {
+			deltas[item.BookID] -= item.Quantity
+		}
+	}
+	return deltas
+}
+
+// releaseDeltas là stock cần trả lại khi huỷ/xoá toàn bộ order.
+func releaseDeltas(order *models.Order) map[int]int {
+	deltas := map[int]int{}
+	for _, item := range order.Items {
+		deltas[item.BookID] += item.Quantity
+	}
+	return deltas
+}
+
+// sameItems so sánh book_id/quantity của hai danh sách items không phụ thuộc thứ tự.
+func sameItems(a, b []models.OrderItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	quantities := make(map[int]int, len(a))
+	for _, item := range a {
+		quantities[item.BookID] = item.Quantity
+	}
+	for _, item := range b {
+		if q, ok := quantities[item.BookID]; !ok || q != item.Quantity {
+			return false
+		}
+	}
+	return true
+}
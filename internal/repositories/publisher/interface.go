@@ -0,0 +1,15 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+type PublisherRepoInterface interface {
+	Create(ctx context.Context, p *models.Publisher) error
+	GetAll(ctx context.Context) ([]*models.Publisher, error)
+	GetByID(ctx context.Context, id int) (*models.Publisher, error)
+	UpdateByID(ctx context.Context, p *models.Publisher) (*models.Publisher, error)
+	DeleteByID(ctx context.Context, id int) (*models.Publisher, error)
+}
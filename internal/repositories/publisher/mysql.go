@@ -0,0 +1,126 @@
+package publisher
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type publisherRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPublisherRepo(db *sql.DB, logger *slog.Logger) PublisherRepoInterface {
+	return &publisherRepo{db: db, logger: logger}
+}
+
+const publisherColumns = "`id`, `name`, `country`, `website`, `created_at`, `updated_at`"
+
+func (r *publisherRepo) Create(ctx context.Context, p *models.Publisher) error {
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO `publishers` (`name`, `country`, `website`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?)",
+		p.Name, p.Country, p.Website, p.CreatedAt, p.CreatedAt)
+	if err != nil {
+		return r.duplicateName(ctx, err, p.Name)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+	p.UpdatedAt = p.CreatedAt
+	return nil
+}
+
+func (r *publisherRepo) GetAll(ctx context.Context) ([]*models.Publisher, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+publisherColumns+" FROM `publishers` ORDER BY `name`, `id`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query publishers: %w", err)
+	}
+	defer rows.Close()
+	publishers := []*models.Publisher{}
+	for rows.Next() {
+		p, err := scanPublisher(rows)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+	return publishers, rows.Err()
+}
+
+func (r *publisherRepo) GetByID(ctx context.Context, id int) (*models.Publisher, error) {
+	p, err := scanPublisher(r.db.QueryRowContext(ctx, "SELECT "+publisherColumns+" FROM `publishers` WHERE `id` = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("publisher with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to fetch publisher: %w", err)
+	}
+	return p, nil
+}
+
+func (r *publisherRepo) UpdateByID(ctx context.Context, p *models.Publisher) (*models.Publisher, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE `publishers` SET `name` = ?, `country` = ?, `website` = ?, `updated_at` = ? WHERE `id` = ?",
+		p.Name, p.Country, p.Website, p.UpdatedAt, p.ID)
+	if err != nil {
+		return nil, r.duplicateName(ctx, err, p.Name)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, apperror.NotFound("no publisher updated with id %d", p.ID)
+	}
+	return p, nil
+}
+
+func (r *publisherRepo) DeleteByID(ctx context.Context, id int) (*models.Publisher, error) {
+	p, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, "DELETE FROM `publishers` WHERE `id` = ?", id)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1451 {
+			r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+			return nil, apperror.ForeignKey(err, "cannot delete publisher: existing books depend on it")
+		}
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, apperror.NotFound("no publisher found with id %d", id)
+	}
+	return p, nil
+}
+
+// duplicateName đổi lỗi trùng unique index name thành Conflict
+func (r *publisherRepo) duplicateName(ctx context.Context, err error, name string) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+		r.logger.DebugContext(ctx, "constraint violation", "mysql_error", mysqlErr.Number, "detail", mysqlErr.Message)
+		return apperror.Conflict("publisher %s already exists", name)
+	}
+	return err
+}
+
+func scanPublisher(row interface{ Scan(dest ...any) error }) (*models.Publisher, error) {
+	p := &models.Publisher{}
+	if err := row.Scan(&p.ID, &p.Name, &p.Country, &p.Website, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,190 @@
+package publisher_test
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/publisher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var publisherColumns = []string{"id", "name", "country", "website", "created_at", "updated_at"}
+
+func newRepo(t *testing.T) (publisher.PublisherRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return publisher.NewPublisherRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+func TestPublisherRepo_Create(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		execErr error
+		errIs   error
+		errMsg  string
+	}{
+		{name: "Created"},
+		{
+			name:    "Duplicate name",
+			execErr: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Kim Đồng' for key 'uq_publishers_name'"},
+			errIs:   apperror.ErrConflict,
+			errMsg:  "publisher Kim Đồng already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			exec := m.ExpectExec("INSERT INTO `publishers`").WithArgs("Kim Đồng", "VN", "https://nxbkimdong.com.vn", now, now)
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(4, 1))
+			}
+
+			p := &models.Publisher{Name: "Kim Đồng", Country: "VN", Website: "https://nxbkimdong.com.vn", CreatedAt: now}
+			err := repo.Create(context.Background(), p)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 4, p.ID)
+				assert.Equal(t, now, p.UpdatedAt)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPublisherRepo_GetAll(t *testing.T) {
+	repo, m := newRepo(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExpectQuery("SELECT .* FROM `publishers` ORDER BY `name`, `id`").
+		WillReturnRows(sqlmock.NewRows(publisherColumns).
+			AddRow(4, "Kim Đồng", "VN", "", now, now).
+			AddRow(2, "Trẻ", "VN", "", now, now))
+
+	publishers, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, publishers, 2)
+	assert.Equal(t, "Kim Đồng", publishers[0].Name)
+	assert.Equal(t, 2, publishers[1].ID)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestPublisherRepo_GetByID_NotFound(t *testing.T) {
+	repo, m := newRepo(t)
+	m.ExpectQuery("SELECT .* FROM `publishers` WHERE `id` = \\?").WithArgs(9).WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.GetByID(context.Background(), 9)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.EqualError(t, err, "publisher with ID 9 not found")
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestPublisherRepo_UpdateByID(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		prepareMock func(exec *sqlmock.ExpectedExec)
+		errIs       error
+		errMsg      string
+	}{
+		{
+			name:        "Updated",
+			prepareMock: func(exec *sqlmock.ExpectedExec) { exec.WillReturnResult(sqlmock.NewResult(0, 1)) },
+		},
+		{
+			name:        "Not found",
+			prepareMock: func(exec *sqlmock.ExpectedExec) { exec.WillReturnResult(sqlmock.NewResult(0, 0)) },
+			errIs:       apperror.ErrNotFound,
+			errMsg:      "no publisher updated with id 4",
+		},
+		{
+			name: "Duplicate name",
+			prepareMock: func(exec *sqlmock.ExpectedExec) {
+				exec.WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Trẻ' for key 'uq_publishers_name'"})
+			},
+			errIs:  apperror.ErrConflict,
+			errMsg: "publisher Trẻ already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			tt.prepareMock(m.ExpectExec("UPDATE `publishers` SET").WithArgs("Trẻ", "VN", "", now, 4))
+
+			p, err := repo.UpdateByID(context.Background(), &models.Publisher{ID: 4, Name: "Trẻ", Country: "VN", UpdatedAt: now})
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "Trẻ", p.Name)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPublisherRepo_DeleteByID(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		prepareMock func(m sqlmock.Sqlmock)
+		errIs       error
+		errMsg      string
+	}{
+		{
+			name: "Deleted",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `publishers` WHERE `id` = \\?").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Books depend on the publisher",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `publishers` WHERE `id` = \\?").WithArgs(4).
+					WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+			},
+			errIs:  apperror.ErrForeignKey,
+			errMsg: "cannot delete publisher: existing books depend on it",
+		},
+		{
+			name: "Deleted concurrently",
+			prepareMock: func(m sqlmock.Sqlmock) {
+				m.ExpectExec("DELETE FROM `publishers` WHERE `id` = \\?").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			errIs:  apperror.ErrNotFound,
+			errMsg: "no publisher found with id 4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectQuery("SELECT .* FROM `publishers` WHERE `id` = \\?").WithArgs(4).
+				WillReturnRows(sqlmock.NewRows(publisherColumns).AddRow(4, "Kim Đồng", "VN", "", now, now))
+			tt.prepareMock(m)
+
+			p, err := repo.DeleteByID(context.Background(), 4)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "Kim Đồng", p.Name)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package reservation
+
+import (
+	"context"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// internal/repositories/reservation/interface.go
+type ReservationRepoInterface interface {
+	// Reserve giữ hàng nếu available-to-sell đủ, không đụng tới books.stock
+	Reserve(ctx context.Context, r *models.Reservation) error
+	GetByID(ctx context.Context, id int) (*models.Reservation, error)
+	// Release trả hàng đang giữ; chỉ reservation active mới release được
+	Release(ctx context.Context, id int, at time.Time) (*models.Reservation, error)
+	// ExpireStale đánh dấu expired các reservation active đã quá hạn, trả về số đã đổi
+	// và các sách (không trùng) vừa hết bị giữ
+	ExpireStale(ctx context.Context, now time.Time) (int64, []int, error)
+}
@@ -0,0 +1,207 @@
+package reservation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+const reservationColumns = "`id`, `book_id`, `user_id`, `quantity`, `status`, `expires_at`, `order_id`, `created_at`, `updated_at`"
+
+type reservationRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewReservationRepo(db *sql.DB, logger *slog.Logger) ReservationRepoInterface {
+	return &reservationRepo{db: db, logger: logger}
+}
+
+// Reserve lock sách trước khi insert để hai reservation (hoặc reservation và order)
+// cùng lúc không giữ quá số hàng còn bán được.
+func (r *reservationRepo) Reserve(ctx context.Context, res *models.Reservation) error {
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return err
+	}
+	current, reserved, err := stock.LockAvailable(ctx, tx, res.BookID, res.CreatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if available := stock.Available(current, reserved); available < res.Quantity {
+		txutil.Rollback(ctx, tx, r.logger)
+		return &apperror.InsufficientStockError{Items: []apperror.StockShortage{
+			{BookID: res.BookID, Requested: res.Quantity, Available: available},
+		}}
+	}
+	res.Status = models.ReservationActive
+	res.UpdatedAt = res.CreatedAt
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO `reservations` (`book_id`, `user_id`, `quantity`, `status`, `expires_at`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		res.BookID, res.UserID, res.Quantity, res.Status, res.ExpiresAt, res.CreatedAt, res.UpdatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	res.ID = int(id)
+	return nil
+}
+
+func (r *reservationRepo) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	res, err := scan(r.db.QueryRowContext(ctx, "SELECT "+reservationColumns+" FROM `reservations` WHERE `id` = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("reservation with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to fetch reservation: %w", err)
+	}
+	return res, nil
+}
+
+func (r *reservationRepo) Release(ctx context.Context, id int, at time.Time) (*models.Reservation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := Lock(ctx, tx, id, at)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `reservations` SET `status` = ?, `updated_at` = ? WHERE `id` = ?",
+		models.ReservationReleased, at, id); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to release reservation: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	res.Status = models.ReservationReleased
+	res.UpdatedAt = at
+	return res, nil
+}
+
+// ExpireStale đổi status để gọn danh sách và để service phân bổ lại hàng vừa hết bị giữ;
+// LockAvailable đã bỏ qua reservation quá hạn nên sweeper chạy trễ cũng không giữ hàng
+// lâu hơn expires_at.
+func (r *reservationRepo) ExpireStale(ctx context.Context, now time.Time) (int64, []int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT `id`, `book_id` FROM `reservations` WHERE `status` = ? AND `expires_at` <= ? ORDER BY `id` FOR UPDATE",
+		models.ReservationActive, now)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return 0, nil, fmt.Errorf("failed to query stale reservations: %w", err)
+	}
+	var (
+		ids     []any
+		bookIDs []int
+	)
+	for rows.Next() {
+		var id, bookID int
+		if err := rows.Scan(&id, &bookID); err != nil {
+			rows.Close()
+			txutil.Rollback(ctx, tx, r.logger)
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+		if !slices.Contains(bookIDs, bookID) {
+			bookIDs = append(bookIDs, bookID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		txutil.Rollback(ctx, tx, r.logger)
+		return 0, nil, nil
+	}
+	args := append([]any{models.ReservationExpired, now}, ids...)
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `reservations` SET `status` = ?, `updated_at` = ? WHERE `id` IN ("+txutil.Placeholders(len(ids))+")", args...); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return 0, nil, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int64(len(ids)), bookIDs, nil
+}
+
+// Lock đọc reservation và giữ row lock tới hết transaction. Reservation không còn
+// active, hoặc đã quá hạn nhưng sweeper chưa kịp đánh dấu, trả về Conflict.
+func Lock(ctx context.Context, tx *sql.Tx, id int, now time.Time) (*models.Reservation, error) {
+	res, err := scan(tx.QueryRowContext(ctx, "SELECT "+reservationColumns+" FROM `reservations` WHERE `id` = ? FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("reservation with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to lock reservation: %w", err)
+	}
+	if res.Status != models.ReservationActive {
+		return nil, apperror.Conflict("reservation %d is already %s", id, res.Status)
+	}
+	if !res.ExpiresAt.After(now) {
+		return nil, apperror.Conflict("reservation %d has expired", id)
+	}
+	return res, nil
+}
+
+// Confirm đánh dấu reservation đã dùng, bằng transaction của caller. Gọi trước khi trừ
+// stock cho order để LockAvailable trong cùng transaction không tính phần hàng này là đang giữ.
+func Confirm(ctx context.Context, tx *sql.Tx, res *models.Reservation, at time.Time) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `reservations` SET `status` = ?, `updated_at` = ? WHERE `id` = ?",
+		models.ReservationConfirmed, at, res.ID); err != nil {
+		return fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+	res.Status = models.ReservationConfirmed
+	res.UpdatedAt = at
+	return nil
+}
+
+// LinkOrder gắn order vừa tạo từ reservation.
+func LinkOrder(ctx context.Context, tx *sql.Tx, res *models.Reservation, orderID int) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE `reservations` SET `order_id` = ? WHERE `id` = ?", orderID, res.ID); err != nil {
+		return fmt.Errorf("failed to link reservation to order: %w", err)
+	}
+	res.OrderID = &orderID
+	return nil
+}
+
+func scan(row *sql.Row) (*models.Reservation, error) {
+	res := &models.Reservation{}
+	var orderID sql.NullInt64
+	if err := row.Scan(&res.ID, &res.BookID, &res.UserID, &res.Quantity, &res.Status, &res.ExpiresAt, &orderID, &res.CreatedAt, &res.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if orderID.Valid {
+		id := int(orderID.Int64)
+		res.OrderID = &id
+	}
+	return res, nil
+}
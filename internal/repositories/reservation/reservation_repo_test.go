@@ -0,0 +1,202 @@
+package reservation_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/reservation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reservationColumns = []string{"id", "book_id", "user_id", "quantity", "status", "expires_at", "order_id", "created_at", "updated_at"}
+
+func newRepo(t *testing.T) (reservation.ReservationRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return reservation.NewReservationRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+// expectLockAvailable giả lập stock.LockAvailable
+func expectLockAvailable(m sqlmock.Sqlmock, bookID, stock, reserved int, now time.Time) {
+	m.ExpectQuery("SELECT stock FROM books WHERE id = \\? FOR UPDATE").WithArgs(bookID).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(stock))
+	m.ExpectQuery("SELECT COALESCE\\(SUM\\(`quantity`\\), 0\\) FROM `reservations`").WithArgs(bookID, now).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(reserved))
+}
+
+func TestReservationRepo_Reserve(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(15 * time.Minute)
+	tests := []struct {
+		name      string
+		prepare   func(sqlmock.Sqlmock)
+		errIs     error
+		shortages []apperror.StockShortage
+	}{
+		{
+			name: "Success",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockAvailable(m, 1, 10, 4, now)
+				m.ExpectExec("INSERT INTO `reservations`").
+					WithArgs(1, 42, 6, "active", expiresAt, now, now).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "Active reservations leave too little",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockAvailable(m, 1, 10, 5, now)
+				m.ExpectRollback()
+			},
+			errIs:     apperror.ErrInsufficientStock,
+			shortages: []apperror.StockShortage{{BookID: 1, Requested: 6, Available: 5}},
+		},
+		{
+			name: "Book not found",
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT stock FROM books").WithArgs(1).WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newRepo(t)
+			tc.prepare(mock)
+
+			res := &models.Reservation{BookID: 1, UserID: 42, Quantity: 6, ExpiresAt: expiresAt, CreatedAt: now}
+			err := repo.Reserve(context.Background(), res)
+
+			if tc.errIs != nil {
+				assert.ErrorIs(t, err, tc.errIs)
+				if tc.shortages != nil {
+					var stockErr *apperror.InsufficientStockError
+					require.True(t, errors.As(err, &stockErr))
+					assert.Equal(t, tc.shortages, stockErr.Items)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 7, res.ID)
+				assert.Equal(t, models.ReservationActive, res.Status)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReservationRepo_GetByID(t *testing.T) {
+	now := time.Now()
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("SELECT .* FROM `reservations` WHERE `id` = \\?").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(reservationColumns).AddRow(7, 1, 42, 2, "confirmed", now, 9, now, now))
+
+		res, err := repo.GetByID(context.Background(), 7)
+
+		require.NoError(t, err)
+		require.NotNil(t, res.OrderID)
+		assert.Equal(t, 9, *res.OrderID)
+		assert.Equal(t, "confirmed", res.Status)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("SELECT .* FROM `reservations`").WithArgs(7).WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetByID(context.Background(), 7)
+
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	})
+}
+
+func TestReservationRepo_Release(t *testing.T) {
+	now := time.Now()
+	lockQuery := "SELECT .* FROM `reservations` WHERE `id` = \\? FOR UPDATE"
+	tests := []struct {
+		name    string
+		status  string
+		expires time.Time
+		release bool
+		errIs   error
+	}{
+		{name: "Active reservation is released", status: "active", expires: now.Add(time.Minute), release: true},
+		{name: "Confirmed reservation cannot be released", status: "confirmed", expires: now.Add(time.Minute), errIs: apperror.ErrConflict},
+		{name: "Expired reservation cannot be released", status: "active", expires: now, errIs: apperror.ErrConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockQuery).WithArgs(7).
+				WillReturnRows(sqlmock.NewRows(reservationColumns).AddRow(7, 1, 42, 2, tc.status, tc.expires, nil, now, now))
+			if tc.release {
+				mock.ExpectExec("UPDATE `reservations` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+					WithArgs("released", now, 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			res, err := repo.Release(context.Background(), 7, now)
+
+			if tc.errIs != nil {
+				assert.ErrorIs(t, err, tc.errIs)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.ReservationReleased, res.Status)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReservationRepo_ExpireStale(t *testing.T) {
+	repo, mock := newRepo(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id`, `book_id` FROM `reservations` WHERE `status` = \\? AND `expires_at` <= \\? ORDER BY `id` FOR UPDATE").
+		WithArgs("active", now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "book_id"}).AddRow(3, 1).AddRow(5, 2).AddRow(8, 1))
+	mock.ExpectExec("UPDATE `reservations` SET `status` = \\?, `updated_at` = \\? WHERE `id` IN \\(\\?, \\?, \\?\\)").
+		WithArgs("expired", now, 3, 5, 8).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	n, bookIDs, err := repo.ExpireStale(context.Background(), now)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, []int{1, 2}, bookIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReservationRepo_ExpireStale_NothingStale(t *testing.T) {
+	repo, mock := newRepo(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id`, `book_id` FROM `reservations`").
+		WithArgs("active", now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "book_id"}))
+	mock.ExpectRollback()
+
+	n, bookIDs, err := repo.ExpireStale(context.Background(), now)
+
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, bookIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,22 @@
+package returns
+
+import (
+	"context"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// internal/repositories/returns/interface.go
+type ReturnRepoInterface interface {
+	// Create kiểm tra số lượng còn được trả của từng sách dưới lock của order rồi tạo yêu cầu
+	Create(ctx context.Context, ret *models.Return) error
+	GetByID(ctx context.Context, id int) (*models.Return, error)
+	ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error)
+	ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error)
+	// UpdateStatus chỉ đổi status khi return vẫn đang ở trạng thái from và order còn nhận trả hàng (approve/reject)
+	UpdateStatus(ctx context.Context, id int, from, to string, at time.Time) (*models.Return, error)
+	// Receive nhận hàng trả của return đã duyệt: nhập lại kho nếu restock, tạo refund
+	// và cập nhật trạng thái trả hàng của order trong cùng transaction
+	Receive(ctx context.Context, id int, restock bool, at time.Time) (*models.Return, error)
+}
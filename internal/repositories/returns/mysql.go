@@ -0,0 +1,431 @@
+package returns
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	orderRepo "github.com/maithuc2003/re-book-api/internal/repositories/order"
+	"github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+const returnColumns = "`id`, `order_id`, `status`, `reason`, `note`, `restock`, `created_at`, `updated_at`"
+
+type returnRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewReturnRepo(db *sql.DB, logger *slog.Logger) ReturnRepoInterface {
+	return &returnRepo{db: db, logger: logger}
+}
+
+// Create lock order trước để hai yêu cầu trả cùng lúc không vượt quá số lượng đã mua.
+func (r *returnRepo) Create(ctx context.Context, ret *models.Return) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	order, err := lockDeliveredOrder(ctx, tx, ret.OrderID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	ordered := map[int]int{}
+	for _, item := range order.Items {
+		ordered[item.BookID] += item.Quantity
+	}
+	// Return bị từ chối không giữ số lượng
+	returned, err := sumByBook(ctx, tx, `
+		SELECT ri.book_id, SUM(ri.quantity) FROM return_items ri
+		JOIN returns r ON r.id = ri.return_id
+		WHERE r.order_id = ? AND r.status <> 'rejected'
+		GROUP BY ri.book_id`, ret.OrderID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	for i, item := range ret.Items {
+		if _, ok := ordered[item.BookID]; !ok {
+			txutil.Rollback(ctx, tx, r.logger)
+			return apperror.NewValidation(fmt.Sprintf("items[%d].book_id", i), fmt.Sprintf("book %d is not in order %d", item.BookID, ret.OrderID))
+		}
+		if left := ordered[item.BookID] - returned[item.BookID]; item.Quantity > left {
+			txutil.Rollback(ctx, tx, r.logger)
+			return apperror.NewValidation(fmt.Sprintf("items[%d].quantity", i), fmt.Sprintf("only %d of book %d can still be returned", left, item.BookID))
+		}
+	}
+
+	ret.Status = models.ReturnRequested
+	ret.UpdatedAt = ret.CreatedAt
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO `returns` (`order_id`, `status`, `reason`, `note`, `restock`, `created_at`, `updated_at`) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		ret.OrderID, ret.Status, ret.Reason, ret.Note, ret.Restock, ret.CreatedAt, ret.UpdatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+	ret.ID = int(id)
+	args := make([]any, 0, 3*len(ret.Items))
+	values := make([]string, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		values = append(values, "(?, ?, ?)")
+		args = append(args, ret.ID, item.BookID, item.Quantity)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO `return_items` (`return_id`, `book_id`, `quantity`) VALUES "+strings.Join(values, ", "), args...); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to create return items: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *returnRepo) GetByID(ctx context.Context, id int) (*models.Return, error) {
+	ret, err := scanReturn(r.db.QueryRowContext(ctx, "SELECT "+returnColumns+" FROM `returns` WHERE `id` = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NotFound("return with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to fetch return: %w", err)
+	}
+	if err := loadItems(ctx, r.db, []*models.Return{ret}); err != nil {
+		return nil, err
+	}
+	refunds, err := r.refunds(ctx, "`return_id` = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(refunds) > 0 {
+		ret.Refund = refunds[0]
+	}
+	return ret, nil
+}
+
+func (r *returnRepo) ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error) {
+	if err := r.orderExists(ctx, orderID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+returnColumns+" FROM `returns` WHERE `order_id` = ? ORDER BY `id`", orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query returns: %w", err)
+	}
+	defer rows.Close()
+	list := []*models.Return{}
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadItems(ctx, r.db, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *returnRepo) ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error) {
+	if err := r.orderExists(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return r.refunds(ctx, "`order_id` = ?", orderID)
+}
+
+// UpdateStatus lock order rồi tới return như Create và Receive: order đã hoàn tiền hay
+// đã huỷ thì không approve/reject được nữa.
+func (r *returnRepo) UpdateStatus(ctx context.Context, id int, from, to string, at time.Time) (*models.Return, error) {
+	orderID, err := r.orderOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := lockReturn(ctx, tx, orderID, id, from); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `returns` SET `status` = ?, `updated_at` = ? WHERE `id` = ?", to, at, id); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to update return status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Receive lock order rồi tới return (cùng thứ tự với Create) và sau cùng là các sách
+// theo id tăng dần như order repo.
+func (r *returnRepo) Receive(ctx context.Context, id int, restock bool, at time.Time) (*models.Return, error) {
+	orderID, err := r.orderOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, txutil.TxOptions)
+	if err != nil {
+		return nil, err
+	}
+	order, ret, err := lockReturn(ctx, tx, orderID, id, models.ReturnApproved)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := loadItems(ctx, tx, []*models.Return{ret}); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	// Các return đã nhận trước đó, để chia coupon luỹ kế (xem Order.RefundAmount)
+	received, err := sumByBook(ctx, tx, `
+		SELECT ri.book_id, SUM(ri.quantity) FROM return_items ri
+		JOIN returns r ON r.id = ri.return_id
+		WHERE r.order_id = ? AND r.status = 'received'
+		GROUP BY ri.book_id`, orderID)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if restock {
+		if err := restockItems(ctx, tx, ret, at); err != nil {
+			txutil.Rollback(ctx, tx, r.logger)
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `returns` SET `status` = ?, `restock` = ?, `updated_at` = ? WHERE `id` = ?",
+		models.ReturnReceived, restock, at, id); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to update return status: %w", err)
+	}
+	ret.Status, ret.Restock, ret.UpdatedAt = models.ReturnReceived, restock, at
+
+	refund := &models.Refund{OrderID: orderID, ReturnID: &ret.ID, Currency: order.Currency, CreatedAt: at}
+	returning := make(map[int]int, len(ret.Items))
+	for _, item := range ret.Items {
+		refund.Quantity += item.Quantity
+		returning[item.BookID] += item.Quantity
+	}
+	refund.Amount = order.RefundAmount(received, returning)
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO `refunds` (`order_id`, `return_id`, `quantity`, `amount`, `currency`, `created_at`) VALUES (?, ?, ?, ?, ?, ?)",
+		refund.OrderID, refund.ReturnID, refund.Quantity, refund.Amount, refund.Currency, refund.CreatedAt)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+	refundID, err := result.LastInsertId()
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+	refund.ID = int(refundID)
+	ret.Refund = refund
+
+	if err := updateOrderStatus(ctx, tx, orderID, at); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ret, nil
+}
+
+// restockItems cộng số sách trả vào stock và ghi sổ cái với lý do return.
+func restockItems(ctx context.Context, tx *sql.Tx, ret *models.Return, at time.Time) error {
+	items := slices.Clone(ret.Items)
+	slices.SortFunc(items, func(a, b models.ReturnItem) int { return a.BookID - b.BookID })
+	movements := make([]*models.StockMovement, 0, len(items))
+	for _, item := range items {
+		current, _, err := stock.LockAvailable(ctx, tx, item.BookID, at)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE books SET stock = stock + ? WHERE id = ?", item.Quantity, item.BookID); err != nil {
+			return fmt.Errorf("failed to update book stock: %w", err)
+		}
+		movements = append(movements, &models.StockMovement{
+			BookID: item.BookID, Delta: item.Quantity, Reason: models.StockReturn, OrderID: &ret.OrderID,
+			Note: fmt.Sprintf("return %d", ret.ID), StockAfter: current + item.Quantity, CreatedAt: at,
+		})
+	}
+	return stock.Record(ctx, tx, movements...)
+}
+
+// updateOrderStatus đặt order thành returned khi mọi sách đã được nhận lại, ngược lại partially_returned.
+func updateOrderStatus(ctx context.Context, tx *sql.Tx, orderID int, at time.Time) error {
+	var ordered, received int
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COALESCE(SUM(quantity), 0) FROM order_items WHERE order_id = ?),
+			(SELECT COALESCE(SUM(ri.quantity), 0) FROM return_items ri
+				JOIN returns r ON r.id = ri.return_id
+				WHERE r.order_id = ? AND r.status = 'received')`, orderID, orderID).
+		Scan(&ordered, &received)
+	if err != nil {
+		return fmt.Errorf("failed to sum returned quantity: %w", err)
+	}
+	status := models.OrderPartiallyReturned
+	if received >= ordered {
+		status = models.OrderReturned
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE `orders` SET `status` = ?, `updated_at` = ? WHERE `id` = ?", status, at, orderID); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	return nil
+}
+
+// orderOf trả về order của return, để lock order trước return.
+func (r *returnRepo) orderOf(ctx context.Context, id int) (int, error) {
+	var orderID int
+	if err := r.db.QueryRowContext(ctx, "SELECT `order_id` FROM `returns` WHERE `id` = ?", id).Scan(&orderID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, apperror.NotFound("return with ID %d not found", id)
+		}
+		return 0, fmt.Errorf("failed to fetch return: %w", err)
+	}
+	return orderID, nil
+}
+
+// lockReturn lock order (phải còn nhận trả hàng) rồi tới return, và kiểm tra return đang ở trạng thái from.
+func lockReturn(ctx context.Context, tx *sql.Tx, orderID, id int, from string) (*models.Order, *models.Return, error) {
+	order, err := lockDeliveredOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret, err := scanReturn(tx.QueryRowContext(ctx, "SELECT "+returnColumns+" FROM `returns` WHERE `id` = ? FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, apperror.NotFound("return with ID %d not found", id)
+		}
+		return nil, nil, fmt.Errorf("failed to lock return: %w", err)
+	}
+	if ret.Status != from {
+		return nil, nil, apperror.Conflict("return %d is %s, expected %s", id, ret.Status, from)
+	}
+	return order, ret, nil
+}
+
+// lockDeliveredOrder giữ row lock của order qua order repo; chỉ order đã giao mới nhận trả hàng.
+func lockDeliveredOrder(ctx context.Context, tx *sql.Tx, orderID int) (*models.Order, error) {
+	order, err := orderRepo.LockOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.OrderAcceptsReturns(order.Status) {
+		return nil, apperror.Conflict("order %d is %s; only delivered orders can be returned", orderID, order.Status)
+	}
+	return order, nil
+}
+
+func (r *returnRepo) orderExists(ctx context.Context, orderID int) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM orders WHERE id = ?)", orderID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return apperror.NotFound("order with ID %d not found", orderID)
+	}
+	return nil
+}
+
+func (r *returnRepo) refunds(ctx context.Context, where string, arg any) ([]*models.Refund, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT `id`, `order_id`, `return_id`, `quantity`, `amount`, `currency`, `created_at` FROM `refunds` WHERE "+where+" ORDER BY `id`", arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+	list := []*models.Refund{}
+	for rows.Next() {
+		refund := &models.Refund{}
+		var returnID sql.NullInt64
+		if err := rows.Scan(&refund.ID, &refund.OrderID, &returnID, &refund.Quantity, &refund.Amount, &refund.Currency, &refund.CreatedAt); err != nil {
+			return nil, err
+		}
+		if returnID.Valid {
+			id := int(returnID.Int64)
+			refund.ReturnID = &id
+		}
+		list = append(list, refund)
+	}
+	return list, rows.Err()
+}
+
+// sumByBook đọc các cặp (book_id, quantity) thành map
+func sumByBook(ctx context.Context, q txutil.Querier, query string, orderID int) (map[int]int, error) {
+	rows, err := q.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum quantities: %w", err)
+	}
+	defer rows.Close()
+	sums := map[int]int{}
+	for rows.Next() {
+		var bookID, quantity int
+		if err := rows.Scan(&bookID, &quantity); err != nil {
+			return nil, err
+		}
+		sums[bookID] += quantity
+	}
+	return sums, rows.Err()
+}
+
+// loadItems đọc items của nhiều return bằng một câu query.
+func loadItems(ctx context.Context, q txutil.Querier, list []*models.Return) error {
+	if len(list) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Return, len(list))
+	args := make([]any, 0, len(list))
+	for _, ret := range list {
+		ret.Items = []models.ReturnItem{}
+		byID[ret.ID] = ret
+		args = append(args, ret.ID)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT `return_id`, `book_id`, `quantity` FROM `return_items` WHERE `return_id` IN ("+txutil.Placeholders(len(args))+") ORDER BY `return_id`, `book_id`", args...)
+	if err != nil {
+		return fmt.Errorf("failed to load return items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var returnID int
+		var item models.ReturnItem
+		if err := rows.Scan(&returnID, &item.BookID, &item.Quantity); err != nil {
+			return err
+		}
+		if ret, ok := byID[returnID]; ok {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+	return rows.Err()
+}
+
+func scanReturn(row interface{ Scan(dest ...any) error }) (*models.Return, error) {
+	ret := &models.Return{}
+	if err := row.Scan(&ret.ID, &ret.OrderID, &ret.Status, &ret.Reason, &ret.Note, &ret.Restock, &ret.CreatedAt, &ret.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,303 @@
+package returns_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/returns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var returnColumns = []string{"id", "order_id", "status", "reason", "note", "restock", "created_at", "updated_at"}
+
+func newRepo(t *testing.T) (returns.ReturnRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return returns.NewReturnRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+var orderColumns = []string{"id", "user_id", "quantity", "status", "coupon_code", "discount", "ordered_at", "updated_at"}
+
+// unitPrice là đơn giá đã chốt của mọi sách trong order giả lập
+const unitPrice = 50000
+
+// expectLockOrder giả lập order repo lock order kèm số lượng đã mua của từng sách
+func expectLockOrder(m sqlmock.Sqlmock, orderID int, status string, discount int64, ordered map[int]int) {
+	rows := sqlmock.NewRows([]string{"order_id", "book_id", "quantity", "unit_price", "currency"})
+	quantity := 0
+	for bookID, q := range ordered {
+		rows.AddRow(orderID, bookID, q, unitPrice, "VND")
+		quantity += q
+	}
+	m.ExpectQuery("SELECT .* FROM `orders` WHERE id = \\? FOR UPDATE").WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(orderID, 2, quantity, status, nil, discount, time.Time{}, time.Time{}))
+	m.ExpectQuery("FROM `order_items` WHERE `order_id` IN \\(\\?\\)").WithArgs(orderID).WillReturnRows(rows)
+}
+
+// expectReturned giả lập số lượng đã yêu cầu trả của từng sách
+func expectReturned(m sqlmock.Sqlmock, orderID int, returned map[int]int) {
+	rows := sqlmock.NewRows([]string{"book_id", "quantity"})
+	for bookID, q := range returned {
+		rows.AddRow(bookID, q)
+	}
+	m.ExpectQuery("FROM return_items ri").WithArgs(orderID).WillReturnRows(rows)
+}
+
+// expectReceived giả lập số lượng đã nhận ở các return trước của order
+func expectReceived(m sqlmock.Sqlmock, orderID int, received map[int]int) {
+	rows := sqlmock.NewRows([]string{"book_id", "quantity"})
+	for bookID, q := range received {
+		rows.AddRow(bookID, q)
+	}
+	m.ExpectQuery("WHERE r.order_id = \\? AND r.status = 'received'").WithArgs(orderID).WillReturnRows(rows)
+}
+
+func TestReturnRepo_Create(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		items   []models.ReturnItem
+		prepare func(sqlmock.Sqlmock)
+		errIs   error
+		errMsg  string
+	}{
+		{
+			name:  "Success",
+			items: []models.ReturnItem{{BookID: 1, Quantity: 1}, {BookID: 2, Quantity: 2}},
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockOrder(m, 5, models.OrderDelivered, 0, map[int]int{1: 2, 2: 2})
+				expectReturned(m, 5, map[int]int{1: 1})
+				m.ExpectExec("INSERT INTO `returns`").
+					WithArgs(5, models.ReturnRequested, "damaged", "", false, now, now).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				m.ExpectExec("INSERT INTO `return_items` \\(`return_id`, `book_id`, `quantity`\\) VALUES \\(\\?, \\?, \\?\\), \\(\\?, \\?, \\?\\)").
+					WithArgs(3, 1, 1, 3, 2, 2).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:  "Quantity already returned",
+			items: []models.ReturnItem{{BookID: 1, Quantity: 2}},
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockOrder(m, 5, models.OrderPartiallyReturned, 0, map[int]int{1: 2})
+				expectReturned(m, 5, map[int]int{1: 1})
+				m.ExpectRollback()
+			},
+			errIs:  apperror.ErrValidation,
+			errMsg: "only 1 of book 1 can still be returned",
+		},
+		{
+			name:  "Book not in order",
+			items: []models.ReturnItem{{BookID: 9, Quantity: 1}},
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockOrder(m, 5, models.OrderDelivered, 10000, map[int]int{1: 3})
+				expectReturned(m, 5, nil)
+				m.ExpectRollback()
+			},
+			errIs:  apperror.ErrValidation,
+			errMsg: "book 9 is not in order 5",
+		},
+		{
+			name:  "Order not delivered",
+			items: []models.ReturnItem{{BookID: 1, Quantity: 1}},
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockOrder(m, 5, models.OrderShipped, 0, map[int]int{1: 2})
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			tt.prepare(m)
+			ret := &models.Return{OrderID: 5, Reason: "damaged", Items: tt.items, CreatedAt: now}
+
+			err := repo.Create(context.Background(), ret)
+
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+				if tt.errMsg != "" {
+					assert.EqualError(t, err, tt.errMsg)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 3, ret.ID)
+				assert.Equal(t, models.ReturnRequested, ret.Status)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReturnRepo_Receive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		restock bool
+		status  string
+		// receivedBefore là số sách đã nhận ở các return trước, received là tổng sau lần này
+		receivedBefore map[int]int
+		received       int
+		prepare        func(sqlmock.Sqlmock)
+		errIs          error
+		orderStatus    string
+		amount         int64
+	}{
+		{
+			name:           "Restock completes return",
+			restock:        true,
+			status:         models.ReturnApproved,
+			receivedBefore: map[int]int{1: 1},
+			received:       3,
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery("SELECT stock FROM books WHERE id = \\? FOR UPDATE").WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(4))
+				m.ExpectQuery("SELECT COALESCE\\(SUM\\(`quantity`\\), 0\\) FROM `reservations`").WithArgs(1, now).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(0))
+				m.ExpectExec("UPDATE books SET stock = stock \\+ \\? WHERE id = \\?").WithArgs(2, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `stock_movements`").
+					WithArgs(1, 2, models.StockReturn, 5, "return 3", 6, now).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			orderStatus: models.OrderReturned,
+			// Lần trước đã hoàn 50000 - 3333; tổng hai lần đúng bằng total 150000 - 10000
+			amount: 93333,
+		},
+		{
+			name:        "Partial return without restock",
+			status:      models.ReturnApproved,
+			received:    2,
+			prepare:     func(sqlmock.Sqlmock) {},
+			orderStatus: models.OrderPartiallyReturned,
+			// 100000 trừ 2/3 coupon 10000
+			amount: 93334,
+		},
+		{
+			name:   "Not approved",
+			status: models.ReturnRequested,
+			errIs:  apperror.ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectQuery("SELECT `order_id` FROM `returns` WHERE `id` = \\?").WithArgs(3).
+				WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(5))
+			m.ExpectBegin()
+			expectLockOrder(m, 5, models.OrderDelivered, 10000, map[int]int{1: 3})
+			m.ExpectQuery("FROM `returns` WHERE `id` = \\? FOR UPDATE").WithArgs(3).
+				WillReturnRows(sqlmock.NewRows(returnColumns).AddRow(3, 5, tt.status, "damaged", "", false, now, now))
+			if tt.errIs != nil {
+				m.ExpectRollback()
+			} else {
+				m.ExpectQuery("FROM `return_items` WHERE `return_id` IN \\(\\?\\)").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"return_id", "book_id", "quantity"}).AddRow(3, 1, 2))
+				expectReceived(m, 5, tt.receivedBefore)
+				tt.prepare(m)
+				m.ExpectExec("UPDATE `returns` SET `status` = \\?, `restock` = \\?").
+					WithArgs(models.ReturnReceived, tt.restock, now, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `refunds`").WithArgs(5, 3, 2, tt.amount, "VND", now).
+					WillReturnResult(sqlmock.NewResult(8, 1))
+				m.ExpectQuery("SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM order_items").WithArgs(5, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"ordered", "received"}).AddRow(3, tt.received))
+				m.ExpectExec("UPDATE `orders` SET `status` = \\?").WithArgs(tt.orderStatus, now, 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			}
+
+			ret, err := repo.Receive(context.Background(), 3, tt.restock, now)
+
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.ReturnReceived, ret.Status)
+				require.NotNil(t, ret.Refund)
+				assert.Equal(t, 8, ret.Refund.ID)
+				assert.Equal(t, 2, ret.Refund.Quantity)
+				assert.Equal(t, tt.amount, ret.Refund.Amount)
+				assert.Equal(t, "VND", ret.Refund.Currency)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReturnRepo_UpdateStatus(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		orderStatus string
+		status      string
+		errIs       error
+		errMsg      string
+	}{
+		{name: "Approve", orderStatus: models.OrderDelivered, status: models.ReturnRequested},
+		{
+			name:        "Return already rejected",
+			orderStatus: models.OrderDelivered,
+			status:      models.ReturnRejected,
+			errIs:       apperror.ErrConflict,
+			errMsg:      "return 3 is rejected, expected requested",
+		},
+		{
+			name:        "Order already refunded",
+			orderStatus: models.OrderRefunded,
+			errIs:       apperror.ErrConflict,
+			errMsg:      "order 5 is refunded; only delivered orders can be returned",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			m.ExpectQuery("SELECT `order_id` FROM `returns` WHERE `id` = \\?").WithArgs(3).
+				WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(5))
+			m.ExpectBegin()
+			expectLockOrder(m, 5, tt.orderStatus, 0, map[int]int{1: 1})
+			if tt.status != "" {
+				m.ExpectQuery("FROM `returns` WHERE `id` = \\? FOR UPDATE").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(returnColumns).AddRow(3, 5, tt.status, "damaged", "", false, now, now))
+			}
+			if tt.errIs != nil {
+				m.ExpectRollback()
+			} else {
+				m.ExpectExec("UPDATE `returns` SET `status` = \\?, `updated_at` = \\? WHERE `id` = \\?").
+					WithArgs(models.ReturnApproved, now, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+				m.ExpectQuery("FROM `returns` WHERE `id` = \\?").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(returnColumns).AddRow(3, 5, models.ReturnApproved, "damaged", "", false, now, now))
+				m.ExpectQuery("FROM `return_items`").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"return_id", "book_id", "quantity"}).AddRow(3, 1, 1))
+				m.ExpectQuery("FROM `refunds` WHERE `return_id` = \\?").WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "return_id", "quantity", "amount", "currency", "created_at"}))
+			}
+
+			ret, err := repo.UpdateStatus(context.Background(), 3, models.ReturnRequested, models.ReturnApproved, now)
+
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.ReturnApproved, ret.Status)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package search
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// SearchRepoInterface là search backend; MySQL FULLTEXT là bản cài đặt mặc định,
+// backend khác (vd: Elasticsearch) chỉ cần cài đặt interface này.
+type SearchRepoInterface interface {
+	// Search trả về tối đa q.Limit kết quả bắt đầu từ q.Offset, xếp theo độ liên quan giảm dần,
+	// cùng tổng số kết quả khớp.
+	Search(ctx context.Context, q models.SearchQuery) ([]*models.SearchHit, int, error)
+	// Reindex ghi lại dữ liệu đã chuẩn hoá dùng cho tìm kiếm của toàn bộ sách và tác giả,
+	// trả về số row đã cập nhật.
+	Reindex(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
+)
+
+type searchRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+	// minToken là innodb_ft_min_token_size của server, 0 khi chưa đọc được
+	minToken atomic.Int32
+}
+
+func NewSearchRepo(db *sql.DB, logger *slog.Logger) SearchRepoInterface {
+	return &searchRepo{db: db, logger: logger}
+}
+
+// source là một bảng được đánh index FULLTEXT trên cột đã bỏ dấu.
+type source struct {
+	typ    string
+	table  string
+	text   string
+	folded string
+}
+
+var sources = []source{
+	{typ: models.SearchTypeBook, table: "books", text: "title", folded: "title_folded"},
+	{typ: models.SearchTypeAuthor, table: "authors", text: "name", folded: "name_folded"},
+}
+
+// defaultMinTokenSize là giá trị mặc định của innodb_ft_min_token_size trên MySQL 5.7
+const defaultMinTokenSize = 3
+
+// minTokenSize đọc innodb_ft_min_token_size một lần rồi nhớ lại. Biến này chỉ đổi được khi
+// khởi động lại server, nên không cần đọc lại; đọc lỗi thì tạm dùng giá trị mặc định.
+func (r *searchRepo) minTokenSize(ctx context.Context) int {
+	if n := r.minToken.Load(); n > 0 {
+		return int(n)
+	}
+	var n int32
+	if err := r.db.QueryRowContext(ctx, "SELECT @@innodb_ft_min_token_size").Scan(&n); err != nil || n <= 0 {
+		r.logger.WarnContext(ctx, "failed to read innodb_ft_min_token_size, assuming default", "default", defaultMinTokenSize, "err", err)
+		return defaultMinTokenSize
+	}
+	r.minToken.Store(n)
+	return int(n)
+}
+
+// splitTerms tách các từ ngắn hơn min token size: InnoDB không đánh index các từ đó,
+// vd: sách "Go" không bao giờ khớp "+go*" khi innodb_ft_min_token_size = 3.
+func splitTerms(terms []string, minToken int) (indexed, short []string) {
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) < minToken {
+			short = append(short, t)
+		} else {
+			indexed = append(indexed, t)
+		}
+	}
+	return indexed, short
+}
+
+// against dựng biểu thức BOOLEAN MODE: mọi từ đều bắt buộc và khớp theo prefix,
+// vd: ["nguyen", "nh"] → "+nguyen* +nh*". Terms chỉ gồm chữ và số nên không lẫn toán tử.
+func against(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = "+" + t + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r *searchRepo) Search(ctx context.Context, q models.SearchQuery) ([]*models.SearchHit, int, error) {
+	indexed, short := splitTerms(q.Terms, r.minTokenSize(ctx))
+	var selects, counts []string
+	var selectArgs, countArgs []any
+	for _, s := range sources {
+		if q.Type != "" && q.Type != s.typ {
+			continue
+		}
+		// Từ ngắn khớp theo đầu từ bằng LIKE: không dùng được index và không góp vào điểm liên quan.
+		// Terms không có % hay _ nên không cần escape
+		var conds []string
+		var condArgs []any
+		score, scoreArgs := "0", []any(nil)
+		if len(indexed) > 0 {
+			expr := against(indexed)
+			match := fmt.Sprintf("MATCH(`%s`) AGAINST(? IN BOOLEAN MODE)", s.folded)
+			conds, condArgs = append(conds, match), append(condArgs, expr)
+			score, scoreArgs = match, []any{expr}
+		}
+		for _, t := range short {
+			conds = append(conds, fmt.Sprintf("CONCAT(' ', `%s`) LIKE ?", s.folded))
+			condArgs = append(condArgs, "% "+t+"%")
+		}
+		where := strings.Join(conds, " AND ")
+		selects = append(selects, fmt.Sprintf("SELECT '%s' AS `type`, `id`, `%s` AS `text`, %s AS `score` FROM `%s` WHERE %s",
+			s.typ, s.text, score, s.table, where))
+		selectArgs = append(append(selectArgs, scoreArgs...), condArgs...)
+		counts = append(counts, fmt.Sprintf("(SELECT COUNT(*) FROM `%s` WHERE %s)", s.table, where))
+		countArgs = append(countArgs, condArgs...)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT "+strings.Join(counts, " + "), countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+	hits := []*models.SearchHit{}
+	if total <= q.Offset {
+		return hits, total, nil
+	}
+
+	query := strings.Join(selects, " UNION ALL ") + " ORDER BY `score` DESC, `type`, `id` LIMIT ? OFFSET ?"
+	rows, err := r.db.QueryContext(ctx, query, append(selectArgs, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		hit := &models.SearchHit{}
+		if err := rows.Scan(&hit.Type, &hit.ID, &hit.Text, &hit.Score); err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+func (r *searchRepo) Reindex(ctx context.Context) (int64, error) {
+	var updated int64
+	for _, s := range sources {
+		n, err := r.reindex(ctx, s)
+		if err != nil {
+			return updated, err
+		}
+		r.logger.InfoContext(ctx, "search index rebuilt", "table", s.table, "updated", n)
+		updated += n
+	}
+	return updated, nil
+}
+
+// reindex tính lại cột folded bằng textnorm.Fold và chỉ ghi các row bị lệch.
+func (r *searchRepo) reindex(ctx context.Context, s source) (int64, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT `id`, `%s`, `%s` FROM `%s`", s.text, s.folded, s.table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", s.table, err)
+	}
+	type row struct {
+		id     int
+		folded string
+	}
+	var stale []row
+	for rows.Next() {
+		var id int
+		var text, folded string
+		if err := rows.Scan(&id, &text, &folded); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if f := textnorm.Fold(text); f != folded {
+			stale = append(stale, row{id, f})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Giữ nguyên updated_at: đây không phải thay đổi dữ liệu của người dùng
+	query := fmt.Sprintf("UPDATE `%s` SET `%s` = ?, `updated_at` = `updated_at` WHERE `id` = ?", s.table, s.folded)
+	var updated int64
+	for _, row := range stale {
+		if _, err := r.db.ExecContext(ctx, query, row.folded, row.id); err != nil {
+			return updated, fmt.Errorf("failed to reindex %s %d: %w", s.table, row.id, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
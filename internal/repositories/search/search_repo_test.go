@@ -0,0 +1,161 @@
+package search_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/repositories/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) (search.SearchRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return search.NewSearchRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+// expectMinTokenSize giả lập innodb_ft_min_token_size của server
+func expectMinTokenSize(m sqlmock.Sqlmock, n int) {
+	m.ExpectQuery("SELECT @@innodb_ft_min_token_size").WillReturnRows(sqlmock.NewRows([]string{"size"}).AddRow(n))
+}
+
+func TestSearchRepo_Search(t *testing.T) {
+	repo, m := newRepo(t)
+	expectMinTokenSize(m, 2)
+	expr := "+nguyen* +nh*"
+	m.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM `books` WHERE MATCH\\(`title_folded`\\) AGAINST\\(\\? IN BOOLEAN MODE\\)\\) \\+ "+
+		"\\(SELECT COUNT\\(\\*\\) FROM `authors` WHERE MATCH\\(`name_folded`\\) AGAINST\\(\\? IN BOOLEAN MODE\\)\\)").
+		WithArgs(expr, expr).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2))
+	m.ExpectQuery("FROM `books` WHERE .* UNION ALL .* FROM `authors` WHERE .* ORDER BY `score` DESC, `type`, `id` LIMIT \\? OFFSET \\?").
+		WithArgs(expr, expr, expr, expr, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "text", "score"}).
+			AddRow("author", 1, "Nguyễn Nhật Ánh", 3.1).
+			AddRow("book", 4, "Nguyễn Nhật Ánh tuyển tập", 1.4))
+
+	hits, total, err := repo.Search(context.Background(), models.SearchQuery{Terms: []string{"nguyen", "nh"}, Limit: 20})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, hits, 2)
+	assert.Equal(t, &models.SearchHit{Type: "author", ID: 1, Text: "Nguyễn Nhật Ánh", Score: 3.1}, hits[0])
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestSearchRepo_Search_TypeAndPastLastPage(t *testing.T) {
+	repo, m := newRepo(t)
+	expectMinTokenSize(m, 2)
+	m.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM `books` WHERE MATCH\\(`title_folded`\\) AGAINST\\(\\? IN BOOLEAN MODE\\)\\)$").
+		WithArgs("+go*").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(3))
+
+	hits, total, err := repo.Search(context.Background(), models.SearchQuery{Terms: []string{"go"}, Type: "book", Limit: 20, Offset: 20})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Empty(t, hits)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestSearchRepo_Search_ShortTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		// minToken < 0 giả lập không đọc được biến, repo dùng giá trị mặc định 3
+		minToken    int
+		terms       []string
+		countSQL    string
+		countArgs   []driver.Value
+		selectSQL   string
+		selectArgs  []driver.Value
+		expectedHit *models.SearchHit
+	}{
+		{
+			name:        "Short term filters with LIKE",
+			minToken:    3,
+			terms:       []string{"nguyen", "nh"},
+			countSQL:    "SELECT \\(SELECT COUNT\\(\\*\\) FROM `books` WHERE MATCH\\(`title_folded`\\) AGAINST\\(\\? IN BOOLEAN MODE\\) AND CONCAT\\(' ', `title_folded`\\) LIKE \\?\\)$",
+			countArgs:   []driver.Value{"+nguyen*", "% nh%"},
+			selectSQL:   "SELECT 'book' AS `type`, `id`, `title` AS `text`, MATCH\\(`title_folded`\\) AGAINST\\(\\? IN BOOLEAN MODE\\) AS `score` FROM `books` WHERE MATCH",
+			selectArgs:  []driver.Value{"+nguyen*", "+nguyen*", "% nh%", 20, 0},
+			expectedHit: &models.SearchHit{Type: "book", ID: 4, Text: "Nguyễn Nhật Ánh tuyển tập", Score: 1.4},
+		},
+		{
+			name:        "Only short terms have no score",
+			minToken:    3,
+			terms:       []string{"go"},
+			countSQL:    "SELECT \\(SELECT COUNT\\(\\*\\) FROM `books` WHERE CONCAT\\(' ', `title_folded`\\) LIKE \\?\\)$",
+			countArgs:   []driver.Value{"% go%"},
+			selectSQL:   "SELECT 'book' AS `type`, `id`, `title` AS `text`, 0 AS `score` FROM `books` WHERE CONCAT",
+			selectArgs:  []driver.Value{"% go%", 20, 0},
+			expectedHit: &models.SearchHit{Type: "book", ID: 2, Text: "Go"},
+		},
+		{
+			name:        "Unreadable token size falls back to default",
+			minToken:    -1,
+			terms:       []string{"go"},
+			countSQL:    "SELECT \\(SELECT COUNT\\(\\*\\) FROM `books` WHERE CONCAT\\(' ', `title_folded`\\) LIKE \\?\\)$",
+			countArgs:   []driver.Value{"% go%"},
+			selectSQL:   "0 AS `score` FROM `books` WHERE CONCAT",
+			selectArgs:  []driver.Value{"% go%", 20, 0},
+			expectedHit: &models.SearchHit{Type: "book", ID: 2, Text: "Go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, m := newRepo(t)
+			if tt.minToken < 0 {
+				m.ExpectQuery("SELECT @@innodb_ft_min_token_size").WillReturnError(errors.New("unknown system variable"))
+			} else {
+				expectMinTokenSize(m, tt.minToken)
+			}
+			m.ExpectQuery(tt.countSQL).WithArgs(tt.countArgs...).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(1))
+			m.ExpectQuery(tt.selectSQL).WithArgs(tt.selectArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"type", "id", "text", "score"}).
+					AddRow(tt.expectedHit.Type, tt.expectedHit.ID, tt.expectedHit.Text, tt.expectedHit.Score))
+
+			hits, total, err := repo.Search(context.Background(), models.SearchQuery{Terms: tt.terms, Type: "book", Limit: 20})
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, total)
+			assert.Equal(t, []*models.SearchHit{tt.expectedHit}, hits)
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSearchRepo_Search_ReadsTokenSizeOnce(t *testing.T) {
+	repo, m := newRepo(t)
+	expectMinTokenSize(m, 2)
+	for range 2 {
+		m.ExpectQuery("FROM `books` WHERE MATCH").WithArgs("+go*").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	}
+
+	for range 2 {
+		_, _, err := repo.Search(context.Background(), models.SearchQuery{Terms: []string{"go"}, Type: "book", Limit: 20})
+		require.NoError(t, err)
+	}
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestSearchRepo_Reindex(t *testing.T) {
+	repo, m := newRepo(t)
+	m.ExpectQuery("SELECT `id`, `title`, `title_folded` FROM `books`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "title_folded"}).
+			AddRow(1, "Mắt biếc", "mắt biếc").
+			AddRow(2, "Go", "go"))
+	m.ExpectExec("UPDATE `books` SET `title_folded` = \\?, `updated_at` = `updated_at` WHERE `id` = \\?").
+		WithArgs("mat biec", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectQuery("SELECT `id`, `name`, `name_folded` FROM `authors`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "name_folded"}).AddRow(1, "Tô Hoài", "to hoai"))
+
+	n, err := repo.Reindex(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.NoError(t, m.ExpectationsWereMet())
+}
@@ -0,0 +1,36 @@
+package stock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+)
+
+// LockAvailable lock row books (FOR UPDATE) rồi đọc số lượng đang bị các reservation
+// active chưa hết hạn giữ. Reservation chỉ được tạo/confirm khi đang giữ lock của sách,
+// nên đọc thường (không lock) sau khi có lock là đủ, không cần khoá thêm reservations.
+func LockAvailable(ctx context.Context, tx *sql.Tx, bookID int, now time.Time) (stock, reserved int, err error) {
+	err = tx.QueryRowContext(ctx, "SELECT stock FROM books WHERE id = ? FOR UPDATE", bookID).Scan(&stock)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, apperror.NotFound("book with ID %d not found", bookID)
+		}
+		return 0, 0, fmt.Errorf("failed to fetch current stock: %w", err)
+	}
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(`quantity`), 0) FROM `reservations` WHERE `book_id` = ? AND `status` = 'active' AND `expires_at` > ?",
+		bookID, now).Scan(&reserved)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch reserved stock: %w", err)
+	}
+	return stock, reserved, nil
+}
+
+// Available là số lượng còn bán được, không âm (stock có thể bị chỉnh tay xuống dưới số đang giữ).
+func Available(stock, reserved int) int {
+	return max(stock-reserved, 0)
+}
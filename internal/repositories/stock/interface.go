@@ -0,0 +1,15 @@
+package stock
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
+
+// internal/repositories/stock/interface.go
+type StockRepoInterface interface {
+	GetMovements(ctx context.Context, filter models.StockMovementFilter) (*pagination.Page[*models.StockMovement], error)
+	Move(ctx context.Context, movement *models.StockMovement) error
+	Reconcile(ctx context.Context) ([]models.StockDrift, error)
+}
@@ -0,0 +1,171 @@
+package stock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/repositories/txutil"
+)
+
+type stockRepo struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewStockRepo(db *sql.DB, logger *slog.Logger) StockRepoInterface {
+	return &stockRepo{db: db, logger: logger}
+}
+
+// Record ghi các dòng sổ cái bằng transaction của caller, để stock và lịch sử của nó
+// cùng commit hoặc cùng rollback. Dùng chung cho order, book và Move.
+func Record(ctx context.Context, tx *sql.Tx, movements ...*models.StockMovement) error {
+	for _, m := range movements {
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO `stock_movements` (`book_id`, `delta`, `reason`, `order_id`, `note`, `stock_after`, `created_at`) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			m.BookID, m.Delta, m.Reason, m.OrderID, m.Note, m.StockAfter, m.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+		m.ID = int(id)
+	}
+	return nil
+}
+
+// Move cộng movement.Delta vào stock của sách và ghi sổ cái; stock không được âm.
+func (r *stockRepo) Move(ctx context.Context, movement *models.StockMovement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	var stock int
+	err = tx.QueryRowContext(ctx, "SELECT stock FROM books WHERE id = ? FOR UPDATE", movement.BookID).Scan(&stock)
+	if err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound("book with ID %d not found", movement.BookID)
+		}
+		return fmt.Errorf("failed to fetch current stock: %w", err)
+	}
+	if stock+movement.Delta < 0 {
+		txutil.Rollback(ctx, tx, r.logger)
+		return &apperror.InsufficientStockError{Items: []apperror.StockShortage{
+			{BookID: movement.BookID, Requested: -movement.Delta, Available: stock},
+		}}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE books SET stock = stock + ? WHERE id = ?", movement.Delta, movement.BookID); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return fmt.Errorf("failed to update book stock: %w", err)
+	}
+	movement.StockAfter = stock + movement.Delta
+	if err := Record(ctx, tx, movement); err != nil {
+		txutil.Rollback(ctx, tx, r.logger)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// movementSortFields là whitelist field được phép dùng trong ?sort=, mặc định mới nhất trước
+var movementSortFields = pagination.Fields{
+	"id":         {Column: "id", Kind: pagination.Int},
+	"created_at": {Column: "created_at", Kind: pagination.Time},
+}
+
+// GetMovements trả về lịch sử tồn kho của một sách.
+func (r *stockRepo) GetMovements(ctx context.Context, filter models.StockMovementFilter) (*pagination.Page[*models.StockMovement], error) {
+	keyset, err := pagination.Resolve(filter.Params, movementSortFields, "-id")
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM books WHERE id = ?)", filter.BookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperror.NotFound("book with ID %d not found", filter.BookID)
+	}
+
+	var where pagination.Conditions
+	where.Add("`book_id` = ?", filter.BookID)
+	if filter.Reason != "" {
+		where.Add("`reason` = ?", filter.Reason)
+	}
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM `stock_movements`"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+	if cond, args := keyset.Where(); cond != "" {
+		where.Add(cond, args...)
+	}
+	query := "SELECT `id`, `book_id`, `delta`, `reason`, `order_id`, `note`, `stock_after`, `created_at` FROM `stock_movements`" + where.SQL() +
+		" ORDER BY " + keyset.OrderBy() + " LIMIT ?"
+	rows, err := r.db.QueryContext(ctx, query, append(where.Args(), keyset.Fetch())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []*models.StockMovement{}
+	for rows.Next() {
+		m := &models.StockMovement{}
+		var orderID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.BookID, &m.Delta, &m.Reason, &orderID, &m.Note, &m.StockAfter, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		if orderID.Valid {
+			id := int(orderID.Int64)
+			m.OrderID = &id
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pagination.Paginate(keyset, movements, total, movementSortKey)
+}
+
+// movementSortKey trả về giá trị của field sort để dựng next_cursor
+func movementSortKey(m *models.StockMovement, field string) (any, int) {
+	if field == "created_at" {
+		return m.CreatedAt, m.ID
+	}
+	return m.ID, m.ID
+}
+
+// Reconcile tính lại stock của từng sách từ sổ cái và trả về các sách bị lệch.
+func (r *stockRepo) Reconcile(ctx context.Context) ([]models.StockDrift, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.id, b.stock, COALESCE(SUM(m.delta), 0) AS ledger_stock
+		FROM books b
+		LEFT JOIN stock_movements m ON m.book_id = b.id
+		GROUP BY b.id, b.stock
+		HAVING b.stock <> ledger_stock
+		ORDER BY b.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile stock: %w", err)
+	}
+	defer rows.Close()
+
+	drifts := []models.StockDrift{}
+	for rows.Next() {
+		var d models.StockDrift
+		if err := rows.Scan(&d.BookID, &d.Stock, &d.LedgerStock); err != nil {
+			return nil, err
+		}
+		d.Drift = d.Stock - d.LedgerStock
+		drifts = append(drifts, d)
+	}
+	return drifts, rows.Err()
+}
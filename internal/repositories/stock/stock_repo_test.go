@@ -0,0 +1,196 @@
+package stock_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) (stock.StockRepoInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return stock.NewStockRepo(db, slog.New(slog.DiscardHandler)), mock
+}
+
+func TestStockRepo_Move(t *testing.T) {
+	fakeTime := time.Now()
+	tests := []struct {
+		name          string
+		delta         int
+		prepare       func(sqlmock.Sqlmock)
+		errIs         error
+		errContains   string
+		expectedAfter int
+	}{
+		{
+			name:  "Restock",
+			delta: 5,
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT stock FROM books WHERE id = \\? FOR UPDATE").WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(3))
+				m.ExpectExec("UPDATE books SET stock = stock \\+ \\? WHERE id = \\?").WithArgs(5, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `stock_movements`").
+					WithArgs(1, 5, "restock", nil, "new shipment", 8, fakeTime).
+					WillReturnResult(sqlmock.NewResult(42, 1))
+				m.ExpectCommit()
+			},
+			expectedAfter: 8,
+		},
+		{
+			name:  "Stock cannot go negative",
+			delta: -5,
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT stock FROM books").WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(3))
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrInsufficientStock,
+		},
+		{
+			name:  "Book not found",
+			delta: 1,
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT stock FROM books").WithArgs(1).WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			errIs: apperror.ErrNotFound,
+		},
+		{
+			name:  "Ledger error rolls back the stock change",
+			delta: 1,
+			prepare: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery("SELECT stock FROM books").WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(3))
+				m.ExpectExec("UPDATE books SET stock").WithArgs(1, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec("INSERT INTO `stock_movements`").WillReturnError(errors.New("insert error"))
+				m.ExpectRollback()
+			},
+			errContains: "failed to record stock movement",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newRepo(t)
+			tc.prepare(mock)
+			movement := &models.StockMovement{BookID: 1, Delta: tc.delta, Reason: models.StockRestock, Note: "new shipment", CreatedAt: fakeTime}
+
+			err := repo.Move(context.Background(), movement)
+
+			switch {
+			case tc.errIs != nil:
+				assert.ErrorIs(t, err, tc.errIs)
+			case tc.errContains != "":
+				assert.ErrorContains(t, err, tc.errContains)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, 42, movement.ID)
+				assert.Equal(t, tc.expectedAfter, movement.StockAfter)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStockRepo_GetMovements(t *testing.T) {
+	fakeTime := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	columns := []string{"id", "book_id", "delta", "reason", "order_id", "note", "stock_after", "created_at"}
+	orderID := 9
+
+	t.Run("Newest first with optional order id", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM books WHERE id = \\?\\)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `stock_movements` WHERE `book_id` = \\? AND `reason` = \\?$").
+			WithArgs(1, "order").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("FROM `stock_movements` WHERE `book_id` = \\? AND `reason` = \\? ORDER BY id DESC LIMIT \\?").
+			WithArgs(1, "order", 3).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(3, 1, -2, "order", orderID, "", 4, fakeTime).
+				AddRow(2, 1, 6, "order", nil, "", 6, fakeTime).
+				AddRow(1, 1, 1, "order", nil, "", 0, fakeTime))
+
+		page, err := repo.GetMovements(context.Background(), models.StockMovementFilter{
+			Params: pagination.Params{Limit: 2}, BookID: 1, Reason: "order",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []*models.StockMovement{
+			{ID: 3, BookID: 1, Delta: -2, Reason: "order", OrderID: &orderID, StockAfter: 4, CreatedAt: fakeTime},
+			{ID: 2, BookID: 1, Delta: 6, Reason: "order", StockAfter: 6, CreatedAt: fakeTime},
+		}, page.Data)
+		assert.Equal(t, 3, page.Total)
+		assert.NotEmpty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Book not found", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(404).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		page, err := repo.GetMovements(context.Background(), models.StockMovementFilter{BookID: 404})
+
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+		assert.Nil(t, page)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Sort field not in whitelist", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		_, err := repo.GetMovements(context.Background(), models.StockMovementFilter{
+			Params: pagination.Params{Sort: "delta"}, BookID: 1,
+		})
+
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStockRepo_Reconcile(t *testing.T) {
+	t.Run("Reports drifted books", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("FROM books b\\s+LEFT JOIN stock_movements m ON m.book_id = b.id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "ledger_stock"}).
+				AddRow(2, 10, 7).
+				AddRow(5, 0, 3))
+
+		drifts, err := repo.Reconcile(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []models.StockDrift{
+			{BookID: 2, Stock: 10, LedgerStock: 7, Drift: 3},
+			{BookID: 5, Stock: 0, LedgerStock: 3, Drift: -3},
+		}, drifts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		mock.ExpectQuery("FROM books").WillReturnError(errors.New("db down"))
+
+		_, err := repo.Reconcile(context.Background())
+
+		assert.ErrorContains(t, err, "failed to reconcile stock")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
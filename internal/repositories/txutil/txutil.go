@@ -0,0 +1,33 @@
+// Package txutil gom các helper transaction dùng chung cho các repository.
+package txutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// TxOptions dùng cho mọi transaction gọi stock.LockAvailable. READ COMMITTED để câu đọc
+// reservations sau khi đã lock books thấy cả reservation vừa commit, thay vì snapshot
+// cũ từ đầu transaction như REPEATABLE READ.
+var TxOptions = &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+
+// Rollback huỷ transaction khi đã có lỗi khác để trả về; lỗi rollback chỉ được log lại.
+// Khi context bị huỷ, database/sql đã tự rollback nên bỏ qua ErrTxDone.
+func Rollback(ctx context.Context, tx *sql.Tx, logger *slog.Logger) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.ErrorContext(ctx, "rollback failed", "err", err)
+	}
+}
+
+// Querier là phần chung của *sql.DB và *sql.Tx, cho các hàm đọc dùng được cả trong lẫn ngoài transaction.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Placeholders trả về n dấu "?" cách nhau bởi dấu phẩy cho mệnh đề IN (...).
+func Placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -1,55 +1,36 @@
-package book
+package author
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
 
 	authorHandler "github.com/maithuc2003/re-book-api/internal/handler/author"
+	"github.com/maithuc2003/re-book-api/internal/middleware"
 	authorRepo "github.com/maithuc2003/re-book-api/internal/repositories/author"
 	authorService "github.com/maithuc2003/re-book-api/internal/service/author"
 )
 
-func SetupServerAuthor(mux *http.ServeMux, db *sql.DB) {
-	repo := authorRepo.NewAuthorRepo(db)
-	service := authorService.NewAuthorService(repo)
-	handler := authorHandler.NewAuthorHandler(service)
-	mux.HandleFunc("/authors", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetAllAuthors(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-
-	mux.HandleFunc("/author", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetByAuthorID(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-
-	mux.HandleFunc("/author/add", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			handler.CreateAuthor(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+func SetupServerAuthor(mux *http.ServeMux, db *sql.DB, logger *slog.Logger) {
+	repo := authorRepo.NewAuthorRepo(db, logger)
+	service := authorService.NewAuthorService(repo, logger)
+	handler := authorHandler.NewAuthorHandler(service, logger)
+	registerRoutes(mux, handler)
+}
 
-	mux.HandleFunc("/author/delete", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			handler.DeleteById(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+// GET /authors/{id}/books được đăng ký ở server book vì do book handler xử lý.
+func registerRoutes(mux *http.ServeMux, handler *authorHandler.AuthorHandler) {
+	mux.HandleFunc("GET /authors", handler.GetAllAuthors)
+	mux.HandleFunc("POST /authors", handler.CreateAuthor)
+	mux.HandleFunc("GET /authors/{id}", handler.GetByAuthorID)
+	mux.HandleFunc("PUT /authors/{id}", handler.UpdateById)
+	mux.HandleFunc("PATCH /authors/{id}", handler.PatchById)
+	mux.HandleFunc("DELETE /authors/{id}", handler.DeleteById)
 
-	mux.HandleFunc("/author/update", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			handler.UpdateById(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+	// Route cũ, giữ lại cho client hiện tại trong thời gian migrate
+	mux.HandleFunc("GET /author", middleware.Deprecated("/authors/{id}", handler.GetByAuthorID))
+	mux.HandleFunc("POST /author/add", middleware.Deprecated("/authors", handler.CreateAuthor))
+	mux.HandleFunc("DELETE /author/delete", middleware.Deprecated("/authors/{id}", handler.DeleteById))
+	mux.HandleFunc("PUT /author/update", middleware.Deprecated("/authors/{id}", handler.UpdateById))
 }
@@ -0,0 +1,154 @@
+package author
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorHandler "github.com/maithuc2003/re-book-api/internal/handler/author"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestMux(service *mockservice.MockAuthorService) *http.ServeMux {
+	mux := http.NewServeMux()
+	registerRoutes(mux, authorHandler.NewAuthorHandler(service, slog.New(slog.DiscardHandler)))
+	return mux
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		prepare        func(m *mockservice.MockAuthorService)
+		expectedStatus int
+		expectedHeader map[string]string
+		expectedBody   string
+	}{
+		{
+			name:   "GET /authors/{id}",
+			method: http.MethodGet,
+			url:    "/authors/3",
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("GetByAuthorID", mock.Anything, 3).Return(&models.Author{ID: 3, Name: "Alan"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"name":"Alan"`,
+		},
+		{
+			name:   "PATCH /authors/{id} keeps fields not in body",
+			method: http.MethodPatch,
+			url:    "/authors/3",
+			body:   `{"nationality":"UK"}`,
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("GetByAuthorID", mock.Anything, 3).Return(&models.Author{ID: 3, Name: "Alan", Nationality: "US"}, nil)
+				m.On("UpdateById", mock.Anything, mock.MatchedBy(func(a *models.Author) bool {
+					return a.ID == 3 && a.Name == "Alan" && a.Nationality == "UK"
+				})).Return(&models.Author{ID: 3, Name: "Alan", Nationality: "UK"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"nationality":"UK"`,
+		},
+		{
+			name:           "Invalid id in path",
+			method:         http.MethodGet,
+			url:            "/authors/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid 'id' parameter",
+		},
+		{
+			name:           "Method not allowed lists allowed methods",
+			method:         http.MethodDelete,
+			url:            "/authors",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "GET, HEAD, POST"},
+		},
+		{
+			name:           "Method not allowed on author resource",
+			method:         http.MethodPost,
+			url:            "/authors/3",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "DELETE, GET, HEAD, PATCH, PUT"},
+		},
+		{
+			name:           "Legacy route rejects wrong method",
+			method:         http.MethodGet,
+			url:            "/author/add",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "POST"},
+		},
+		{
+			name:   "Legacy GET /author is a deprecated alias",
+			method: http.MethodGet,
+			url:    "/author?id=3",
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("GetByAuthorID", mock.Anything, 3).Return(&models.Author{ID: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true", "Link": `</authors/{id}>; rel="successor-version"`},
+			expectedBody:   `"id":3`,
+		},
+		{
+			name:   "Legacy POST /author/add is a deprecated alias",
+			method: http.MethodPost,
+			url:    "/author/add",
+			body:   `{"name":"Alan","nationality":"UK"}`,
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("CreateAuthor", mock.Anything, mock.MatchedBy(func(a *models.Author) bool {
+					return a.Name == "Alan"
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+		},
+		{
+			name:   "Legacy DELETE /author/delete is a deprecated alias",
+			method: http.MethodDelete,
+			url:    "/author/delete?id=3",
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("DeleteById", mock.Anything, 3).Return(&models.Author{ID: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+		},
+		{
+			name:   "Legacy PUT /author/update is a deprecated alias",
+			method: http.MethodPut,
+			url:    "/author/update?id=3",
+			body:   `{"name":"Alan","nationality":"UK"}`,
+			prepare: func(m *mockservice.MockAuthorService) {
+				m.On("UpdateById", mock.Anything, mock.MatchedBy(func(a *models.Author) bool {
+					return a.ID == 3
+				})).Return(&models.Author{ID: 3, Name: "Alan"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+			expectedBody:   `"name":"Alan"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockAuthorService)
+			if tc.prepare != nil {
+				tc.prepare(service)
+			}
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+
+			newTestMux(service).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for k, v := range tc.expectedHeader {
+				assert.Equal(t, v, w.Header().Get(k))
+			}
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
@@ -2,53 +2,41 @@ package book
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
 
 	bookHandler "github.com/maithuc2003/re-book-api/internal/handler/book"
+	"github.com/maithuc2003/re-book-api/internal/middleware"
 	bookRepo "github.com/maithuc2003/re-book-api/internal/repositories/book"
 	bookService "github.com/maithuc2003/re-book-api/internal/service/book"
 )
 
-func SetupServerBook(mux *http.ServeMux, db *sql.DB) {
-	repo := bookRepo.NewBookRepo(db)
-	service := bookService.NewBookService(repo)
-	handler := bookHandler.NewBookHandler(service)
-
-	mux.HandleFunc("/book/add", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			handler.CreateBook(w, r) // Gọi hàm CreateBook từ handle
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+// allocator phân bổ stock tăng qua PUT/PATCH cho các order backordered.
+func SetupServerBook(mux *http.ServeMux, db *sql.DB, logger *slog.Logger, allocator bookService.BackorderAllocator) {
+	repo := bookRepo.NewBookRepo(db, logger)
+	service := bookService.NewBookService(repo, logger, allocator)
+	handler := bookHandler.NewBookHandler(service, logger)
+	registerRoutes(mux, handler)
+}
 
-	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetAllBooks(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/book", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetByBookID(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/book/delete", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			handler.DeleteById(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/book/update", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			handler.UpdateById(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+// ServeMux tự trả 405 kèm header Allow khi path khớp nhưng method không khớp.
+func registerRoutes(mux *http.ServeMux, handler *bookHandler.BookHandler) {
+	mux.HandleFunc("GET /books", handler.GetAllBooks)
+	mux.HandleFunc("POST /books", handler.CreateBook)
+	mux.HandleFunc("GET /books/{id}", handler.GetByBookID)
+	mux.HandleFunc("GET /books/isbn/{isbn}", handler.GetByISBN)
+	mux.HandleFunc("PUT /books/{id}", handler.UpdateById)
+	mux.HandleFunc("PATCH /books/{id}", handler.PatchById)
+	mux.HandleFunc("DELETE /books/{id}", handler.DeleteById)
+	mux.HandleFunc("GET /authors/{id}/books", handler.GetBooksByAuthor)
+	mux.HandleFunc("GET /categories/{id}/books", handler.GetBooksByCategory)
+	mux.HandleFunc("GET /works/{id}/editions", handler.GetEditions)
+	mux.HandleFunc("GET /publishers/{id}/books", handler.GetBooksByPublisher)
 
+	// Route cũ, giữ lại cho client hiện tại trong thời gian migrate
+	mux.HandleFunc("POST /book/add", middleware.Deprecated("/books", handler.CreateBook))
+	mux.HandleFunc("GET /book", middleware.Deprecated("/books/{id}", handler.GetByBookID))
+	mux.HandleFunc("DELETE /book/delete", middleware.Deprecated("/books/{id}", handler.DeleteById))
+	mux.HandleFunc("PUT /book/update", middleware.Deprecated("/books/{id}", handler.UpdateById))
 }
@@ -0,0 +1,227 @@
+package book
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	bookHandler "github.com/maithuc2003/re-book-api/internal/handler/book"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestMux(service *mockservice.MockBookService) *http.ServeMux {
+	mux := http.NewServeMux()
+	registerRoutes(mux, bookHandler.NewBookHandler(service, slog.New(slog.DiscardHandler)))
+	return mux
+}
+
+// Route của server book không được trùng route server khác đăng ký trên cùng mux
+func TestRoutes_NoConflictWithStockMovements(t *testing.T) {
+	service := new(mockservice.MockBookService)
+	mux := newTestMux(service)
+	teapot := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }
+	// Các pattern server stock đăng ký
+	assert.NotPanics(t, func() {
+		mux.HandleFunc("GET /books/{id}/stock/movements", teapot)
+		mux.HandleFunc("POST /books/{id}/stock/movements", teapot)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/7/stock/movements", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	service.On("GetByISBN", mock.Anything, "9780306406157").Return(&models.Book{ID: 7, ISBN: "9780306406157"}, nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/isbn/9780306406157", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":7`)
+	service.AssertExpectations(t)
+
+	// Path hai segment khác dưới /books/ không khớp route nào, với mọi method
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/books/7/foo", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, method)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		prepare        func(m *mockservice.MockBookService)
+		expectedStatus int
+		expectedHeader map[string]string
+		expectedBody   string
+	}{
+		{
+			name:   "GET /books/{id}",
+			method: http.MethodGet,
+			url:    "/books/7",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetByBookID", mock.Anything, 7).Return(&models.Book{ID: 7, Title: "Go"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":7`,
+		},
+		{
+			name:   "GET /books/isbn/{isbn}",
+			method: http.MethodGet,
+			url:    "/books/isbn/0-306-40615-2",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetByISBN", mock.Anything, "0-306-40615-2").Return(&models.Book{ID: 7, ISBN: "9780306406157", ISBN10: "0306406152"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"isbn":"9780306406157","isbn_10":"0306406152"`,
+		},
+		{
+			name:   "PATCH /books/{id} keeps fields not in body",
+			method: http.MethodPatch,
+			url:    "/books/7",
+			body:   `{"stock":10}`,
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetByBookID", mock.Anything, 7).Return(&models.Book{ID: 7, Title: "Go", Stock: 3, AuthorID: 1}, nil)
+				m.On("UpdateById", mock.Anything, mock.MatchedBy(func(b *models.Book) bool {
+					return b.ID == 7 && b.Title == "Go" && b.Stock == 10 && b.AuthorID == 1
+				})).Return(&models.Book{ID: 7, Title: "Go", Stock: 10, AuthorID: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"stock":10`,
+		},
+		{
+			name:   "PATCH /books/{id} with legacy author_id replaces the author list",
+			method: http.MethodPatch,
+			url:    "/books/7",
+			body:   `{"author_id":5}`,
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetByBookID", mock.Anything, 7).Return(&models.Book{ID: 7, Title: "Go", AuthorID: 1,
+					Authors: []models.BookAuthor{{AuthorID: 1, Role: "author"}, {AuthorID: 2, Role: "translator"}}}, nil)
+				m.On("UpdateById", mock.Anything, mock.MatchedBy(func(b *models.Book) bool {
+					return b.AuthorID == 5 && b.Authors == nil
+				})).Return(&models.Book{ID: 7, Title: "Go", AuthorID: 5}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"author_id":5`,
+		},
+		{
+			name:   "PATCH /books/{id} keeps authors not in body",
+			method: http.MethodPatch,
+			url:    "/books/7",
+			body:   `{"title":"Go 2"}`,
+			prepare: func(m *mockservice.MockBookService) {
+				authors := []models.BookAuthor{{AuthorID: 1, Role: "author"}, {AuthorID: 2, Role: "translator"}}
+				m.On("GetByBookID", mock.Anything, 7).Return(&models.Book{ID: 7, Title: "Go", AuthorID: 1, Authors: authors}, nil)
+				m.On("UpdateById", mock.Anything, mock.MatchedBy(func(b *models.Book) bool {
+					return b.Title == "Go 2" && b.AuthorID == 1 && len(b.Authors) == 2
+				})).Return(&models.Book{ID: 7, Title: "Go 2", AuthorID: 1, Authors: authors}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"role":"translator"`,
+		},
+		{
+			name:   "GET /authors/{id}/books",
+			method: http.MethodGet,
+			url:    "/authors/3/books",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetBooksByAuthorID", mock.Anything, 3).Return([]*models.Book{{ID: 1, AuthorID: 3}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"author_id":3`,
+		},
+		{
+			name:   "GET /categories/{id}/books",
+			method: http.MethodGet,
+			url:    "/categories/3/books?limit=5",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetAllBooks", mock.Anything, mock.MatchedBy(func(f models.BookFilter) bool {
+					return f.CategoryID == 3 && f.Limit == 5
+				})).Return(&pagination.Page[*models.Book]{Data: []*models.Book{{ID: 1}}, Total: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"total":1`,
+		},
+		{
+			name:   "GET /works/{id}/editions",
+			method: http.MethodGet,
+			url:    "/works/2/editions",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetAllBooks", mock.Anything, mock.MatchedBy(func(f models.BookFilter) bool {
+					return f.WorkID == 2
+				})).Return(&pagination.Page[*models.Book]{Data: []*models.Book{{ID: 1, WorkID: 2, Format: "ebook"}}, Total: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"format":"ebook"`,
+		},
+		{
+			name:   "GET /publishers/{id}/books",
+			method: http.MethodGet,
+			url:    "/publishers/5/books",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetAllBooks", mock.Anything, mock.MatchedBy(func(f models.BookFilter) bool {
+					return f.PublisherID == 5
+				})).Return(&pagination.Page[*models.Book]{Data: []*models.Book{}, Total: 0}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"total":0`,
+		},
+		{
+			name:           "Invalid id in path",
+			method:         http.MethodGet,
+			url:            "/books/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid 'id' parameter",
+		},
+		{
+			name:           "Method not allowed lists allowed methods",
+			method:         http.MethodDelete,
+			url:            "/books",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "GET, HEAD, POST"},
+		},
+		{
+			name:           "Legacy route rejects wrong method",
+			method:         http.MethodGet,
+			url:            "/book/add",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "POST"},
+		},
+		{
+			name:   "Legacy route is a deprecated alias",
+			method: http.MethodGet,
+			url:    "/book?id=7",
+			prepare: func(m *mockservice.MockBookService) {
+				m.On("GetByBookID", mock.Anything, 7).Return(&models.Book{ID: 7}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+			expectedBody:   `"id":7`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockBookService)
+			if tc.prepare != nil {
+				tc.prepare(service)
+			}
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+
+			newTestMux(service).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for k, v := range tc.expectedHeader {
+				assert.Equal(t, v, w.Header().Get(k))
+			}
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
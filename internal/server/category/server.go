@@ -0,0 +1,30 @@
+package category
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	categoryHandler "github.com/maithuc2003/re-book-api/internal/handler/category"
+	categoryRepo "github.com/maithuc2003/re-book-api/internal/repositories/category"
+	categoryService "github.com/maithuc2003/re-book-api/internal/service/category"
+)
+
+func SetupServerCategory(mux *http.ServeMux, db *sql.DB, logger *slog.Logger) {
+	repo := categoryRepo.NewCategoryRepo(db, logger)
+	service := categoryService.NewCategoryService(repo, logger)
+	handler := categoryHandler.NewCategoryHandler(service, logger)
+	registerRoutes(mux, handler)
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+// GET /categories/{id} nhận cả slug; không dùng route /categories/slug/{slug} vì trùng
+// GET /categories/{id}/books được đăng ký ở server book (do book handler xử lý).
+func registerRoutes(mux *http.ServeMux, handler *categoryHandler.CategoryHandler) {
+	mux.HandleFunc("GET /categories", handler.GetTree)
+	mux.HandleFunc("POST /categories", handler.CreateCategory)
+	mux.HandleFunc("GET /categories/{id}", handler.GetByID)
+	mux.HandleFunc("PUT /categories/{id}", handler.UpdateByID)
+	mux.HandleFunc("PATCH /categories/{id}", handler.PatchByID)
+	mux.HandleFunc("DELETE /categories/{id}", handler.DeleteByID)
+}
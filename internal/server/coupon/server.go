@@ -0,0 +1,27 @@
+package coupon
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	couponHandler "github.com/maithuc2003/re-book-api/internal/handler/coupon"
+	couponRepo "github.com/maithuc2003/re-book-api/internal/repositories/coupon"
+	couponService "github.com/maithuc2003/re-book-api/internal/service/coupon"
+)
+
+// SetupServerCoupon đăng ký CRUD coupon; coupon được áp dụng khi tạo order ở server/order.
+func SetupServerCoupon(mux *http.ServeMux, db *sql.DB, logger *slog.Logger) {
+	repo := couponRepo.NewCouponRepo(db, logger)
+	service := couponService.NewCouponService(repo, logger)
+	handler := couponHandler.NewCouponHandler(service, logger)
+	registerRoutes(mux, handler)
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+func registerRoutes(mux *http.ServeMux, handler *couponHandler.CouponHandler) {
+	mux.HandleFunc("POST /coupons", handler.CreateCoupon)
+	mux.HandleFunc("GET /coupons", handler.GetAll)
+	mux.HandleFunc("GET /coupons/{id}", handler.GetByID)
+	mux.HandleFunc("DELETE /coupons/{id}", handler.DeleteByID)
+}
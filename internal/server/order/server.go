@@ -2,53 +2,45 @@ package order
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
+	"time"
 
 	orderHandler "github.com/maithuc2003/re-book-api/internal/handler/order"
+	"github.com/maithuc2003/re-book-api/internal/middleware"
+	"github.com/maithuc2003/re-book-api/internal/models"
 	orderRepo "github.com/maithuc2003/re-book-api/internal/repositories/order"
 	orderService "github.com/maithuc2003/re-book-api/internal/service/order"
 )
 
-func SetupOrderServer(mux *http.ServeMux, db *sql.DB) {
+// SetupOrderServer trả về service để main chạy các job nền (vd: xoá Idempotency-Key hết hạn).
+func SetupOrderServer(mux *http.ServeMux, db *sql.DB, logger *slog.Logger, recorder orderService.Recorder, idempotencyTTL time.Duration) *orderService.OrderService {
 	// Khởi tạo các tầng
-	repo := orderRepo.NewOrderRepo(db)
-	service := orderService.NewOrderService(repo)
-	handler := orderHandler.NewOrderHandler(service)
+	repo := orderRepo.NewOrderRepo(db, logger)
+	service := orderService.NewOrderService(repo, logger, recorder, idempotencyTTL)
+	handler := orderHandler.NewOrderHandler(service, logger)
+	registerRoutes(mux, handler)
+	return service
+}
 
-	mux.HandleFunc("/order/add", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			handler.CreateOrder(w, r) // Gọi hàm CreateOrder từ handler
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetAllOrders(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.GetByOrderID(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/order/delete", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			handler.DeleteByOrderID(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/order/update", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			handler.UpdateByOrderID(w, r)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
-	})
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+func registerRoutes(mux *http.ServeMux, handler *orderHandler.OrderHandler) {
+	mux.HandleFunc("GET /orders", handler.GetAllOrders)
+	mux.HandleFunc("POST /orders", handler.CreateOrder)
+	mux.HandleFunc("GET /orders/{id}", handler.GetByOrderID)
+	mux.HandleFunc("PUT /orders/{id}", handler.UpdateByOrderID)
+	mux.HandleFunc("PATCH /orders/{id}", handler.PatchByOrderID)
+	mux.HandleFunc("DELETE /orders/{id}", handler.DeleteByOrderID)
+	// POST /orders/{id}/cancel, /ship, ... (xem models.OrderActions)
+	for action, status := range models.OrderActions {
+		mux.HandleFunc("POST /orders/{id}/"+action, handler.Transition(status))
+	}
+	// Confirm tạo order nên nằm ở đây; các route /reservations khác ở server/reservation
+	mux.HandleFunc("POST /reservations/{id}/confirm", handler.ConfirmReservation)
 
+	// Route cũ, giữ lại cho client hiện tại trong thời gian migrate
+	mux.HandleFunc("POST /order/add", middleware.Deprecated("/orders", handler.CreateOrder))
+	mux.HandleFunc("GET /order", middleware.Deprecated("/orders/{id}", handler.GetByOrderID))
+	mux.HandleFunc("DELETE /order/delete", middleware.Deprecated("/orders/{id}", handler.DeleteByOrderID))
+	mux.HandleFunc("PUT /order/update", middleware.Deprecated("/orders/{id}", handler.UpdateByOrderID))
 }
@@ -0,0 +1,158 @@
+package order
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	orderHandler "github.com/maithuc2003/re-book-api/internal/handler/order"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/test/mockservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestMux(service *mockservice.MockOrderService) *http.ServeMux {
+	mux := http.NewServeMux()
+	registerRoutes(mux, orderHandler.NewOrderHandler(service, slog.New(slog.DiscardHandler)))
+	return mux
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		prepare        func(m *mockservice.MockOrderService)
+		expectedStatus int
+		expectedHeader map[string]string
+		expectedBody   string
+	}{
+		{
+			name:   "GET /orders/{id}",
+			method: http.MethodGet,
+			url:    "/orders/7",
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("GetByOrderID", mock.Anything, 7).Return(&models.Order{ID: 7, Status: models.OrderPending}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":7`,
+		},
+		{
+			name:   "POST /orders/{id}/cancel",
+			method: http.MethodPost,
+			url:    "/orders/7/cancel",
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("TransitionOrder", mock.Anything, 7, models.OrderCancelled).Return(&models.Order{ID: 7, Status: models.OrderCancelled}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"cancelled"`,
+		},
+		{
+			name:           "Invalid id in path",
+			method:         http.MethodGet,
+			url:            "/orders/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid 'id' parameter",
+		},
+		{
+			name:           "Method not allowed lists allowed methods",
+			method:         http.MethodDelete,
+			url:            "/orders",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "GET, HEAD, POST"},
+		},
+		{
+			name:           "Method not allowed on order resource",
+			method:         http.MethodPost,
+			url:            "/orders/7",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "DELETE, GET, HEAD, PATCH, PUT"},
+		},
+		{
+			name:           "Transition only accepts POST",
+			method:         http.MethodGet,
+			url:            "/orders/7/cancel",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "POST"},
+		},
+		{
+			name:           "Legacy route rejects wrong method",
+			method:         http.MethodGet,
+			url:            "/order/add",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedHeader: map[string]string{"Allow": "POST"},
+		},
+		{
+			name:   "Legacy GET /order is a deprecated alias",
+			method: http.MethodGet,
+			url:    "/order?id=7",
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("GetByOrderID", mock.Anything, 7).Return(&models.Order{ID: 7}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true", "Link": `</orders/{id}>; rel="successor-version"`},
+			expectedBody:   `"id":7`,
+		},
+		{
+			name:   "Legacy POST /order/add is a deprecated alias",
+			method: http.MethodPost,
+			url:    "/order/add",
+			body:   `{"book_id":1,"user_id":2,"quantity":1}`,
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+					return o.BookID == 1 && o.UserID == 2
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+			expectedBody:   "Order created successfully",
+		},
+		{
+			name:   "Legacy DELETE /order/delete is a deprecated alias",
+			method: http.MethodDelete,
+			url:    "/order/delete?id=7",
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("DeleteByOrderID", mock.Anything, 7).Return(&models.Order{ID: 7}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+		},
+		{
+			name:   "Legacy PUT /order/update is a deprecated alias",
+			method: http.MethodPut,
+			url:    "/order/update?id=7",
+			body:   `{"book_id":1,"user_id":2,"quantity":3}`,
+			prepare: func(m *mockservice.MockOrderService) {
+				m.On("UpdateByOrderID", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+					return o.ID == 7
+				})).Return(&models.Order{ID: 7, Quantity: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeader: map[string]string{"Deprecation": "true"},
+			expectedBody:   `"quantity":3`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockservice.MockOrderService)
+			if tc.prepare != nil {
+				tc.prepare(service)
+			}
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+
+			newTestMux(service).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			for k, v := range tc.expectedHeader {
+				assert.Equal(t, v, w.Header().Get(k))
+			}
+			assert.Contains(t, w.Body.String(), tc.expectedBody)
+			service.AssertExpectations(t)
+		})
+	}
+}
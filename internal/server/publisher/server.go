@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	publisherHandler "github.com/maithuc2003/re-book-api/internal/handler/publisher"
+	publisherRepo "github.com/maithuc2003/re-book-api/internal/repositories/publisher"
+	publisherService "github.com/maithuc2003/re-book-api/internal/service/publisher"
+)
+
+func SetupServerPublisher(mux *http.ServeMux, db *sql.DB, logger *slog.Logger) {
+	repo := publisherRepo.NewPublisherRepo(db, logger)
+	service := publisherService.NewPublisherService(repo, logger)
+	handler := publisherHandler.NewPublisherHandler(service, logger)
+	registerRoutes(mux, handler)
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+// GET /publishers/{id}/books được đăng ký ở server book vì do book handler xử lý.
+func registerRoutes(mux *http.ServeMux, handler *publisherHandler.PublisherHandler) {
+	mux.HandleFunc("GET /publishers", handler.GetAll)
+	mux.HandleFunc("POST /publishers", handler.CreatePublisher)
+	mux.HandleFunc("GET /publishers/{id}", handler.GetByID)
+	mux.HandleFunc("PUT /publishers/{id}", handler.UpdateByID)
+	mux.HandleFunc("PATCH /publishers/{id}", handler.PatchByID)
+	mux.HandleFunc("DELETE /publishers/{id}", handler.DeleteByID)
+}
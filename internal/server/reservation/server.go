@@ -0,0 +1,30 @@
+package reservation
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	reservationHandler "github.com/maithuc2003/re-book-api/internal/handler/reservation"
+	reservationRepo "github.com/maithuc2003/re-book-api/internal/repositories/reservation"
+	reservationService "github.com/maithuc2003/re-book-api/internal/service/reservation"
+)
+
+// SetupServerReservation trả về service để main chạy sweeper đánh dấu reservation hết hạn.
+// POST /reservations/{id}/confirm tạo order nên được đăng ký ở server/order.
+// allocator nhận hàng vừa hết bị giữ (release hoặc hết hạn) để phân bổ cho các order backordered.
+func SetupServerReservation(mux *http.ServeMux, db *sql.DB, logger *slog.Logger, allocator reservationService.BackorderAllocator, ttl, maxTTL time.Duration) *reservationService.ReservationService {
+	repo := reservationRepo.NewReservationRepo(db, logger)
+	service := reservationService.NewReservationService(repo, logger, allocator, ttl, maxTTL)
+	handler := reservationHandler.NewReservationHandler(service, logger)
+	registerRoutes(mux, handler)
+	return service
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+func registerRoutes(mux *http.ServeMux, handler *reservationHandler.ReservationHandler) {
+	mux.HandleFunc("POST /reservations", handler.Reserve)
+	mux.HandleFunc("GET /reservations/{id}", handler.GetByID)
+	mux.HandleFunc("POST /reservations/{id}/release", handler.Release)
+}
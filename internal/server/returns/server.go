@@ -0,0 +1,30 @@
+package returns
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	returnHandler "github.com/maithuc2003/re-book-api/internal/handler/returns"
+	returnRepo "github.com/maithuc2003/re-book-api/internal/repositories/returns"
+	returnService "github.com/maithuc2003/re-book-api/internal/service/returns"
+)
+
+// allocator phân bổ sách trả đã nhập lại kho cho các order backordered.
+func SetupServerReturns(mux *http.ServeMux, db *sql.DB, logger *slog.Logger, allocator returnService.BackorderAllocator) {
+	repo := returnRepo.NewReturnRepo(db, logger)
+	service := returnService.NewReturnService(repo, logger, allocator)
+	handler := returnHandler.NewReturnHandler(service, logger)
+	registerRoutes(mux, handler)
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+func registerRoutes(mux *http.ServeMux, handler *returnHandler.ReturnHandler) {
+	mux.HandleFunc("POST /orders/{id}/returns", handler.RequestReturn)
+	mux.HandleFunc("GET /orders/{id}/returns", handler.ListByOrder)
+	mux.HandleFunc("GET /orders/{id}/refunds", handler.ListRefunds)
+	mux.HandleFunc("GET /returns/{id}", handler.GetByID)
+	mux.HandleFunc("POST /returns/{id}/approve", handler.Approve)
+	mux.HandleFunc("POST /returns/{id}/reject", handler.Reject)
+	mux.HandleFunc("POST /returns/{id}/receive", handler.Receive)
+}
@@ -0,0 +1,18 @@
+package search
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	searchHandler "github.com/maithuc2003/re-book-api/internal/handler/search"
+	searchRepo "github.com/maithuc2003/re-book-api/internal/repositories/search"
+	searchService "github.com/maithuc2003/re-book-api/internal/service/search"
+)
+
+func SetupServerSearch(mux *http.ServeMux, db *sql.DB, logger *slog.Logger) {
+	repo := searchRepo.NewSearchRepo(db, logger)
+	service := searchService.NewSearchService(repo, logger)
+	handler := searchHandler.NewSearchHandler(service, logger)
+	mux.HandleFunc("GET /search", handler.Search)
+}
@@ -0,0 +1,25 @@
+package stock
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	stockHandler "github.com/maithuc2003/re-book-api/internal/handler/stock"
+	stockRepo "github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	stockService "github.com/maithuc2003/re-book-api/internal/service/stock"
+)
+
+// allocator nhận hàng vừa nhập để phân bổ cho các order backordered.
+func SetupServerStock(mux *http.ServeMux, db *sql.DB, logger *slog.Logger, allocator stockService.BackorderAllocator) {
+	repo := stockRepo.NewStockRepo(db, logger)
+	service := stockService.NewStockService(repo, logger, allocator)
+	handler := stockHandler.NewStockHandler(service, logger)
+	registerRoutes(mux, handler)
+}
+
+// registerRoutes khai báo route theo pattern method + path của ServeMux (Go 1.22+).
+func registerRoutes(mux *http.ServeMux, handler *stockHandler.StockHandler) {
+	mux.HandleFunc("GET /books/{id}/stock/movements", handler.GetMovements)
+	mux.HandleFunc("POST /books/{id}/stock/movements", handler.CreateMovement)
+}
@@ -1,33 +1,50 @@
 package author_test
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"strings"
 	"testing"
 
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/maithuc2003/re-book-api/internal/service/author"
 	"github.com/maithuc2003/re-book-api/test/mockrepo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
+// authorPage bọc danh sách author thành page như repository trả về (nil khi có lỗi)
+func authorPage(authors []*models.Author, err error) any {
+	if err != nil {
+		return nil
+	}
+	return &pagination.Page[*models.Author]{Data: authors, Total: len(authors)}
+}
+
 func TestGetAllAuthors(t *testing.T) {
 	tests := []struct {
 		name           string
-		mockReturn     []*models.Author
+		filter         models.AuthorFilter
+		mockReturn     *pagination.Page[*models.Author]
 		mockError      error
-		expectedResult []*models.Author
+		expectedResult *pagination.Page[*models.Author]
 		expectErrorMsg string
 	}{
 		{
-			name: "Success with authors",
-			mockReturn: []*models.Author{
-				{ID: 1, Name: "Author A"},
-				{ID: 2, Name: "Author B"},
+			name:   "Success with authors",
+			filter: models.AuthorFilter{Nationality: "UK"},
+			mockReturn: &pagination.Page[*models.Author]{
+				Data:  []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}},
+				Total: 2,
+			},
+			mockError: nil,
+			expectedResult: &pagination.Page[*models.Author]{
+				Data:  []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}},
+				Total: 2,
 			},
-			mockError:      nil,
-			expectedResult: []*models.Author{{ID: 1, Name: "Author A"}, {ID: 2, Name: "Author B"}},
 		},
 		{
 			name:           "Repository error",
@@ -38,19 +55,25 @@ func TestGetAllAuthors(t *testing.T) {
 		},
 		{
 			name:           "Empty author list",
-			mockReturn:     []*models.Author{},
+			mockReturn:     &pagination.Page[*models.Author]{Data: []*models.Author{}},
 			mockError:      nil,
 			expectedResult: nil,
 			expectErrorMsg: "no authors found in the system",
 		},
+		{
+			name:           "Empty page after the last cursor",
+			filter:         models.AuthorFilter{Params: pagination.Params{Cursor: "abc"}},
+			mockReturn:     &pagination.Page[*models.Author]{Data: []*models.Author{}},
+			expectedResult: &pagination.Page[*models.Author]{Data: []*models.Author{}},
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockrepo := new(mockrepo.MockAuthorRepository)
-			mockrepo.On("GetAllAuthors").Return(tc.mockReturn, tc.mockError)
+			mockrepo.On("GetAllAuthors", mock.Anything, tc.filter).Return(tc.mockReturn, tc.mockError)
 
-			service := author.NewAuthorService(mockrepo)
-			result, err := service.GetAllAuthors()
+			service := author.NewAuthorService(mockrepo, slog.New(slog.DiscardHandler))
+			result, err := service.GetAllAuthors(context.Background(), tc.filter)
 			if tc.expectErrorMsg != "" {
 				require.Error(t, err)
 				assert.Nil(t, result)
@@ -121,16 +144,17 @@ func TestCreateAuthor(t *testing.T) {
 
 			// Only mock GetAllAuthors if input is non-nil and name is not empty
 			if tc.inputAuthor != nil && strings.TrimSpace(tc.inputAuthor.Name) != "" {
-				mockrepo.On("GetAllAuthors").Return(tc.existingAuthors, tc.getAllErr)
+				mockrepo.On("GetAllAuthors", mock.Anything, models.AuthorFilter{Name: tc.inputAuthor.Name}).
+					Return(authorPage(tc.existingAuthors, tc.getAllErr), tc.getAllErr)
 			}
 
 			//Mock CreateAuthor only when we expect the service to reach that point
 			if tc.expectErr == "" || strings.HasPrefix(tc.expectErr, "failed to create author") {
-				mockrepo.On("CreateAuthor", tc.inputAuthor).Return(tc.createErr)
+				mockrepo.On("CreateAuthor", mock.Anything, tc.inputAuthor).Return(tc.createErr)
 			}
 
-			service := author.NewAuthorService(mockrepo)
-			err := service.CreateAuthor(tc.inputAuthor)
+			service := author.NewAuthorService(mockrepo, slog.New(slog.DiscardHandler))
+			err := service.CreateAuthor(context.Background(), tc.inputAuthor)
 
 			// Assert expected error or success
 			if tc.expectErr != "" {
@@ -189,11 +213,11 @@ func TestGetByAuthor(t *testing.T) {
 
 			//Only mock if ID is positive
 			if tc.inputID > 0 {
-				mockrepo.On("GetByAuthorID", tc.inputID).Return(tc.mockReturn, tc.mockError)
+				mockrepo.On("GetByAuthorID", mock.Anything, tc.inputID).Return(tc.mockReturn, tc.mockError)
 			}
 
-			service := author.NewAuthorService(mockrepo)
-			result, err := service.GetByAuthorID(tc.inputID)
+			service := author.NewAuthorService(mockrepo, slog.New(slog.DiscardHandler))
+			result, err := service.GetByAuthorID(context.Background(), tc.inputID)
 			if tc.expectedErr != "" {
 				require.Error(t, err)
 				assert.EqualError(t, err, tc.expectedErr)
@@ -249,11 +273,11 @@ func TestDeleteById(t *testing.T) {
 
 			// Mock GetByAuthorID nếu DeleteById cần nó
 			if tc.inputID > 0 {
-				mockrepo.On("DeleteById", tc.inputID).Return(tc.mockReturn, tc.mockError)
+				mockrepo.On("DeleteById", mock.Anything, tc.inputID).Return(tc.mockReturn, tc.mockError)
 			}
 
-			service := author.NewAuthorService(mockrepo)
-			result, err := service.DeleteById(tc.inputID)
+			service := author.NewAuthorService(mockrepo, slog.New(slog.DiscardHandler))
+			result, err := service.DeleteById(context.Background(), tc.inputID)
 			if tc.expectedErr != "" {
 				require.Error(t, err)
 				assert.EqualError(t, err, tc.expectedErr)
@@ -350,21 +374,22 @@ func TestUpdateById(t *testing.T) {
 			mockrepo := new(mockrepo.MockAuthorRepository)
 
 			if tc.input != nil && tc.input.ID > 0 && strings.TrimSpace(tc.input.Name) != "" {
-				mockrepo.On("GetByAuthorID", tc.input.ID).Return(tc.mockGetByID, tc.mockErrors.getByID)
+				mockrepo.On("GetByAuthorID", mock.Anything, tc.input.ID).Return(tc.mockGetByID, tc.mockErrors.getByID)
 			}
 
 			if tc.mockGetAll != nil || tc.mockErrors.getAll != nil {
-				mockrepo.On("GetAllAuthors").Return(tc.mockGetAll, tc.mockErrors.getAll)
+				mockrepo.On("GetAllAuthors", mock.Anything, models.AuthorFilter{Name: tc.input.Name}).
+					Return(authorPage(tc.mockGetAll, tc.mockErrors.getAll), tc.mockErrors.getAll)
 			}
 
 			if tc.mockUpdate != nil && tc.mockErrors.update == nil {
-				mockrepo.On("UpdateById", tc.input).Return(tc.mockUpdate, nil)
+				mockrepo.On("UpdateById", mock.Anything, tc.input).Return(tc.mockUpdate, nil)
 			} else if tc.mockErrors.update != nil {
-				mockrepo.On("UpdateById", tc.input).Return(nil, tc.mockErrors.update)
+				mockrepo.On("UpdateById", mock.Anything, tc.input).Return(nil, tc.mockErrors.update)
 			}
 
-			service := author.NewAuthorService(mockrepo)
-			result, err := service.UpdateById(tc.input)
+			service := author.NewAuthorService(mockrepo, slog.New(slog.DiscardHandler))
+			result, err := service.UpdateById(context.Background(), tc.input)
 
 			if tc.expectedErr != "" {
 				require.Error(t, err)
@@ -1,11 +1,16 @@
 package author
 
-import "github.com/maithuc2003/re-book-api/internal/models"
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
 
 type AuthorServiceInterface interface {
-	CreateAuthor(author *models.Author) error
-	GetAllAuthors() ([]*models.Author, error)
-	GetByAuthorID(id int) (*models.Author, error)
-	DeleteById(id int) (*models.Author, error)
-	UpdateById(author *models.Author) (*models.Author, error)
+	CreateAuthor(ctx context.Context, author *models.Author) error
+	GetAllAuthors(ctx context.Context, filter models.AuthorFilter) (*pagination.Page[*models.Author], error)
+	GetByAuthorID(ctx context.Context, id int) (*models.Author, error)
+	DeleteById(ctx context.Context, id int) (*models.Author, error)
+	UpdateById(ctx context.Context, author *models.Author) (*models.Author, error)
 }
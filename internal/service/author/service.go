@@ -1,122 +1,128 @@
 package author
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 
+	"github.com/maithuc2003/re-book-api/internal/apperror"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	repositories "github.com/maithuc2003/re-book-api/internal/repositories/author"
 )
 
 type AuthorService struct {
-	repo repositories.AuthorRepositoriesInterface
+	repo   repositories.AuthorRepositoriesInterface
+	logger *slog.Logger
 }
 
-func NewAuthorService(repo repositories.AuthorRepositoriesInterface) *AuthorService {
-	return &AuthorService{repo: repo}
+func NewAuthorService(repo repositories.AuthorRepositoriesInterface, logger *slog.Logger) *AuthorService {
+	return &AuthorService{repo: repo, logger: logger}
 }
 
-func (s *AuthorService) CreateAuthor(author *models.Author) error {
+func (s *AuthorService) CreateAuthor(ctx context.Context, author *models.Author) error {
 	if author == nil {
-		return errors.New("author is nil")
+		return apperror.NewValidation("", "author is nil")
 	}
 	if strings.TrimSpace(author.Name) == "" {
-		return errors.New("author name cannot be empty")
+		return apperror.NewValidation("name", "author name cannot be empty")
 	}
-	existingAuthors, err := s.repo.GetAllAuthors()
+	existingAuthors, err := s.repo.GetAllAuthors(ctx, models.AuthorFilter{Name: author.Name})
 
 	if err != nil {
-		return fmt.Errorf("failed to fetch authors for validation: %v", err)
+		return fmt.Errorf("failed to fetch authors for validation: %w", err)
 	}
-	for _, existing := range existingAuthors {
+	for _, existing := range existingAuthors.Data {
 		if strings.EqualFold(existing.Name, author.Name) {
-			return errors.New("author with the same name already exists")
+			return apperror.Conflict("author with the same name already exists")
 		}
 	}
-	err = s.repo.CreateAuthor(author)
+	err = s.repo.CreateAuthor(ctx, author)
 	if err != nil {
-		return fmt.Errorf("failed to create author: %v", err)
+		return fmt.Errorf("failed to create author: %w", err)
 	}
+	s.logger.InfoContext(ctx, "author created", "author_id", author.ID)
 	return nil
 }
-func (s *AuthorService) GetAllAuthors() ([]*models.Author, error) {
-	authors, err := s.repo.GetAllAuthors()
+func (s *AuthorService) GetAllAuthors(ctx context.Context, filter models.AuthorFilter) (*pagination.Page[*models.Author], error) {
+	page, err := s.repo.GetAllAuthors(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	if len(authors) == 0 {
-		return nil, errors.New("no authors found in the system")
+	if page.Total == 0 && filter.Cursor == "" {
+		return nil, apperror.NotFound("no authors found in the system")
 	}
-	return s.repo.GetAllAuthors()
+	return page, nil
 }
 
-func (s *AuthorService) GetByAuthorID(id int) (*models.Author, error) {
+func (s *AuthorService) GetByAuthorID(ctx context.Context, id int) (*models.Author, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid author ID")
+		return nil, apperror.NewValidation("id", "invalid author ID")
 	}
-	author, err := s.repo.GetByAuthorID(id)
+	author, err := s.repo.GetByAuthorID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve author: %v", err)
+		return nil, fmt.Errorf("failed to retrieve author: %w", err)
 	}
 	if author == nil {
-		return nil, errors.New("author not found")
+		return nil, apperror.NotFound("author not found")
 	}
 	return author, nil
 }
 
-func (s *AuthorService) DeleteById(id int) (*models.Author, error) {
+func (s *AuthorService) DeleteById(ctx context.Context, id int) (*models.Author, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid author ID")
+		return nil, apperror.NewValidation("id", "invalid author ID")
 	}
 
-	deletedAuthor, err := s.repo.DeleteById(id)
+	deletedAuthor, err := s.repo.DeleteById(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete author: %v", err)
+		return nil, fmt.Errorf("failed to delete author: %w", err)
 	}
 	if deletedAuthor == nil {
-		return nil, errors.New("author not found or already deleted")
+		return nil, apperror.NotFound("author not found or already deleted")
 	}
+	s.logger.InfoContext(ctx, "author deleted", "author_id", id)
 	return deletedAuthor, nil
 }
 
-func (s *AuthorService) UpdateById(author *models.Author) (*models.Author, error) {
+func (s *AuthorService) UpdateById(ctx context.Context, author *models.Author) (*models.Author, error) {
 	if author == nil {
-		return nil, errors.New("author is nil")
+		return nil, apperror.NewValidation("", "author is nil")
 	}
 	//Validate the author ID
 	if author.ID <= 0 {
-		return nil, errors.New("invalid author ID")
+		return nil, apperror.NewValidation("id", "invalid author ID")
 	}
 	//Ensure the author's name is not empty or just whitespace
 	if strings.TrimSpace(author.Name) == "" {
-		return nil, errors.New("author name cannot be empty")
+		return nil, apperror.NewValidation("name", "author name cannot be empty")
 	}
 	// Check if the author with the given ID actually exists
-	existring, err := s.repo.GetByAuthorID(author.ID)
+	existring, err := s.repo.GetByAuthorID(ctx, author.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch existing author: %v", err)
+		return nil, fmt.Errorf("failed to fetch existing author: %w", err)
 	}
 	if existring == nil {
-		return nil, errors.New("author not found")
+		return nil, apperror.NotFound("author not found")
 	}
 	//Ensure the new same does not conflict with any other author's name
-	authors, err := s.repo.GetAllAuthors()
+	authors, err := s.repo.GetAllAuthors(ctx, models.AuthorFilter{Name: author.Name})
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate author name: %v", err)
+		return nil, fmt.Errorf("failed to validate author name: %w", err)
 	}
 
-	for _, a := range authors {
+	for _, a := range authors.Data {
 		//Allow the current author to keep their name, but prevent duplicate
 		if a.ID != author.ID && strings.EqualFold(a.Name, author.Name) {
-			return nil, errors.New("another author with the same name already exists")
+			return nil, apperror.Conflict("another author with the same name already exists")
 		}
 	}
 
 	// Attempt to update the author in the repository
-	updateAuthor, err := s.repo.UpdateById(author)
+	updateAuthor, err := s.repo.UpdateById(ctx, author)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update author : %v", err)
+		return nil, fmt.Errorf("failed to update author : %w", err)
 	}
 	return updateAuthor, nil
 }
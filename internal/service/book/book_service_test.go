@@ -0,0 +1,318 @@
+package book_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/book"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAllocator ghi lại các sách được yêu cầu phân bổ backorder
+type fakeAllocator struct {
+	books []int
+	err   error
+}
+
+func (a *fakeAllocator) AllocateBackorders(_ context.Context, bookID int) ([]int, error) {
+	a.books = append(a.books, bookID)
+	return nil, a.err
+}
+
+func newService(repo *mockrepo.MockBookRepository, allocator book.BackorderAllocator) *book.BookService {
+	return book.NewBookService(repo, slog.New(slog.DiscardHandler), allocator)
+}
+
+func priceOf(v int64) *int64 { return &v }
+
+// validBook là sách hợp lệ tối thiểu; từng test sửa field cần kiểm tra
+func validBook() *models.Book {
+	return &models.Book{Title: "Mắt biếc", AuthorID: 1, Price: priceOf(85000)}
+}
+
+func TestCreateBook_Backorder(t *testing.T) {
+	expected := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		policy         string
+		expectedAt     *time.Time
+		expectedPolicy string
+		errMsg         string
+	}{
+		{name: "Default policy is none", expectedPolicy: "none"},
+		{name: "Policy is normalized", policy: " Backorder ", expectedPolicy: "backorder"},
+		{name: "Preorder with expected date", policy: "preorder", expectedAt: &expected, expectedPolicy: "preorder"},
+		{name: "Preorder requires expected_at", policy: "preorder", errMsg: "expected_at is required for preorder books"},
+		{name: "Unknown policy", policy: "later", errMsg: "backorder_policy must be one of none, backorder, preorder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			b := validBook()
+			b.BackorderPolicy, b.ExpectedAt = tt.policy, tt.expectedAt
+			if tt.errMsg == "" {
+				repo.On("Create", mock.Anything, b).Return(nil)
+			}
+
+			err := newService(repo, &fakeAllocator{}).CreateBook(context.Background(), b)
+
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedPolicy, b.BackorderPolicy)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateById_AllocatesBackorders(t *testing.T) {
+	tests := []struct {
+		name          string
+		stock         int
+		delta         int
+		allocErr      error
+		expectedBooks []int
+	}{
+		{name: "Restock allocates", stock: 3, delta: 2, expectedBooks: []int{7}},
+		{name: "Allocation failure is only logged", stock: 3, delta: 2, allocErr: errors.New("deadlock"), expectedBooks: []int{7}},
+		{name: "Unchanged stock does not allocate", stock: 3},
+		{name: "Lower stock does not allocate", stock: 1, delta: -2},
+		{name: "Out of stock does not allocate", stock: 0, delta: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			b := validBook()
+			b.ID, b.Stock = 7, tt.stock
+			repo.On("UpdateById", mock.Anything, b).Return(&models.Book{ID: 7, Stock: tt.stock}, tt.delta, nil)
+			allocator := &fakeAllocator{err: tt.allocErr}
+			var logs bytes.Buffer
+			svc := book.NewBookService(repo, slog.New(slog.NewTextHandler(&logs, nil)), allocator)
+
+			updated, err := svc.UpdateById(context.Background(), b)
+
+			require.NoError(t, err)
+			assert.Equal(t, 7, updated.ID)
+			assert.Equal(t, tt.expectedBooks, allocator.books)
+			if tt.allocErr != nil {
+				assert.Contains(t, logs.String(), `msg="allocate backorders failed" book_id=7 err=deadlock`)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBook_Price(t *testing.T) {
+	tests := []struct {
+		name             string
+		price            *int64
+		currency         string
+		expectedCurrency string
+		errMsg           string
+	}{
+		{name: "Default currency", price: priceOf(85000), expectedCurrency: "VND"},
+		{name: "Currency is normalized", price: priceOf(1299), currency: " usd ", expectedCurrency: "USD"},
+		{name: "Missing price", errMsg: "book price is required"},
+		{name: "Free book", price: priceOf(0), expectedCurrency: "VND"},
+		{name: "Negative price", price: priceOf(-1), errMsg: "book price cannot be negative"},
+		{name: "Invalid currency", price: priceOf(1299), currency: "dollar", errMsg: "currency must be a 3-letter ISO 4217 code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			b := validBook()
+			b.Price, b.Currency = tt.price, tt.currency
+			if tt.errMsg == "" {
+				repo.On("Create", mock.Anything, b).Return(nil)
+			}
+
+			err := newService(repo, &fakeAllocator{}).CreateBook(context.Background(), b)
+
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedCurrency, b.Currency)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateById_RejectsMissingPrice(t *testing.T) {
+	repo := new(mockrepo.MockBookRepository)
+	b := validBook()
+	b.ID, b.Price = 7, nil
+
+	_, err := newService(repo, &fakeAllocator{}).UpdateById(context.Background(), b)
+
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.EqualError(t, err, "book price is required")
+	repo.AssertExpectations(t)
+}
+
+func TestCreateBook_Authors(t *testing.T) {
+	tests := []struct {
+		name             string
+		authorID         int
+		authors          []models.BookAuthor
+		expectedAuthorID int
+		expectedAuthors  []models.BookAuthor
+		errField         string
+		errMsg           string
+	}{
+		{
+			name:             "Legacy author_id becomes the only author",
+			authorID:         1,
+			expectedAuthorID: 1,
+			expectedAuthors:  []models.BookAuthor{{AuthorID: 1, Role: "author"}},
+		},
+		{
+			name:     "Authors list wins over legacy author_id",
+			authorID: 9,
+			authors: []models.BookAuthor{
+				{AuthorID: 3, Role: "translator"},
+				{AuthorID: 4, Role: " Author "},
+			},
+			expectedAuthorID: 4,
+			expectedAuthors: []models.BookAuthor{
+				{AuthorID: 3, Role: "translator"},
+				{AuthorID: 4, Role: "author"},
+			},
+		},
+		{
+			name: "Same author in two roles",
+			authors: []models.BookAuthor{
+				{AuthorID: 3, Role: "author"},
+				{AuthorID: 3, Role: "illustrator"},
+			},
+			expectedAuthorID: 3,
+			expectedAuthors: []models.BookAuthor{
+				{AuthorID: 3, Role: "author"},
+				{AuthorID: 3, Role: "illustrator"},
+			},
+		},
+		{
+			name: "Duplicate author and role",
+			authors: []models.BookAuthor{
+				{AuthorID: 3, Role: "author"},
+				{AuthorID: 5, Role: "editor"},
+				{AuthorID: 3},
+			},
+			errField: "authors[2]",
+			errMsg:   "author 3 is listed twice as author",
+		},
+		{name: "Empty list without author_id", authors: []models.BookAuthor{}, errField: "author_id", errMsg: "book author ID is required"},
+		{name: "Invalid author ID in list", authors: []models.BookAuthor{{AuthorID: 0}}, errField: "authors[0].author_id", errMsg: "book author ID is required"},
+		{name: "Unknown role", authors: []models.BookAuthor{{AuthorID: 3, Role: "ghost"}}, errField: "authors[0].role", errMsg: "role must be one of author, editor, translator, illustrator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			b := validBook()
+			b.AuthorID, b.Authors = tt.authorID, tt.authors
+			if tt.errMsg == "" {
+				repo.On("Create", mock.Anything, b).Return(nil)
+			}
+
+			err := newService(repo, &fakeAllocator{}).CreateBook(context.Background(), b)
+
+			if tt.errMsg != "" {
+				var verr *apperror.ValidationError
+				require.ErrorAs(t, err, &verr)
+				assert.Equal(t, tt.errField, verr.Field)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedAuthorID, b.AuthorID)
+				assert.Equal(t, tt.expectedAuthors, b.Authors)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBook_ISBN(t *testing.T) {
+	tests := []struct {
+		name           string
+		isbn           string
+		expectedISBN   string
+		expectedISBN10 string
+		errMsg         string
+	}{
+		{name: "ISBN-10 is stored as ISBN-13", isbn: "0-306-40615-2", expectedISBN: "9780306406157", expectedISBN10: "0306406152"},
+		{name: "ISBN-13 with hyphens", isbn: "978-0-306-40615-7", expectedISBN: "9780306406157", expectedISBN10: "0306406152"},
+		{name: "979 prefix has no ISBN-10", isbn: "979-10-90636-07-1", expectedISBN: "9791090636071"},
+		{name: "ISBN is optional", isbn: "  "},
+		{name: "Invalid ISBN-10 checksum", isbn: "0-306-40615-3", errMsg: "isbn must be a valid ISBN-10 or ISBN-13"},
+		{name: "Invalid ISBN-13 checksum", isbn: "9780306406158", errMsg: "isbn must be a valid ISBN-10 or ISBN-13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			b := validBook()
+			b.ISBN = tt.isbn
+			if tt.errMsg == "" {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(saved *models.Book) bool {
+					return saved.ISBN == tt.expectedISBN && saved.ISBN10 == tt.expectedISBN10
+				})).Return(nil)
+			}
+
+			err := newService(repo, &fakeAllocator{}).CreateBook(context.Background(), b)
+
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetByISBN(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		lookup string
+		errMsg string
+	}{
+		{name: "ISBN-10 is looked up as ISBN-13", code: "0-306-40615-2", lookup: "9780306406157"},
+		{name: "ISBN-13 with hyphens", code: "978-0-306-40615-7", lookup: "9780306406157"},
+		{name: "Invalid checksum", code: "0306406153", errMsg: "isbn must be a valid ISBN-10 or ISBN-13"},
+		{name: "Not an ISBN", code: "abc", errMsg: "isbn must be a valid ISBN-10 or ISBN-13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockBookRepository)
+			if tt.errMsg == "" {
+				repo.On("GetByISBN", mock.Anything, tt.lookup).Return(&models.Book{ID: 7, ISBN: tt.lookup}, nil)
+			}
+
+			found, err := newService(repo, &fakeAllocator{}).GetByISBN(context.Background(), tt.code)
+
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 7, found.ID)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package book
 
-import "github.com/maithuc2003/re-book-api/internal/models"
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
 
 type BookServiceInterface interface {
-	CreateBook(book *models.Book) error
-	GetAllBooks() ([]*models.Book, error)
-	GetByBookID(id int) (*models.Book, error)
-	DeleteById(id int) (*models.Book, error)
-	UpdateById(book *models.Book) (*models.Book, error)
+	CreateBook(ctx context.Context, book *models.Book) error
+	GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error)
+	GetByBookID(ctx context.Context, id int) (*models.Book, error)
+	GetByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	GetBooksByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error)
+	DeleteById(ctx context.Context, id int) (*models.Book, error)
+	UpdateById(ctx context.Context, book *models.Book) (*models.Book, error)
 }
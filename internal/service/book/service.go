@@ -1,82 +1,297 @@
 package book
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
 
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/isbn"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	repositories "github.com/maithuc2003/re-book-api/internal/repositories/book"
 )
 
+// BackorderAllocator phân bổ stock cho các order đang chờ hàng (vd: *order.OrderService).
+type BackorderAllocator interface {
+	AllocateBackorders(ctx context.Context, bookID int) ([]int, error)
+}
+
 type BookService struct {
-	repo repositories.BookRepoInterface
+	repo      repositories.BookRepoInterface
+	logger    *slog.Logger
+	allocator BackorderAllocator
 }
 
-func NewBookService(repo repositories.BookRepoInterface) *BookService {
-	return &BookService{repo: repo}
+func NewBookService(repo repositories.BookRepoInterface, logger *slog.Logger, allocator BackorderAllocator) *BookService {
+	return &BookService{repo: repo, logger: logger, allocator: allocator}
 }
-func (s *BookService) CreateBook(book *models.Book) error {
+func (s *BookService) CreateBook(ctx context.Context, book *models.Book) error {
 	if book == nil {
-		return errors.New("book is nil")
+		return apperror.NewValidation("", "book is nil")
 	}
 	if strings.TrimSpace(book.Title) == "" {
-		return errors.New("book title is required")
+		return apperror.NewValidation("title", "book title is required")
 	}
-	if book.AuthorID <= 0 {
-		return errors.New("book author ID is required")
+	if err := validateAuthors(book); err != nil {
+		return err
 	}
 	if book.Stock < 0 {
-		return errors.New("book quantity cannot be negative")
+		return apperror.NewValidation("stock", "book quantity cannot be negative")
+	}
+	if err := validateCategories(book); err != nil {
+		return err
+	}
+	if err := validateEdition(book); err != nil {
+		return err
+	}
+	if err := validateISBN(book); err != nil {
+		return err
+	}
+	if err := validatePrice(book); err != nil {
+		return err
+	}
+	if err := validateBackorder(book); err != nil {
+		return err
 	}
 
-	return s.repo.Create(book)
+	if err := s.repo.Create(ctx, book); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "book created", "book_id", book.ID, "author_id", book.AuthorID, "authors", len(book.Authors), "stock", book.Stock)
+	return nil
 }
 
-// GetAllBooks trả về lỗi nếu không có sách nào
-func (s *BookService) GetAllBooks() ([]*models.Book, error) {
-	books, err := s.repo.GetAllBooks()
+// GetAllBooks trả về lỗi nếu không có sách nào khớp filter.
+// Trang sau cùng (có cursor) được phép rỗng.
+func (s *BookService) GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error) {
+	if filter.AuthorID < 0 {
+		return nil, apperror.NewValidation("author_id", "invalid author ID")
+	}
+	if filter.CategoryID < 0 {
+		return nil, apperror.NewValidation("category_id", "invalid category ID")
+	}
+	if filter.WorkID < 0 {
+		return nil, apperror.NewValidation("work_id", "invalid work ID")
+	}
+	if filter.PublisherID < 0 {
+		return nil, apperror.NewValidation("publisher_id", "invalid publisher ID")
+	}
+	if filter.MinStock != nil && *filter.MinStock < 0 {
+		return nil, apperror.NewValidation("min_stock", "min_stock cannot be negative")
+	}
+	if filter.MinStock != nil && filter.MaxStock != nil && *filter.MinStock > *filter.MaxStock {
+		return nil, apperror.NewValidation("max_stock", "max_stock must be greater than or equal to min_stock")
+	}
+	page, err := s.repo.GetAllBooks(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	if len(books) == 0 {
-		return nil, errors.New("no books found")
+	if page.Total == 0 && filter.Cursor == "" {
+		return nil, apperror.NotFound("no books found")
 	}
-	return books, nil
+	return page, nil
 }
 
 // GetByBookID kiểm tra ID hợp lệ
-func (s *BookService) GetByBookID(id int) (*models.Book, error) {
+func (s *BookService) GetByBookID(ctx context.Context, id int) (*models.Book, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid book ID")
+		return nil, apperror.NewValidation("id", "invalid book ID")
 	}
-	return s.repo.GetByBookID(id)
+	return s.repo.GetByBookID(ctx, id)
+}
+
+// GetByISBN nhận ISBN-10 hoặc ISBN-13 (có thể có dấu gạch), tìm theo dạng ISBN-13
+func (s *BookService) GetByISBN(ctx context.Context, code string) (*models.Book, error) {
+	normalized, err := isbn.Normalize(code)
+	if err != nil {
+		return nil, apperror.NewValidation("isbn", "isbn must be a valid ISBN-10 or ISBN-13")
+	}
+	return s.repo.GetByISBN(ctx, normalized)
+}
+
+// GetBooksByAuthorID trả về danh sách rỗng nếu tác giả chưa có sách nào
+func (s *BookService) GetBooksByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error) {
+	if authorID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid author ID")
+	}
+	return s.repo.GetByAuthorID(ctx, authorID)
 }
 
 // DeleteById kiểm tra ID hợp lệ
-func (s *BookService) DeleteById(id int) (*models.Book, error) {
+func (s *BookService) DeleteById(ctx context.Context, id int) (*models.Book, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid book ID")
+		return nil, apperror.NewValidation("id", "invalid book ID")
 	}
-	return s.repo.DeleteById(id)
+	book, err := s.repo.DeleteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "book deleted", "book_id", id)
+	return book, nil
 }
 
 // UpdateById kiểm tra dữ liệu trước khi cập nhật
-func (s *BookService) UpdateById(book *models.Book) (*models.Book, error) {
+func (s *BookService) UpdateById(ctx context.Context, book *models.Book) (*models.Book, error) {
 	if book == nil {
-		return nil, errors.New("book is nil")
+		return nil, apperror.NewValidation("", "book is nil")
 	}
 	if book.ID <= 0 {
-		return nil, errors.New("invalid book ID")
+		return nil, apperror.NewValidation("id", "invalid book ID")
 	}
 	if strings.TrimSpace(book.Title) == "" {
-		return nil, errors.New("book title is required")
+		return nil, apperror.NewValidation("title", "book title is required")
 	}
-	if book.AuthorID <= 0 {
-		return nil, errors.New("book author ID is required")
+	if err := validateAuthors(book); err != nil {
+		return nil, err
 	}
 	if book.Stock < 0 {
-		return nil, errors.New("book quantity cannot be negative")
+		return nil, apperror.NewValidation("stock", "book quantity cannot be negative")
+	}
+	if err := validateCategories(book); err != nil {
+		return nil, err
+	}
+	if err := validateEdition(book); err != nil {
+		return nil, err
+	}
+	if err := validateISBN(book); err != nil {
+		return nil, err
+	}
+	if err := validatePrice(book); err != nil {
+		return nil, err
+	}
+	if err := validateBackorder(book); err != nil {
+		return nil, err
 	}
 
-	return s.repo.UpdateById(book)
+	updated, delta, err := s.repo.UpdateById(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+	// Tăng stock qua PUT/PATCH cũng là nhập hàng với các order đang chờ; đổi tên, giá... thì không
+	if delta > 0 {
+		if _, err := s.allocator.AllocateBackorders(ctx, updated.ID); err != nil {
+			s.logger.ErrorContext(ctx, "allocate backorders failed", "book_id", updated.ID, "err", err)
+		}
+	}
+	return updated, nil
+}
+
+// validateAuthors chuẩn hoá danh sách tác giả: payload cũ {author_id} thành một tác giả,
+// role mặc định author, không trùng cặp (author_id, role).
+func validateAuthors(book *models.Book) error {
+	if len(book.Authors) == 0 {
+		if book.AuthorID <= 0 {
+			return apperror.NewValidation("author_id", "book author ID is required")
+		}
+		book.NormalizeAuthors()
+		return nil
+	}
+	seen := make(map[models.BookAuthor]bool, len(book.Authors))
+	for i := range book.Authors {
+		a := &book.Authors[i]
+		if a.AuthorID <= 0 {
+			return apperror.NewValidation(fmt.Sprintf("authors[%d].author_id", i), "book author ID is required")
+		}
+		a.Role = strings.ToLower(strings.TrimSpace(a.Role))
+		if a.Role == "" {
+			a.Role = models.RoleAuthor
+		}
+		if !slices.Contains(models.AuthorRoles, a.Role) {
+			return apperror.NewValidation(fmt.Sprintf("authors[%d].role", i), "role must be one of author, editor, translator, illustrator")
+		}
+		// Name do server điền khi đọc
+		a.Name = ""
+		if seen[*a] {
+			return apperror.NewValidation(fmt.Sprintf("authors[%d]", i), fmt.Sprintf("author %d is listed twice as %s", a.AuthorID, a.Role))
+		}
+		seen[*a] = true
+	}
+	book.NormalizeAuthors()
+	return nil
+}
+
+// validateCategories bỏ category_id trùng, giữ thứ tự; id phải dương.
+func validateCategories(book *models.Book) error {
+	ids := make([]int, 0, len(book.CategoryIDs))
+	for _, id := range book.CategoryIDs {
+		if id <= 0 {
+			return apperror.NewValidation("category_ids", "category IDs must be positive")
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	book.CategoryIDs = ids
+	return nil
+}
+
+// validateEdition chuẩn hoá thông tin ấn bản; format và language không bắt buộc.
+func validateEdition(book *models.Book) error {
+	if book.WorkID < 0 {
+		return apperror.NewValidation("work_id", "invalid work ID")
+	}
+	book.Format = strings.ToLower(strings.TrimSpace(book.Format))
+	if book.Format != "" && !slices.Contains(models.BookFormats, book.Format) {
+		return apperror.NewValidation("format", "format must be one of hardcover, paperback, ebook, audiobook")
+	}
+	book.Language = strings.ToLower(strings.TrimSpace(book.Language))
+	if book.Language != "" && !models.IsLanguageCode(book.Language) {
+		return apperror.NewValidation("language", "language must be an ISO 639 code such as vi or en")
+	}
+	if book.PublisherID != nil && *book.PublisherID <= 0 {
+		return apperror.NewValidation("publisher_id", "invalid publisher ID")
+	}
+	return nil
+}
+
+// validateISBN chuẩn hoá ISBN (nếu có) về ISBN-13; sách không bắt buộc có ISBN.
+func validateISBN(book *models.Book) error {
+	if strings.TrimSpace(book.ISBN) == "" {
+		book.ISBN = ""
+	} else {
+		normalized, err := isbn.Normalize(book.ISBN)
+		if err != nil {
+			return apperror.NewValidation("isbn", "isbn must be a valid ISBN-10 or ISBN-13")
+		}
+		book.ISBN = normalized
+	}
+	book.SetISBN10()
+	return nil
+}
+
+// validatePrice bắt buộc có giá và không âm (0 là sách miễn phí); currency được chuẩn hoá in hoa,
+// mặc định DefaultCurrency.
+func validatePrice(book *models.Book) error {
+	if book.Price == nil {
+		return apperror.NewValidation("price", "book price is required")
+	}
+	if *book.Price < 0 {
+		return apperror.NewValidation("price", "book price cannot be negative")
+	}
+	book.Currency = strings.ToUpper(strings.TrimSpace(book.Currency))
+	if book.Currency == "" {
+		book.Currency = models.DefaultCurrency
+	}
+	if !models.IsCurrencyCode(book.Currency) {
+		return apperror.NewValidation("currency", "currency must be a 3-letter ISO 4217 code")
+	}
+	return nil
+}
+
+// validateBackorder chuẩn hoá backorder_policy (mặc định none); preorder phải có ngày dự kiến có hàng.
+func validateBackorder(book *models.Book) error {
+	book.BackorderPolicy = strings.ToLower(strings.TrimSpace(book.BackorderPolicy))
+	if book.BackorderPolicy == "" {
+		book.BackorderPolicy = models.BackorderNone
+	}
+	if !models.IsBackorderPolicy(book.BackorderPolicy) {
+		return apperror.NewValidation("backorder_policy", "backorder_policy must be one of none, backorder, preorder")
+	}
+	if book.BackorderPolicy == models.BackorderPreorder && book.ExpectedAt == nil {
+		return apperror.NewValidation("expected_at", "expected_at is required for preorder books")
+	}
+	return nil
 }
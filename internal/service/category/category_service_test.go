@@ -0,0 +1,92 @@
+package category_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/category"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(repo *mockrepo.MockCategoryRepository) *category.CategoryService {
+	return category.NewCategoryService(repo, slog.New(slog.DiscardHandler))
+}
+
+func TestCreateCategory(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name         string
+		category     *models.Category
+		expectedSlug string
+		expectedErr  string
+	}{
+		{name: "Slug generated from name", category: &models.Category{Name: " Văn học Việt Nam "}, expectedSlug: "van-hoc-viet-nam"},
+		{name: "Given slug is normalized", category: &models.Category{Name: "Thiếu nhi", Slug: "Sách Thiếu Nhi"}, expectedSlug: "sach-thieu-nhi"},
+		{name: "Missing name", category: &models.Category{Name: " "}, expectedErr: "name must be 1 to 100 characters"},
+		{name: "Slug without letters or digits", category: &models.Category{Name: "Văn học", Slug: "---"}, expectedErr: "slug must contain 1 to 120 letters, digits or dashes"},
+		{name: "Numeric slug would look like an id", category: &models.Category{Name: "2024"}, expectedErr: "slug must contain at least one letter"},
+		{name: "Invalid parent", category: &models.Category{Name: "Văn học", ParentID: &zero}, expectedErr: "invalid parent ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockCategoryRepository)
+			if tt.expectedErr == "" {
+				repo.On("Create", mock.Anything, tt.category).Return(nil)
+			}
+
+			err := newService(repo).CreateCategory(context.Background(), tt.category)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedSlug, tt.category.Slug)
+				assert.False(t, tt.category.CreatedAt.IsZero())
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateByID_OwnParent(t *testing.T) {
+	repo := new(mockrepo.MockCategoryRepository)
+	self := 4
+
+	_, err := newService(repo).UpdateByID(context.Background(), &models.Category{ID: 4, Name: "Văn học", ParentID: &self})
+
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.EqualError(t, err, "category cannot be its own parent")
+	repo.AssertExpectations(t)
+}
+
+func TestGetTree(t *testing.T) {
+	one, two := 1, 2
+	repo := new(mockrepo.MockCategoryRepository)
+	repo.On("GetAll", mock.Anything).Return([]*models.Category{
+		{ID: 1, Name: "Văn học"},
+		{ID: 2, ParentID: &one, Name: "Tiểu thuyết", Position: 2},
+		{ID: 3, ParentID: &one, Name: "Thơ", Position: 1},
+		{ID: 4, ParentID: &two, Name: "Trinh thám"},
+		{ID: 5, Name: "Kinh tế", Position: -1},
+	}, nil)
+
+	tree, err := newService(repo).GetTree(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Kinh tế", tree[0].Name)
+	literature := tree[1]
+	require.Len(t, literature.Children, 2)
+	assert.Equal(t, "Thơ", literature.Children[0].Name)
+	assert.Equal(t, "Tiểu thuyết", literature.Children[1].Name)
+	require.Len(t, literature.Children[1].Children, 1)
+	assert.Equal(t, 4, literature.Children[1].Children[0].ID)
+	repo.AssertExpectations(t)
+}
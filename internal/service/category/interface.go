@@ -0,0 +1,17 @@
+package category
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+type CategoryServiceInterface interface {
+	CreateCategory(ctx context.Context, c *models.Category) error
+	// GetTree trả về các category gốc, mỗi category kèm Children
+	GetTree(ctx context.Context) ([]*models.Category, error)
+	GetByID(ctx context.Context, id int) (*models.Category, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Category, error)
+	UpdateByID(ctx context.Context, c *models.Category) (*models.Category, error)
+	DeleteByID(ctx context.Context, id int) (*models.Category, error)
+}
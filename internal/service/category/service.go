@@ -0,0 +1,122 @@
+package category
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/category"
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
+)
+
+type CategoryService struct {
+	repo   repositories.CategoryRepoInterface
+	logger *slog.Logger
+}
+
+func NewCategoryService(repo repositories.CategoryRepoInterface, logger *slog.Logger) *CategoryService {
+	return &CategoryService{repo: repo, logger: logger}
+}
+
+// CreateCategory sinh slug từ name nếu không gửi lên
+func (s *CategoryService) CreateCategory(ctx context.Context, c *models.Category) error {
+	if c == nil {
+		return apperror.NewValidation("", "category is nil")
+	}
+	if err := validateCategory(c); err != nil {
+		return err
+	}
+	c.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, c); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "category created", "category_id", c.ID, "slug", c.Slug, "parent_id", c.ParentID)
+	return nil
+}
+
+func (s *CategoryService) GetTree(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.BuildCategoryTree(categories), nil
+}
+
+// GetByID kiểm tra ID hợp lệ
+func (s *CategoryService) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid category ID")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetBySlug chuẩn hoá slug như khi tạo, vd: "Van-Hoc" tìm thấy "van-hoc"
+func (s *CategoryService) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	slug = textnorm.Slug(slug)
+	if slug == "" {
+		return nil, apperror.NewValidation("slug", "invalid category slug")
+	}
+	return s.repo.GetBySlug(ctx, slug)
+}
+
+// UpdateByID ghi đè toàn bộ field; parent_id thay đổi thì cả cây con được chuyển theo
+func (s *CategoryService) UpdateByID(ctx context.Context, c *models.Category) (*models.Category, error) {
+	if c == nil {
+		return nil, apperror.NewValidation("", "category is nil")
+	}
+	if c.ID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid category ID")
+	}
+	if err := validateCategory(c); err != nil {
+		return nil, err
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return nil, apperror.NewValidation("parent_id", "category cannot be its own parent")
+	}
+	c.UpdatedAt = time.Now()
+	updated, err := s.repo.Update(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "category updated", "category_id", c.ID, "slug", c.Slug, "parent_id", c.ParentID)
+	return updated, nil
+}
+
+// DeleteByID kiểm tra ID hợp lệ
+func (s *CategoryService) DeleteByID(ctx context.Context, id int) (*models.Category, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid category ID")
+	}
+	c, err := s.repo.DeleteByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "category deleted", "category_id", id)
+	return c, nil
+}
+
+func validateCategory(c *models.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > 100 {
+		return apperror.NewValidation("name", "name must be 1 to 100 characters")
+	}
+	if strings.TrimSpace(c.Slug) == "" {
+		c.Slug = c.Name
+	}
+	c.Slug = textnorm.Slug(c.Slug)
+	if c.Slug == "" || len(c.Slug) > 120 {
+		return apperror.NewValidation("slug", "slug must contain 1 to 120 letters, digits or dashes")
+	}
+	// GET /categories/{id} coi path toàn chữ số là id
+	if strings.Trim(c.Slug, "0123456789") == "" {
+		return apperror.NewValidation("slug", "slug must contain at least one letter")
+	}
+	if c.ParentID != nil && *c.ParentID <= 0 {
+		return apperror.NewValidation("parent_id", "invalid parent ID")
+	}
+	return nil
+}
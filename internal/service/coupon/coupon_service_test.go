@@ -0,0 +1,62 @@
+package coupon_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/coupon"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCoupon(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(-time.Hour)
+	tests := []struct {
+		name        string
+		coupon      *models.Coupon
+		expectedErr string
+	}{
+		{name: "Success", coupon: &models.Coupon{Code: " sale10 ", Type: "Percentage", Value: 10, BookIDs: []int{3, 1, 3}}},
+		{name: "Missing code", coupon: &models.Coupon{Type: "fixed", Value: 1000}, expectedErr: "code must be 1 to 32 characters"},
+		{name: "Unknown type", coupon: &models.Coupon{Code: "X", Type: "bogo", Value: 1}, expectedErr: "type must be one of percentage, fixed"},
+		{name: "Percentage above 100", coupon: &models.Coupon{Code: "X", Type: "percentage", Value: 101}, expectedErr: "percentage value must be between 1 and 100"},
+		{name: "Zero fixed amount", coupon: &models.Coupon{Code: "X", Type: "fixed"}, expectedErr: "fixed value must be greater than zero"},
+		{name: "Bad currency", coupon: &models.Coupon{Code: "X", Type: "fixed", Value: 1, Currency: "dollars"}, expectedErr: "currency must be a 3-letter ISO 4217 code"},
+		{
+			name:        "Window ends before it starts",
+			coupon:      &models.Coupon{Code: "X", Type: "fixed", Value: 1, StartsAt: &start, EndsAt: &end},
+			expectedErr: "ends_at must be after starts_at",
+		},
+		{name: "Negative per-user limit", coupon: &models.Coupon{Code: "X", Type: "fixed", Value: 1, MaxPerUser: -1}, expectedErr: "max_per_user cannot be negative"},
+		{name: "Invalid author", coupon: &models.Coupon{Code: "X", Type: "fixed", Value: 1, AuthorIDs: []int{0}}, expectedErr: "ids must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockCouponRepository)
+			if tt.expectedErr == "" {
+				repo.On("Create", mock.Anything, tt.coupon).Return(nil)
+			}
+
+			err := coupon.NewCouponService(repo, slog.New(slog.DiscardHandler)).CreateCoupon(context.Background(), tt.coupon)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "SALE10", tt.coupon.Code)
+				assert.Equal(t, models.CouponPercentage, tt.coupon.Type)
+				assert.Equal(t, models.DefaultCurrency, tt.coupon.Currency)
+				assert.Equal(t, []int{3, 1}, tt.coupon.BookIDs)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
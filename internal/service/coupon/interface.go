@@ -0,0 +1,14 @@
+package coupon
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+type CouponServiceInterface interface {
+	CreateCoupon(ctx context.Context, c *models.Coupon) error
+	GetByID(ctx context.Context, id int) (*models.Coupon, error)
+	GetAll(ctx context.Context) ([]*models.Coupon, error)
+	DeleteByID(ctx context.Context, id int) (*models.Coupon, error)
+}
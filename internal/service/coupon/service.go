@@ -0,0 +1,125 @@
+package coupon
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/coupon"
+)
+
+type CouponService struct {
+	repo   repositories.CouponRepoInterface
+	logger *slog.Logger
+}
+
+func NewCouponService(repo repositories.CouponRepoInterface, logger *slog.Logger) *CouponService {
+	return &CouponService{repo: repo, logger: logger}
+}
+
+// CreateCoupon chuẩn hoá code/currency rồi kiểm tra các điều kiện của coupon
+func (s *CouponService) CreateCoupon(ctx context.Context, c *models.Coupon) error {
+	if c == nil {
+		return apperror.NewValidation("", "coupon is nil")
+	}
+	if err := validateCoupon(c); err != nil {
+		return err
+	}
+	c.Redemptions = 0
+	c.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, c); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "coupon created", "coupon_id", c.ID, "code", c.Code, "type", c.Type, "value", c.Value)
+	return nil
+}
+
+// GetByID kiểm tra ID hợp lệ
+func (s *CouponService) GetByID(ctx context.Context, id int) (*models.Coupon, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid coupon ID")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetAll trả về danh sách rỗng nếu chưa có coupon nào
+func (s *CouponService) GetAll(ctx context.Context) ([]*models.Coupon, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// DeleteByID kiểm tra ID hợp lệ
+func (s *CouponService) DeleteByID(ctx context.Context, id int) (*models.Coupon, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid coupon ID")
+	}
+	c, err := s.repo.DeleteByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "coupon deleted", "coupon_id", id)
+	return c, nil
+}
+
+func validateCoupon(c *models.Coupon) error {
+	c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+	if c.Code == "" || len(c.Code) > 32 {
+		return apperror.NewValidation("code", "code must be 1 to 32 characters")
+	}
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	switch c.Type {
+	case models.CouponPercentage:
+		if c.Value < 1 || c.Value > 100 {
+			return apperror.NewValidation("value", "percentage value must be between 1 and 100")
+		}
+	case models.CouponFixed:
+		if c.Value <= 0 {
+			return apperror.NewValidation("value", "fixed value must be greater than zero")
+		}
+	default:
+		return apperror.NewValidation("type", "type must be one of percentage, fixed")
+	}
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	if c.Currency == "" {
+		c.Currency = models.DefaultCurrency
+	}
+	if !models.IsCurrencyCode(c.Currency) {
+		return apperror.NewValidation("currency", "currency must be a 3-letter ISO 4217 code")
+	}
+	if c.MinOrderValue < 0 {
+		return apperror.NewValidation("min_order_value", "min_order_value cannot be negative")
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return apperror.NewValidation("ends_at", "ends_at must be after starts_at")
+	}
+	if c.MaxRedemptions < 0 {
+		return apperror.NewValidation("max_redemptions", "max_redemptions cannot be negative")
+	}
+	if c.MaxPerUser < 0 {
+		return apperror.NewValidation("max_per_user", "max_per_user cannot be negative")
+	}
+	var err error
+	if c.BookIDs, err = uniqueIDs("book_ids", c.BookIDs); err != nil {
+		return err
+	}
+	c.AuthorIDs, err = uniqueIDs("author_ids", c.AuthorIDs)
+	return err
+}
+
+// uniqueIDs bỏ id trùng, giữ thứ tự; id phải dương
+func uniqueIDs(field string, ids []int) ([]int, error) {
+	out := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, apperror.NewValidation(field, "ids must be positive")
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
@@ -1,11 +1,20 @@
 package order
 
-import "github.com/maithuc2003/re-book-api/internal/models"
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
 
 type OrderServiceInterface interface {
-	CreateOrder(order *models.Order) error
-	GetAllOrders() ([]*models.Order, error)
-	GetByOrderID(id int) (*models.Order, error)
-	DeleteByOrderID(id int) (*models.Order, error)
-	UpdateByOrderID(order *models.Order) (*models.Order, error)
+	CreateOrder(ctx context.Context, order *models.Order) error
+	CreateOrderIdempotent(ctx context.Context, order *models.Order, key string) (replayed bool, err error)
+	GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error)
+	GetByOrderID(ctx context.Context, id int) (*models.Order, error)
+	DeleteByOrderID(ctx context.Context, id int) (*models.Order, error)
+	UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error)
+	// ConfirmReservation tạo order từ reservation đang giữ hàng
+	ConfirmReservation(ctx context.Context, reservationID int) (*models.Order, error)
+	TransitionOrder(ctx context.Context, id int, to string) (*models.Order, error)
 }
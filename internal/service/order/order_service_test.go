@@ -0,0 +1,445 @@
+package order_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/order"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// nopRecorder bỏ qua metric trong test
+type nopRecorder struct{}
+
+func (nopRecorder) OrderCreated()        {}
+func (nopRecorder) OrderRejected(string) {}
+
+func newService(repo *mockrepo.MockOrderRepository) *order.OrderService {
+	return order.NewOrderService(repo, slog.New(slog.DiscardHandler), nopRecorder{}, time.Hour)
+}
+
+func TestCreateOrder_Status(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         string
+		expectedStatus string
+		expectedErr    string
+	}{
+		{name: "Empty status defaults to pending", status: "", expectedStatus: models.OrderPending},
+		{name: "Status is normalized", status: " Pending ", expectedStatus: models.OrderPending},
+		{name: "Cannot start in a later status", status: "shipped", expectedErr: "new orders must start as pending"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockOrderRepository)
+			if tt.expectedErr == "" {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			}
+			o := &models.Order{BookID: 1, UserID: 2, Quantity: 1, Status: tt.status}
+
+			err := newService(repo).CreateOrder(context.Background(), o)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedStatus, o.Status)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransitionOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		current         string
+		to              string
+		updateErr       error
+		expectedErrIs   error
+		expectedAllowed []string
+	}{
+		{name: "pending to confirmed", current: models.OrderPending, to: models.OrderConfirmed},
+		{name: "paid to shipped", current: models.OrderPaid, to: models.OrderShipped},
+		{name: "cancel before payment", current: models.OrderConfirmed, to: models.OrderCancelled},
+		{
+			name: "delivered back to pending", current: models.OrderDelivered, to: models.OrderPending,
+			expectedErrIs: apperror.ErrConflict, expectedAllowed: []string{models.OrderRefunded},
+		},
+		{
+			name: "cancel after shipping", current: models.OrderShipped, to: models.OrderCancelled,
+			expectedErrIs: apperror.ErrConflict, expectedAllowed: []string{models.OrderDelivered},
+		},
+		{
+			name: "terminal status", current: models.OrderCancelled, to: models.OrderConfirmed,
+			expectedErrIs: apperror.ErrConflict, expectedAllowed: []string{},
+		},
+		{
+			name: "changed concurrently", current: models.OrderPaid, to: models.OrderShipped,
+			updateErr: apperror.Conflict("order 1 is no longer paid"), expectedErrIs: apperror.ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockOrderRepository)
+			repo.On("GetByOrderID", mock.Anything, 1).Return(&models.Order{ID: 1, Status: tt.current}, nil)
+			if tt.expectedAllowed == nil {
+				repo.On("UpdateStatus", mock.Anything, 1, tt.current, tt.to, mock.Anything).Return(tt.updateErr)
+			}
+
+			result, err := newService(repo).TransitionOrder(context.Background(), 1, tt.to)
+
+			if tt.expectedErrIs != nil {
+				assert.ErrorIs(t, err, tt.expectedErrIs)
+				assert.Nil(t, result)
+				var terr *apperror.TransitionError
+				if tt.expectedAllowed != nil {
+					require.True(t, errors.As(err, &terr))
+					assert.Equal(t, tt.expectedAllowed, terr.Allowed)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.to, result.Status)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransitionOrder_InvalidInput(t *testing.T) {
+	svc := newService(new(mockrepo.MockOrderRepository))
+
+	_, err := svc.TransitionOrder(context.Background(), 0, models.OrderPaid)
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+
+	_, err = svc.TransitionOrder(context.Background(), 1, "lost")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+}
+
+func TestUpdateByOrderID_Status(t *testing.T) {
+	tests := []struct {
+		name          string
+		current       string
+		status        string
+		expectUpdate  bool
+		expectedErrIs error
+	}{
+		{name: "Status unchanged", current: models.OrderPaid, status: "paid", expectUpdate: true},
+		{name: "Legal transition", current: models.OrderShipped, status: "Delivered", expectUpdate: true},
+		{name: "Illegal transition", current: models.OrderDelivered, status: "pending", expectedErrIs: apperror.ErrConflict},
+		{name: "Unknown status", current: models.OrderPending, status: "lost", expectedErrIs: apperror.ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockOrderRepository)
+			repo.On("GetByOrderID", mock.Anything, 1).Return(&models.Order{ID: 1, Status: tt.current}, nil).Maybe()
+			if tt.expectUpdate {
+				repo.On("UpdateByOrderID", mock.Anything, mock.Anything).Return(&models.Order{ID: 1}, nil)
+			}
+			input := &models.Order{ID: 1, BookID: 1, UserID: 2, Quantity: 1, Status: tt.status}
+
+			_, err := newService(repo).UpdateByOrderID(context.Background(), input)
+
+			if tt.expectedErrIs != nil {
+				assert.ErrorIs(t, err, tt.expectedErrIs)
+			} else {
+				require.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateOrder_Items(t *testing.T) {
+	tests := []struct {
+		name             string
+		order            *models.Order
+		expectedField    string
+		expectedErr      string
+		expectedQuantity int
+	}{
+		{
+			name:             "Legacy payload becomes one item",
+			order:            &models.Order{BookID: 1, UserID: 2, Quantity: 3},
+			expectedQuantity: 3,
+		},
+		{
+			name:             "Quantity is the total of all items",
+			order:            &models.Order{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 2}, {BookID: 2, Quantity: 5}}},
+			expectedQuantity: 7,
+		},
+		{
+			name: "Client prices are ignored",
+			order: &models.Order{UserID: 2, Currency: "USD", Total: 1,
+				Items: []models.OrderItem{{BookID: 1, Quantity: 2, UnitPrice: 1, Currency: "USD"}}},
+			expectedQuantity: 2,
+		},
+		{
+			name:          "No items",
+			order:         &models.Order{UserID: 2},
+			expectedField: "items",
+			expectedErr:   "order must contain at least one item",
+		},
+		{
+			name:          "Legacy payload keeps legacy field names",
+			order:         &models.Order{BookID: 1, UserID: 2, Quantity: 0},
+			expectedField: "quantity",
+			expectedErr:   "quantity must be greater than zero",
+		},
+		{
+			name:          "Invalid book in second item",
+			order:         &models.Order{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 1}, {BookID: -1, Quantity: 1}}},
+			expectedField: "items[1].book_id",
+			expectedErr:   "invalid book ID",
+		},
+		{
+			name:          "Duplicate book",
+			order:         &models.Order{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 1}, {BookID: 1, Quantity: 2}}},
+			expectedField: "items[1].book_id",
+			expectedErr:   "duplicate book in order items",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockOrderRepository)
+			if tt.expectedErr == "" {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			err := newService(repo).CreateOrder(context.Background(), tt.order)
+
+			if tt.expectedErr != "" {
+				var validation *apperror.ValidationError
+				require.ErrorAs(t, err, &validation)
+				assert.Equal(t, tt.expectedField, validation.Field)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedQuantity, tt.order.Quantity)
+				assert.NotEmpty(t, tt.order.Items)
+				// Giá do repository chốt từ books
+				assert.Zero(t, tt.order.Items[0].UnitPrice)
+				assert.Zero(t, tt.order.Total)
+				assert.Empty(t, tt.order.Currency)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+// countingRecorder đếm số order được ghi nhận là đã tạo
+type countingRecorder struct{ created int }
+
+func (r *countingRecorder) OrderCreated()        { r.created++ }
+func (r *countingRecorder) OrderRejected(string) {}
+
+func TestCreateOrderIdempotent(t *testing.T) {
+	t.Run("Replay is not counted as a new order", func(t *testing.T) {
+		repo := new(mockrepo.MockOrderRepository)
+		recorder := &countingRecorder{}
+		svc := order.NewOrderService(repo, slog.New(slog.DiscardHandler), recorder, time.Hour)
+		repo.On("CreateIdempotent", mock.Anything, mock.Anything, mock.MatchedBy(func(k models.IdempotencyKey) bool {
+			return k.UserID == 2 && k.Key == "retry-1" && len(k.Fingerprint) == 64 && time.Since(k.ExpiredBefore) >= time.Hour
+		})).Return(false, nil).Once()
+		repo.On("CreateIdempotent", mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Once()
+
+		replayed, err := svc.CreateOrderIdempotent(context.Background(), &models.Order{BookID: 1, UserID: 2, Quantity: 1}, "retry-1")
+		require.NoError(t, err)
+		assert.False(t, replayed)
+		replayed, err = svc.CreateOrderIdempotent(context.Background(), &models.Order{BookID: 1, UserID: 2, Quantity: 1}, "retry-1")
+		require.NoError(t, err)
+		assert.True(t, replayed)
+
+		assert.Equal(t, 1, recorder.created)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Equivalent payloads share a fingerprint", func(t *testing.T) {
+		var fingerprints []string
+		repo := new(mockrepo.MockOrderRepository)
+		repo.On("CreateIdempotent", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			fingerprints = append(fingerprints, args.Get(2).(models.IdempotencyKey).Fingerprint)
+		}).Return(false, nil)
+		svc := newService(repo)
+
+		payloads := []*models.Order{
+			{BookID: 1, UserID: 2, Quantity: 3},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 3}}},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 4}}},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 1}, {BookID: 2, Quantity: 1}}},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 2, Quantity: 1}, {BookID: 1, Quantity: 1}}},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 3}}, CouponCode: " sale "},
+			{UserID: 2, Items: []models.OrderItem{{BookID: 1, Quantity: 3}}, CouponCode: "SALE"},
+		}
+		for _, p := range payloads {
+			_, err := svc.CreateOrderIdempotent(context.Background(), p, "k")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, fingerprints[0], fingerprints[1])
+		assert.NotEqual(t, fingerprints[1], fingerprints[2])
+		assert.Equal(t, fingerprints[3], fingerprints[4])
+		// Coupon là một phần của request; mã được chuẩn hoá trước khi hash
+		assert.NotEqual(t, fingerprints[1], fingerprints[5])
+		assert.Equal(t, fingerprints[5], fingerprints[6])
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		svc := newService(new(mockrepo.MockOrderRepository))
+		for _, key := range []string{"", "has space", strings.Repeat("k", 256)} {
+			_, err := svc.CreateOrderIdempotent(context.Background(), &models.Order{BookID: 1, UserID: 2, Quantity: 1}, key)
+			assert.ErrorIs(t, err, apperror.ErrValidation, key)
+		}
+	})
+
+	t.Run("Reused key with another payload", func(t *testing.T) {
+		repo := new(mockrepo.MockOrderRepository)
+		repo.On("CreateIdempotent", mock.Anything, mock.Anything, mock.Anything).
+			Return(false, apperror.Unprocessable("Idempotency-Key \"k\" was already used with a different request"))
+
+		_, err := newService(repo).CreateOrderIdempotent(context.Background(), &models.Order{BookID: 1, UserID: 2, Quantity: 1}, "k")
+
+		assert.ErrorIs(t, err, apperror.ErrUnprocessable)
+	})
+}
+
+func TestConfirmReservation(t *testing.T) {
+	t.Run("Creates a pending order and counts it", func(t *testing.T) {
+		repo := new(mockrepo.MockOrderRepository)
+		recorder := &countingRecorder{}
+		svc := order.NewOrderService(repo, slog.New(slog.DiscardHandler), recorder, time.Hour)
+		repo.On("ConfirmReservation", mock.Anything, 5, mock.MatchedBy(func(o *models.Order) bool {
+			return o.Status == models.OrderPending && !o.OrderedAt.IsZero()
+		})).Run(func(args mock.Arguments) {
+			o := args.Get(2).(*models.Order)
+			o.ID, o.UserID, o.Items = 9, 42, []models.OrderItem{{BookID: 1, Quantity: 3}}
+			o.NormalizeItems()
+		}).Return(&models.Reservation{ID: 5, Status: models.ReservationConfirmed}, nil)
+
+		created, err := svc.ConfirmReservation(context.Background(), 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, 9, created.ID)
+		assert.Equal(t, 3, created.Quantity)
+		assert.Equal(t, 1, recorder.created)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Expired reservation", func(t *testing.T) {
+		repo := new(mockrepo.MockOrderRepository)
+		repo.On("ConfirmReservation", mock.Anything, 5, mock.Anything).Return(nil, apperror.Conflict("reservation 5 has expired"))
+
+		_, err := newService(repo).ConfirmReservation(context.Background(), 5)
+
+		assert.ErrorIs(t, err, apperror.ErrConflict)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		_, err := newService(new(mockrepo.MockOrderRepository)).ConfirmReservation(context.Background(), 0)
+
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+	})
+}
+
+// Stock được trả về kho khi huỷ, xoá hay giảm số lượng order thì phân bổ ngay cho các order backordered
+func TestAllocateBackorders_WhenStockIsFreed(t *testing.T) {
+	lines := func(pairs ...int) []models.OrderItem {
+		var out []models.OrderItem
+		for i := 0; i+1 < len(pairs); i += 2 {
+			out = append(out, models.OrderItem{BookID: pairs[i], Quantity: pairs[i+1]})
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		current  *models.Order
+		run      func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error
+		books    []int
+		allocErr error
+	}{
+		{
+			name:    "Cancel releases every book",
+			current: &models.Order{ID: 1, Status: models.OrderConfirmed, Items: lines(3, 1, 1, 2)},
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("UpdateStatus", mock.Anything, 1, models.OrderConfirmed, models.OrderCancelled, mock.Anything).Return(nil)
+				_, err := svc.TransitionOrder(context.Background(), 1, models.OrderCancelled)
+				return err
+			},
+			books: []int{1, 3},
+		},
+		{
+			name:    "Shipping frees nothing",
+			current: &models.Order{ID: 1, Status: models.OrderPaid, Items: lines(1, 2)},
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("UpdateStatus", mock.Anything, 1, models.OrderPaid, models.OrderShipped, mock.Anything).Return(nil)
+				_, err := svc.TransitionOrder(context.Background(), 1, models.OrderShipped)
+				return err
+			},
+		},
+		{
+			name: "Delete an order holding stock",
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("DeleteByOrderID", mock.Anything, 1).Return(&models.Order{ID: 1, Status: models.OrderPaid, Items: lines(2, 1)}, nil)
+				_, err := svc.DeleteByOrderID(context.Background(), 1)
+				return err
+			},
+			books: []int{2},
+		},
+		{
+			name: "Delete a backordered order",
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("DeleteByOrderID", mock.Anything, 1).Return(&models.Order{ID: 1, Status: models.OrderBackordered, Items: lines(2, 1)}, nil)
+				_, err := svc.DeleteByOrderID(context.Background(), 1)
+				return err
+			},
+		},
+		{
+			name:    "Update lowers one quantity",
+			current: &models.Order{ID: 1, Status: models.OrderPending, Items: lines(1, 3, 2, 1)},
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("UpdateByOrderID", mock.Anything, mock.Anything).Return(&models.Order{ID: 1}, nil)
+				_, err := svc.UpdateByOrderID(context.Background(), &models.Order{ID: 1, UserID: 2, Status: "pending", Items: lines(1, 1, 2, 2)})
+				return err
+			},
+			books: []int{1},
+		},
+		{
+			name:    "Allocation failure is only logged",
+			current: &models.Order{ID: 1, Status: models.OrderPending, Items: lines(1, 1)},
+			run: func(svc *order.OrderService, repo *mockrepo.MockOrderRepository) error {
+				repo.On("UpdateStatus", mock.Anything, 1, models.OrderPending, models.OrderCancelled, mock.Anything).Return(nil)
+				_, err := svc.TransitionOrder(context.Background(), 1, models.OrderCancelled)
+				return err
+			},
+			books:    []int{1},
+			allocErr: errors.New("deadlock"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockOrderRepository)
+			if tt.current != nil {
+				repo.On("GetByOrderID", mock.Anything, 1).Return(tt.current, nil)
+			}
+			for _, bookID := range tt.books {
+				repo.On("AllocateBackorders", mock.Anything, bookID, mock.Anything).Return([]int{}, tt.allocErr).Once()
+			}
+
+			err := tt.run(newService(repo), repo)
+
+			require.NoError(t, err)
+			repo.AssertExpectations(t)
+			repo.AssertNumberOfCalls(t, "AllocateBackorders", len(tt.books))
+		})
+	}
+}
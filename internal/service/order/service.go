@@ -1,96 +1,377 @@
 package order
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/metrics"
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	repositories "github.com/maithuc2003/re-book-api/internal/repositories/order"
 )
 
+// Recorder nhận các sự kiện nghiệp vụ của order để đếm metric (vd: *metrics.Metrics).
+type Recorder interface {
+	OrderCreated()
+	OrderRejected(reason string)
+}
+
 type OrderService struct {
-	repo repositories.OrderReposiotoryInterface
+	repo     repositories.OrderReposiotoryInterface
+	logger   *slog.Logger
+	recorder Recorder
+	// idempotencyTTL là thời gian một Idempotency-Key còn trả lại order cũ
+	idempotencyTTL time.Duration
+}
+
+func NewOrderService(repo repositories.OrderReposiotoryInterface, logger *slog.Logger, recorder Recorder, idempotencyTTL time.Duration) *OrderService {
+	return &OrderService{repo: repo, logger: logger, recorder: recorder, idempotencyTTL: idempotencyTTL}
+}
+
+// CreateOrder kiểm tra dữ liệu đầu vào trước khi tạo, và đếm kết quả (tạo được / bị từ chối)
+func (s *OrderService) CreateOrder(ctx context.Context, order *models.Order) error {
+	err := s.createOrder(ctx, order, "")
+	s.recordCreate(err)
+	return err
 }
 
-func NewOrderService(repo repositories.OrderReposiotoryInterface) *OrderService {
-	return &OrderService{repo: repo}
+// CreateOrderIdempotent tạo order đúng một lần cho mỗi Idempotency-Key của một user trong thời gian giữ key.
+// Request lặp lại cùng payload nhận lại order đã tạo (replayed = true); khác payload thì lỗi 422.
+func (s *OrderService) CreateOrderIdempotent(ctx context.Context, order *models.Order, key string) (bool, error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return false, err
+	}
+	err := s.createOrder(ctx, order, key)
+	if errors.Is(err, errReplayed) {
+		s.logger.InfoContext(ctx, "order replayed", "order_id", order.ID, "idempotency_key", key)
+		return true, nil
+	}
+	s.recordCreate(err)
+	return false, err
 }
 
-// CreateOrder kiểm tra dữ liệu đầu vào trước khi tạo
-func (s *OrderService) CreateOrder(order *models.Order) error {
+// errReplayed báo createOrder đã trả lại order cũ thay vì tạo mới
+var errReplayed = errors.New("order replayed")
+
+func (s *OrderService) recordCreate(err error) {
+	switch {
+	case err == nil:
+		s.recorder.OrderCreated()
+	case errors.Is(err, apperror.ErrInsufficientStock):
+		s.recorder.OrderRejected(metrics.ReasonInsufficientStock)
+	case errors.Is(err, apperror.ErrNotFound):
+		s.recorder.OrderRejected(metrics.ReasonBookNotFound)
+	case errors.Is(err, apperror.ErrValidation):
+		s.recorder.OrderRejected(metrics.ReasonValidation)
+	}
+}
+
+func (s *OrderService) createOrder(ctx context.Context, order *models.Order, idempotencyKey string) error {
 	if order == nil {
-		return errors.New("order is nil")
+		return apperror.NewValidation("", "order is nil")
 	}
-	if order.BookID <= 0 {
-		return errors.New("invalid book ID")
+	if err := validateItems(order); err != nil {
+		return err
 	}
 	if order.UserID <= 0 {
-		return errors.New("invalid user ID")
+		return apperror.NewValidation("user_id", "invalid user ID")
+	}
+	// Order mới luôn bắt đầu ở pending; các bước sau đi qua TransitionOrder
+	order.Status = normalizeStatus(order.Status)
+	if order.Status == "" {
+		order.Status = models.OrderPending
 	}
-	if order.Quantity <= 0 {
-		return errors.New("quantity must be greater than zero")
+	if order.Status != models.OrderPending {
+		return apperror.NewValidation("status", "new orders must start as "+models.OrderPending)
 	}
-	if strings.TrimSpace(order.Status) == "" {
-		return errors.New("status is required")
+	// Mã coupon không phân biệt hoa thường; điều kiện được kiểm tra trong transaction tạo order
+	order.CouponCode = strings.ToUpper(strings.TrimSpace(order.CouponCode))
+	if len(order.CouponCode) > 32 {
+		return apperror.NewValidation("coupon_code", "coupon_code must be at most 32 characters")
 	}
 
 	order.OrderedAt = time.Now()
 	order.UpdatedAt = time.Now()
 
-	return s.repo.Create(order)
+	if idempotencyKey != "" {
+		replayed, err := s.repo.CreateIdempotent(ctx, order, models.IdempotencyKey{
+			UserID:        order.UserID,
+			Key:           idempotencyKey,
+			Fingerprint:   fingerprint(order),
+			ExpiredBefore: order.OrderedAt.Add(-s.idempotencyTTL),
+		})
+		if err != nil {
+			return err
+		}
+		if replayed {
+			return errReplayed
+		}
+	} else if err := s.repo.Create(ctx, order); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "order created", "order_id", order.ID, "user_id", order.UserID, "items", len(order.Items), "quantity", order.Quantity)
+	return nil
+}
+
+// ConfirmReservation tạo order pending từ reservation đang active; hàng đã giữ được
+// trừ khỏi stock như một order bình thường nên cũng được đếm vào metric tạo order.
+func (s *OrderService) ConfirmReservation(ctx context.Context, reservationID int) (*models.Order, error) {
+	if reservationID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid reservation ID")
+	}
+	now := time.Now()
+	order := &models.Order{Status: models.OrderPending, OrderedAt: now, UpdatedAt: now}
+	_, err := s.repo.ConfirmReservation(ctx, reservationID, order)
+	s.recordCreate(err)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "reservation confirmed", "reservation_id", reservationID, "order_id", order.ID, "user_id", order.UserID, "quantity", order.Quantity)
+	return order, nil
+}
+
+// AllocateBackorders được gọi sau khi stock của bookID tăng: các order backordered được
+// chuyển sang pending theo thứ tự đặt cho tới khi hết hàng.
+func (s *OrderService) AllocateBackorders(ctx context.Context, bookID int) ([]int, error) {
+	allocated, err := s.repo.AllocateBackorders(ctx, bookID, time.Now())
+	if len(allocated) > 0 {
+		s.logger.InfoContext(ctx, "backorders allocated", "book_id", bookID, "order_ids", allocated)
+	}
+	return allocated, err
 }
 
-// GetAllOrders kiểm tra lỗi khi lấy danh sách
-func (s *OrderService) GetAllOrders() ([]*models.Order, error) {
-	orders, err := s.repo.GetAllOrders()
+// GetAllOrders kiểm tra filter và lỗi khi lấy danh sách
+func (s *OrderService) GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error) {
+	if filter.UserID < 0 {
+		return nil, apperror.NewValidation("user_id", "invalid user ID")
+	}
+	if filter.BookID < 0 {
+		return nil, apperror.NewValidation("book_id", "invalid book ID")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, apperror.NewValidation("to", "'to' must be after 'from'")
+	}
+	page, err := s.repo.GetAllOrders(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	if len(orders) == 0 {
-		return nil, errors.New("no orders found")
+	if page.Total == 0 && filter.Cursor == "" {
+		return nil, apperror.NotFound("no orders found")
 	}
-	return orders, nil
+	return page, nil
 }
 
 // GetByOrderID kiểm tra ID hợp lệ
-func (s *OrderService) GetByOrderID(id int) (*models.Order, error) {
+func (s *OrderService) GetByOrderID(ctx context.Context, id int) (*models.Order, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid order ID")
+		return nil, apperror.NewValidation("id", "invalid order ID")
 	}
-	return s.repo.GetByOrderID(id)
+	return s.repo.GetByOrderID(ctx, id)
 }
 
 // DeleteByOrderID kiểm tra ID hợp lệ
-func (s *OrderService) DeleteByOrderID(id int) (*models.Order, error) {
+func (s *OrderService) DeleteByOrderID(ctx context.Context, id int) (*models.Order, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid order ID")
+		return nil, apperror.NewValidation("id", "invalid order ID")
+	}
+	order, err := s.repo.DeleteByOrderID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.DeleteByOrderID(id)
+	s.logger.InfoContext(ctx, "order deleted", "order_id", id)
+	// Xoá order đang giữ stock cũng trả toàn bộ hàng về kho như huỷ
+	s.allocateFreed(ctx, order, &models.Order{Status: models.OrderCancelled})
+	return order, nil
 }
 
 // UpdateByOrderID kiểm tra dữ liệu trước khi cập nhật
-func (s *OrderService) UpdateByOrderID(order *models.Order) (*models.Order, error) {
+func (s *OrderService) UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error) {
 	if order == nil {
-		return nil, errors.New("order is nil")
+		return nil, apperror.NewValidation("", "order is nil")
 	}
 	if order.ID <= 0 {
-		return nil, errors.New("invalid order ID")
+		return nil, apperror.NewValidation("id", "invalid order ID")
 	}
-	if order.BookID <= 0 {
-		return nil, errors.New("invalid book ID")
+	if err := validateItems(order); err != nil {
+		return nil, err
 	}
 	if order.UserID <= 0 {
-		return nil, errors.New("invalid user ID")
+		return nil, apperror.NewValidation("user_id", "invalid user ID")
+	}
+	order.Status = normalizeStatus(order.Status)
+	if order.Status == "" {
+		return nil, apperror.NewValidation("status", "status is required")
+	}
+	if !models.IsOrderStatus(order.Status) {
+		return nil, apperror.NewValidation("status", "unknown order status")
+	}
+	// Đổi status qua PUT/PATCH cũng phải theo đúng vòng đời
+	existing, err := s.repo.GetByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != order.Status && !models.CanTransitionOrder(existing.Status, order.Status) {
+		return nil, apperror.InvalidTransition(existing.Status, order.Status, models.NextOrderStatuses(existing.Status))
+	}
+
+	order.UpdatedAt = time.Now()
+
+	updated, err := s.repo.UpdateByOrderID(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	s.allocateFreed(ctx, existing, order)
+	return updated, nil
+}
+
+// TransitionOrder chuyển order sang trạng thái to nếu vòng đời cho phép,
+// ngược lại trả về lỗi conflict kèm các trạng thái kế tiếp hợp lệ.
+func (s *OrderService) TransitionOrder(ctx context.Context, id int, to string) (*models.Order, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid order ID")
 	}
-	if order.Quantity <= 0 {
-		return nil, errors.New("quantity must be greater than zero")
+	if !models.IsOrderStatus(to) {
+		return nil, apperror.NewValidation("status", "unknown order status")
 	}
-	if strings.TrimSpace(order.Status) == "" {
-		return nil, errors.New("status is required")
+	order, err := s.repo.GetByOrderID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from := order.Status
+	if !models.CanTransitionOrder(from, to) {
+		return nil, apperror.InvalidTransition(from, to, models.NextOrderStatuses(from))
 	}
 
+	before := *order
+	order.Status = to
 	order.UpdatedAt = time.Now()
+	if err := s.repo.UpdateStatus(ctx, id, from, to, order.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "order status changed", "order_id", id, "from", from, "to", to)
+	s.allocateFreed(ctx, &before, order)
+	return order, nil
+}
+
+// allocateFreed phân bổ cho các order backordered những sách được trả về kho khi order
+// chuyển từ before sang after. Stock đã ghi xong; lỗi phân bổ chỉ log lại.
+func (s *OrderService) allocateFreed(ctx context.Context, before, after *models.Order) {
+	for _, bookID := range freedBooks(before, after) {
+		if _, err := s.AllocateBackorders(ctx, bookID); err != nil {
+			s.logger.ErrorContext(ctx, "allocate backorders failed", "book_id", bookID, "err", err)
+		}
+	}
+}
+
+// freedBooks trả về các sách có stock tăng khi order chuyển từ before sang after,
+// cùng cách tính với stockDeltas của repository: order đang giữ stock trả lại các dòng cũ,
+// rồi trừ các dòng mới nếu không bị huỷ/hoàn tiền.
+func freedBooks(before, after *models.Order) []int {
+	if !models.OrderHoldsStock(before.Status) {
+		return nil
+	}
+	deltas := map[int]int{}
+	for _, item := range before.Items {
+		deltas[item.BookID] += item.Quantity
+	}
+	if !models.OrderReleasesStock(before.Status, after.Status) {
+		for _, item := range after.Items {
+			deltas[item.BookID] -= item.Quantity
+		}
+	}
+	var books []int
+	for _, bookID := range slices.Sorted(maps.Keys(deltas)) {
+		if deltas[bookID] > 0 {
+			books = append(books, bookID)
+		}
+	}
+	return books
+}
+
+// validateItems đổi payload cũ {book_id, quantity} thành một dòng rồi kiểm tra từng dòng.
+func validateItems(order *models.Order) error {
+	legacy := len(order.Items) == 0
+	// Giá do repository lấy từ books; bỏ qua giá client gửi lên
+	for i := range order.Items {
+		order.Items[i].UnitPrice, order.Items[i].Currency = 0, ""
+	}
+	order.Currency, order.Discount = "", 0
+	order.NormalizeItems()
+	if len(order.Items) == 0 {
+		return apperror.NewValidation("items", "order must contain at least one item")
+	}
+	seen := make(map[int]bool, len(order.Items))
+	for i, item := range order.Items {
+		// Payload cũ vẫn báo lỗi theo tên field cũ
+		bookField, quantityField := fmt.Sprintf("items[%d].book_id", i), fmt.Sprintf("items[%d].quantity", i)
+		if legacy {
+			bookField, quantityField = "book_id", "quantity"
+		}
+		if item.BookID <= 0 {
+			return apperror.NewValidation(bookField, "invalid book ID")
+		}
+		if item.Quantity <= 0 {
+			return apperror.NewValidation(quantityField, "quantity must be greater than zero")
+		}
+		if seen[item.BookID] {
+			return apperror.NewValidation(bookField, "duplicate book in order items")
+		}
+		seen[item.BookID] = true
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys xoá các Idempotency-Key đã quá thời gian giữ.
+func (s *OrderService) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.repo.PurgeIdempotencyKeys(ctx, time.Now().Add(-s.idempotencyTTL))
+}
+
+// validateIdempotencyKey chấp nhận 1-255 ký tự ASCII in được, khớp với cột idempotency_key.
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > 255 {
+		return apperror.NewValidation("Idempotency-Key", "Idempotency-Key must be 1 to 255 characters")
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return apperror.NewValidation("Idempotency-Key", "Idempotency-Key must contain printable ASCII characters only")
+		}
+	}
+	return nil
+}
+
+// fingerprint hash payload đã chuẩn hoá: payload cũ {book_id, quantity} và items một dòng
+// tương đương nhau, thứ tự items không quan trọng.
+func fingerprint(order *models.Order) string {
+	// Chỉ hash book_id/quantity: giá do server ghi lại, không thuộc payload của client
+	type line struct {
+		BookID   int `json:"book_id"`
+		Quantity int `json:"quantity"`
+	}
+	items := make([]line, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, line{item.BookID, item.Quantity})
+	}
+	slices.SortFunc(items, func(a, b line) int { return a.BookID - b.BookID })
+	payload, _ := json.Marshal(struct {
+		UserID     int    `json:"user_id"`
+		Status     string `json:"status"`
+		Items      []line `json:"items"`
+		CouponCode string `json:"coupon_code,omitempty"`
+	}{order.UserID, order.Status, items, order.CouponCode})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
 
-	return s.repo.UpdateByOrderID(order)
+func normalizeStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
 }
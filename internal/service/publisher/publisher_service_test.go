@@ -0,0 +1,72 @@
+package publisher_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/publisher"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(repo *mockrepo.MockPublisherRepository) *publisher.PublisherService {
+	return publisher.NewPublisherService(repo, slog.New(slog.DiscardHandler))
+}
+
+func TestCreatePublisher(t *testing.T) {
+	tests := []struct {
+		name        string
+		publisher   *models.Publisher
+		mockError   error
+		expectedErr error
+		errMsg      string
+	}{
+		{name: "Valid publisher", publisher: &models.Publisher{Name: " NXB Trẻ ", Country: "VN", Website: "https://nxbtre.com.vn"}},
+		{name: "Missing name", publisher: &models.Publisher{Name: "  "}, expectedErr: apperror.ErrValidation, errMsg: "name must be 1 to 255 characters"},
+		{name: "Name too long", publisher: &models.Publisher{Name: strings.Repeat("a", 256)}, expectedErr: apperror.ErrValidation, errMsg: "name must be 1 to 255 characters"},
+		{name: "Website without scheme", publisher: &models.Publisher{Name: "Kim Đồng", Website: "nxbkimdong.com.vn"}, expectedErr: apperror.ErrValidation, errMsg: "website must be an http or https URL"},
+		{
+			name:        "Duplicate name",
+			publisher:   &models.Publisher{Name: "Kim Đồng"},
+			mockError:   apperror.Conflict("publisher Kim Đồng already exists"),
+			expectedErr: apperror.ErrConflict,
+			errMsg:      "publisher Kim Đồng already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockPublisherRepository)
+			if tt.expectedErr == nil || tt.mockError != nil {
+				repo.On("Create", mock.Anything, tt.publisher).Return(tt.mockError)
+			}
+
+			err := newService(repo).CreatePublisher(context.Background(), tt.publisher)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "NXB Trẻ", tt.publisher.Name)
+				assert.False(t, tt.publisher.CreatedAt.IsZero())
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteByID_InUse(t *testing.T) {
+	repo := new(mockrepo.MockPublisherRepository)
+	repo.On("DeleteByID", mock.Anything, 3).Return(nil, apperror.ForeignKey(nil, "cannot delete publisher: existing books depend on it"))
+
+	_, err := newService(repo).DeleteByID(context.Background(), 3)
+
+	assert.ErrorIs(t, err, apperror.ErrForeignKey)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/publisher"
+)
+
+type PublisherService struct {
+	repo   repositories.PublisherRepoInterface
+	logger *slog.Logger
+}
+
+func NewPublisherService(repo repositories.PublisherRepoInterface, logger *slog.Logger) *PublisherService {
+	return &PublisherService{repo: repo, logger: logger}
+}
+
+func (s *PublisherService) CreatePublisher(ctx context.Context, p *models.Publisher) error {
+	if p == nil {
+		return apperror.NewValidation("", "publisher is nil")
+	}
+	if err := validatePublisher(p); err != nil {
+		return err
+	}
+	p.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, p); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "publisher created", "publisher_id", p.ID, "name", p.Name)
+	return nil
+}
+
+// GetAll trả về danh sách rỗng nếu chưa có nhà xuất bản nào
+func (s *PublisherService) GetAll(ctx context.Context) ([]*models.Publisher, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// GetByID kiểm tra ID hợp lệ
+func (s *PublisherService) GetByID(ctx context.Context, id int) (*models.Publisher, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid publisher ID")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *PublisherService) UpdateByID(ctx context.Context, p *models.Publisher) (*models.Publisher, error) {
+	if p == nil {
+		return nil, apperror.NewValidation("", "publisher is nil")
+	}
+	if p.ID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid publisher ID")
+	}
+	if err := validatePublisher(p); err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = time.Now()
+	return s.repo.UpdateByID(ctx, p)
+}
+
+// DeleteByID: nhà xuất bản còn sách không xoá được
+func (s *PublisherService) DeleteByID(ctx context.Context, id int) (*models.Publisher, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid publisher ID")
+	}
+	p, err := s.repo.DeleteByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "publisher deleted", "publisher_id", id)
+	return p, nil
+}
+
+func validatePublisher(p *models.Publisher) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > 255 {
+		return apperror.NewValidation("name", "name must be 1 to 255 characters")
+	}
+	p.Country = strings.TrimSpace(p.Country)
+	if utf8.RuneCountInString(p.Country) > 100 {
+		return apperror.NewValidation("country", "country must be at most 100 characters")
+	}
+	p.Website = strings.TrimSpace(p.Website)
+	if p.Website != "" {
+		u, err := url.Parse(p.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(p.Website) > 255 {
+			return apperror.NewValidation("website", "website must be an http or https URL")
+		}
+	}
+	return nil
+}
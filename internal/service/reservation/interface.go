@@ -0,0 +1,14 @@
+package reservation
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+// Confirm nằm ở OrderServiceInterface.ConfirmReservation vì nó tạo order
+type ReservationServiceInterface interface {
+	Reserve(ctx context.Context, reservation *models.Reservation) error
+	GetByID(ctx context.Context, id int) (*models.Reservation, error)
+	Release(ctx context.Context, id int) (*models.Reservation, error)
+}
@@ -0,0 +1,128 @@
+package reservation_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/reservation"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAllocator ghi lại các sách được yêu cầu phân bổ backorder
+type fakeAllocator struct {
+	books []int
+	err   error
+}
+
+func (a *fakeAllocator) AllocateBackorders(_ context.Context, bookID int) ([]int, error) {
+	a.books = append(a.books, bookID)
+	return nil, a.err
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name        string
+		reservation *models.Reservation
+		expectedTTL time.Duration
+		expectedErr string
+	}{
+		{name: "Default TTL", reservation: &models.Reservation{BookID: 1, UserID: 2, Quantity: 3}, expectedTTL: 15 * time.Minute},
+		{name: "Requested TTL", reservation: &models.Reservation{BookID: 1, UserID: 2, Quantity: 3, TTLSeconds: 120}, expectedTTL: 2 * time.Minute},
+		{name: "TTL above maximum", reservation: &models.Reservation{BookID: 1, UserID: 2, Quantity: 3, TTLSeconds: 3601}, expectedErr: "ttl_seconds must be between 1 and 3600"},
+		{name: "Negative TTL", reservation: &models.Reservation{BookID: 1, UserID: 2, Quantity: 3, TTLSeconds: -1}, expectedErr: "ttl_seconds must be between 1 and 3600"},
+		{name: "Invalid book", reservation: &models.Reservation{UserID: 2, Quantity: 3}, expectedErr: "invalid book ID"},
+		{name: "Invalid user", reservation: &models.Reservation{BookID: 1, Quantity: 3}, expectedErr: "invalid user ID"},
+		{name: "Zero quantity", reservation: &models.Reservation{BookID: 1, UserID: 2}, expectedErr: "quantity must be greater than zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockReservationRepository)
+			if tt.expectedErr == "" {
+				repo.On("Reserve", mock.Anything, tt.reservation).Return(nil)
+			}
+			svc := reservation.NewReservationService(repo, slog.New(slog.DiscardHandler), nil, 15*time.Minute, time.Hour)
+
+			err := svc.Reserve(context.Background(), tt.reservation)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedTTL, tt.reservation.ExpiresAt.Sub(tt.reservation.CreatedAt))
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	repo := new(mockrepo.MockReservationRepository)
+	repo.On("Release", mock.Anything, 7, mock.AnythingOfType("time.Time")).
+		Return(nil, apperror.Conflict("reservation 7 is already confirmed"))
+	svc := reservation.NewReservationService(repo, slog.New(slog.DiscardHandler), nil, 15*time.Minute, time.Hour)
+
+	_, err := svc.Release(context.Background(), 7)
+
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	_, err = svc.Release(context.Background(), 0)
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	repo.AssertExpectations(t)
+}
+
+func TestRelease_AllocatesBackorders(t *testing.T) {
+	repo := new(mockrepo.MockReservationRepository)
+	repo.On("Release", mock.Anything, 7, mock.AnythingOfType("time.Time")).
+		Return(&models.Reservation{ID: 7, BookID: 3, Status: models.ReservationReleased}, nil)
+	// Lỗi phân bổ không làm hỏng việc release
+	allocator := &fakeAllocator{err: errors.New("lock wait timeout")}
+	svc := reservation.NewReservationService(repo, slog.New(slog.DiscardHandler), allocator, 15*time.Minute, time.Hour)
+
+	res, err := svc.Release(context.Background(), 7)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ReservationReleased, res.Status)
+	assert.Equal(t, []int{3}, allocator.books)
+	repo.AssertExpectations(t)
+}
+
+func TestExpireStale(t *testing.T) {
+	tests := []struct {
+		name          string
+		expired       int64
+		bookIDs       []int
+		err           error
+		expectedBooks []int
+	}{
+		{name: "Expired books are allocated", expired: 3, bookIDs: []int{1, 4}, expectedBooks: []int{1, 4}},
+		{name: "Nothing expired"},
+		{name: "Repository error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockReservationRepository)
+			repo.On("ExpireStale", mock.Anything, mock.AnythingOfType("time.Time")).Return(tt.expired, tt.bookIDs, tt.err)
+			allocator := &fakeAllocator{}
+			svc := reservation.NewReservationService(repo, slog.New(slog.DiscardHandler), allocator, 15*time.Minute, time.Hour)
+
+			n, err := svc.ExpireStale(context.Background())
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expired, n)
+			}
+			assert.Equal(t, tt.expectedBooks, allocator.books)
+			repo.AssertExpectations(t)
+		})
+	}
+}
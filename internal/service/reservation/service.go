@@ -0,0 +1,107 @@
+package reservation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/reservation"
+)
+
+// BackorderAllocator phân bổ hàng vừa hết bị giữ cho các order đang chờ (vd: *order.OrderService).
+type BackorderAllocator interface {
+	AllocateBackorders(ctx context.Context, bookID int) ([]int, error)
+}
+
+type ReservationService struct {
+	repo      repositories.ReservationRepoInterface
+	logger    *slog.Logger
+	allocator BackorderAllocator
+	// ttl là thời gian giữ hàng mặc định, maxTTL là giới hạn client được yêu cầu
+	ttl    time.Duration
+	maxTTL time.Duration
+}
+
+func NewReservationService(repo repositories.ReservationRepoInterface, logger *slog.Logger, allocator BackorderAllocator, ttl, maxTTL time.Duration) *ReservationService {
+	return &ReservationService{repo: repo, logger: logger, allocator: allocator, ttl: ttl, maxTTL: maxTTL}
+}
+
+// Reserve kiểm tra dữ liệu đầu vào và tính expires_at từ ttl_seconds (0 là mặc định)
+func (s *ReservationService) Reserve(ctx context.Context, reservation *models.Reservation) error {
+	if reservation == nil {
+		return apperror.NewValidation("", "reservation is nil")
+	}
+	if reservation.BookID <= 0 {
+		return apperror.NewValidation("book_id", "invalid book ID")
+	}
+	if reservation.UserID <= 0 {
+		return apperror.NewValidation("user_id", "invalid user ID")
+	}
+	if reservation.Quantity <= 0 {
+		return apperror.NewValidation("quantity", "quantity must be greater than zero")
+	}
+	ttl := s.ttl
+	if reservation.TTLSeconds != 0 {
+		ttl = time.Duration(reservation.TTLSeconds) * time.Second
+		if reservation.TTLSeconds < 0 || ttl > s.maxTTL {
+			return apperror.NewValidation("ttl_seconds", fmt.Sprintf("ttl_seconds must be between 1 and %d", int(s.maxTTL/time.Second)))
+		}
+	}
+	// Thời gian lưu trong DATETIME chỉ tới giây
+	reservation.CreatedAt = time.Now().Truncate(time.Second)
+	reservation.ExpiresAt = reservation.CreatedAt.Add(ttl)
+	reservation.OrderID = nil
+	if err := s.repo.Reserve(ctx, reservation); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "stock reserved", "reservation_id", reservation.ID, "book_id", reservation.BookID, "quantity", reservation.Quantity, "expires_at", reservation.ExpiresAt)
+	return nil
+}
+
+// GetByID kiểm tra ID hợp lệ
+func (s *ReservationService) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid reservation ID")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Release trả lại hàng đang giữ trước khi hết hạn
+func (s *ReservationService) Release(ctx context.Context, id int) (*models.Reservation, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid reservation ID")
+	}
+	reservation, err := s.repo.Release(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "reservation released", "reservation_id", id)
+	s.allocate(ctx, reservation.BookID)
+	return reservation, nil
+}
+
+// ExpireStale được sweeper gọi định kỳ để đánh dấu các reservation đã hết hạn.
+func (s *ReservationService) ExpireStale(ctx context.Context) (int64, error) {
+	n, bookIDs, err := s.repo.ExpireStale(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, bookID := range bookIDs {
+		s.allocate(ctx, bookID)
+	}
+	return n, nil
+}
+
+// allocate phân bổ hàng vừa được trả lại cho các order backordered. Reservation đã đổi
+// status xong; lỗi phân bổ chỉ log lại, lần nhập hàng sau sẽ thử lại.
+func (s *ReservationService) allocate(ctx context.Context, bookID int) {
+	if s.allocator == nil {
+		return
+	}
+	if _, err := s.allocator.AllocateBackorders(ctx, bookID); err != nil {
+		s.logger.ErrorContext(ctx, "allocate backorders failed", "book_id", bookID, "err", err)
+	}
+}
@@ -0,0 +1,17 @@
+package returns
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+)
+
+type ReturnServiceInterface interface {
+	RequestReturn(ctx context.Context, ret *models.Return) error
+	GetByID(ctx context.Context, id int) (*models.Return, error)
+	ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error)
+	ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error)
+	Approve(ctx context.Context, id int) (*models.Return, error)
+	Reject(ctx context.Context, id int) (*models.Return, error)
+	Receive(ctx context.Context, id int, restock bool) (*models.Return, error)
+}
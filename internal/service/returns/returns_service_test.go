@@ -0,0 +1,106 @@
+package returns_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/returns"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAllocator struct {
+	books []int
+}
+
+func (f *fakeAllocator) AllocateBackorders(_ context.Context, bookID int) ([]int, error) {
+	f.books = append(f.books, bookID)
+	return nil, nil
+}
+
+func TestRequestReturn(t *testing.T) {
+	item := []models.ReturnItem{{BookID: 1, Quantity: 2}}
+	tests := []struct {
+		name        string
+		ret         *models.Return
+		expectedErr string
+	}{
+		{name: "Success", ret: &models.Return{OrderID: 5, Reason: " Damaged ", Items: item, Restock: true}},
+		{name: "Invalid order", ret: &models.Return{Reason: "damaged", Items: item}, expectedErr: "invalid order ID"},
+		{name: "Unknown reason", ret: &models.Return{OrderID: 5, Reason: "bored", Items: item}, expectedErr: "reason must be one of damaged, defective, wrong_item, not_as_described, no_longer_needed, other"},
+		{name: "No items", ret: &models.Return{OrderID: 5, Reason: "other"}, expectedErr: "return must contain at least one item"},
+		{name: "Zero quantity", ret: &models.Return{OrderID: 5, Reason: "other", Items: []models.ReturnItem{{BookID: 1}}}, expectedErr: "quantity must be greater than zero"},
+		{
+			name:        "Duplicate book",
+			ret:         &models.Return{OrderID: 5, Reason: "other", Items: []models.ReturnItem{{BookID: 1, Quantity: 1}, {BookID: 1, Quantity: 1}}},
+			expectedErr: "duplicate book in return items",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockReturnRepository)
+			if tt.expectedErr == "" {
+				repo.On("Create", mock.Anything, tt.ret).Return(nil)
+			}
+			svc := returns.NewReturnService(repo, slog.New(slog.DiscardHandler), &fakeAllocator{})
+
+			err := svc.RequestReturn(context.Background(), tt.ret)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "damaged", tt.ret.Reason)
+				assert.False(t, tt.ret.Restock)
+				assert.False(t, tt.ret.CreatedAt.IsZero())
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApprove_Conflict(t *testing.T) {
+	repo := new(mockrepo.MockReturnRepository)
+	repo.On("UpdateStatus", mock.Anything, 3, models.ReturnRequested, models.ReturnApproved, mock.AnythingOfType("time.Time")).
+		Return(nil, apperror.Conflict("return 3 is rejected, expected requested"))
+	svc := returns.NewReturnService(repo, slog.New(slog.DiscardHandler), &fakeAllocator{})
+
+	_, err := svc.Approve(context.Background(), 3)
+
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	repo.AssertExpectations(t)
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name          string
+		restock       bool
+		expectedBooks []int
+	}{
+		{name: "Restock allocates backorders", restock: true, expectedBooks: []int{1, 2}},
+		{name: "Without restock", restock: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockReturnRepository)
+			received := &models.Return{ID: 3, OrderID: 5, Status: models.ReturnReceived, Restock: tt.restock,
+				Items: []models.ReturnItem{{BookID: 1, Quantity: 1}, {BookID: 2, Quantity: 1}}}
+			repo.On("Receive", mock.Anything, 3, tt.restock, mock.AnythingOfType("time.Time")).Return(received, nil)
+			allocator := &fakeAllocator{}
+			svc := returns.NewReturnService(repo, slog.New(slog.DiscardHandler), allocator)
+
+			ret, err := svc.Receive(context.Background(), 3, tt.restock)
+
+			require.NoError(t, err)
+			assert.Equal(t, received, ret)
+			assert.Equal(t, tt.expectedBooks, allocator.books)
+			repo.AssertExpectations(t)
+		})
+	}
+}
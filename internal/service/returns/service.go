@@ -0,0 +1,136 @@
+package returns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/returns"
+)
+
+// BackorderAllocator phân bổ sách trả đã nhập lại kho cho các order đang chờ (vd: *order.OrderService).
+type BackorderAllocator interface {
+	AllocateBackorders(ctx context.Context, bookID int) ([]int, error)
+}
+
+type ReturnService struct {
+	repo      repositories.ReturnRepoInterface
+	logger    *slog.Logger
+	allocator BackorderAllocator
+}
+
+func NewReturnService(repo repositories.ReturnRepoInterface, logger *slog.Logger, allocator BackorderAllocator) *ReturnService {
+	return &ReturnService{repo: repo, logger: logger, allocator: allocator}
+}
+
+// RequestReturn kiểm tra dữ liệu đầu vào; số lượng còn được trả do repository kiểm tra dưới lock
+func (s *ReturnService) RequestReturn(ctx context.Context, ret *models.Return) error {
+	if ret == nil {
+		return apperror.NewValidation("", "return is nil")
+	}
+	if ret.OrderID <= 0 {
+		return apperror.NewValidation("id", "invalid order ID")
+	}
+	ret.Reason = strings.ToLower(strings.TrimSpace(ret.Reason))
+	if !slices.Contains(models.ReturnReasons, ret.Reason) {
+		return apperror.NewValidation("reason", "reason must be one of "+strings.Join(models.ReturnReasons, ", "))
+	}
+	if len(ret.Note) > 255 {
+		return apperror.NewValidation("note", "note must be at most 255 characters")
+	}
+	if len(ret.Items) == 0 {
+		return apperror.NewValidation("items", "return must contain at least one item")
+	}
+	seen := make(map[int]bool, len(ret.Items))
+	for i, item := range ret.Items {
+		if item.BookID <= 0 {
+			return apperror.NewValidation(fmt.Sprintf("items[%d].book_id", i), "invalid book ID")
+		}
+		if item.Quantity <= 0 {
+			return apperror.NewValidation(fmt.Sprintf("items[%d].quantity", i), "quantity must be greater than zero")
+		}
+		if seen[item.BookID] {
+			return apperror.NewValidation(fmt.Sprintf("items[%d].book_id", i), "duplicate book in return items")
+		}
+		seen[item.BookID] = true
+	}
+	// Nhập lại kho hay không được quyết định khi nhận hàng
+	ret.Restock = false
+	ret.Refund = nil
+	ret.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, ret); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "return requested", "return_id", ret.ID, "order_id", ret.OrderID, "reason", ret.Reason)
+	return nil
+}
+
+// GetByID kiểm tra ID hợp lệ
+func (s *ReturnService) GetByID(ctx context.Context, id int) (*models.Return, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid return ID")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListByOrder trả về các yêu cầu trả hàng của order, rỗng nếu chưa có
+func (s *ReturnService) ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error) {
+	if orderID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid order ID")
+	}
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// ListRefunds trả về các lần hoàn tiền của order
+func (s *ReturnService) ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error) {
+	if orderID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid order ID")
+	}
+	return s.repo.ListRefunds(ctx, orderID)
+}
+
+func (s *ReturnService) Approve(ctx context.Context, id int) (*models.Return, error) {
+	return s.transition(ctx, id, models.ReturnRequested, models.ReturnApproved)
+}
+
+func (s *ReturnService) Reject(ctx context.Context, id int) (*models.Return, error) {
+	return s.transition(ctx, id, models.ReturnRequested, models.ReturnRejected)
+}
+
+func (s *ReturnService) transition(ctx context.Context, id int, from, to string) (*models.Return, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid return ID")
+	}
+	ret, err := s.repo.UpdateStatus(ctx, id, from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "return status changed", "return_id", id, "from", from, "to", to)
+	return ret, nil
+}
+
+// Receive ghi nhận đã nhận hàng trả và tạo refund; restock nhập lại kho các sách còn bán được.
+func (s *ReturnService) Receive(ctx context.Context, id int, restock bool) (*models.Return, error) {
+	if id <= 0 {
+		return nil, apperror.NewValidation("id", "invalid return ID")
+	}
+	ret, err := s.repo.Receive(ctx, id, restock, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "return received", "return_id", id, "order_id", ret.OrderID, "restock", restock)
+	if restock {
+		// Stock đã ghi xong; lỗi phân bổ chỉ log lại
+		for _, item := range ret.Items {
+			if _, err := s.allocator.AllocateBackorders(ctx, item.BookID); err != nil {
+				s.logger.ErrorContext(ctx, "allocate backorders failed", "book_id", item.BookID, "err", err)
+			}
+		}
+	}
+	return ret, nil
+}
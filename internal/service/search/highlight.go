@@ -0,0 +1,56 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// Highlight HTML-escape text và bọc trong <mark> những từ mà dạng bỏ dấu bắt đầu bằng một trong các term,
+// giống cách FULLTEXT khớp prefix, vd: Highlight("Nguyễn Nhật Ánh", [nhat]) → "Nguyễn <mark>Nhật</mark> Ánh".
+func Highlight(text string, terms []string) string {
+	var b strings.Builder
+	rest := text
+	for rest != "" {
+		// Tách phần không phải chữ/số đứng trước, rồi tới một từ (kể cả dấu tổ hợp đi kèm)
+		start := strings.IndexFunc(rest, isWordPart)
+		if start < 0 {
+			b.WriteString(html.EscapeString(rest))
+			break
+		}
+		b.WriteString(html.EscapeString(rest[:start]))
+		rest = rest[start:]
+		end := strings.IndexFunc(rest, func(r rune) bool { return !isWordPart(r) })
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		if matches(textnorm.Fold(word), terms) {
+			b.WriteString(markOpen + html.EscapeString(word) + markClose)
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+	}
+	return b.String()
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func matches(word string, terms []string) bool {
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package search
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
+
+type SearchServiceInterface interface {
+	Search(ctx context.Context, filter models.SearchFilter) (*pagination.Page[*models.SearchHit], error)
+}
@@ -0,0 +1,101 @@
+package search_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/maithuc2003/re-book-api/internal/service/search"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(repo *mockrepo.MockSearchRepository) *search.SearchService {
+	return search.NewSearchService(repo, slog.New(slog.DiscardHandler))
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"nguyen", "nhat", "anh"}, search.Terms("  Nguyễn  nhật-ÁNH "))
+	assert.Equal(t, []string{"mat", "biec"}, search.Terms("Mắt biếc, mat BIEC"))
+	assert.Empty(t, search.Terms("+-*\"()"))
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		expected string
+	}{
+		{name: "Diacritic-insensitive", text: "Nguyễn Nhật Ánh", terms: []string{"nhat"}, expected: "Nguyễn <mark>Nhật</mark> Ánh"},
+		{name: "Prefix match marks the whole word", text: "Đất rừng phương Nam", terms: []string{"dat", "phu"}, expected: "<mark>Đất</mark> rừng <mark>phương</mark> Nam"},
+		{name: "Combining marks stay inside the word", text: "Tiếng Việt", terms: []string{"tieng"}, expected: "<mark>Tiếng</mark> Việt"},
+		{name: "Text is HTML-escaped", text: "<b>Go</b> & Rust", terms: []string{"go"}, expected: "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; &amp; Rust"},
+		{name: "No match", text: "Go", terms: []string{"rust"}, expected: "Go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, search.Highlight(tt.text, tt.terms))
+		})
+	}
+}
+
+func TestSearch_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.SearchFilter
+		errMsg string
+	}{
+		{name: "Missing query", filter: models.SearchFilter{Query: "  "}, errMsg: "q is required"},
+		{name: "Only punctuation", filter: models.SearchFilter{Query: "+*-"}, errMsg: "q must contain at least one letter or digit"},
+		{name: "Query too long", filter: models.SearchFilter{Query: strings.Repeat("a", 201)}, errMsg: "q must be at most 200 characters"},
+		{name: "Too many words", filter: models.SearchFilter{Query: "a b c d e f g h i"}, errMsg: "q must contain at most 8 words"},
+		{name: "Unknown type", filter: models.SearchFilter{Query: "go", Type: "order"}, errMsg: "type must be one of book, author"},
+		{name: "Sort is not supported", filter: models.SearchFilter{Query: "go", Params: pagination.Params{Sort: "title"}}, errMsg: "search results are always sorted by relevance"},
+		{name: "Limit too large", filter: models.SearchFilter{Query: "go", Params: pagination.Params{Limit: 101}}, errMsg: "limit must be between 1 and 100"},
+		{name: "Invalid cursor", filter: models.SearchFilter{Query: "go", Params: pagination.Params{Cursor: "abc"}}, errMsg: "invalid cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockSearchRepository)
+
+			_, err := newService(repo).Search(context.Background(), tt.filter)
+
+			assert.ErrorIs(t, err, apperror.ErrValidation)
+			assert.EqualError(t, err, tt.errMsg)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSearch_Pagination(t *testing.T) {
+	repo := new(mockrepo.MockSearchRepository)
+	first := models.SearchQuery{Terms: []string{"nguyen"}, Limit: 2}
+	repo.On("Search", mock.Anything, first).Return([]*models.SearchHit{
+		{Type: "author", ID: 1, Text: "Nguyễn Nhật Ánh", Score: 2.5},
+		{Type: "book", ID: 7, Text: "Nguyễn Du", Score: 1.2},
+	}, 3, nil)
+	second := models.SearchQuery{Terms: []string{"nguyen"}, Limit: 2, Offset: 2}
+	repo.On("Search", mock.Anything, second).Return([]*models.SearchHit{{Type: "book", ID: 9, Text: "Truyện Nguyễn"}}, 3, nil)
+	service := newService(repo)
+
+	page, err := service.Search(context.Background(), models.SearchFilter{Query: "Nguyễn", Params: pagination.Params{Limit: 2}})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 2)
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, "<mark>Nguyễn</mark> Nhật Ánh", page.Data[0].Highlight)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = service.Search(context.Background(), models.SearchFilter{Query: "Nguyễn", Params: pagination.Params{Limit: 2, Cursor: page.NextCursor}})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "Truyện <mark>Nguyễn</mark>", page.Data[0].Highlight)
+	assert.Empty(t, page.NextCursor)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/search"
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
+)
+
+const (
+	maxQueryLength = 200
+	maxTerms       = 8
+)
+
+type SearchService struct {
+	repo   repositories.SearchRepoInterface
+	logger *slog.Logger
+}
+
+func NewSearchService(repo repositories.SearchRepoInterface, logger *slog.Logger) *SearchService {
+	return &SearchService{repo: repo, logger: logger}
+}
+
+// Search tìm sách theo title và tác giả theo tên, không phân biệt hoa thường và dấu tiếng Việt.
+// Kết quả xếp theo độ liên quan nên cursor là vị trí (offset) trong danh sách kết quả.
+func (s *SearchService) Search(ctx context.Context, filter models.SearchFilter) (*pagination.Page[*models.SearchHit], error) {
+	q, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	hits, total, err := s.repo.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range hits {
+		hit.Highlight = Highlight(hit.Text, q.Terms)
+	}
+	page := &pagination.Page[*models.SearchHit]{Data: hits, Total: total}
+	if page.Data == nil {
+		page.Data = []*models.SearchHit{}
+	}
+	if next := q.Offset + len(hits); len(hits) == q.Limit && next < total {
+		page.NextCursor = encodeCursor(next)
+	}
+	s.logger.DebugContext(ctx, "search", "terms", q.Terms, "type", q.Type, "total", total)
+	return page, nil
+}
+
+func parseFilter(filter models.SearchFilter) (models.SearchQuery, error) {
+	q := models.SearchQuery{Type: filter.Type, Limit: filter.Limit}
+	query := strings.TrimSpace(filter.Query)
+	if query == "" {
+		return q, apperror.NewValidation("q", "q is required")
+	}
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return q, apperror.NewValidation("q", fmt.Sprintf("q must be at most %d characters", maxQueryLength))
+	}
+	q.Terms = Terms(query)
+	if len(q.Terms) == 0 {
+		return q, apperror.NewValidation("q", "q must contain at least one letter or digit")
+	}
+	if len(q.Terms) > maxTerms {
+		return q, apperror.NewValidation("q", fmt.Sprintf("q must contain at most %d words", maxTerms))
+	}
+	if q.Type != "" && q.Type != models.SearchTypeBook && q.Type != models.SearchTypeAuthor {
+		return q, apperror.NewValidation("type", "type must be one of book, author")
+	}
+	if filter.Sort != "" {
+		return q, apperror.NewValidation("sort", "search results are always sorted by relevance")
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = pagination.DefaultLimit
+	case q.Limit < 0 || q.Limit > pagination.MaxLimit:
+		return q, apperror.NewValidation("limit", fmt.Sprintf("limit must be between 1 and %d", pagination.MaxLimit))
+	}
+	if filter.Cursor != "" {
+		offset, ok := decodeCursor(filter.Cursor)
+		if !ok {
+			return q, apperror.NewValidation("cursor", "invalid cursor")
+		}
+		q.Offset = offset
+	}
+	return q, nil
+}
+
+// Terms tách query thành các từ đã bỏ dấu, bỏ từ trùng, vd: "Nguyễn  nhật-ánh" → [nguyen nhat anh].
+func Terms(query string) []string {
+	var terms []string
+	words := strings.FieldsFunc(textnorm.Fold(query), func(r rune) bool { return !isWordPart(r) })
+	for _, t := range words {
+		if !slices.Contains(terms, t) {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset <= 0 {
+		return 0, false
+	}
+	return offset, true
+}
@@ -0,0 +1,14 @@
+package stock
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+)
+
+type StockServiceInterface interface {
+	GetMovements(ctx context.Context, filter models.StockMovementFilter) (*pagination.Page[*models.StockMovement], error)
+	MoveStock(ctx context.Context, movement *models.StockMovement) error
+	Reconcile(ctx context.Context) ([]models.StockDrift, error)
+}
@@ -0,0 +1,89 @@
+package stock
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	repositories "github.com/maithuc2003/re-book-api/internal/repositories/stock"
+)
+
+// BackorderAllocator phân bổ hàng vừa nhập cho các order đang chờ (vd: *order.OrderService).
+type BackorderAllocator interface {
+	AllocateBackorders(ctx context.Context, bookID int) ([]int, error)
+}
+
+type StockService struct {
+	repo   repositories.StockRepoInterface
+	logger *slog.Logger
+	// allocator có thể nil khi chỉ dùng để đọc/đối soát (vd: subcommand reconcile-stock)
+	allocator BackorderAllocator
+}
+
+func NewStockService(repo repositories.StockRepoInterface, logger *slog.Logger, allocator BackorderAllocator) *StockService {
+	return &StockService{repo: repo, logger: logger, allocator: allocator}
+}
+
+// GetMovements kiểm tra filter trước khi lấy lịch sử tồn kho của một sách
+func (s *StockService) GetMovements(ctx context.Context, filter models.StockMovementFilter) (*pagination.Page[*models.StockMovement], error) {
+	if filter.BookID <= 0 {
+		return nil, apperror.NewValidation("id", "invalid book ID")
+	}
+	filter.Reason = strings.ToLower(strings.TrimSpace(filter.Reason))
+	if filter.Reason != "" && !isStockReason(filter.Reason) {
+		return nil, apperror.NewValidation("reason", "unknown stock movement reason")
+	}
+	return s.repo.GetMovements(ctx, filter)
+}
+
+// MoveStock ghi một lần nhập hàng / chỉnh tay / kiểm kê; order và cancellation chỉ do order ghi.
+func (s *StockService) MoveStock(ctx context.Context, movement *models.StockMovement) error {
+	if movement == nil {
+		return apperror.NewValidation("", "stock movement is nil")
+	}
+	if movement.BookID <= 0 {
+		return apperror.NewValidation("id", "invalid book ID")
+	}
+	movement.Reason = strings.ToLower(strings.TrimSpace(movement.Reason))
+	if !slices.Contains(models.ManualStockReasons, movement.Reason) {
+		return apperror.NewValidation("reason", "reason must be one of "+strings.Join(models.ManualStockReasons, ", "))
+	}
+	if movement.Delta == 0 {
+		return apperror.NewValidation("delta", "delta must not be zero")
+	}
+	if movement.Reason == models.StockRestock && movement.Delta < 0 {
+		return apperror.NewValidation("delta", "restock delta must be positive")
+	}
+	if len(movement.Note) > 255 {
+		return apperror.NewValidation("note", "note must be at most 255 characters")
+	}
+	movement.OrderID = nil
+	movement.CreatedAt = time.Now()
+
+	if err := s.repo.Move(ctx, movement); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "stock moved", "book_id", movement.BookID, "delta", movement.Delta, "reason", movement.Reason, "stock", movement.StockAfter)
+	// Stock đã ghi xong; lỗi phân bổ chỉ log lại, lần nhập hàng sau sẽ thử lại
+	if movement.Delta > 0 && s.allocator != nil {
+		if _, err := s.allocator.AllocateBackorders(ctx, movement.BookID); err != nil {
+			s.logger.ErrorContext(ctx, "allocate backorders failed", "book_id", movement.BookID, "err", err)
+		}
+	}
+	return nil
+}
+
+// Reconcile trả về các sách có stock lệch với sổ cái.
+func (s *StockService) Reconcile(ctx context.Context) ([]models.StockDrift, error) {
+	return s.repo.Reconcile(ctx)
+}
+
+func isStockReason(reason string) bool {
+	return reason == models.StockOrder || reason == models.StockCancellation || reason == models.StockReturn ||
+		slices.Contains(models.ManualStockReasons, reason)
+}
@@ -0,0 +1,81 @@
+package stock_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/apperror"
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/service/stock"
+	"github.com/maithuc2003/re-book-api/test/mockrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveStock(t *testing.T) {
+	orderID := 3
+	tests := []struct {
+		name        string
+		movement    *models.StockMovement
+		expectedErr string
+	}{
+		{name: "Restock", movement: &models.StockMovement{BookID: 1, Delta: 5, Reason: " Restock "}},
+		{name: "Stocktake can lower stock", movement: &models.StockMovement{BookID: 1, Delta: -2, Reason: "stocktake"}},
+		{name: "Order id from client is ignored", movement: &models.StockMovement{BookID: 1, Delta: 1, Reason: "adjustment", OrderID: &orderID}},
+		{name: "Invalid book", movement: &models.StockMovement{Delta: 1, Reason: "restock"}, expectedErr: "invalid book ID"},
+		{name: "Order reasons are reserved", movement: &models.StockMovement{BookID: 1, Delta: -1, Reason: "order"}, expectedErr: "reason must be one of restock, adjustment, stocktake"},
+		{name: "Zero delta", movement: &models.StockMovement{BookID: 1, Reason: "adjustment"}, expectedErr: "delta must not be zero"},
+		{name: "Negative restock", movement: &models.StockMovement{BookID: 1, Delta: -1, Reason: "restock"}, expectedErr: "restock delta must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockrepo.MockStockRepository)
+			if tt.expectedErr == "" {
+				repo.On("Move", mock.Anything, tt.movement).Return(nil)
+			}
+
+			err := stock.NewStockService(repo, slog.New(slog.DiscardHandler), nil).MoveStock(context.Background(), tt.movement)
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, apperror.ErrValidation)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Nil(t, tt.movement.OrderID)
+				assert.False(t, tt.movement.CreatedAt.IsZero())
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMovements_UnknownReason(t *testing.T) {
+	svc := stock.NewStockService(new(mockrepo.MockStockRepository), slog.New(slog.DiscardHandler), nil)
+
+	_, err := svc.GetMovements(context.Background(), models.StockMovementFilter{BookID: 1, Reason: "theft"})
+
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+}
+
+// fakeAllocator ghi lại các sách được yêu cầu phân bổ backorder
+type fakeAllocator struct{ books []int }
+
+func (a *fakeAllocator) AllocateBackorders(_ context.Context, bookID int) ([]int, error) {
+	a.books = append(a.books, bookID)
+	return nil, nil
+}
+
+func TestMoveStock_AllocatesBackorders(t *testing.T) {
+	repo := new(mockrepo.MockStockRepository)
+	repo.On("Move", mock.Anything, mock.Anything).Return(nil)
+	allocator := &fakeAllocator{}
+	svc := stock.NewStockService(repo, slog.New(slog.DiscardHandler), allocator)
+
+	require.NoError(t, svc.MoveStock(context.Background(), &models.StockMovement{BookID: 1, Delta: 5, Reason: "restock"}))
+	require.NoError(t, svc.MoveStock(context.Background(), &models.StockMovement{BookID: 2, Delta: -1, Reason: "adjustment"}))
+
+	// Chỉ lần tăng stock mới phân bổ cho order đang chờ
+	assert.Equal(t, []int{1}, allocator.books)
+}
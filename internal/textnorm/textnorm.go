@@ -0,0 +1,63 @@
+// Package textnorm chuẩn hoá chuỗi tiếng Việt: bỏ dấu và tạo slug.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// folds ánh xạ chữ có dấu (đã viết thường) về chữ không dấu.
+var folds = func() map[rune]rune {
+	groups := map[rune]string{
+		'a': "àáảãạăằắẳẵặâầấẩẫậ",
+		'e': "èéẻẽẹêềếểễệ",
+		'i': "ìíỉĩị",
+		'o': "òóỏõọôồốổỗộơờớởỡợ",
+		'u': "ùúủũụưừứửữự",
+		'y': "ỳýỷỹỵ",
+		'd': "đ",
+	}
+	m := make(map[rune]rune)
+	for base, variants := range groups {
+		for _, r := range variants {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// Fold viết thường và bỏ dấu tiếng Việt, vd: "Đất Rừng Phương Nam" → "dat rung phuong nam".
+// Dấu viết dạng tổ hợp (NFD) cũng được bỏ.
+func Fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if base, ok := folds[r]; ok {
+			r = base
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Slug tạo slug chỉ gồm a-z, 0-9 và dấu gạch, vd: "Văn học Việt Nam" → "van-hoc-viet-nam".
+func Slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range Fold(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package textnorm_test
+
+import (
+	"testing"
+
+	"github.com/maithuc2003/re-book-api/internal/textnorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Đất Rừng Phương Nam", expected: "dat rung phuong nam"},
+		{input: "NGƯỜI LÁI ĐÒ SÔNG ĐÀ", expected: "nguoi lai do song da"},
+		// "ế" viết dạng tổ hợp: e + dấu mũ + dấu sắc
+		{input: "Tie\u0302\u0301ng Vie\u0323\u0302t", expected: "tieng viet"},
+		{input: "Go 101", expected: "go 101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, textnorm.Fold(tt.input))
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Văn học Việt Nam", expected: "van-hoc-viet-nam"},
+		{input: "  Sách thiếu nhi -- (0-6 tuổi)  ", expected: "sach-thieu-nhi-0-6-tuoi"},
+		{input: "Khoa học & Công nghệ", expected: "khoa-hoc-cong-nghe"},
+		{input: "!!!", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, textnorm.Slug(tt.input))
+		})
+	}
+}
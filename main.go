@@ -1,35 +1,192 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/maithuc2003/re-book-api/internal/db"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/config"
+	"github.com/maithuc2003/re-book-api/internal/db"
+	"github.com/maithuc2003/re-book-api/internal/health"
+	"github.com/maithuc2003/re-book-api/internal/logging"
+	"github.com/maithuc2003/re-book-api/internal/metrics"
+	"github.com/maithuc2003/re-book-api/internal/middleware"
+	"github.com/maithuc2003/re-book-api/internal/migrations"
 	server_author "github.com/maithuc2003/re-book-api/internal/server/author"
 	server_book "github.com/maithuc2003/re-book-api/internal/server/book"
+	server_category "github.com/maithuc2003/re-book-api/internal/server/category"
+	server_coupon "github.com/maithuc2003/re-book-api/internal/server/coupon"
 	server_order "github.com/maithuc2003/re-book-api/internal/server/order"
+	server_publisher "github.com/maithuc2003/re-book-api/internal/server/publisher"
+	server_reservation "github.com/maithuc2003/re-book-api/internal/server/reservation"
+	server_returns "github.com/maithuc2003/re-book-api/internal/server/returns"
+	server_search "github.com/maithuc2003/re-book-api/internal/server/search"
+	server_stock "github.com/maithuc2003/re-book-api/internal/server/stock"
 )
+
 func main() {
-	// sdq
-	conn, err := db.NewMySQLConnection() // nhận biến conn và err
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		// xử lý lỗi, ví dụ in ra và thoát
-		fmt.Println("Failed to connect:", err)
+		fmt.Fprintln(os.Stderr, err)
+		config.Usage(os.Stderr)
+		os.Exit(2)
+	}
+	logger := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	// log.Printf còn sót (vd: từ thư viện) cũng đi qua slog
+	slog.SetDefault(logger)
+
+	if len(cfg.Args) > 0 && cfg.Args[0] == "migrate" {
+		if err := runMigrate(cfg, logger, cfg.Args[1:]); err != nil {
+			logger.Error("migrate failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(cfg.Args) > 0 && cfg.Args[0] == "reconcile-stock" {
+		if err := runReconcileStock(cfg, logger); err != nil {
+			logger.Error("reconcile stock failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(cfg.Args) > 0 && cfg.Args[0] == "reindex-search" {
+		if err := runReindexSearch(cfg, logger); err != nil {
+			logger.Error("reindex search failed", "err", err)
+			os.Exit(1)
+		}
 		return
 	}
-	// OK đó
-	defer conn.Close() // gọi đóng kết nối khi main kết thúc
+	if err := run(cfg, logger); err != nil {
+		logger.Error("server exited with error", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, logger *slog.Logger) error {
+	conn, err := db.NewMySQLConnection(cfg.DB) // nhận biến conn và err
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	// Đóng kết nối DB sau khi server đã drain xong request
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Error("failed to close database", "err", err)
+		}
+	}()
+
+	if cfg.MigrateOnStart {
+		if err := migrateUp(context.Background(), conn.DB, logger); err != nil {
+			return err
+		}
+	}
+
+	m := metrics.New(conn.DB, cfg.LowStockThreshold, logger)
+
+	migrator, err := migrations.New(conn.DB)
+	if err != nil {
+		return err
+	}
+	checks := []health.Check{health.DB(conn.DB), health.Migrations(migrator)}
+	if cfg.RedisAddr != "" {
+		checks = append(checks, health.Redis(cfg.RedisAddr))
+	}
+	probes := health.New(cfg.Health.ReadinessTimeout, checks...)
 
 	// Route api
 	mux := http.NewServeMux()
-	server_book.SetupServerBook(mux, conn.DB)
-	server_order.SetupOrderServer(mux, conn.DB)
-	server_author.SetupServerAuthor(mux, conn.DB)
-
-	// Port
-	log.Println("Server started at", os.Getenv("PORT"))
-	if err := http.ListenAndServe(":"+os.Getenv("PORT"), mux); err != nil {
-		log.Fatal(err)
+	orders := server_order.SetupOrderServer(mux, conn.DB, logger, m, cfg.IdempotencyTTL)
+	server_book.SetupServerBook(mux, conn.DB, logger, orders)
+	server_author.SetupServerAuthor(mux, conn.DB, logger)
+	server_stock.SetupServerStock(mux, conn.DB, logger, orders)
+	server_returns.SetupServerReturns(mux, conn.DB, logger, orders)
+	server_coupon.SetupServerCoupon(mux, conn.DB, logger)
+	server_category.SetupServerCategory(mux, conn.DB, logger)
+	server_publisher.SetupServerPublisher(mux, conn.DB, logger)
+	server_search.SetupServerSearch(mux, conn.DB, logger)
+	reservations := server_reservation.SetupServerReservation(mux, conn.DB, logger, orders, cfg.Reservation.TTL, cfg.Reservation.MaxTTL)
+	mux.Handle("GET /metrics", m.Handler())
+	mux.HandleFunc("GET /healthz", probes.Liveness)
+	mux.HandleFunc("GET /readyz", probes.Readiness)
+
+	// AccessLog và Instrument bọc trực tiếp mux để đọc được route pattern đã match
+	var handler http.Handler = middleware.Instrument(m)(mux)
+	handler = middleware.AccessLog(logger)(handler)
+	handler = middleware.Timeout(cfg.RequestTimeout)(handler)
+	handler = middleware.RequestID(handler)
+
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           handler,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go runPeriodically(ctx, time.Hour, "purge idempotency keys", orders.PurgeIdempotencyKeys, logger)
+	go runPeriodically(ctx, cfg.Reservation.SweepInterval, "expire reservations", reservations.ExpireStale, logger)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server started", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // nhận tín hiệu lần 2 sẽ kill process ngay
+
+	// Báo /readyz = 503 và vẫn phục vụ bình thường một lúc, để load balancer ngừng route vào đây
+	// trước khi server đóng listener
+	probes.SetDraining()
+	if cfg.Health.DrainDelay > 0 {
+		logger.Info("draining", "delay", cfg.Health.DrainDelay)
+		time.Sleep(cfg.Health.DrainDelay)
+	}
+
+	// Ngừng nhận kết nối mới, chờ các request (vd: transaction tạo order) chạy xong
+	logger.Info("shutting down server", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	logger.Info("server stopped")
+	return nil
+}
+
+// runPeriodically chạy job mỗi interval cho tới khi ctx bị huỷ; job trả về số row đã xử lý.
+func runPeriodically(ctx context.Context, interval time.Duration, name string, job func(context.Context) (int64, error), logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := job(ctx)
+			if err != nil {
+				logger.Error(name+" failed", "err", err)
+				continue
+			}
+			logger.Debug(name+" done", "count", n)
+		}
 	}
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/maithuc2003/re-book-api/config"
+	"github.com/maithuc2003/re-book-api/internal/db"
+	"github.com/maithuc2003/re-book-api/internal/migrations"
+)
+
+const migrateUsage = "usage: re-book-api migrate up | down | status | to N"
+
+// runMigrate xử lý subcommand "migrate": up, down, status, to N.
+func runMigrate(cfg *config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	conn, err := db.NewMySQLConnection(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	migrator, err := migrations.New(conn.DB)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(ctx, conn.DB, logger)
+	case "down":
+		done, err := migrator.Down(ctx)
+		logMigrations(logger, "migration rolled back", done)
+		return err
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		done, err := migrator.To(ctx, version)
+		logMigrations(logger, "migration executed", done)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		w.Flush()
+		return err
+	}
+	return errors.New(migrateUsage)
+}
+
+// migrateUp chạy tất cả migration còn thiếu, dùng cho cả "migrate up" và MIGRATE_ON_START.
+func migrateUp(ctx context.Context, conn *sql.DB, logger *slog.Logger) error {
+	migrator, err := migrations.New(conn)
+	if err != nil {
+		return err
+	}
+	done, err := migrator.Up(ctx)
+	logMigrations(logger, "migration applied", done)
+	if err != nil {
+		return fmt.Errorf("migrate up failed: %w", err)
+	}
+	return nil
+}
+
+func logMigrations(logger *slog.Logger, msg string, done []migrations.Migration) {
+	for _, m := range done {
+		logger.Info(msg, "version", m.Version, "name", m.Name)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/maithuc2003/re-book-api/config"
+	"github.com/maithuc2003/re-book-api/internal/db"
+	stockRepo "github.com/maithuc2003/re-book-api/internal/repositories/stock"
+	stockService "github.com/maithuc2003/re-book-api/internal/service/stock"
+)
+
+// runReconcileStock xử lý subcommand "reconcile-stock": tính lại stock từ sổ cái
+// và in các sách bị lệch. Trả về lỗi (exit code 1) khi có lệch để cron/CI bắt được.
+func runReconcileStock(cfg *config.Config, logger *slog.Logger) error {
+	conn, err := db.NewMySQLConnection(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	service := stockService.NewStockService(stockRepo.NewStockRepo(conn.DB, logger), logger, nil)
+	drifts, err := service.Reconcile(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		logger.Info("stock matches ledger")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BOOK ID\tSTOCK\tLEDGER\tDRIFT")
+	for _, d := range drifts {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%+d\n", d.BookID, d.Stock, d.LedgerStock, d.Drift)
+	}
+	w.Flush()
+	return fmt.Errorf("stock drifted from ledger for %d book(s)", len(drifts))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/maithuc2003/re-book-api/config"
+	"github.com/maithuc2003/re-book-api/internal/db"
+	searchRepo "github.com/maithuc2003/re-book-api/internal/repositories/search"
+)
+
+// runReindexSearch xử lý subcommand "reindex-search": tính lại title/tên không dấu dùng cho
+// FULLTEXT search. Cần chạy một lần sau migration 0018 vì SQL không bỏ dấu tiếng Việt được.
+func runReindexSearch(cfg *config.Config, logger *slog.Logger) error {
+	conn, err := db.NewMySQLConnection(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	n, err := searchRepo.NewSearchRepo(conn.DB, logger).Reindex(context.Background())
+	if err != nil {
+		return err
+	}
+	logger.Info("search index up to date", "updated", n)
+	return nil
+}
@@ -1,7 +1,10 @@
 package mockrepo
 
 import (
+	"context"
+
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -9,37 +12,37 @@ type MockAuthorRepository struct {
 	mock.Mock
 }
 
-func (m *MockAuthorRepository) GetAllAuthors() ([]*models.Author, error) {
-	args := m.Called()
+func (m *MockAuthorRepository) GetAllAuthors(ctx context.Context, filter models.AuthorFilter) (*pagination.Page[*models.Author], error) {
+	args := m.Called(ctx, filter)
 	if args.Get(0) != nil {
-		return args.Get(0).([]*models.Author), args.Error(1)
+		return args.Get(0).(*pagination.Page[*models.Author]), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockAuthorRepository) GetByAuthorID(id int) (*models.Author, error) {
-	args := m.Called(id)
+func (m *MockAuthorRepository) GetByAuthorID(ctx context.Context, id int) (*models.Author, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.Author), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockAuthorRepository) CreateAuthor(author *models.Author) error {
-	args := m.Called(author)
+func (m *MockAuthorRepository) CreateAuthor(ctx context.Context, author *models.Author) error {
+	args := m.Called(ctx, author)
 	return args.Error(0)
 }
 
-func (m *MockAuthorRepository) UpdateById(author *models.Author) (*models.Author, error) {
-	args := m.Called(author)
+func (m *MockAuthorRepository) UpdateById(ctx context.Context, author *models.Author) (*models.Author, error) {
+	args := m.Called(ctx, author)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.Author), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockAuthorRepository) DeleteById(id int) (*models.Author, error) {
-	args := m.Called(id)
+func (m *MockAuthorRepository) DeleteById(ctx context.Context, id int) (*models.Author, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.Author), args.Error(1)
 	}
@@ -0,0 +1,66 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBookRepository struct {
+	mock.Mock
+}
+
+func (m *MockBookRepository) Create(ctx context.Context, book *models.Book) error {
+	args := m.Called(ctx, book)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.Book]), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetByBookID(ctx context.Context, id int) (*models.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	args := m.Called(ctx, isbn)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) GetByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error) {
+	args := m.Called(ctx, authorID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) DeleteById(ctx context.Context, id int) (*models.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookRepository) UpdateById(ctx context.Context, book *models.Book) (*models.Book, int, error) {
+	args := m.Called(ctx, book)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Int(1), args.Error(2)
+	}
+	return nil, args.Int(1), args.Error(2)
+}
@@ -0,0 +1,57 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) Create(ctx context.Context, c *models.Category) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) GetAll(ctx context.Context) ([]*models.Category, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) Update(ctx context.Context, c *models.Category) (*models.Category, error) {
+	args := m.Called(ctx, c)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryRepository) DeleteByID(ctx context.Context, id int) (*models.Category, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Category), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,41 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCouponRepository struct {
+	mock.Mock
+}
+
+func (m *MockCouponRepository) Create(ctx context.Context, c *models.Coupon) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockCouponRepository) GetByID(ctx context.Context, id int) (*models.Coupon, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Coupon), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCouponRepository) GetAll(ctx context.Context) ([]*models.Coupon, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Coupon), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCouponRepository) DeleteByID(ctx context.Context, id int) (*models.Coupon, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Coupon), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
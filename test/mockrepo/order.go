@@ -0,0 +1,82 @@
+package mockrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrderRepository) GetByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.Order]), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error) {
+	args := m.Called(ctx, order)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) DeleteByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) Create(ctx context.Context, order *models.Order) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepository) UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error {
+	args := m.Called(ctx, id, from, to, updatedAt)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepository) CreateIdempotent(ctx context.Context, order *models.Order, key models.IdempotencyKey) (bool, error) {
+	args := m.Called(ctx, order, key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrderRepository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOrderRepository) ConfirmReservation(ctx context.Context, reservationID int, order *models.Order) (*models.Reservation, error) {
+	args := m.Called(ctx, reservationID, order)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Reservation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) AllocateBackorders(ctx context.Context, bookID int, at time.Time) ([]int, error) {
+	args := m.Called(ctx, bookID, at)
+	if args.Get(0) != nil {
+		return args.Get(0).([]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
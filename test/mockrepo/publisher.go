@@ -0,0 +1,49 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPublisherRepository struct {
+	mock.Mock
+}
+
+func (m *MockPublisherRepository) Create(ctx context.Context, p *models.Publisher) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockPublisherRepository) GetAll(ctx context.Context) ([]*models.Publisher, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Publisher), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPublisherRepository) GetByID(ctx context.Context, id int) (*models.Publisher, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Publisher), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPublisherRepository) UpdateByID(ctx context.Context, p *models.Publisher) (*models.Publisher, error) {
+	args := m.Called(ctx, p)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Publisher), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPublisherRepository) DeleteByID(ctx context.Context, id int) (*models.Publisher, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Publisher), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,42 @@
+package mockrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReservationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReservationRepository) Reserve(ctx context.Context, r *models.Reservation) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Reservation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReservationRepository) Release(ctx context.Context, id int, at time.Time) (*models.Reservation, error) {
+	args := m.Called(ctx, id, at)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Reservation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReservationRepository) ExpireStale(ctx context.Context, now time.Time) (int64, []int, error) {
+	args := m.Called(ctx, now)
+	if args.Get(1) != nil {
+		return args.Get(0).(int64), args.Get(1).([]int), args.Error(2)
+	}
+	return args.Get(0).(int64), nil, args.Error(2)
+}
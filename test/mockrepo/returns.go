@@ -0,0 +1,58 @@
+package mockrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReturnRepository struct {
+	mock.Mock
+}
+
+func (m *MockReturnRepository) Create(ctx context.Context, ret *models.Return) error {
+	args := m.Called(ctx, ret)
+	return args.Error(0)
+}
+
+func (m *MockReturnRepository) GetByID(ctx context.Context, id int) (*models.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnRepository) ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnRepository) ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Refund), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnRepository) UpdateStatus(ctx context.Context, id int, from, to string, at time.Time) (*models.Return, error) {
+	args := m.Called(ctx, id, from, to, at)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnRepository) Receive(ctx context.Context, id int, restock bool, at time.Time) (*models.Return, error) {
+	args := m.Called(ctx, id, restock, at)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
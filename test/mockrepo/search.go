@@ -0,0 +1,25 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearchRepository struct {
+	mock.Mock
+}
+
+func (m *MockSearchRepository) Search(ctx context.Context, q models.SearchQuery) ([]*models.SearchHit, int, error) {
+	args := m.Called(ctx, q)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.SearchHit), args.Int(1), args.Error(2)
+	}
+	return nil, args.Int(1), args.Error(2)
+}
+
+func (m *MockSearchRepository) Reindex(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
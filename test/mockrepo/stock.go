@@ -0,0 +1,34 @@
+package mockrepo
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStockRepository struct {
+	mock.Mock
+}
+
+func (m *MockStockRepository) GetMovements(ctx context.Context, filter models.StockMovementFilter) (*pagination.Page[*models.StockMovement], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.StockMovement]), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStockRepository) Move(ctx context.Context, movement *models.StockMovement) error {
+	args := m.Called(ctx, movement)
+	return args.Error(0)
+}
+
+func (m *MockStockRepository) Reconcile(ctx context.Context) ([]models.StockDrift, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.StockDrift), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -1,7 +1,10 @@
 package mockservice
 
 import (
+	"context"
+
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -9,28 +12,30 @@ type MockAuthorService struct {
 	mock.Mock
 }
 
-func (m *MockAuthorService) GetAllAuthors() ([]*models.Author, error) {
-	args := m.Called()
-	return args.Get(0).([]*models.Author), args.Error(1)
+func (m *MockAuthorService) GetAllAuthors(ctx context.Context, filter models.AuthorFilter) (*pagination.Page[*models.Author], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.Author]), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-
-func (m *MockAuthorService) GetByAuthorID(id int) (*models.Author, error) {
-	args := m.Called(id)
+func (m *MockAuthorService) GetByAuthorID(ctx context.Context, id int) (*models.Author, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Author), args.Error(1)
 }
 
-func (m *MockAuthorService) CreateAuthor(author *models.Author) error {
-	args := m.Called(author)
+func (m *MockAuthorService) CreateAuthor(ctx context.Context, author *models.Author) error {
+	args := m.Called(ctx, author)
 	return args.Error(0)
 }
 
-func (m *MockAuthorService) DeleteById(id int) (*models.Author, error) {
-	args := m.Called(id)
+func (m *MockAuthorService) DeleteById(ctx context.Context, id int) (*models.Author, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Author), args.Error(1)
 }
 
-func (m *MockAuthorService) UpdateById(author *models.Author) (*models.Author, error) {
-	args := m.Called(author)
+func (m *MockAuthorService) UpdateById(ctx context.Context, author *models.Author) (*models.Author, error) {
+	args := m.Called(ctx, author)
 	return args.Get(0).(*models.Author), args.Error(1)
-}
\ No newline at end of file
+}
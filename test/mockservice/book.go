@@ -1,7 +1,10 @@
 package mockservice
 
 import (
+	"context"
+
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -9,27 +12,43 @@ type MockBookService struct {
 	mock.Mock
 }
 
-func (m *MockBookService) CreateBook(book *models.Book) error {
-	args := m.Called(book)
+func (m *MockBookService) CreateBook(ctx context.Context, book *models.Book) error {
+	args := m.Called(ctx, book)
 	return args.Error(0)
 }
 
-func (m *MockBookService) GetAllBooks() ([]*models.Book, error) {
-	args := m.Called()
-	return args.Get(0).([]*models.Book), args.Error(1)
+func (m *MockBookService) GetAllBooks(ctx context.Context, filter models.BookFilter) (*pagination.Page[*models.Book], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.Book]), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockBookService) GetByBookID(id int) (*models.Book, error) {
-	args := m.Called(id)
+func (m *MockBookService) GetByBookID(ctx context.Context, id int) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookService) DeleteById(id int) (*models.Book, error) {
-	args := m.Called(id)
+func (m *MockBookService) GetByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	args := m.Called(ctx, isbn)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Book), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBookService) GetBooksByAuthorID(ctx context.Context, authorID int) ([]*models.Book, error) {
+	args := m.Called(ctx, authorID)
+	return args.Get(0).([]*models.Book), args.Error(1)
+}
+
+func (m *MockBookService) DeleteById(ctx context.Context, id int) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookService) UpdateById(book *models.Book) (*models.Book, error) {
-	args := m.Called(book)
+func (m *MockBookService) UpdateById(ctx context.Context, book *models.Book) (*models.Book, error) {
+	args := m.Called(ctx, book)
 	return args.Get(0).(*models.Book), args.Error(1)
 }
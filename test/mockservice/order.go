@@ -1,8 +1,11 @@
-package  mockservice
+package mockservice
 
 import (
-	"github.com/stretchr/testify/mock"
+	"context"
+
 	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/maithuc2003/re-book-api/internal/pagination"
+	"github.com/stretchr/testify/mock"
 )
 
 // MockOrderService mocks the OrderServiceInterface
@@ -10,27 +13,51 @@ type MockOrderService struct {
 	mock.Mock
 }
 
-func (m *MockOrderService) CreateOrder(order *models.Order) error {
-	args := m.Called(order)
+func (m *MockOrderService) CreateOrder(ctx context.Context, order *models.Order) error {
+	args := m.Called(ctx, order)
 	return args.Error(0)
 }
 
-func (m *MockOrderService) GetAllOrders() ([]*models.Order, error) {
-	args := m.Called()
-	return args.Get(0).([]*models.Order), args.Error(1)
+func (m *MockOrderService) GetAllOrders(ctx context.Context, filter models.OrderFilter) (*pagination.Page[*models.Order], error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*pagination.Page[*models.Order]), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockOrderService) GetByOrderID(id int) (*models.Order, error) {
-	args := m.Called(id)
+func (m *MockOrderService) GetByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
-func (m *MockOrderService) DeleteByOrderID(id int) (*models.Order, error) {
-	args := m.Called(id)
+func (m *MockOrderService) DeleteByOrderID(ctx context.Context, id int) (*models.Order, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
-func (m *MockOrderService) UpdateByOrderID(order *models.Order) (*models.Order, error) {
-	args := m.Called(order)
+func (m *MockOrderService) UpdateByOrderID(ctx context.Context, order *models.Order) (*models.Order, error) {
+	args := m.Called(ctx, order)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
+
+func (m *MockOrderService) TransitionOrder(ctx context.Context, id int, to string) (*models.Order, error) {
+	args := m.Called(ctx, id, to)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderService) CreateOrderIdempotent(ctx context.Context, order *models.Order, key string) (bool, error) {
+	args := m.Called(ctx, order, key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrderService) ConfirmReservation(ctx context.Context, reservationID int) (*models.Order, error) {
+	args := m.Called(ctx, reservationID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
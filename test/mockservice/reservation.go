@@ -0,0 +1,33 @@
+package mockservice
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReservationService struct {
+	mock.Mock
+}
+
+func (m *MockReservationService) Reserve(ctx context.Context, reservation *models.Reservation) error {
+	args := m.Called(ctx, reservation)
+	return args.Error(0)
+}
+
+func (m *MockReservationService) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Reservation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReservationService) Release(ctx context.Context, id int) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Reservation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
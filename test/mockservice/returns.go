@@ -0,0 +1,65 @@
+package mockservice
+
+import (
+	"context"
+
+	"github.com/maithuc2003/re-book-api/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReturnService struct {
+	mock.Mock
+}
+
+func (m *MockReturnService) RequestReturn(ctx context.Context, ret *models.Return) error {
+	args := m.Called(ctx, ret)
+	return args.Error(0)
+}
+
+func (m *MockReturnService) GetByID(ctx context.Context, id int) (*models.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnService) ListByOrder(ctx context.Context, orderID int) ([]*models.Return, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnService) ListRefunds(ctx context.Context, orderID int) ([]*models.Refund, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*models.Refund), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnService) Approve(ctx context.Context, id int) (*models.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnService) Reject(ctx context.Context, id int) (*models.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReturnService) Receive(ctx context.Context, id int, restock bool) (*models.Return, error) {
+	args := m.Called(ctx, id, restock)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Return), args.Error(1)
+	}
+	return nil, args.Error(1)
+}